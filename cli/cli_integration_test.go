@@ -2,11 +2,16 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -23,7 +28,7 @@ func TestCLIBasicRun(t *testing.T) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0")
+	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0", "-checkpoint", filepath.Join(t.TempDir(), "checkpoint.log"))
 	out, err := cmd.CombinedOutput()
 	if ctx.Err() == context.DeadlineExceeded {
 		t.Fatalf("cli run timed out output=%s", string(out))
@@ -53,7 +58,7 @@ func TestCLIMetricsAndHealth(t *testing.T) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0", "-enable-metrics", "-metrics", ":19111", "-health", ":19112")
+	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0", "-checkpoint", filepath.Join(t.TempDir(), "checkpoint.log"), "-enable-metrics", "-metrics", ":19111", "-health", ":19112")
 	out, err := cmd.CombinedOutput()
 	if ctx.Err() == context.DeadlineExceeded {
 		t.Fatalf("cli metrics/health run timed out output=%s", string(out))
@@ -72,3 +77,104 @@ func TestCLIMetricsAndHealth(t *testing.T) {
 		t.Fatalf("expected final snapshot marker, output=%s", o)
 	}
 }
+
+// TestCLIDiffRuns records two runs with -manifest-dir and compares them with the diff
+// subcommand.
+func TestCLIDiffRuns(t *testing.T) {
+	var second atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		link := "/old"
+		if second.Load() {
+			link = "/new"
+		}
+		// The link sits in navigation, which content processing drops, so the root
+		// page's content is the same in both runs.
+		_, _ = w.Write([]byte(`<html><body><nav><a href="` + link + `">l</a></nav><main><p>Hello</p></main></body></html>`))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	crawl := func(id string) {
+		t.Helper()
+		out, err := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0", "-checkpoint", filepath.Join(t.TempDir(), "checkpoint.log"), "-max-depth", "1", "-manifest-dir", dir, "-run-id", id).CombinedOutput()
+		if err != nil {
+			t.Fatalf("crawl %s: %v output=%s", id, err, out)
+		}
+	}
+	crawl("one")
+	second.Store(true)
+	crawl("two")
+
+	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "diff", "-format", "json", "-exit-code", filepath.Join(dir, "one"), filepath.Join(dir, "two"))
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected exit status 1 for differing runs, got %v output=%s", err, out)
+	}
+	var report struct {
+		Added, Removed []struct{ URL string }
+		Unchanged      int
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("decode report: %v output=%s", err, out)
+	}
+	if len(report.Added) != 1 || report.Added[0].URL != srv.URL+"/new" || len(report.Removed) != 1 || report.Removed[0].URL != srv.URL+"/old" || report.Unchanged != 1 {
+		t.Fatalf("unexpected report: %s", out)
+	}
+}
+
+// TestCLIReplayKeepsCheckpoint replays a dead letter into the checkpoint of the crawl
+// that recorded it; the crawl's records must survive.
+func TestCLIReplayKeepsCheckpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><a href="/missing">m</a></body></html>`))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	checkpoint, deadLetters := filepath.Join(dir, "checkpoint.log"), filepath.Join(dir, "dead-letters.jsonl")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "-seeds", srv.URL, "-snapshot-interval", "0", "-checkpoint", checkpoint, "-dead-letter", deadLetters, "-max-depth", "1").CombinedOutput(); err != nil {
+		t.Fatalf("crawl: %v output=%s", err, out)
+	}
+	succeeded := func() map[string]bool {
+		t.Helper()
+		data, err := os.ReadFile(checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := map[string]bool{}
+		for _, line := range strings.Split(string(data), "\n") {
+			var rec struct{ Op, Key string }
+			if json.Unmarshal([]byte(line), &rec) == nil && rec.Op == "success" {
+				keys[rec.Key] = true
+			}
+		}
+		return keys
+	}
+	before := succeeded()
+	if len(before) == 0 {
+		t.Fatal("expected the crawl to record a finished URL")
+	}
+
+	out, err := exec.CommandContext(ctx, "go", "run", "./cmd/ariadne", "replay", "-checkpoint", checkpoint, deadLetters).CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected exit status 1 for a URL failing again, got %v output=%s", err, out)
+	}
+	after := succeeded()
+	for key := range before {
+		if !after[key] {
+			t.Fatalf("replay dropped checkpoint record %s: %v", key, after)
+		}
+	}
+}
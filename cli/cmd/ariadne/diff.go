@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/99souls/ariadne/engine"
+)
+
+// runDiff implements `ariadne diff [flags] OLD_RUN_DIR NEW_RUN_DIR`, comparing two runs
+// recorded with -manifest-dir. It returns the process exit code.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "markdown", "Report format: markdown|json")
+	contextLines := fs.Int("context", 3, "Unchanged lines shown around each change in page diffs")
+	outPath := fs.String("o", "", "Write the report to this file instead of stdout")
+	exitCode := fs.Bool("exit-code", false, "Exit with status 1 when the runs differ")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: ariadne diff [flags] OLD_RUN_DIR NEW_RUN_DIR")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "markdown" && *format != "json" {
+		_, _ = fmt.Fprintf(stderr, "unknown -format %q (want markdown or json)\n", *format)
+		return 2
+	}
+	d, err := engine.DiffRuns(fs.Arg(0), fs.Arg(1), engine.RunDiffOptions{Context: *contextLines})
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "diff runs: %v\n", err)
+		return 2
+	}
+	var report []byte
+	if *format == "json" {
+		report, err = json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "encode report: %v\n", err)
+			return 2
+		}
+		report = append(report, '\n')
+	} else {
+		report = []byte(d.Markdown())
+	}
+	if *outPath != "" {
+		err = os.WriteFile(*outPath, report, 0o644)
+	} else {
+		_, err = stdout.Write(report)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "write report: %v\n", err)
+		return 2
+	}
+	if *exitCode && !d.Empty() {
+		return 1
+	}
+	return 0
+}
@@ -27,6 +27,20 @@ type simpleJSONConfig struct {
 	RetryBaseDelay    *time.Duration `json:"retry_base_delay"`
 	RetryMaxDelay     *time.Duration `json:"retry_max_delay"`
 	RetryMaxAttempts  *int           `json:"retry_max_attempts"`
+	MaxDepth          *int           `json:"max_depth"`
+	MaxPages          *int           `json:"max_pages"`
+	MaxPagesPerHost   *int           `json:"max_pages_per_host"`
+	MaxBytesPerHost   *int64         `json:"max_bytes_per_host"`
+	AllowedDomains    []string       `json:"allowed_domains"`
+	BlockedDomains    []string       `json:"blocked_domains"`
+	PathPrefixes      []string       `json:"path_prefixes"`
+	Include           []string       `json:"include"`
+	Exclude           []string       `json:"exclude"`
+	ContentSelectors  []string       `json:"content_selectors"`
+	RemoveSelectors   []string       `json:"remove_selectors"`
+	Extractor         *string        `json:"extractor"`
+	// Schemas decode into engine.ExtractionSchema; keys match its field names case-insensitively.
+	Schemas []engine.ExtractionSchema `json:"schemas"`
 }
 
 func applySimpleConfig(base engine.Config, sc *simpleJSONConfig) engine.Config {
@@ -57,10 +71,40 @@ func applySimpleConfig(base engine.Config, sc *simpleJSONConfig) engine.Config {
 	if sc.RetryMaxAttempts != nil {
 		base.RetryMaxAttempts = *sc.RetryMaxAttempts
 	}
+	if sc.MaxDepth != nil {
+		base.Scope.MaxDepth = *sc.MaxDepth
+	}
+	if sc.MaxPages != nil {
+		base.Scope.MaxPages = *sc.MaxPages
+	}
+	if sc.MaxPagesPerHost != nil {
+		base.Scope.MaxPagesPerHost = *sc.MaxPagesPerHost
+	}
+	if sc.MaxBytesPerHost != nil {
+		base.Scope.MaxBytesPerHost = *sc.MaxBytesPerHost
+	}
+	base.Scope.AllowedDomains = append(base.Scope.AllowedDomains, sc.AllowedDomains...)
+	base.Scope.BlockedDomains = append(base.Scope.BlockedDomains, sc.BlockedDomains...)
+	base.Scope.PathPrefixes = append(base.Scope.PathPrefixes, sc.PathPrefixes...)
+	base.Scope.Include = append(base.Scope.Include, sc.Include...)
+	base.Scope.Exclude = append(base.Scope.Exclude, sc.Exclude...)
+	base.Processing.ContentSelectors = append(base.Processing.ContentSelectors, sc.ContentSelectors...)
+	base.Processing.RemoveSelectors = append(base.Processing.RemoveSelectors, sc.RemoveSelectors...)
+	if sc.Extractor != nil {
+		base.Processing.Extractor = *sc.Extractor
+	}
+	base.Processing.Schemas = append(base.Processing.Schemas, sc.Schemas...)
 	return base
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var (
 		seedList       string
 		seedFile       string
@@ -73,10 +117,32 @@ func main() {
 		configPath     string
 		metricsBackend string
 		enableMetrics  bool
+		maxDepth       int
+		maxPages       int
+		maxHostPages   int
+		maxHostBytes   int64
+		allowDomains   string
+		blockDomains   string
+		pathPrefixes   string
+		include        listFlag
+		exclude        listFlag
+		contentSel     listFlag
+		removeSel      listFlag
+		extractor      string
+		sitemap        bool
+		sitemapURLs    string
+		sitemapSince   string
+		frontierDir    string
+		conditionalGet bool
+		limiterState   string
+		manifestDir    string
+		runID          string
+		deadLetterPath string
+		autoscale      bool
 	)
 	flag.StringVar(&seedList, "seeds", "", "Comma separated list of seed URLs")
 	flag.StringVar(&seedFile, "seed-file", "", "Path to file containing one seed URL per line")
-	flag.BoolVar(&resume, "resume", false, "Resume from existing checkpoint (skip already processed URLs)")
+	flag.BoolVar(&resume, "resume", false, "Resume the crawl recorded in the checkpoint (skip finished URLs, re-queue pending ones)")
 	flag.StringVar(&checkpointPath, "checkpoint", "checkpoint.log", "Path to checkpoint log file")
 	flag.DurationVar(&snapshotEvery, "snapshot-interval", 10*time.Second, "Interval between progress snapshots (0=disabled)")
 	flag.BoolVar(&showVersion, "version", false, "Show version / build info")
@@ -85,6 +151,28 @@ func main() {
 	flag.StringVar(&configPath, "config", "", "Optional JSON config file (temporary minimal format)")
 	flag.StringVar(&metricsBackend, "metrics-backend", "prom", "Metrics backend: prom|otel|noop (effective only if -metrics set and enabled)")
 	flag.BoolVar(&enableMetrics, "enable-metrics", false, "Enable metrics provider (required to serve metrics)")
+	flag.IntVar(&maxDepth, "max-depth", engine.Defaults().Scope.MaxDepth, "Maximum link hops followed from each seed (0=seeds only)")
+	flag.IntVar(&maxPages, "max-pages", engine.Defaults().Scope.MaxPages, "Maximum number of pages admitted to the crawl (0=unlimited)")
+	flag.IntVar(&maxHostPages, "max-pages-per-host", 0, "Maximum number of pages admitted per host (0=unlimited)")
+	flag.Int64Var(&maxHostBytes, "max-bytes-per-host", 0, "Stop crawling a host once this many body bytes were fetched from it (0=unlimited)")
+	flag.StringVar(&allowDomains, "allow-domain", "", "Comma separated domains (with subdomains) links may be followed to (default: the seed hosts)")
+	flag.StringVar(&blockDomains, "block-domain", "", "Comma separated domains (with subdomains) never crawled")
+	flag.StringVar(&pathPrefixes, "path-prefix", "", "Comma separated URL path prefixes the crawl is restricted to")
+	flag.Var(&include, "include", "Only crawl URLs matching this glob (path if it starts with /, else full URL) or re:REGEXP; repeatable")
+	flag.Var(&exclude, "exclude", "Skip URLs matching this glob (path if it starts with /, else full URL) or re:REGEXP; repeatable")
+	flag.Var(&contentSel, "content-selector", "CSS selector locating the main content of a page, tried in order; repeatable (default: main, article, .content, ...)")
+	flag.Var(&removeSel, "remove-selector", "CSS selector removed from pages before content extraction; repeatable")
+	flag.StringVar(&extractor, "extractor", "", "Main content extractor: selector (first -content-selector match) or readability (scores text and link density)")
+	flag.BoolVar(&sitemap, "sitemap", false, "Discover sitemaps for seed origins (robots.txt Sitemap lines, then /sitemap.xml) and crawl their entries")
+	flag.StringVar(&sitemapURLs, "sitemap-url", "", "Comma separated sitemap or sitemap index URLs to load as seeds")
+	flag.StringVar(&sitemapSince, "sitemap-since", "", "Skip sitemap entries with <lastmod> before this date (YYYY-MM-DD or RFC3339)")
+	flag.StringVar(&frontierDir, "frontier-dir", "", "Keep the crawl frontier on disk in this directory; rerunning with the same directory resumes pending URLs")
+	flag.StringVar(&limiterState, "limiter-state", "", "Load learned per-domain rate limiter state from this file and save it on exit")
+	flag.BoolVar(&conditionalGet, "conditional-get", false, "Revalidate pages from earlier runs with If-None-Match / If-Modified-Since and reuse unchanged ones (validators are kept next to -checkpoint)")
+	flag.StringVar(&manifestDir, "manifest-dir", "", "Record a manifest of this run (page hashes and markdown) under this directory for the diff subcommand")
+	flag.StringVar(&runID, "run-id", "", "Run directory name under -manifest-dir (default: start time)")
+	flag.StringVar(&deadLetterPath, "dead-letter", "", "Record URLs whose fetch failed for good in this file for the replay subcommand")
+	flag.BoolVar(&autoscale, "autoscale", false, "Resize stage worker pools from queue depth and worker load (worker counts are starting sizes)")
 	flag.Parse()
 
 	if showVersion {
@@ -96,8 +184,8 @@ func main() {
 	if err != nil {
 		log.Fatalf("collect seeds: %v", err)
 	}
-	if len(seeds) == 0 {
-		fmt.Println("No seeds provided. Use -seeds or -seed-file. Example: -seeds https://example.com,https://example.org")
+	if len(seeds) == 0 && sitemapURLs == "" && frontierDir == "" {
+		fmt.Println("No seeds provided. Use -seeds, -seed-file, -sitemap-url or -frontier-dir. Example: -seeds https://example.com,https://example.org")
 		os.Exit(1)
 	}
 
@@ -124,6 +212,44 @@ func main() {
 		cfg.MetricsBackend = metricsBackend
 	}
 	cfg.CheckpointPath = checkpointPath
+	cfg.Frontier.Dir = frontierDir
+	cfg.Recrawl.ConditionalGet = conditionalGet
+	cfg.RateLimit.StatePath = limiterState
+	cfg.Manifest = engine.ManifestConfig{Dir: manifestDir, RunID: runID}
+	cfg.DeadLetter.Path = deadLetterPath
+	cfg.Autoscale.Enabled = autoscale
+	cfg.Sitemap.Discover = sitemap
+	cfg.Sitemap.URLs = splitList(sitemapURLs)
+	if sitemapSince != "" {
+		since, err := parseDate(sitemapSince)
+		if err != nil {
+			log.Fatalf("parse -sitemap-since: %v", err)
+		}
+		cfg.Sitemap.Since = since
+	}
+	// Explicit crawl bound flags win over the config file; list flags add to it.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-depth":
+			cfg.Scope.MaxDepth = maxDepth
+		case "max-pages":
+			cfg.Scope.MaxPages = maxPages
+		case "max-pages-per-host":
+			cfg.Scope.MaxPagesPerHost = maxHostPages
+		case "max-bytes-per-host":
+			cfg.Scope.MaxBytesPerHost = maxHostBytes
+		}
+	})
+	cfg.Scope.AllowedDomains = append(cfg.Scope.AllowedDomains, splitList(allowDomains)...)
+	cfg.Scope.BlockedDomains = append(cfg.Scope.BlockedDomains, splitList(blockDomains)...)
+	cfg.Scope.PathPrefixes = append(cfg.Scope.PathPrefixes, splitList(pathPrefixes)...)
+	cfg.Scope.Include = append(cfg.Scope.Include, include...)
+	cfg.Scope.Exclude = append(cfg.Scope.Exclude, exclude...)
+	cfg.Processing.ContentSelectors = append(cfg.Processing.ContentSelectors, contentSel...)
+	cfg.Processing.RemoveSelectors = append(cfg.Processing.RemoveSelectors, removeSel...)
+	if extractor != "" {
+		cfg.Processing.Extractor = extractor
+	}
 
 	eng, err := engine.New(cfg)
 	if err != nil {
@@ -231,6 +357,33 @@ func main() {
 	fmt.Fprintf(os.Stderr, "\n=== FINAL SNAPSHOT %s ===\n%s\n", time.Now().Format(time.RFC3339), string(b))
 }
 
+// listFlag collects the values of a repeatable flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
 func gatherSeeds(seedList, seedFile string) ([]string, error) {
 	seeds := []string{}
 	if seedList != "" {
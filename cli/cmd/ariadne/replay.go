@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/99souls/ariadne/engine"
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+// runReplay implements `ariadne replay [flags] DEAD_LETTER_FILE`, re-driving URLs
+// recorded with -dead-letter. Results are written to stdout as JSON lines, like a crawl.
+// It returns the process exit code.
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	codes := fs.String("code", "", "Comma separated error codes to replay (e.g. http_5xx,timeout; default: all)")
+	domains := fs.String("domain", "", "Comma separated domains (with subdomains) to replay (default: all)")
+	list := fs.Bool("list", false, "Print the matching dead letters as JSON lines instead of replaying them")
+	maxDepth := fs.Int("max-depth", engine.Defaults().Scope.MaxDepth, "Maximum link depth followed from replayed URLs (they keep their recorded depth)")
+	checkpointPath := fs.String("checkpoint", "", "Checkpoint log to append replayed URLs to")
+	manifestDir := fs.String("manifest-dir", "", "Add replayed pages to the run manifest under this directory (requires -run-id)")
+	runID := fs.String("run-id", "", "Run directory under -manifest-dir whose manifest is extended")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: ariadne replay [flags] DEAD_LETTER_FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *manifestDir != "" && *runID == "" {
+		_, _ = fmt.Fprintln(stderr, "-manifest-dir requires -run-id naming the run to extend")
+		return 2
+	}
+	path := fs.Arg(0)
+	filter := engine.DeadLetterFilter{Domains: splitList(*domains)}
+	for _, c := range splitList(*codes) {
+		filter.Codes = append(filter.Codes, engmodels.ErrorCode(c))
+	}
+
+	if *list {
+		letters, err := engine.ReadDeadLetters(path, filter)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "read dead letters: %v\n", err)
+			return 2
+		}
+		enc := json.NewEncoder(stdout)
+		for _, d := range letters {
+			if err := enc.Encode(d); err != nil {
+				_, _ = fmt.Fprintf(stderr, "encode dead letter: %v\n", err)
+				return 2
+			}
+		}
+		return 0
+	}
+
+	cfg := engine.Defaults()
+	cfg.Scope.MaxDepth = *maxDepth
+	if *checkpointPath != "" {
+		// Resume loads the existing log so replayed URLs are appended to it instead of
+		// replacing the original crawl's records.
+		cfg.CheckpointPath, cfg.Resume = *checkpointPath, true
+	}
+	cfg.DeadLetter.Path = path
+	if *manifestDir != "" {
+		cfg.Manifest = engine.ManifestConfig{Dir: *manifestDir, RunID: *runID, Append: true}
+	}
+	eng, err := engine.New(cfg)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "create engine: %v\n", err)
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results, err := eng.Replay(ctx, filter)
+	if err != nil {
+		_ = eng.Stop()
+		_, _ = fmt.Fprintf(stderr, "replay: %v\n", err)
+		return 2
+	}
+	enc := json.NewEncoder(stdout)
+	failed := 0
+	for r := range results {
+		if !r.Success {
+			failed++
+		}
+		if err := enc.Encode(r); err != nil {
+			_, _ = fmt.Fprintf(stderr, "encode result: %v\n", err)
+		}
+	}
+	snap := eng.Snapshot()
+	if err := eng.Stop(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "stop engine: %v\n", err)
+		return 2
+	}
+	if dl := snap.DeadLetter; dl != nil {
+		_, _ = fmt.Fprintf(stderr, "replay: %d recovered, %d failed again, %d dead letters pending\n", dl.Cleared, failed, dl.Pending)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,10 +1,19 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	intcanonical "github.com/99souls/ariadne/engine/internal/canonical"
+	intfrontier "github.com/99souls/ariadne/engine/internal/frontier"
 	engpipeline "github.com/99souls/ariadne/engine/internal/pipeline"
+	intprocessor "github.com/99souls/ariadne/engine/internal/processor"
+	intrat "github.com/99souls/ariadne/engine/internal/ratelimit"
 	intresources "github.com/99souls/ariadne/engine/internal/resources"
+	introbots "github.com/99souls/ariadne/engine/internal/robots"
+	intscope "github.com/99souls/ariadne/engine/internal/scope"
 	"github.com/99souls/ariadne/engine/models"
 )
 
@@ -12,23 +21,400 @@ import (
 // Experimental: Shape and semantics may change before v1.0.
 // Mirrors internal/resources.Config but kept separate to permit future reduction.
 type ResourcesConfig struct {
-	CacheCapacity      int
-	MaxInFlight        int
-	SpillDirectory     string
+	// CacheCapacity bounds the page cache by entries and CacheMaxBytes by the
+	// estimated size of the cached pages (Content, Markdown and CleanedText). Pages
+	// evicted by either limit are spilled to SpillDirectory when set.
+	CacheCapacity int
+	CacheMaxBytes int64
+	// HeapSoftLimit, when set, ties the cache to the live heap reported by
+	// runtime/metrics: above the limit the cache sheds half its bytes at every
+	// sample (4 per second), and new fetches are held back while shedding and a
+	// forced collection cannot bring the heap below it. One fetch always proceeds.
+	HeapSoftLimit int64
+	MaxInFlight   int
+	// SpillDirectory receives pages evicted from the cache, which are served from
+	// there until promoted back.
+	SpillDirectory string
+	// SpillFormat is "json" (default: one uncompressed file per page) or "segments"
+	// (gzip-compressed pages packed into segment files of SpillSegmentBytes, default
+	// 4MiB, with an index).
+	SpillFormat       string
+	SpillSegmentBytes int64
+	// SpillMaxAge and SpillMaxBytes bound the spill directory: files older than
+	// SpillMaxAge and, oldest first, files beyond SpillMaxBytes are deleted (a segment
+	// is deleted as a whole). Zero means no limit.
+	SpillMaxAge   time.Duration
+	SpillMaxBytes int64
+	// SpillReuse keeps spill files across runs: files left by an earlier run are
+	// adopted at startup as a warm cache. Without it they are deleted at startup, and
+	// the run's own spill files on Stop. Adopted pages are served as they were spilled,
+	// without fetching or revalidating them, so they can be stale; SpillReuse
+	// therefore requires SpillMaxAge, which bounds how old a served page can be.
+	SpillReuse bool
+	// CheckpointPath enables the checkpoint write-ahead log, which records every
+	// admitted URL with its depth, each fetch attempt and each final result (see
+	// Config.Resume). Records are synced in batches every CheckpointInterval.
 	CheckpointPath     string
 	CheckpointInterval time.Duration
 }
 
 func (rc ResourcesConfig) toInternal() intresources.Config {
 	return intresources.Config{
-		CacheCapacity:      rc.CacheCapacity,
-		MaxInFlight:        rc.MaxInFlight,
-		SpillDirectory:     rc.SpillDirectory,
-		CheckpointPath:     rc.CheckpointPath,
-		CheckpointInterval: rc.CheckpointInterval,
+		CacheCapacity:     rc.CacheCapacity,
+		CacheMaxBytes:     rc.CacheMaxBytes,
+		HeapSoftLimit:     rc.HeapSoftLimit,
+		MaxInFlight:       rc.MaxInFlight,
+		SpillDirectory:    rc.SpillDirectory,
+		SpillFormat:       intresources.SpillFormat(rc.SpillFormat),
+		SpillSegmentBytes: rc.SpillSegmentBytes,
+		SpillMaxAge:       rc.SpillMaxAge,
+		SpillMaxBytes:     rc.SpillMaxBytes,
+		SpillReuse:        rc.SpillReuse,
 	}
 }
 
+// CanonicalConfig controls URL canonicalization, which derives the keys used for
+// crawl de-duplication, checkpoints and cache lookups. Scheme/host case, default
+// ports, fragments, dot segments, percent-encoding and query parameter order are
+// always normalized.
+// Experimental: Field set may change before v1.0.
+type CanonicalConfig struct {
+	// StripTrackingParams removes tracking query parameters (utm_*, fbclid, gclid, ...).
+	StripTrackingParams bool
+	// TrackingParams replaces the built-in tracking parameter list; a trailing '*'
+	// matches a name prefix.
+	TrackingParams []string
+	// KeepTrailingSlash treats "/docs/" and "/docs" as distinct URLs.
+	KeepTrailingSlash bool
+	// IgnoreRelCanonical disables collapsing pages onto their <link rel="canonical">.
+	IgnoreRelCanonical bool
+}
+
+func (cc CanonicalConfig) canonicalizer() *intcanonical.Canonicalizer {
+	return intcanonical.New(intcanonical.Config{StripTrackingParams: cc.StripTrackingParams, TrackingParams: cc.TrackingParams, KeepTrailingSlash: cc.KeepTrailingSlash})
+}
+
+// FrontierConfig moves the crawl frontier (pending URLs and the de-duplication set)
+// to disk so memory stays bounded on very large crawls and an interrupted crawl can
+// be continued: a directory left by a previous run has its pending URLs resumed and
+// its already admitted URLs skipped (Scope.MaxPages counts them too).
+// Experimental: Field set and on-disk format may change before v1.0.
+type FrontierConfig struct {
+	// Dir enables the disk-backed frontier; empty keeps the frontier in memory.
+	Dir string
+	// SegmentBytes is the log segment size (default 16MiB).
+	SegmentBytes int64
+	// ReadAhead bounds the pending URLs held in memory per priority band (default 256).
+	ReadAhead int
+	// SyncInterval is how often the frontier is made durable while crawling (default
+	// 1s); after a crash the URLs handed out since the last sync are crawled again.
+	SyncInterval time.Duration
+}
+
+func (fc FrontierConfig) toInternal() intfrontier.Options {
+	return intfrontier.Options{Dir: fc.Dir, SegmentBytes: fc.SegmentBytes, ReadAhead: fc.ReadAhead}
+}
+
+// RecrawlConfig enables cheap incremental recrawls with conditional GET. The ETag and
+// Last-Modified of every processed page are recorded per canonical URL next to the
+// checkpoint; later runs send them as If-None-Match / If-Modified-Since and a 304 Not
+// Modified reuses the previously processed page (kept in Resources.SpillDirectory) as
+// a result with Unchanged set, skipping processing but not output sinks.
+// Experimental: Field set and on-disk format may change before v1.0.
+type RecrawlConfig struct {
+	// ConditionalGet turns revalidation on. It requires a checkpoint path; the spill
+	// directory defaults to "<checkpoint>.spill".
+	ConditionalGet bool
+	// ValidatorsPath overrides the validator store location (default
+	// "<checkpoint>.validators").
+	ValidatorsPath string
+}
+
+// ManifestConfig persists a manifest of every page a run produced (URL, title, content
+// hash and page text) under Dir/RunID so two runs can be compared with DiffRuns.
+// Experimental: Layout and field set may change before v1.0.
+type ManifestConfig struct {
+	// Dir enables run manifests; each run gets its own subdirectory.
+	Dir string
+	// RunID names the run directory (default: start time as 20060102T150405Z). A
+	// directory already holding a manifest is rejected unless Append is set.
+	RunID string
+	// Append extends the manifest already recorded under RunID (for example when
+	// replaying the run's dead letters); pages crawled again replace their entries.
+	Append bool
+}
+
+// DeadLetterConfig records URLs whose fetch failed for good so they can be re-driven
+// later with Engine.Replay. Entries keep the error code, attempts, last status and
+// first/last failure times; a URL that later succeeds is removed.
+// Experimental: On-disk format may change before v1.0.
+type DeadLetterConfig struct {
+	// Path enables the dead-letter file (JSON lines, compacted on Stop).
+	Path string
+}
+
+// AutoscaleConfig resizes the stage worker pools while crawling. Every Interval the
+// engine looks at each stage's queue depth and worker busy ratio (and, for
+// extraction, rate limiter throttling): a busy stage with work queued grows by a
+// quarter, an idle one with nothing queued shrinks by one worker. The configured
+// worker counts are the starting sizes and extraction never grows beyond
+// Resources.MaxInFlight. Decisions are reported as "workers_scaled" events and in
+// Snapshot.Pipeline (Workers, Scaling).
+// Experimental: Field set and scaling policy may change before v1.0.
+type AutoscaleConfig struct {
+	Enabled bool
+	// Interval between scaling decisions (default 1s).
+	Interval time.Duration
+	// Per-stage pool bounds. A zero WorkerBounds keeps that stage at its configured size.
+	Discovery  WorkerBounds
+	Extraction WorkerBounds
+	Processing WorkerBounds
+	Output     WorkerBounds
+}
+
+// WorkerBounds is the range a stage's worker pool is kept in (Min is at least 1).
+// Experimental.
+type WorkerBounds struct {
+	Min int
+	Max int
+}
+
+func (ac AutoscaleConfig) toInternal(maxInFlight int, onScale func(engpipeline.ScaleDecision)) *engpipeline.AutoscaleConfig {
+	if !ac.Enabled {
+		return nil
+	}
+	out := &engpipeline.AutoscaleConfig{Interval: ac.Interval, Bounds: map[string]engpipeline.WorkerBounds{}, MaxInFlight: maxInFlight, OnScale: onScale}
+	for stage, b := range map[string]WorkerBounds{engpipeline.StageDiscovery: ac.Discovery, engpipeline.StageExtraction: ac.Extraction, engpipeline.StageProcessing: ac.Processing, engpipeline.StageOutput: ac.Output} {
+		if b != (WorkerBounds{}) {
+			out.Bounds[stage] = engpipeline.WorkerBounds{Min: b.Min, Max: b.Max}
+		}
+	}
+	return out
+}
+
+// ScopeConfig decides which URLs belong to a crawl. Every URL kept out is counted under
+// the rule that rejected it in ScopeSnapshot.Rejected and reported as a debug
+// "scope_reject" event; see the Scope* rule constants.
+// Experimental: Field set and rule names may change before v1.0.
+type ScopeConfig struct {
+	// MaxDepth bounds how many link hops from a seed are followed. Seeds are depth 0;
+	// zero disables link following (only seeds are fetched).
+	MaxDepth int
+	// MaxPages caps the total number of URLs admitted to a crawl (seeds included).
+	// Zero means unlimited.
+	MaxPages int
+	// MaxPagesPerHost caps the URLs admitted per host (0 = unlimited).
+	MaxPagesPerHost int
+	// MaxBytesPerHost stops admitting and fetching a host's URLs once the page bodies
+	// fetched from it reach this many bytes (0 = unlimited). Admitted URLs dropped
+	// this way produce a failed result with Stage "scope".
+	MaxBytesPerHost int64
+
+	// AllowedDomains lists the domains (and their subdomains) links may be followed
+	// to. Empty restricts link following to the seed hosts.
+	AllowedDomains []string
+	// BlockedDomains lists domains (and their subdomains) never crawled, seeds included.
+	BlockedDomains []string
+	// PathPrefixes, when non-empty, restricts the crawl to URL paths with one of these
+	// prefixes.
+	PathPrefixes []string
+	// Include, when non-empty, restricts the crawl to URLs matching one of these
+	// patterns; Exclude drops URLs matching any of them. A pattern prefixed "re:" is
+	// a regular expression matched against the whole URL; anything else is a glob
+	// (* within a path segment, ** across segments, ? one character) matched against
+	// the path when it starts with "/" and against the whole URL otherwise.
+	Include []string
+	Exclude []string
+}
+
+// Scope rule names reported in ScopeSnapshot.Rejected and scope_reject events. Rules
+// with a parameter are reported as "<rule>:<value>" (for example
+// "exclude:/private/**" or "blocked_domain:ads.example.com").
+// Experimental: Names may change before v1.0.
+const (
+	ScopeRuleScheme          = intscope.RuleScheme
+	ScopeRuleMaxDepth        = intscope.RuleMaxDepth
+	ScopeRuleMaxPages        = intscope.RuleMaxPages
+	ScopeRuleMaxPagesPerHost = intscope.RuleMaxPagesPerHost
+	ScopeRuleMaxBytesPerHost = intscope.RuleMaxBytesPerHost
+	ScopeRuleOffsite         = intscope.RuleOffsite
+	ScopeRuleAllowedDomains  = intscope.RuleAllowedDomains
+	ScopeRuleBlockedDomain   = intscope.RuleBlockedDomain
+	ScopeRulePathPrefix      = intscope.RulePathPrefix
+	ScopeRuleInclude         = intscope.RuleInclude
+	ScopeRuleExclude         = intscope.RuleExclude
+)
+
+// rules compiles the domain, path and pattern rules; nil when none are configured.
+func (sc ScopeConfig) rules() (*intscope.Rules, error) {
+	if len(sc.AllowedDomains)+len(sc.BlockedDomains)+len(sc.PathPrefixes)+len(sc.Include)+len(sc.Exclude) == 0 && sc.MaxPagesPerHost <= 0 && sc.MaxBytesPerHost <= 0 {
+		return nil, nil
+	}
+	return intscope.Compile(intscope.Config{
+		AllowedDomains:  sc.AllowedDomains,
+		BlockedDomains:  sc.BlockedDomains,
+		PathPrefixes:    sc.PathPrefixes,
+		Include:         sc.Include,
+		Exclude:         sc.Exclude,
+		MaxPagesPerHost: sc.MaxPagesPerHost,
+		MaxBytesPerHost: sc.MaxBytesPerHost,
+	})
+}
+
+// RobotsConfig controls robots.txt compliance.
+// Experimental: Field set may change before v1.0.
+type RobotsConfig struct {
+	// Enabled turns on robots.txt checks. Disallowed URLs produce a failed result with
+	// Stage "robots" and are never fetched; Crawl-delay spaces requests to the host
+	// whether or not RateLimit is enabled.
+	Enabled bool
+	// UserAgent selects the robots.txt group by product token; empty uses Config.UserAgent.
+	UserAgent string
+	// CacheTTL bounds how long a host's robots.txt is cached (default 24h).
+	CacheTTL time.Duration
+	// ErrorTTL is how long a host stays disallowed after its robots.txt answered 5xx or
+	// could not be fetched (default 1m); its URLs are retried after it (within
+	// RetryMaxAttempts) and finally fail as retryable. A 4xx robots.txt allows everything.
+	ErrorTTL time.Duration
+	// Overrides supplies robots.txt bodies per host (URL host, including any port)
+	// instead of fetching them; an empty body allows everything. Intended for test
+	// harnesses and per-run policy exceptions.
+	Overrides map[string]string
+}
+
+func (rc RobotsConfig) toInternal() *introbots.Config {
+	if !rc.Enabled {
+		return nil
+	}
+	return &introbots.Config{UserAgent: rc.UserAgent, CacheTTL: rc.CacheTTL, ErrorTTL: rc.ErrorTTL, Overrides: rc.Overrides}
+}
+
+// ProcessingConfig controls the built-in content processing of fetched pages: main
+// content extraction, removal of noise elements, relative URL resolution, metadata and
+// image extraction, markdown conversion and validation. It fills Page.CleanedText,
+// Markdown, Metadata, Images and Quality and replaces Page.Content with the extracted
+// main content. Processors from EngineStrategies run after it.
+// Experimental: Field set may change before v1.0.
+type ProcessingConfig struct {
+	// Enabled turns on content processing (enabled by default). Without it pages pass
+	// through as fetched.
+	Enabled bool
+	// ContentSelectors are CSS selectors tried in order to locate the main content;
+	// empty uses main, article, .content, #content, .post, .entry, .article-content.
+	// Without a match the cleaned body is used.
+	ContentSelectors []string
+	// Extractor selects how the main content is located: "selector" (default) takes
+	// the first ContentSelectors match; "readability" scores blocks by text and link
+	// density, paragraphs and class/id hints, for sites without semantic markup.
+	// Page.Quality reports the extractor and its confidence.
+	Extractor string
+	// RemoveSelectors are CSS selectors removed before extraction in addition to the
+	// built-in noise (scripts, styles, navigation, headers, footers, sidebars, ads and
+	// comments).
+	RemoveSelectors []string
+	// MinQuality fails pages whose Page.Quality.Score (0..1) is below it at Stage
+	// "processing" with code "processing"; zero keeps every page.
+	MinQuality float64
+	// Schemas declare per-site fields extracted into Page.Extracted. Each applies to
+	// its Domain and subdomains (the most specific domain wins); domains must be
+	// unique.
+	Schemas []ExtractionSchema
+}
+
+// ExtractionSchema declares the record extracted from the pages of a site. Records
+// are validated against it: required fields that are missing and values that cannot
+// be coerced to their type are listed in Page.Quality.SchemaErrors, or fail the page
+// at Stage "processing" when Strict is set.
+// Experimental: Field set may change before v1.0.
+type ExtractionSchema struct {
+	// Domain is the host the schema applies to, e.g. "shop.example.com".
+	Domain string
+	Fields []ExtractionField
+	Strict bool
+}
+
+// ExtractionField maps a record field to a value in the page.
+// Experimental: Field set may change before v1.0.
+type ExtractionField struct {
+	Name string
+	// Selector is a CSS selector relative to the enclosing object (the page for top
+	// level fields); empty uses the enclosing element itself.
+	Selector string
+	// Attr reads an attribute instead of the element's text; href and src are
+	// resolved against the page URL.
+	Attr string
+	// Pattern is a regular expression applied to the value; its first group (or the
+	// whole match) is kept and a value that does not match is missing.
+	Pattern string
+	// Type is one of string (default), number, integer, boolean, date (time.Time in
+	// UTC), list (every match, each of type Items) or object (the first match, holding
+	// Fields).
+	Type string
+	// Items is the element type of a list (default string); lists of objects describe
+	// their elements with Fields.
+	Items    string
+	Fields   []ExtractionField
+	Required bool
+}
+
+func (pc ProcessingConfig) toInternal() *engpipeline.ContentConfig {
+	if !pc.Enabled {
+		return nil
+	}
+	cc := &engpipeline.ContentConfig{ContentSelectors: pc.ContentSelectors, Extractor: pc.Extractor, RemoveSelectors: pc.RemoveSelectors, MinQuality: pc.MinQuality}
+	if len(pc.Schemas) > 0 {
+		cc.Schemas = make(map[string]*intprocessor.Schema, len(pc.Schemas))
+		for _, es := range pc.Schemas {
+			cc.Schemas[strings.ToLower(es.Domain)] = &intprocessor.Schema{Fields: toSchemaFields(es.Fields), Strict: es.Strict}
+		}
+	}
+	return cc
+}
+
+// validateSchemas rejects schemas without a domain or sharing one; the fields
+// themselves are validated with the pipeline strategies.
+func (pc ProcessingConfig) validateSchemas() error {
+	seen := make(map[string]bool, len(pc.Schemas))
+	for _, es := range pc.Schemas {
+		domain := strings.ToLower(es.Domain)
+		if domain == "" {
+			return errors.New("processing: extraction schema without domain")
+		}
+		if seen[domain] {
+			return fmt.Errorf("processing: duplicate extraction schema for %s", domain)
+		}
+		seen[domain] = true
+	}
+	return nil
+}
+
+func toSchemaFields(fields []ExtractionField) []intprocessor.Field {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]intprocessor.Field, len(fields))
+	for i, f := range fields {
+		out[i] = intprocessor.Field{Name: f.Name, Selector: f.Selector, Attr: f.Attr, Pattern: f.Pattern, Type: f.Type, Items: f.Items, Fields: toSchemaFields(f.Fields), Required: f.Required}
+	}
+	return out
+}
+
+// SitemapConfig enables XML sitemaps as a seed source.
+// Experimental: Field set may change before v1.0.
+type SitemapConfig struct {
+	// Discover looks up sitemaps for every seed origin: Sitemap lines in robots.txt,
+	// falling back to /sitemap.xml and /sitemap_index.xml.
+	Discover bool
+	// URLs lists sitemap or sitemap index URLs to load in addition to discovery.
+	URLs []string
+	// Since skips entries whose <lastmod> is older (entries without lastmod are kept).
+	Since time.Time
+	// MaxEntries caps the number of sitemap seeds (0 = unlimited; Scope still applies).
+	MaxEntries int
+}
+
+func (sc SitemapConfig) enabled() bool { return sc.Discover || len(sc.URLs) > 0 }
+
 // Config is the public configuration surface for the Engine facade.
 // Experimental: Field set, names, and semantics may change before v1.0.
 // Most fields are pass-through tuning knobs for underlying subsystems and
@@ -49,6 +435,9 @@ type Config struct {
 	// BufferSize tunes internal channel buffering between stages.
 	// Experimental: Subject to removal if adaptive backpressure is introduced.
 	BufferSize int
+	// Autoscale resizes the worker pools above within bounds (disabled by default).
+	// Experimental.
+	Autoscale AutoscaleConfig
 
 	// RetryBaseDelay is the initial backoff delay for transient fetch failures.
 	// Experimental: Retry model may be replaced by policy struct.
@@ -60,6 +449,44 @@ type Config struct {
 	// Experimental.
 	RetryMaxAttempts int
 
+	// UserAgent is sent with every page request made by the built-in fetcher.
+	// Experimental: May move under a fetch policy group.
+	UserAgent string
+	// RequestTimeout bounds a single page request (connect through body read).
+	// Experimental.
+	RequestTimeout time.Duration
+
+	// Scope bounds the crawl: depth and page budgets, per-host budgets, domain lists
+	// and URL patterns.
+	// Experimental.
+	Scope ScopeConfig
+
+	// Frontier configures the disk-backed crawl frontier (in memory by default).
+	// Experimental.
+	Frontier FrontierConfig
+
+	// Canonical configures URL canonicalization and rel=canonical de-duplication.
+	// Pages whose canonical URL was already crawled produce a result with Stage
+	// "duplicate"; the canonical page lists such URLs in Page.Aliases.
+	// Experimental.
+	Canonical CanonicalConfig
+
+	// Robots configures robots.txt compliance (enabled by default).
+	// Experimental.
+	Robots RobotsConfig
+
+	// Sitemap adds sitemap entries to the seeds passed to Start. Sitemap indexes are
+	// followed recursively and gzip sitemaps are accepted. Entries are held to the
+	// scope of the seeds like discovered links (rejections are counted under their
+	// scope rule) and their <priority> schedules them: 0.75 and above with the seeds,
+	// the default 0.5 with links one hop away, below 0.25 with links two hops away.
+	// Experimental.
+	Sitemap SitemapConfig
+
+	// Processing configures the built-in content processing (enabled by default).
+	// Experimental.
+	Processing ProcessingConfig
+
 	// RateLimit configures adaptive per-domain rate limiting.
 	// Experimental: Location may change (likely to move fully under ratelimit/).
 	RateLimit models.RateLimitConfig
@@ -68,7 +495,22 @@ type Config struct {
 	// Experimental: Will be narrowed to higher-level policy before v1.0; implementation hidden.
 	Resources ResourcesConfig
 
-	// Resume enables filtering of already-processed seeds based on a checkpoint file.
+	// Recrawl configures conditional GET revalidation of pages seen by earlier runs.
+	// Experimental.
+	Recrawl RecrawlConfig
+
+	// Manifest records the pages of this run for change detection (disabled by default).
+	// Experimental.
+	Manifest ManifestConfig
+
+	// DeadLetter records permanently failed URLs for replay (disabled by default).
+	// Experimental.
+	DeadLetter DeadLetterConfig
+
+	// Resume continues the crawl recorded in the checkpoint log: URLs with a final
+	// result are skipped (seeds included), and URLs left queued or in flight are crawled
+	// again at their depth. Without Resume an existing checkpoint log is discarded.
+	// Dead-letter Replay and jobs started with Submit are not resumed.
 	// Experimental: Mechanism & file format may change.
 	Resume bool
 	// CheckpointPath overrides Resources.CheckpointPath when non-empty.
@@ -93,27 +535,46 @@ type Config struct {
 // toPipelineConfig adapts the facade Config to the internal pipeline config.
 // engineOptions are internal construction options resolved by New().
 type engineOptions struct {
-	limiter         interface{} // ratelimit.RateLimiter (avoid import cycle comments here)
+	limiter         intrat.RateLimiter
 	resourceManager *intresources.Manager
+	strategies      EngineStrategies
+	scope           *intscope.Rules
+	scopeRejected   func(rawURL, rule string)
+	scaled          func(engpipeline.ScaleDecision)
 }
 
 func (c Config) toPipelineConfig(opts engineOptions) *engpipeline.PipelineConfig {
 	pc := &engpipeline.PipelineConfig{
-		DiscoveryWorkers:  c.DiscoveryWorkers,
-		ExtractionWorkers: c.ExtractionWorkers,
-		ProcessingWorkers: c.ProcessingWorkers,
-		OutputWorkers:     c.OutputWorkers,
-		BufferSize:        c.BufferSize,
-		RetryBaseDelay:    c.RetryBaseDelay,
-		RetryMaxDelay:     c.RetryMaxDelay,
-		RetryMaxAttempts:  c.RetryMaxAttempts,
-		RateLimiter:       nil,
-		ResourceManager:   opts.resourceManager,
+		DiscoveryWorkers:   c.DiscoveryWorkers,
+		ExtractionWorkers:  c.ExtractionWorkers,
+		ProcessingWorkers:  c.ProcessingWorkers,
+		OutputWorkers:      c.OutputWorkers,
+		BufferSize:         c.BufferSize,
+		RetryBaseDelay:     c.RetryBaseDelay,
+		RetryMaxDelay:      c.RetryMaxDelay,
+		RetryMaxAttempts:   c.RetryMaxAttempts,
+		RateLimiter:        opts.limiter,
+		ResourceManager:    opts.resourceManager,
+		UserAgent:          c.UserAgent,
+		RequestTimeout:     c.RequestTimeout,
+		MaxDepth:           c.Scope.MaxDepth,
+		MaxPages:           c.Scope.MaxPages,
+		Scope:              opts.scope,
+		ScopeRejected:      opts.scopeRejected,
+		Robots:             c.Robots.toInternal(),
+		Canonicalizer:      c.Canonical.canonicalizer(),
+		IgnoreRelCanonical: c.Canonical.IgnoreRelCanonical,
+		Autoscale:          c.Autoscale.toInternal(c.Resources.MaxInFlight, opts.scaled),
+		Content:            c.Processing.toInternal(),
+	}
+	if opts.strategies.Fetcher != nil {
+		pc.Fetcher = opts.strategies.Fetcher
 	}
-	if rl, ok := opts.limiter.(interface {
-		Acquire(ctx interface{}, domain string) (interface{}, error)
-	}); ok {
-		_ = rl // placeholder to suppress unused warning if build tags differ
+	for _, proc := range opts.strategies.Processors {
+		pc.Processors = append(pc.Processors, proc)
+	}
+	for _, sink := range opts.strategies.OutputSinks {
+		pc.OutputSinks = append(pc.OutputSinks, sink)
 	}
 	return pc
 }
@@ -131,6 +592,18 @@ func Defaults() Config {
 		RetryBaseDelay:    200 * time.Millisecond,
 		RetryMaxDelay:     5 * time.Second,
 		RetryMaxAttempts:  3,
+		UserAgent:         "Ariadne/1.0 (+https://github.com/99souls/ariadne)",
+		RequestTimeout:    30 * time.Second,
+		Canonical:         CanonicalConfig{StripTrackingParams: true},
+		Robots:            RobotsConfig{Enabled: true, CacheTTL: 24 * time.Hour, ErrorTTL: time.Minute},
+		Processing:        ProcessingConfig{Enabled: true},
+		Autoscale: AutoscaleConfig{
+			Interval:   time.Second,
+			Discovery:  WorkerBounds{Min: 1, Max: 4},
+			Extraction: WorkerBounds{Min: 1, Max: 16},
+			Processing: WorkerBounds{Min: 1, Max: 8},
+			Output:     WorkerBounds{Min: 1, Max: 4},
+		},
 		RateLimit: models.RateLimitConfig{
 			Enabled:                  true,
 			InitialRPS:               2.0,
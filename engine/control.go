@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	engpipeline "github.com/99souls/ariadne/engine/internal/pipeline"
+	telemEvents "github.com/99souls/ariadne/engine/internal/telemetry/events"
+)
+
+// EngineState is the lifecycle state reported by Engine.State and Snapshot.State.
+// Experimental: Values may be added before v1.0.
+type EngineState string
+
+const (
+	// EngineIdle: constructed, no crawl started yet.
+	EngineIdle EngineState = "idle"
+	// EngineRunning: queued URLs are being dispatched.
+	EngineRunning EngineState = "running"
+	// EnginePaused: dispatch is suspended by Pause; in-flight URLs still complete.
+	EnginePaused EngineState = "paused"
+	// EngineDraining: nothing is left queued and the last admitted URLs are in flight.
+	EngineDraining EngineState = "draining"
+	// EngineStopped: the crawl finished or Stop was called.
+	EngineStopped EngineState = "stopped"
+)
+
+// State returns the current lifecycle state.
+// Experimental.
+func (e *Engine) State() EngineState {
+	switch {
+	case e.stopped.Load():
+		return EngineStopped
+	case e.pl == nil:
+		return EngineIdle
+	case e.crawling.Load() && e.pl.Finished():
+		return EngineStopped
+	case e.pl.Paused():
+		return EnginePaused
+	case !e.crawling.Load():
+		return EngineIdle
+	}
+	if m := e.pl.Metrics(); m.URLsQueued == 0 && m.URLsPending > 0 {
+		return EngineDraining
+	}
+	return EngineRunning
+}
+
+// Pause stops dispatching queued URLs: no new fetch starts until Resume, including
+// scheduled retries. URLs already being fetched or processed complete normally and
+// rate limiter state is kept.
+// Pausing before Start starts the crawl paused.
+// Experimental.
+func (e *Engine) Pause() error {
+	if e.stopped.Load() {
+		return errors.New("engine stopped")
+	}
+	if !e.pl.Paused() {
+		e.pl.Pause()
+		e.controlEvent("paused", nil)
+	}
+	return nil
+}
+
+// Resume restarts dispatch after Pause.
+// Experimental.
+func (e *Engine) Resume() error {
+	if e.stopped.Load() {
+		return errors.New("engine stopped")
+	}
+	if e.pl.Paused() {
+		e.pl.Resume()
+		e.controlEvent("resumed", nil)
+	}
+	return nil
+}
+
+// Enqueue adds urls to the running crawl as seeds (depth 0, their hosts become
+// followable) and returns how many were admitted; URLs already seen or out of scope
+// are skipped. It fails before Start and once the crawl has finished.
+// Experimental: Signature may change before v1.0.
+func (e *Engine) Enqueue(ctx context.Context, urls ...string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if !e.crawling.Load() {
+		return 0, errors.New("enqueue: no crawl started")
+	}
+	if e.stopped.Load() {
+		return 0, fmt.Errorf("enqueue: %w", engpipeline.ErrFinished)
+	}
+	n, err := e.pl.Enqueue(urls)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue: %w", err)
+	}
+	return n, nil
+}
+
+// CancelURLs drops queued URLs matching pattern and keeps matching URLs out of the
+// rest of the run. Patterns use the ScopeConfig.Include / Exclude syntax. Dropped URLs
+// produce failed results with stage "scope" (code out_of_scope) and are counted in
+// ScopeSnapshot.Rejected under "cancelled:<pattern>". It returns the number of queued
+// URLs dropped at once; with a disk-backed frontier matches are dropped as they are
+// dequeued and not included in the count.
+// Experimental.
+func (e *Engine) CancelURLs(pattern string) (int, error) {
+	if e.stopped.Load() {
+		return 0, errors.New("engine stopped")
+	}
+	n, err := e.pl.CancelURLs(pattern)
+	if err != nil {
+		return 0, err
+	}
+	e.controlEvent("urls_cancelled", map[string]string{"pattern": pattern, "dropped": strconv.Itoa(n)})
+	return n, nil
+}
+
+func (e *Engine) controlEvent(typ string, labels map[string]string) {
+	iev := telemEvents.Event{Category: telemEvents.CategoryPipeline, Type: typ, Severity: "info", Labels: labels}
+	if e.eventBus != nil {
+		_ = e.eventBus.Publish(iev)
+	}
+	e.dispatchEvent(iev)
+}
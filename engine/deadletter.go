@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/deadletter"
+	engpipeline "github.com/99souls/ariadne/engine/internal/pipeline"
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+// DeadLetter is a URL whose fetch failed for good, as recorded by Config.DeadLetter.
+// Experimental: Field set may change before v1.0.
+type DeadLetter struct {
+	URL string `json:"url"`
+	// Depth is the link depth the URL was crawled at; Replay re-admits it there.
+	Depth      int                 `json:"depth"`
+	Stage      string              `json:"stage"`
+	Code       engmodels.ErrorCode `json:"code"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Attempts   int                 `json:"attempts"`
+	Error      string              `json:"error"`
+	// Failures counts the runs in which the URL failed.
+	Failures      int       `json:"failures"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+// DeadLetterFilter selects dead letters. Empty fields match everything.
+// Experimental.
+type DeadLetterFilter struct {
+	// Codes keeps entries with one of these error codes, e.g. models.ErrorCodeHTTP5xx.
+	Codes []engmodels.ErrorCode
+	// Domains keeps entries whose host is one of these domains or a subdomain.
+	Domains []string
+}
+
+func (f DeadLetterFilter) toInternal() deadletter.Filter {
+	return deadletter.Filter{Codes: f.Codes, Domains: f.Domains}
+}
+
+// DeadLetterSnapshot reports dead-letter activity for the current run.
+// Experimental: Only present when Config.DeadLetter.Path is set.
+type DeadLetterSnapshot struct {
+	Path string `json:"path"`
+	// Recorded counts failures written this run; Cleared counts dead letters removed
+	// because their URL succeeded.
+	Recorded int `json:"recorded"`
+	Cleared  int `json:"cleared"`
+	// Pending is the number of URLs in the file.
+	Pending int `json:"pending"`
+}
+
+// ReadDeadLetters lists the dead letters in the file at path that match filter,
+// sorted by URL. A missing file holds none.
+// Experimental.
+func ReadDeadLetters(path string, filter DeadLetterFilter) ([]DeadLetter, error) {
+	entries, err := deadletter.Read(path, filter.toInternal())
+	if err != nil {
+		return nil, err
+	}
+	return publicDeadLetters(entries), nil
+}
+
+func publicDeadLetters(in []deadletter.Entry) []DeadLetter {
+	out := make([]DeadLetter, 0, len(in))
+	for _, d := range in {
+		out = append(out, DeadLetter{URL: d.URL, Depth: d.Depth, Stage: d.Stage, Code: d.Code, StatusCode: d.StatusCode, Attempts: d.Attempts, Error: d.Error, Failures: d.Failures, FirstFailedAt: d.FirstFailedAt, LastFailedAt: d.LastFailedAt})
+	}
+	return out
+}
+
+// Replay re-drives the dead letters matching filter instead of crawling seeds: each
+// URL is admitted at its recorded depth (bypassing Resume filtering) and links found
+// on it are followed as in the original run. URLs that succeed leave the dead-letter
+// file; URLs that fail again have their entry updated. Like Start it may be called
+// once per engine; combine with Config.Manifest.Append to add the recovered pages to
+// the original run's manifest.
+// Experimental: Signature may change before v1.0.
+func (e *Engine) Replay(ctx context.Context, filter DeadLetterFilter) (<-chan *engmodels.CrawlResult, error) {
+	if !e.started.Load() {
+		return nil, errors.New("engine not started")
+	}
+	if e.deadLetters == nil {
+		return nil, errors.New("replay: Config.DeadLetter.Path is not set")
+	}
+	if !e.crawling.CompareAndSwap(false, true) {
+		return nil, errors.New("engine: crawl already started")
+	}
+	entries := e.deadLetters.Entries(filter.toInternal())
+	seeds := make([]engpipeline.Seed, 0, len(entries))
+	for _, d := range entries {
+		seeds = append(seeds, engpipeline.Seed{URL: d.URL, Depth: d.Depth})
+	}
+	return e.pl.ProcessSeeds(ctx, seeds), nil
+}
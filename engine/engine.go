@@ -1,19 +1,26 @@
 package engine
 
 import (
-	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
-	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/99souls/ariadne/engine/internal/checkpoint"
+	"github.com/99souls/ariadne/engine/internal/deadletter"
+	intfrontier "github.com/99souls/ariadne/engine/internal/frontier"
 	engpipeline "github.com/99souls/ariadne/engine/internal/pipeline"
 	intrat "github.com/99souls/ariadne/engine/internal/ratelimit"
 	intresources "github.com/99souls/ariadne/engine/internal/resources"
+	"github.com/99souls/ariadne/engine/internal/revisit"
+	"github.com/99souls/ariadne/engine/internal/runs"
+	intsitemap "github.com/99souls/ariadne/engine/internal/sitemap"
 	telemEvents "github.com/99souls/ariadne/engine/internal/telemetry/events"
 	intmetrics "github.com/99souls/ariadne/engine/internal/telemetry/metrics"
 	inttelempolicy "github.com/99souls/ariadne/engine/internal/telemetry/policy"
@@ -25,12 +32,25 @@ import (
 // Snapshot is a unified view of engine state.
 // Stable: Field additions are allowed; existing fields retain semantics.
 type Snapshot struct {
+	// State is the lifecycle state (see EngineState).
+	State     EngineState                  `json:"state"`
 	StartedAt time.Time                    `json:"started_at"`
 	Uptime    time.Duration                `json:"uptime"`
 	Pipeline  *engpipeline.PipelineMetrics `json:"pipeline,omitempty"`
 	Limiter   *LimiterSnapshot             `json:"limiter,omitempty"`
 	Resources *ResourceSnapshot            `json:"resources,omitempty"`
 	Resume    *ResumeSnapshot              `json:"resume,omitempty"`
+	Sitemap   *SitemapSnapshot             `json:"sitemap,omitempty"`
+	Frontier  *FrontierSnapshot            `json:"frontier,omitempty"`
+	Recrawl   *RecrawlSnapshot             `json:"recrawl,omitempty"`
+	Manifest  *ManifestSnapshot            `json:"manifest,omitempty"`
+	Scope     *ScopeSnapshot               `json:"scope,omitempty"`
+	// Jobs lists the jobs started with Submit (running and recently finished).
+	Jobs []JobSnapshot `json:"jobs,omitempty"`
+	// DeadLetter is only present when Config.DeadLetter.Path is set.
+	DeadLetter *DeadLetterSnapshot `json:"dead_letter,omitempty"`
+	// Checkpoint is only present when a checkpoint path is set.
+	Checkpoint *CheckpointSnapshot `json:"checkpoint,omitempty"`
 }
 
 // TelemetryEvent is a reduced, stable event representation for external observers.
@@ -79,12 +99,14 @@ func telemetryConfigFromLegacy(cfg Config) TelemetryOptions {
 // Experimental: Field set may shrink prior to v1.0; external consumers should treat as
 // best-effort diagnostics (subject to consolidation under a future telemetry facade).
 type LimiterSnapshot struct {
-	TotalRequests    int64                `json:"total_requests"`
-	Throttled        int64                `json:"throttled"`
-	Denied           int64                `json:"denied"`
-	OpenCircuits     int64                `json:"open_circuits"`
-	HalfOpenCircuits int64                `json:"half_open_circuits"`
-	Domains          []LimiterDomainState `json:"domains,omitempty"`
+	TotalRequests    int64 `json:"total_requests"`
+	Throttled        int64 `json:"throttled"`
+	Denied           int64 `json:"denied"`
+	OpenCircuits     int64 `json:"open_circuits"`
+	HalfOpenCircuits int64 `json:"half_open_circuits"`
+	// RestoredDomains counts domains whose state was loaded from RateLimit.StatePath.
+	RestoredDomains int                  `json:"restored_domains,omitempty"`
+	Domains         []LimiterDomainState `json:"domains,omitempty"`
 }
 
 // LimiterDomainState summarizes recent domain-level adaptive state.
@@ -94,15 +116,33 @@ type LimiterDomainState struct {
 	FillRate     float64   `json:"fill_rate"`
 	CircuitState string    `json:"circuit_state"`
 	LastActivity time.Time `json:"last_activity"`
+	// LatencyEWMA is the moving average response latency; ErrorRate the failure share
+	// of the last 64 responses.
+	LatencyEWMA time.Duration `json:"latency_ewma,omitempty"`
+	ErrorRate   float64       `json:"error_rate,omitempty"`
+	// Restored marks state carried over from a previous run (RateLimit.StatePath).
+	Restored bool `json:"restored,omitempty"`
 }
 
 // ResourceSnapshot summarizes resource manager internal counters.
 // Experimental: Field set & naming may change pre-v1.0.
 type ResourceSnapshot struct {
-	CacheEntries     int `json:"cache_entries"`
-	SpillFiles       int `json:"spill_files"`
-	InFlight         int `json:"in_flight"`
-	CheckpointQueued int `json:"checkpoint_queued"`
+	CacheEntries int   `json:"cache_entries"`
+	CacheBytes   int64 `json:"cache_bytes"`
+	SpillFiles   int   `json:"spill_files"`
+	// SpillBytes is the total size of the spill files; Evictions counts pages evicted
+	// from the cache, spilled or not.
+	SpillBytes       int64 `json:"spill_bytes"`
+	Evictions        int64 `json:"evictions"`
+	InFlight         int   `json:"in_flight"`
+	CheckpointQueued int   `json:"checkpoint_queued"`
+	// HeapLive is the last live heap sample taken for ResourcesConfig.HeapSoftLimit
+	// and MemoryPressure reports it above the limit. Shed counts pages evicted under
+	// pressure and Throttled the fetches held back by it.
+	HeapLive       uint64 `json:"heap_live,omitempty"`
+	MemoryPressure bool   `json:"memory_pressure,omitempty"`
+	Shed           int64  `json:"shed,omitempty"`
+	Throttled      int64  `json:"throttled,omitempty"`
 }
 
 // ResumeSnapshot contains resume filter statistics.
@@ -110,6 +150,82 @@ type ResourceSnapshot struct {
 type ResumeSnapshot struct {
 	SeedsBefore int   `json:"seeds_before"`
 	Skipped     int64 `json:"skipped"`
+	// Finished is the number of URLs the checkpoint recorded a final result for (and
+	// the run skips); Requeued the URLs it left queued or in flight, admitted again.
+	Finished int `json:"finished"`
+	Requeued int `json:"requeued"`
+}
+
+// CheckpointSnapshot reports the checkpoint log: URLs per status and the log's size.
+// Experimental: Only present when a checkpoint path is set; fields may change.
+type CheckpointSnapshot struct {
+	Path      string `json:"path"`
+	Queued    int    `json:"queued"`
+	InFlight  int    `json:"in_flight"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// Records is the number of records in the file (reset by compaction) and Errors
+	// the number of failed writes; the first error is also returned by Stop.
+	Records     int `json:"records"`
+	Compactions int `json:"compactions"`
+	Errors      int `json:"errors,omitempty"`
+}
+
+// SitemapSnapshot reports sitemap seed ingestion for the last Start call.
+// Experimental: Only present when Config.Sitemap is configured; fields may change.
+type SitemapSnapshot struct {
+	Sitemaps int      `json:"sitemaps"`
+	URLs     int      `json:"urls"`
+	Filtered int      `json:"filtered"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// FrontierSnapshot is the per-host view of URLs waiting in the crawl frontier.
+// Experimental: Field set may change pre-v1.0.
+type FrontierSnapshot struct {
+	// Persistent reports whether the frontier is disk-backed (Config.Frontier.Dir).
+	Persistent bool `json:"persistent"`
+	// Queued counts URLs admitted but not yet dispatched to a worker.
+	Queued int            `json:"queued"`
+	Hosts  []FrontierHost `json:"hosts,omitempty"`
+}
+
+// FrontierHost is one host's share of the frontier queue.
+// Experimental: May be folded into a broader per-host snapshot.
+type FrontierHost struct {
+	Host   string `json:"host"`
+	Queued int    `json:"queued"`
+}
+
+// RecrawlSnapshot counts conditional GET outcomes for the current run.
+// Experimental: Only present when Config.Recrawl.ConditionalGet is set.
+type RecrawlSnapshot struct {
+	// New pages had no recorded validators and were fetched in full.
+	New int `json:"new"`
+	// Refetched pages had validators but changed (or their stored copy was missing).
+	Refetched int `json:"refetched"`
+	// Unchanged pages were answered 304 Not Modified and reused.
+	Unchanged int `json:"unchanged"`
+	// Tracked is the number of URLs with recorded validators.
+	Tracked int `json:"tracked"`
+}
+
+// ManifestSnapshot identifies the run manifest being recorded.
+// Experimental: Only present when Config.Manifest.Dir is set.
+type ManifestSnapshot struct {
+	RunID string `json:"run_id"`
+	// Dir is the run directory to pass to DiffRuns.
+	Dir   string `json:"dir"`
+	Pages int    `json:"pages"`
+}
+
+// ScopeSnapshot counts URLs kept out of the crawl by Config.Scope.
+// Experimental: Field set may change pre-v1.0.
+type ScopeSnapshot struct {
+	// Rejected maps a rule name (see the ScopeRule* constants) to the number of URLs it
+	// rejected. Links seen more than once are counted each time they are rejected.
+	Rejected map[string]int `json:"rejected,omitempty"`
+	Total    int            `json:"total"`
 }
 
 // Engine composes all subsystems behind a single facade.
@@ -122,10 +238,22 @@ type Engine struct {
 	pl            *engpipeline.Pipeline
 	limiter       intrat.RateLimiter
 	rm            *intresources.Manager
+	frontier      *intfrontier.Queue
+	revisit       *revisit.Store
+	deadLetters   *deadletter.Store
+	checkpoint    *checkpoint.Log
+	manifest      *runs.Recorder
 	started       atomic.Bool
+	crawling      atomic.Bool // Start or Replay was called
+	stopped       atomic.Bool
+	jobsMu        sync.Mutex
+	jobs          []*Job
+	jobSeq        int
 	startedAt     time.Time
 	resumeMetrics resumeState
-	strategies    interface{} // Placeholder for broader strategy sets (future)
+	sitemapSnap   atomic.Pointer[SitemapSnapshot]
+	strategies    EngineStrategies
+	sinksOnce     sync.Once
 	assetStrategy AssetStrategy
 	assetMetrics  *AssetMetrics
 	assetEvents   []AssetEvent // simple in-memory buffer for now (Iteration 6 minimal impl)
@@ -222,15 +350,18 @@ func (e *Engine) healthProbes() (telemetryhealth.Probe, telemetryhealth.Probe, t
 		return telemetryhealth.Unhealthy("rate_limiter", "many open circuits")
 	})
 	resourceProbe := telemetryhealth.ProbeFunc(func(ctx context.Context) telemetryhealth.ProbeResult {
-		if e.rm == nil {
+		if e.rm != nil && e.rm.Stats().Pressure {
+			return telemetryhealth.Degraded("resources", "memory pressure")
+		}
+		if e.checkpoint == nil {
 			return telemetryhealth.Healthy("resources")
 		}
-		st := e.rm.Stats()
+		backlog := e.checkpoint.Stats().Unsynced
 		pol := e.Policy()
-		if st.CheckpointQueued >= pol.Health.ResourceUnhealthyCheckpoint {
+		if backlog >= pol.Health.ResourceUnhealthyCheckpoint {
 			return telemetryhealth.Unhealthy("resources", "checkpoint backlog severe")
 		}
-		if st.CheckpointQueued >= pol.Health.ResourceDegradedCheckpoint {
+		if backlog >= pol.Health.ResourceDegradedCheckpoint {
 			return telemetryhealth.Degraded("resources", "checkpoint backlog")
 		}
 		return telemetryhealth.Healthy("resources")
@@ -239,6 +370,9 @@ func (e *Engine) healthProbes() (telemetryhealth.Probe, telemetryhealth.Probe, t
 		if e.pl == nil {
 			return telemetryhealth.Unknown("pipeline", "not initialized")
 		}
+		if e.State() == EnginePaused {
+			return telemetryhealth.Degraded("pipeline", "paused")
+		}
 		m := e.pl.Metrics()
 		if m == nil {
 			return telemetryhealth.Unknown("pipeline", "metrics nil")
@@ -264,6 +398,8 @@ func (e *Engine) healthProbes() (telemetryhealth.Probe, telemetryhealth.Probe, t
 type resumeState struct {
 	skipped     int64
 	totalBefore int
+	finished    int
+	requeued    int
 }
 
 // optionFn is an internal functional option for future extension.
@@ -274,15 +410,100 @@ type optionFn func(*Config)
 // removed (previously ...Option) during Wave 3 pruning; callers now configure
 // exclusively via the Config struct.
 func New(cfg Config, opts ...optionFn) (*Engine, error) {
+	return newEngine(cfg, EngineStrategies{}, opts...)
+}
+
+func newEngine(cfg Config, strategies EngineStrategies, opts ...optionFn) (e *Engine, err error) {
 	for _, o := range opts {
 		if o != nil {
 			o(&cfg)
 		}
 	}
 
+	// Override checkpoint path if provided directly on facade config. This must happen
+	// before the resource manager is built so the checkpoint is actually written.
+	if cfg.CheckpointPath != "" {
+		cfg.Resources.CheckpointPath = cfg.CheckpointPath
+	}
+	if cfg.Recrawl.ConditionalGet {
+		if cfg.Resources.CheckpointPath == "" {
+			return nil, errors.New("recrawl: conditional GET requires a checkpoint path")
+		}
+		if cfg.Resources.SpillDirectory == "" {
+			cfg.Resources.SpillDirectory = cfg.Resources.CheckpointPath + ".spill"
+		}
+		if cfg.Recrawl.ValidatorsPath == "" {
+			cfg.Recrawl.ValidatorsPath = cfg.Resources.CheckpointPath + ".validators"
+		}
+	}
+	if err := cfg.Processing.validateSchemas(); err != nil {
+		return nil, err
+	}
+	if cfg.AssetPolicy.Enabled {
+		if err := cfg.AssetPolicy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var recorder *runs.Recorder
+	if cfg.Manifest.Dir != "" {
+		if cfg.Manifest.RunID == "" {
+			cfg.Manifest.RunID = time.Now().UTC().Format("20060102T150405Z")
+		}
+		open := runs.Create
+		if cfg.Manifest.Append {
+			open = runs.Append
+		}
+		rec, err := open(filepath.Join(cfg.Manifest.Dir, cfg.Manifest.RunID), cfg.Manifest.RunID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		recorder = rec
+		// Recorded last so it only sees pages every user sink accepted; closed by Stop.
+		strategies.OutputSinks = append(slices.Clip(strategies.OutputSinks), rec)
+	}
+
+	// Everything opened from here on is released if construction fails part way.
+	var (
+		limiter intrat.RateLimiter
+		rm      *intresources.Manager
+		pc      *engpipeline.PipelineConfig
+		pl      *engpipeline.Pipeline
+	)
+	closeAll := func() {
+		if pl != nil {
+			pl.Stop()
+		}
+		if c, ok := limiter.(interface{ Close() error }); ok {
+			_ = c.Close()
+		}
+		if rm != nil {
+			_ = rm.Close()
+		}
+		if pc == nil {
+			return
+		}
+		if pc.Frontier != nil {
+			_ = pc.Frontier.Close()
+		}
+		if pc.Revisit != nil {
+			_ = pc.Revisit.Close()
+		}
+		if pc.DeadLetters != nil {
+			_ = pc.DeadLetters.Close()
+		}
+		if pc.Checkpoint != nil {
+			_ = pc.Checkpoint.Close()
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeAll()
+		}
+	}()
+
 	// Build resource manager if configured
-	var rm *intresources.Manager
-	if cfg.Resources.CacheCapacity > 0 || cfg.Resources.MaxInFlight > 0 || cfg.Resources.CheckpointPath != "" {
+	if cfg.Resources.CacheCapacity > 0 || cfg.Resources.CacheMaxBytes > 0 || cfg.Resources.HeapSoftLimit > 0 || cfg.Resources.MaxInFlight > 0 || cfg.Resources.CheckpointPath != "" {
 		manager, err := intresources.NewManager(cfg.Resources.toInternal())
 		if err != nil {
 			return nil, err
@@ -291,21 +512,57 @@ func New(cfg Config, opts ...optionFn) (*Engine, error) {
 	}
 
 	// Build rate limiter
-	var limiter intrat.RateLimiter
 	if cfg.RateLimit.Enabled {
-		limiter = intrat.NewAdaptiveRateLimiter(cfg.RateLimit)
+		adaptive := intrat.NewAdaptiveRateLimiter(cfg.RateLimit)
+		limiter = adaptive
+		if cfg.RateLimit.StatePath != "" {
+			if _, err := adaptive.LoadState(cfg.RateLimit.StatePath, cfg.RateLimit.StateMaxAge); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// Override checkpoint path if provided directly on facade config
-	if cfg.CheckpointPath != "" {
-		cfg.Resources.CheckpointPath = cfg.CheckpointPath
+	scopeRules, err := cfg.Scope.rules()
+	if err != nil {
+		return nil, err
 	}
-
-	pc := (&cfg).toPipelineConfig(engineOptions{limiter: limiter, resourceManager: rm})
-	pl := engpipeline.NewPipeline(pc)
+	// e is assigned below; rejections are only reported once the crawl starts.
+	pc = (&cfg).toPipelineConfig(engineOptions{limiter: limiter, resourceManager: rm, strategies: strategies, scope: scopeRules, scopeRejected: func(rawURL, rule string) { e.reportScopeReject("", rawURL, rule) }, scaled: func(d engpipeline.ScaleDecision) { e.reportScale("", d) }})
+	if err := engpipeline.ValidateStrategies(pc); err != nil {
+		return nil, fmt.Errorf("engine strategies: %w", err)
+	}
+	if cfg.Frontier.Dir != "" {
+		q, err := intfrontier.Open(cfg.Frontier.toInternal())
+		if err != nil {
+			return nil, err
+		}
+		pc.Frontier, pc.FrontierSyncInterval = q, cfg.Frontier.SyncInterval
+	}
+	if cfg.Recrawl.ConditionalGet {
+		store, err := revisit.Open(cfg.Recrawl.ValidatorsPath)
+		if err != nil {
+			return nil, err
+		}
+		pc.Revisit = store
+	}
+	if cfg.DeadLetter.Path != "" {
+		store, err := deadletter.Open(cfg.DeadLetter.Path)
+		if err != nil {
+			return nil, err
+		}
+		pc.DeadLetters = store
+	}
+	if cfg.Resources.CheckpointPath != "" {
+		log, err := checkpoint.Open(checkpoint.Options{Path: cfg.Resources.CheckpointPath, Interval: cfg.Resources.CheckpointInterval, Resume: cfg.Resume, Key: pc.Canonicalizer.Key})
+		if err != nil {
+			return nil, err
+		}
+		pc.Checkpoint = log
+	}
+	pl = engpipeline.NewPipeline(pc)
 
 	telemOpts := telemetryConfigFromLegacy(cfg)
-	e := &Engine{cfg: cfg, telemetry: telemOpts, pl: pl, limiter: limiter, rm: rm, startedAt: time.Now()}
+	e = &Engine{cfg: cfg, telemetry: telemOpts, pl: pl, limiter: limiter, rm: rm, frontier: pc.Frontier, revisit: pc.Revisit, deadLetters: pc.DeadLetters, checkpoint: pc.Checkpoint, manifest: recorder, startedAt: time.Now(), strategies: strategies}
 
 	// Initialize metrics provider (Wave 4 W4-05: delegated to helper for reuse & clarity)
 	e.metricsProvider = selectMetricsProvider(cfg)
@@ -351,9 +608,6 @@ func New(cfg Config, opts ...optionFn) (*Engine, error) {
 		m := &AssetMetrics{}
 		publisher := assetEventCollector{engine: e}
 		as := NewDefaultAssetStrategy(m, publisher)
-		if err := cfg.AssetPolicy.Validate(); err != nil {
-			return nil, err
-		}
 		e.assetStrategy = as
 		e.assetMetrics = m
 		// Inject hook into pipeline for per-page processing.
@@ -481,6 +735,34 @@ func (e *Engine) HealthSnapshot(ctx context.Context) telemetryhealth.Snapshot {
 	return snap
 }
 
+// reportScopeReject reports a URL kept out of the crawl (or out of the named job) as a
+// debug "scope_reject" event.
+func (e *Engine) reportScopeReject(job, rawURL, rule string) {
+	labels := map[string]string{"rule": rule}
+	if job != "" {
+		labels["job"] = job
+	}
+	iev := telemEvents.Event{Category: telemEvents.CategoryPipeline, Type: "scope_reject", Severity: "debug", Labels: labels, Fields: map[string]interface{}{"url": rawURL}}
+	if e.eventBus != nil {
+		_ = e.eventBus.Publish(iev)
+	}
+	e.dispatchEvent(iev)
+}
+
+// reportScale reports an autoscaler decision of the main crawl (or the named job) as
+// an info "workers_scaled" event.
+func (e *Engine) reportScale(job string, d engpipeline.ScaleDecision) {
+	labels := map[string]string{"stage": d.Stage, "reason": d.Reason}
+	if job != "" {
+		labels["job"] = job
+	}
+	iev := telemEvents.Event{Category: telemEvents.CategoryPipeline, Type: "workers_scaled", Severity: "info", Labels: labels, Fields: map[string]interface{}{"from": d.From, "to": d.To, "queue_depth": d.QueueDepth, "busy_ratio": d.BusyRatio}}
+	if e.eventBus != nil {
+		_ = e.eventBus.Publish(iev)
+	}
+	e.dispatchEvent(iev)
+}
+
 // RegisterEventObserver adds an observer invoked synchronously for each internal telemetry
 // event. Safe for concurrent use. No-op if nil provided.
 // Experimental: May gain filtering / async delivery options pre-v1.0.
@@ -515,60 +797,121 @@ func (e *Engine) Start(ctx context.Context, seeds []string) (<-chan *engmodels.C
 	if !e.started.Load() {
 		return nil, errors.New("engine not started")
 	}
-	filtered := seeds
-	if e.cfg.Resume && e.cfg.Resources.CheckpointPath != "" {
-		filtered = e.filterSeeds(seeds)
+	if !e.crawling.CompareAndSwap(false, true) {
+		return nil, errors.New("engine: crawl already started; use Submit for further crawls")
+	}
+	if e.cfg.Resume && e.checkpoint != nil {
+		e.resumeCheckpoint(seeds)
+	}
+	admit := make([]engpipeline.Seed, 0, len(seeds))
+	for _, s := range seeds {
+		admit = append(admit, engpipeline.Seed{URL: s})
+	}
+	if e.cfg.Sitemap.enabled() {
+		admit = e.sitemapSeeds(ctx, seeds, admit)
 	}
-	results := e.pl.ProcessURLs(ctx, filtered)
+	results := e.pl.ProcessSeeds(ctx, admit)
 	return results, nil
 }
 
-func (e *Engine) filterSeeds(seeds []string) []string {
-	e.resumeMetrics.totalBefore = len(seeds)
-	path := e.cfg.Resources.CheckpointPath
-	file, err := os.Open(path)
-	if err != nil {
-		return seeds // if missing treat as fresh
-	}
-	defer func() { _ = file.Close() }()
-	seen := make(map[string]struct{}, len(seeds))
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		seen[strings.TrimSpace(scanner.Text())] = struct{}{}
+// sitemapSeeds appends the sitemap entries found for seeds (highest priority first)
+// to admit and records the outcome for Snapshot.
+func (e *Engine) sitemapSeeds(ctx context.Context, seeds []string, admit []engpipeline.Seed) []engpipeline.Seed {
+	sc := e.cfg.Sitemap
+	res := intsitemap.Load(ctx, e.pl.RawFetch, intsitemap.Config{URLs: sc.URLs, Discover: sc.Discover, Since: sc.Since, MaxEntries: sc.MaxEntries}, seeds)
+	snap := &SitemapSnapshot{Sitemaps: res.Sitemaps, URLs: len(res.Entries), Filtered: res.Filtered}
+	for _, err := range res.Errors {
+		snap.Errors = append(snap.Errors, err.Error())
 	}
-	if err := scanner.Err(); err != nil {
-		return seeds
+	e.sitemapSnap.Store(snap)
+	for _, entry := range res.Entries {
+		admit = append(admit, engpipeline.Seed{URL: entry.Loc, Sitemap: true, Priority: entry.Priority})
 	}
-	out := make([]string, 0, len(seeds))
+	return admit
+}
+
+// resumeCheckpoint restores the crawl recorded in the checkpoint and counts the seeds
+// it already finished, which the frontier then skips. Seeds are still handed to the
+// pipeline so their hosts stay followable.
+func (e *Engine) resumeCheckpoint(seeds []string) {
+	e.resumeMetrics.totalBefore = len(seeds)
+	canon := e.pl.Config().Canonicalizer
 	for _, s := range seeds {
-		if _, ok := seen[s]; ok {
+		if e.checkpoint.Finished(canon.Key(s)) {
 			e.resumeMetrics.skipped++
-			continue
 		}
-		out = append(out, s)
 	}
-	return out
+	e.resumeMetrics.finished, e.resumeMetrics.requeued = e.pl.RestoreCheckpoint()
 }
 
 // Stop gracefully stops the engine and underlying components.
 // Stable: Idempotent; safe to call multiple times after v1.0.
 func (e *Engine) Stop() error {
+	e.stopped.Store(true)
+	e.stopJobs()
 	if e.pl != nil {
 		e.pl.Stop()
 	}
+	var limiterErr error
+	if s, ok := e.limiter.(interface{ SaveState(string) error }); ok && e.cfg.RateLimit.StatePath != "" {
+		limiterErr = s.SaveState(e.cfg.RateLimit.StatePath)
+	}
 	if c, ok := e.limiter.(interface{ Close() error }); ok {
 		_ = c.Close()
 	}
 	if e.rm != nil {
 		_ = e.rm.Close()
 	}
-	return nil
+	var frontierErr error
+	if e.frontier != nil {
+		if frontierErr = e.frontier.Close(); frontierErr != nil {
+			frontierErr = fmt.Errorf("close frontier: %w", frontierErr)
+		}
+	}
+	var revisitErr error
+	if e.revisit != nil {
+		if revisitErr = e.revisit.Close(); revisitErr != nil {
+			revisitErr = fmt.Errorf("close validator store: %w", revisitErr)
+		}
+	}
+	var checkpointErr error
+	if e.checkpoint != nil {
+		if checkpointErr = e.checkpoint.Close(); checkpointErr != nil {
+			checkpointErr = fmt.Errorf("close checkpoint: %w", checkpointErr)
+		}
+	}
+	var deadLetterErr error
+	if e.deadLetters != nil {
+		if deadLetterErr = e.deadLetters.Close(); deadLetterErr != nil {
+			deadLetterErr = fmt.Errorf("close dead-letter store: %w", deadLetterErr)
+		}
+	}
+	return errors.Join(limiterErr, frontierErr, revisitErr, deadLetterErr, checkpointErr, e.closeSinks())
+}
+
+// closeSinks flushes and closes the injected output sinks exactly once, after the
+// pipeline has stopped writing to them.
+func (e *Engine) closeSinks() error {
+	var errs []error
+	e.sinksOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, sink := range e.strategies.OutputSinks {
+			if err := sink.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("flush sink %s: %w", sink.Name(), err))
+			}
+			if err := sink.Close(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("close sink %s: %w", sink.Name(), err))
+			}
+		}
+	})
+	return errors.Join(errs...)
 }
 
 // Snapshot returns a unified state view.
 // Stable: See Snapshot field stability guarantees.
 func (e *Engine) Snapshot() Snapshot {
-	snap := Snapshot{StartedAt: e.startedAt}
+	snap := Snapshot{State: e.State(), StartedAt: e.startedAt}
 	if e.startedAt.IsZero() {
 		snap.StartedAt = time.Now()
 	}
@@ -578,11 +921,11 @@ func (e *Engine) Snapshot() Snapshot {
 	}
 	if e.limiter != nil {
 		is := e.limiter.Snapshot()
-		pub := LimiterSnapshot{TotalRequests: is.TotalRequests, Throttled: is.Throttled, Denied: is.Denied, OpenCircuits: is.OpenCircuits, HalfOpenCircuits: is.HalfOpenCircuits}
+		pub := LimiterSnapshot{TotalRequests: is.TotalRequests, Throttled: is.Throttled, Denied: is.Denied, OpenCircuits: is.OpenCircuits, HalfOpenCircuits: is.HalfOpenCircuits, RestoredDomains: is.RestoredDomains}
 		if len(is.Domains) > 0 {
 			pub.Domains = make([]LimiterDomainState, 0, len(is.Domains))
 			for _, d := range is.Domains {
-				pub.Domains = append(pub.Domains, LimiterDomainState{Domain: d.Domain, FillRate: d.FillRate, CircuitState: d.CircuitState, LastActivity: d.LastActivity})
+				pub.Domains = append(pub.Domains, LimiterDomainState{Domain: d.Domain, FillRate: d.FillRate, CircuitState: d.CircuitState, LastActivity: d.LastActivity, LatencyEWMA: d.LatencyEWMA, ErrorRate: d.ErrorRate, Restored: d.Restored})
 			}
 		}
 		snap.Limiter = &pub
@@ -594,14 +937,57 @@ func (e *Engine) Snapshot() Snapshot {
 	if e.rm != nil {
 		rs := e.rm.Stats()
 		snap.Resources = &ResourceSnapshot{
-			CacheEntries:     rs.CacheEntries,
-			SpillFiles:       rs.SpillFiles,
-			InFlight:         rs.InFlight,
-			CheckpointQueued: rs.CheckpointQueued,
+			CacheEntries:   rs.CacheEntries,
+			CacheBytes:     rs.CacheBytes,
+			SpillFiles:     rs.SpillFiles,
+			SpillBytes:     rs.SpillBytes,
+			Evictions:      rs.Evictions,
+			InFlight:       rs.InFlight,
+			HeapLive:       rs.HeapLive,
+			MemoryPressure: rs.Pressure,
+			Shed:           rs.Shed,
+			Throttled:      rs.Throttled,
 		}
 	}
+	if e.checkpoint != nil {
+		cs := e.checkpoint.Stats()
+		if snap.Resources != nil {
+			snap.Resources.CheckpointQueued = cs.Unsynced
+		}
+		snap.Checkpoint = &CheckpointSnapshot{Path: e.cfg.Resources.CheckpointPath, Queued: cs.Queued, InFlight: cs.InFlight, Succeeded: cs.Succeeded, Failed: cs.Failed, Records: cs.Records, Compactions: cs.Compactions, Errors: cs.Errors}
+	}
 	if e.cfg.Resume {
-		snap.Resume = &ResumeSnapshot{SeedsBefore: e.resumeMetrics.totalBefore, Skipped: e.resumeMetrics.skipped}
+		snap.Resume = &ResumeSnapshot{SeedsBefore: e.resumeMetrics.totalBefore, Skipped: e.resumeMetrics.skipped, Finished: e.resumeMetrics.finished, Requeued: e.resumeMetrics.requeued}
+	}
+	if sm := e.sitemapSnap.Load(); sm != nil {
+		cp := *sm
+		snap.Sitemap = &cp
+	}
+	if e.pl != nil {
+		fs := &FrontierSnapshot{Persistent: e.frontier != nil, Queued: snap.Pipeline.URLsQueued}
+		for _, h := range e.pl.FrontierHosts() {
+			fs.Hosts = append(fs.Hosts, FrontierHost{Host: h.Host, Queued: h.Pending})
+		}
+		snap.Frontier = fs
+	}
+	if e.revisit != nil && snap.Pipeline != nil {
+		snap.Recrawl = &RecrawlSnapshot{New: snap.Pipeline.PagesNew, Refetched: snap.Pipeline.PagesRefetched, Unchanged: snap.Pipeline.PagesUnchanged, Tracked: e.revisit.Len()}
+	}
+	if snap.Pipeline != nil {
+		ss := &ScopeSnapshot{Rejected: snap.Pipeline.URLsRejected}
+		for _, n := range ss.Rejected {
+			ss.Total += n
+		}
+		snap.Scope = ss
+	}
+	for _, j := range e.Jobs() {
+		snap.Jobs = append(snap.Jobs, j.Stats())
+	}
+	if e.deadLetters != nil && snap.Pipeline != nil {
+		snap.DeadLetter = &DeadLetterSnapshot{Path: e.cfg.DeadLetter.Path, Recorded: snap.Pipeline.DeadLettered, Cleared: snap.Pipeline.DeadLetterCleared, Pending: e.deadLetters.Len()}
+	}
+	if e.manifest != nil {
+		snap.Manifest = &ManifestSnapshot{RunID: e.cfg.Manifest.RunID, Dir: e.manifest.Dir(), Pages: e.manifest.Len()}
 	}
 	return snap
 }
@@ -612,23 +998,24 @@ func (e *Engine) Snapshot() Snapshot {
 // RegisterEventObserver and future span helper (if introduced). Intentionally no replacement exported now.
 
 // EngineStrategies defines business logic components for dependency injection.
-// Experimental: Placeholder for future strategy extension wiring; not yet integrated.
+// Zero-valued fields select the built-in implementations: the net/http fetcher,
+// pass-through processing and no sinks (results are always delivered on the channel
+// returned by Start).
+// Experimental: Field set may grow (e.g. asset strategy) before v1.0.
 type EngineStrategies struct {
-	Fetcher     interface{} // Placeholder for crawler.Fetcher interface
-	Processors  interface{} // Placeholder for []processor.Processor slice
-	OutputSinks interface{} // Placeholder for []output.OutputSink slice
+	// Fetcher replaces the built-in page fetcher for the extraction stage.
+	Fetcher Fetcher
+	// Processors run in order on every fetched page, after the built-in content
+	// processing (Config.Processing); an error fails that page.
+	Processors []Processor
+	// OutputSinks receive every successfully processed page in order. The engine
+	// flushes and closes them on Stop.
+	OutputSinks []OutputSink
 }
 
 // NewWithStrategies creates an engine with custom business logic strategies.
-// Experimental: Construction path likely to change once strategy integration lands.
+// Nil Processors or OutputSinks entries are rejected.
+// Experimental: Construction path may change once strategy integration settles.
 func NewWithStrategies(cfg Config, strategies EngineStrategies, opts ...optionFn) (*Engine, error) {
-	// Build engine using existing constructor
-	engine, err := New(cfg, opts...)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store strategies for future use in pipeline integration
-	engine.strategies = strategies
-	return engine, nil
+	return newEngine(cfg, strategies, opts...)
 }
@@ -20,12 +20,25 @@ import (
 func TestEngineExportAllowlist(t *testing.T) {
 	allowed := map[string]struct{}{
 		// Core types
-		"Engine": {}, "Config": {}, "ResourcesConfig": {}, "Snapshot": {}, "ResourceSnapshot": {}, "ResumeSnapshot": {},
+		"Engine": {}, "Config": {}, "ResourcesConfig": {}, "RobotsConfig": {}, "SitemapConfig": {}, "ProcessingConfig": {}, "ExtractionSchema": {}, "ExtractionField": {}, "CanonicalConfig": {}, "FrontierConfig": {}, "RecrawlConfig": {}, "ManifestConfig": {}, "Snapshot": {}, "ResourceSnapshot": {}, "ResumeSnapshot": {}, "CheckpointSnapshot": {}, "SitemapSnapshot": {}, "FrontierSnapshot": {}, "FrontierHost": {}, "RecrawlSnapshot": {}, "ManifestSnapshot": {},
+		// Crawl scope
+		"ScopeConfig": {}, "ScopeSnapshot": {}, "ScopeRuleScheme": {}, "ScopeRuleMaxDepth": {}, "ScopeRuleMaxPages": {}, "ScopeRuleMaxPagesPerHost": {}, "ScopeRuleMaxBytesPerHost": {},
+		"ScopeRuleOffsite": {}, "ScopeRuleAllowedDomains": {}, "ScopeRuleBlockedDomain": {}, "ScopeRulePathPrefix": {}, "ScopeRuleInclude": {}, "ScopeRuleExclude": {},
+		// Run change detection
+		"DiffRuns": {}, "RunDiff": {}, "RunDiffOptions": {}, "PageChange": {},
+		// Live control
+		"EngineState": {}, "EngineIdle": {}, "EngineRunning": {}, "EnginePaused": {}, "EngineDraining": {}, "EngineStopped": {},
+		// Worker pool autoscaling
+		"AutoscaleConfig": {}, "WorkerBounds": {},
+		// Multi-job engine
+		"Job": {}, "JobSpec": {}, "JobState": {}, "JobSnapshot": {}, "JobRunning": {}, "JobCompleted": {}, "JobCancelled": {},
+		// Dead letters and replay
+		"DeadLetterConfig": {}, "DeadLetter": {}, "DeadLetterFilter": {}, "DeadLetterSnapshot": {}, "ReadDeadLetters": {},
 		// Rate limiter reduced public snapshot (Phase C5)
 		"LimiterSnapshot": {}, "LimiterDomainState": {},
 		// Telemetry facade additions (Phase C6 begin)
 		"TelemetryEvent": {}, "TelemetryOptions": {}, "EventObserver": {}, "TelemetryPolicy": {}, "HealthPolicy": {}, "TracingPolicy": {}, "EventBusPolicy": {}, "DefaultTelemetryPolicy": {},
-		// Construction & strategies placeholder
+		// Construction & strategies
 		"New": {}, "NewWithStrategies": {}, "EngineStrategies": {},
 		// Consolidated strategy interfaces
 		"Fetcher": {}, "Processor": {}, "OutputSink": {}, "AssetStrategy": {},
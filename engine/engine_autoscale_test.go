@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngineAutoscaleWithinMaxInFlight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(15 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, "<html><body>%s</body></html>", r.URL.Path)
+	}))
+	defer srv.Close()
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	cfg.Robots.Enabled = false
+	cfg.ExtractionWorkers = 1
+	cfg.Resources.MaxInFlight = 2
+	cfg.Autoscale.Enabled = true
+	cfg.Autoscale.Interval = 40 * time.Millisecond
+	cfg.Autoscale.Extraction = WorkerBounds{Min: 1, Max: 8}
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	var mu sync.Mutex
+	var scaled []TelemetryEvent
+	eng.RegisterEventObserver(func(ev TelemetryEvent) {
+		if ev.Type == "workers_scaled" {
+			mu.Lock()
+			scaled = append(scaled, ev)
+			mu.Unlock()
+		}
+	})
+	var seeds []string
+	for i := 0; i < 40; i++ {
+		seeds = append(seeds, fmt.Sprintf("%s/page/%d", srv.URL, i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, seeds)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for r := range results {
+		if !r.Success {
+			t.Fatalf("unexpected failure: %+v", r)
+		}
+	}
+	snap := eng.Snapshot()
+	if got := snap.Pipeline.Workers["extraction"]; got != 2 {
+		t.Fatalf("expected extraction to grow to MaxInFlight (2), got %d (%v)", got, snap.Pipeline.Workers)
+	}
+	if len(snap.Pipeline.Scaling) == 0 {
+		t.Fatalf("expected scaling decisions in the snapshot")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var grew bool
+	for _, ev := range scaled {
+		if ev.Labels["stage"] == "extraction" && ev.Labels["reason"] == "busy" && ev.Fields["to"] == 2 {
+			grew = true
+		}
+	}
+	if !grew {
+		t.Fatalf("missing extraction scale-up event in %+v", scaled)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func TestEnginePauseEnqueueCancelResume(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "<html><body>%s</body></html>", r.URL.Path)
+	}))
+	defer srv.Close()
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	cfg.Robots.Enabled = false
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	var mu sync.Mutex
+	var events []string
+	eng.RegisterEventObserver(func(ev TelemetryEvent) {
+		mu.Lock()
+		events = append(events, ev.Type)
+		mu.Unlock()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := eng.Enqueue(ctx, srv.URL+"/early"); err == nil {
+		t.Fatalf("enqueue before start must fail")
+	}
+	if eng.State() != EngineIdle {
+		t.Fatalf("expected idle, got %s", eng.State())
+	}
+
+	if err := eng.Pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	results, err := eng.Start(ctx, []string{srv.URL + "/a", srv.URL + "/skip/1"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if n, err := eng.Enqueue(ctx, srv.URL+"/b", srv.URL+"/a", srv.URL+"/skip/2"); err != nil || n != 2 {
+		t.Fatalf("enqueue: n=%d err=%v", n, err)
+	}
+	if n, err := eng.CancelURLs("/skip/**"); err != nil || n != 2 {
+		t.Fatalf("cancel: n=%d err=%v", n, err)
+	}
+	if _, err := eng.CancelURLs("re:("); err == nil {
+		t.Fatalf("expected an invalid pattern error")
+	}
+	snap := eng.Snapshot()
+	if snap.State != EnginePaused || snap.Pipeline.URLsQueued != 2 {
+		t.Fatalf("expected paused with two queued URLs, got %s queued=%d", snap.State, snap.Pipeline.URLsQueued)
+	}
+	health := eng.HealthSnapshot(ctx)
+	for _, p := range health.Probes {
+		if p.Name == "pipeline" && (p.Status != "degraded" || p.Detail != "paused") {
+			t.Fatalf("expected a degraded pipeline probe while paused, got %+v", p)
+		}
+	}
+
+	got := map[string]*engmodels.CrawlResult{}
+	var resumed bool
+	for r := range results {
+		got[r.URL] = r
+		if !resumed && len(got) == 2 {
+			// Both cancelled URLs are reported while still paused.
+			if r.Success || r.Stage != "scope" || r.Error.Code != engmodels.ErrorCodeOutOfScope {
+				t.Fatalf("expected a cancelled result, got %+v", r)
+			}
+			if err := eng.Resume(); err != nil {
+				t.Fatalf("resume: %v", err)
+			}
+			resumed = true
+		}
+	}
+	if len(got) != 4 || !got[srv.URL+"/a"].Success || !got[srv.URL+"/b"].Success || got[srv.URL+"/skip/1"].Success {
+		t.Fatalf("unexpected results: %v", got)
+	}
+	snap = eng.Snapshot()
+	if snap.State != EngineStopped || snap.Scope.Rejected["cancelled:/skip/**"] != 2 {
+		t.Fatalf("unexpected final snapshot: state=%s scope=%+v", snap.State, snap.Scope)
+	}
+	if _, err := eng.Enqueue(ctx, srv.URL+"/late"); err == nil {
+		t.Fatalf("enqueue after the crawl finished must fail")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]bool{"paused": false, "resumed": false, "urls_cancelled": false}
+	for _, typ := range events {
+		if _, ok := want[typ]; ok {
+			want[typ] = true
+		}
+	}
+	for typ, seen := range want {
+		if !seen {
+			t.Fatalf("missing %q event in %v", typ, events)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func TestEngineDeadLettersAndReplay(t *testing.T) {
+	var recovered atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = fmt.Fprint(w, `<html><body><a href="/flaky">f</a><a href="/missing">m</a></body></html>`)
+		case "/flaky":
+			if !recovered.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = fmt.Fprint(w, `<html><head><title>Flaky</title></head><body><a href="/child">c</a></body></html>`)
+		case "/child":
+			_, _ = fmt.Fprint(w, "<html><body>child</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dead-letters.jsonl")
+
+	run := func(replay bool) (map[string]*engmodels.CrawlResult, Snapshot) {
+		t.Helper()
+		cfg := Defaults()
+		cfg.RateLimit.Enabled = false
+		cfg.Robots.Enabled = false
+		cfg.RetryMaxAttempts = 1
+		cfg.Scope.MaxDepth = 2
+		cfg.DeadLetter.Path = path
+		cfg.Manifest = ManifestConfig{Dir: dir, RunID: "run", Append: replay}
+		eng, err := NewWithStrategies(cfg, EngineStrategies{Processors: []Processor{bodyAsMarkdown{}}})
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var results <-chan *engmodels.CrawlResult
+		if replay {
+			results, err = eng.Replay(ctx, DeadLetterFilter{Codes: []engmodels.ErrorCode{engmodels.ErrorCodeHTTP5xx}, Domains: []string{"127.0.0.1"}})
+		} else {
+			results, err = eng.Start(ctx, []string{srv.URL + "/"})
+		}
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		got := make(map[string]*engmodels.CrawlResult)
+		for r := range results {
+			got[r.URL] = r
+		}
+		snap := eng.Snapshot()
+		if err := eng.Stop(); err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+		return got, snap
+	}
+
+	first, snap := run(false)
+	if r := first[srv.URL+"/flaky"]; r == nil || r.Success {
+		t.Fatalf("expected /flaky to fail: %+v", r)
+	}
+	if snap.DeadLetter == nil || snap.DeadLetter.Recorded != 2 || snap.DeadLetter.Pending != 2 {
+		t.Fatalf("unexpected dead-letter snapshot: %+v", snap.DeadLetter)
+	}
+	letters, err := ReadDeadLetters(path, DeadLetterFilter{})
+	if err != nil || len(letters) != 2 {
+		t.Fatalf("read dead letters: %+v %v", letters, err)
+	}
+	flaky := letters[0]
+	if flaky.URL != srv.URL+"/flaky" || flaky.Code != engmodels.ErrorCodeHTTP5xx || flaky.StatusCode != 503 || flaky.Depth != 1 || flaky.Attempts != 1 || flaky.Failures != 1 || flaky.FirstFailedAt.IsZero() {
+		t.Fatalf("unexpected dead letter: %+v", flaky)
+	}
+	if letters[1].Code != engmodels.ErrorCodeHTTP4xx || letters[1].StatusCode != 404 {
+		t.Fatalf("unexpected dead letter: %+v", letters[1])
+	}
+
+	recovered.Store(true)
+	second, snap := run(true)
+	if len(second) != 2 || second[srv.URL+"/flaky"] == nil || !second[srv.URL+"/flaky"].Success || second[srv.URL+"/child"] == nil {
+		t.Fatalf("expected the replay to fetch /flaky and follow its link, got %v", second)
+	}
+	if second[srv.URL+"/missing"] != nil {
+		t.Fatalf("the 4xx dead letter must be filtered out")
+	}
+	if snap.DeadLetter.Cleared != 1 || snap.DeadLetter.Pending != 1 || snap.Manifest.Pages != 3 {
+		t.Fatalf("unexpected snapshots after replay: %+v %+v", snap.DeadLetter, snap.Manifest)
+	}
+	if letters, _ := ReadDeadLetters(path, DeadLetterFilter{}); len(letters) != 1 || letters[0].URL != srv.URL+"/missing" {
+		t.Fatalf("expected only the 4xx dead letter to remain: %+v", letters)
+	}
+
+	eng, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = eng.Stop() }()
+	if _, err := eng.Replay(context.Background(), DeadLetterFilter{}); err == nil {
+		t.Fatalf("replay without a dead-letter path must fail")
+	}
+}
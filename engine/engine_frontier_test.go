@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineDiskFrontierPersistsAcrossRuns(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`)
+			return
+		}
+		_, _ = fmt.Fprint(w, "<html><body>leaf</body></html>")
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	run := func(seeds ...string) (map[string]bool, Snapshot) {
+		t.Helper()
+		cfg := Defaults()
+		cfg.RateLimit.Enabled = false
+		cfg.Robots.Enabled = false
+		cfg.Scope.MaxDepth = 1
+		cfg.Frontier = FrontierConfig{Dir: dir}
+		eng, err := New(cfg)
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results, err := eng.Start(ctx, seeds)
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		got := map[string]bool{}
+		for r := range results {
+			got[r.URL] = r.Success
+		}
+		snap := eng.Snapshot()
+		if err := eng.Stop(); err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+		return got, snap
+	}
+
+	first, snap := run(srv.URL + "/")
+	if len(first) != 4 || !first[srv.URL+"/c"] {
+		t.Fatalf("expected seed and three links, got %v", first)
+	}
+	if snap.Frontier == nil || !snap.Frontier.Persistent || snap.Frontier.Queued != 0 || snap.Pipeline.URLsAdmitted != 4 {
+		t.Fatalf("unexpected frontier snapshot: %+v pipeline=%+v", snap.Frontier, snap.Pipeline)
+	}
+
+	// The de-duplication set survives the restart: only the new seed is crawled.
+	second, snap := run(srv.URL+"/", srv.URL+"/a", srv.URL+"/new")
+	if len(second) != 1 || !second[srv.URL+"/new"] {
+		t.Fatalf("expected only the unseen seed, got %v", second)
+	}
+	if snap.Pipeline.URLsAdmitted != 5 {
+		t.Fatalf("admitted count should carry over, got %d", snap.Pipeline.URLsAdmitted)
+	}
+}
+
+func TestEngineNewReleasesStoresOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Defaults()
+	cfg.Robots.Enabled = false
+	cfg.Frontier = FrontierConfig{Dir: filepath.Join(dir, "frontier")}
+	cfg.DeadLetter.Path = filepath.Join(dir, "dead-letters.jsonl")
+	cfg.CheckpointPath = dir // a directory: opening the checkpoint log fails
+	if _, err := New(cfg); err == nil {
+		t.Fatal("expected New to fail on an unusable checkpoint path")
+	}
+	// The frontier opened before the failure was closed cleanly.
+	data, err := os.ReadFile(filepath.Join(dir, "frontier", "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"clean":true`) {
+		t.Fatalf("frontier left open after failed New: %s", data)
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"github.com/99souls/ariadne/engine/internal/testutil/httpmock"
 )
 
 // TestEngineBasicFlow validates facade can process a small set of URLs and produce a snapshot.
 func TestEngineBasicFlow(t *testing.T) {
+	srv := httpmock.NewServer([]httpmock.RouteSpec{{Pattern: "/", MatchPrefix: true, Body: "<html><head><title>Page</title></head><body><p>hello</p></body></html>", Headers: map[string]string{"Content-Type": "text/html"}}})
+	defer srv.Close()
+
 	cfg := Defaults()
 	cfg.Resources.CacheCapacity = 4
 	cfg.Resources.MaxInFlight = 4
@@ -19,9 +24,9 @@ func TestEngineBasicFlow(t *testing.T) {
 	defer func() { _ = eng.Stop() }()
 
 	urls := []string{
-		"https://example.com/one",
-		"https://example.com/two",
-		"https://example.com/three",
+		srv.URL() + "/one",
+		srv.URL() + "/two",
+		srv.URL() + "/three",
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -33,7 +38,10 @@ func TestEngineBasicFlow(t *testing.T) {
 	}
 
 	var count int
-	for range resultsCh {
+	for r := range resultsCh {
+		if !r.Success || r.Page == nil || r.Page.Title != "Page" {
+			t.Fatalf("expected fetched page, got %+v", r)
+		}
 		count++
 	}
 	if count != len(urls) {
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineConcurrentJobs(t *testing.T) {
+	site := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/" {
+				_, _ = fmt.Fprintf(w, `<html><body>%s <a href="/one">1</a><a href="/two">2</a></body></html>`, name)
+				return
+			}
+			_, _ = fmt.Fprintf(w, "<html><body>%s %s</body></html>", name, r.URL.Path)
+		}))
+	}
+	a, b := site("a"), site("b")
+	defer a.Close()
+	defer b.Close()
+	blocked := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blocked:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(blocked)
+
+	cfg := Defaults()
+	cfg.Robots.Enabled = false
+	cfg.Scope.MaxDepth = 1
+	cfg.RateLimit.InitialRPS = 1000
+	cfg.RateLimit.MaxRPS = 1000
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ja, err := eng.Submit(ctx, JobSpec{Name: "site-a", Seeds: []string{a.URL + "/"}})
+	if err != nil {
+		t.Fatalf("submit a: %v", err)
+	}
+	if _, err := eng.Submit(ctx, JobSpec{Name: "site-a", Seeds: []string{a.URL + "/"}}); err == nil {
+		t.Fatalf("expected a duplicate running job name to be rejected")
+	}
+	jb, err := eng.Submit(ctx, JobSpec{Seeds: []string{b.URL + "/"}, Scope: &ScopeConfig{MaxDepth: 1, PathPrefixes: []string{"/", "/one"}, Exclude: []string{"/two"}}})
+	if err != nil {
+		t.Fatalf("submit b: %v", err)
+	}
+	js, err := eng.Submit(ctx, JobSpec{Name: "slow", Seeds: []string{slow.URL + "/"}})
+	if err != nil {
+		t.Fatalf("submit slow: %v", err)
+	}
+
+	collect := func(j *Job, host string) int {
+		n := 0
+		for r := range j.Results() {
+			if !r.Success || !strings.HasPrefix(r.URL, host) {
+				t.Errorf("job %s: unexpected result %+v", j.Name(), r)
+			}
+			n++
+		}
+		<-j.Done()
+		return n
+	}
+	type count struct{ a, b int }
+	done := make(chan count)
+	go func() { done <- count{a: collect(ja, a.URL)} }()
+	go func() { done <- count{b: collect(jb, b.URL)} }()
+	var got count
+	for i := 0; i < 2; i++ {
+		c := <-done
+		got.a += c.a
+		got.b += c.b
+	}
+	if got.a != 3 || got.b != 2 {
+		t.Fatalf("expected 3 results for site-a and 2 for job-2, got %+v", got)
+	}
+	if jb.Name() != "job-2" {
+		t.Fatalf("expected a generated job name, got %q", jb.Name())
+	}
+
+	js.Cancel()
+	for range js.Results() {
+	}
+	<-js.Done()
+
+	snap := eng.Snapshot()
+	states := map[string]JobSnapshot{}
+	for _, s := range snap.Jobs {
+		states[s.Name] = s
+	}
+	if s := states["site-a"]; s.State != JobCompleted || s.Processed != 3 || s.Admitted != 3 || s.FinishedAt.IsZero() {
+		t.Fatalf("unexpected site-a snapshot: %+v", s)
+	}
+	if s := states["job-2"]; s.State != JobCompleted || s.Processed != 2 || s.Rejected != 1 {
+		t.Fatalf("unexpected job-2 snapshot: %+v", s)
+	}
+	if s := states["slow"]; s.State != JobCancelled {
+		t.Fatalf("unexpected slow snapshot: %+v", s)
+	}
+	domains := map[string]bool{}
+	for _, d := range snap.Limiter.Domains {
+		domains[d.Domain] = true
+	}
+	if len(domains) < 2 {
+		t.Fatalf("expected the jobs to share the engine limiter, got domains %v", domains)
+	}
+
+	if _, err := eng.Start(ctx, []string{a.URL + "/"}); err != nil {
+		t.Fatalf("start next to jobs: %v", err)
+	}
+	if _, err := eng.Start(ctx, []string{a.URL + "/"}); err == nil {
+		t.Fatalf("a second Start must fail")
+	}
+}
+
+func TestEngineJobsKeepOutOfRunManifestAndDeadLetters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, "<html><head><title>Job page</title></head><body>job</body></html>")
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	cfg.Robots.Enabled = false
+	cfg.RetryMaxAttempts = 1
+	cfg.DeadLetter.Path = filepath.Join(dir, "dead-letters.jsonl")
+	cfg.Manifest = ManifestConfig{Dir: dir, RunID: "main"}
+	sink := &recordingSink{}
+	eng, err := NewWithStrategies(cfg, EngineStrategies{OutputSinks: []OutputSink{sink}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	j, err := eng.Submit(ctx, JobSpec{Seeds: []string{srv.URL + "/page", srv.URL + "/missing"}})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	for range j.Results() {
+	}
+	<-j.Done()
+	if s := j.Stats(); s.Processed != 1 || s.Failed != 1 {
+		t.Fatalf("unexpected job stats: %+v", s)
+	}
+	sink.mu.Lock()
+	titles := append([]string(nil), sink.titles...)
+	sink.mu.Unlock()
+	if len(titles) != 1 || titles[0] != "Job page" {
+		t.Fatalf("expected the job page in the injected sink, got %q", titles)
+	}
+	snap := eng.Snapshot()
+	if snap.Manifest == nil || snap.Manifest.Pages != 0 {
+		t.Fatalf("job pages must not be recorded in the run manifest: %+v", snap.Manifest)
+	}
+	if snap.DeadLetter == nil || snap.DeadLetter.Pending != 0 {
+		t.Fatalf("job failures must not be dead-lettered: %+v", snap.DeadLetter)
+	}
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEngineLimiterStateSurvivesRestart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "<html><body>leaf</body></html>")
+	}))
+	defer srv.Close()
+	statePath := filepath.Join(t.TempDir(), "limiter.json")
+	cfg := Defaults()
+	cfg.Robots.Enabled = false
+	cfg.Scope.MaxDepth = 0
+	cfg.RateLimit.StatePath = statePath
+
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, []string{srv.URL + "/"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for range results {
+	}
+	if snap := eng.Snapshot(); snap.Limiter.RestoredDomains != 0 {
+		t.Fatalf("nothing should be restored on the first run: %+v", snap.Limiter)
+	}
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	next, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new after restart: %v", err)
+	}
+	defer func() { _ = next.Stop() }()
+	snap := next.Snapshot()
+	if snap.Limiter.RestoredDomains != 1 || len(snap.Limiter.Domains) != 1 {
+		t.Fatalf("expected one restored domain, got %+v", snap.Limiter)
+	}
+	if d := snap.Limiter.Domains[0]; !d.Restored || d.CircuitState != "closed" || d.LatencyEWMA <= 0 {
+		t.Fatalf("unexpected restored domain state: %+v", d)
+	}
+
+	cfg.RateLimit.StateMaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	aged, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new with short max age: %v", err)
+	}
+	defer func() { _ = aged.Stop() }()
+	if n := aged.Snapshot().Limiter.RestoredDomains; n != 0 {
+		t.Fatalf("expected stale state to be ignored, restored %d", n)
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+// bodyAsMarkdown stands in for content extraction: the raw body becomes the markdown.
+type bodyAsMarkdown struct{}
+
+func (bodyAsMarkdown) Process(_ context.Context, p *engmodels.Page) (*engmodels.Page, error) {
+	p.Markdown = p.Content
+	return p, nil
+}
+
+func TestEngineRunManifestsAndDiff(t *testing.T) {
+	var second atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			links := `<a href="/guide">g</a><a href="/old">o</a>`
+			if second.Load() {
+				links = `<a href="/guide">g</a><a href="/new">n</a>`
+			}
+			_, _ = fmt.Fprintf(w, "<html><head><title>Home</title></head><body>\n%s\n</body></html>", links)
+		case r.URL.Path == "/guide" && second.Load():
+			_, _ = fmt.Fprint(w, "<html><head><title>Guide</title></head><body>\nstep 1\nstep two\n</body></html>")
+		case r.URL.Path == "/guide":
+			_, _ = fmt.Fprint(w, "<html><head><title>Guide</title></head><body>\nstep 1\nstep 2\n</body></html>")
+		default:
+			_, _ = fmt.Fprint(w, "<html><body>leaf</body></html>")
+		}
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	run := func(id string) Snapshot {
+		t.Helper()
+		cfg := Defaults()
+		cfg.RateLimit.Enabled = false
+		cfg.Robots.Enabled = false
+		cfg.Scope.MaxDepth = 1
+		cfg.Manifest = ManifestConfig{Dir: dir, RunID: id}
+		eng, err := NewWithStrategies(cfg, EngineStrategies{Processors: []Processor{bodyAsMarkdown{}}})
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results, err := eng.Start(ctx, []string{srv.URL + "/"})
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		for r := range results {
+			if r.Success && (r.Page == nil || !strings.HasPrefix(r.Page.ContentHash, "sha256:")) {
+				t.Fatalf("processed page without content hash: %+v", r.Page)
+			}
+		}
+		snap := eng.Snapshot()
+		if err := eng.Stop(); err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+		return snap
+	}
+
+	first := run("r1")
+	if first.Manifest == nil || first.Manifest.Pages != 3 || first.Manifest.RunID != "r1" {
+		t.Fatalf("unexpected manifest snapshot: %+v", first.Manifest)
+	}
+	if _, err := New(Config{Manifest: ManifestConfig{Dir: dir, RunID: "r1"}}); err == nil {
+		t.Fatalf("expected an error when reusing a recorded run id")
+	}
+	second.Store(true)
+	next := run("r2")
+
+	d, err := DiffRuns(first.Manifest.Dir, next.Manifest.Dir, RunDiffOptions{Context: -1})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(d.Added) != 1 || d.Added[0].URL != srv.URL+"/new" || len(d.Removed) != 1 || d.Removed[0].URL != srv.URL+"/old" {
+		t.Fatalf("unexpected added/removed: %+v / %+v", d.Added, d.Removed)
+	}
+	if len(d.Modified) != 2 || d.Unchanged != 0 {
+		t.Fatalf("expected home and guide modified, got %+v (unchanged %d)", d.Modified, d.Unchanged)
+	}
+	guide := d.Modified[1]
+	if guide.URL != srv.URL+"/guide" || guide.Title != "Guide" || !strings.Contains(guide.Diff, "-step 2\n+step two\n") {
+		t.Fatalf("unexpected guide change: %+v", guide)
+	}
+	bare, err := DiffRuns(first.Manifest.Dir, next.Manifest.Dir, RunDiffOptions{})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if diff := bare.Modified[1].Diff; !strings.Contains(diff, "-step 2\n+step two\n") || strings.Contains(diff, "\n ") {
+		t.Fatalf("zero Context should drop unchanged lines:\n%s", diff)
+	}
+	md := d.Markdown()
+	for _, want := range []string{"# Crawl changes: r1 → r2", "1 added, 1 removed, 2 modified", "## Added", "### [Guide](<" + srv.URL + "/guide>)", "```diff\n--- r1/"} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("markdown report missing %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+const processingTestPage = `<html><head><title>Processing guide</title><meta name="description" content="How pages are processed"><meta name="author" content="Ariadne"></head>
+<body><nav><a href="/elsewhere">Menu</a></nav>
+<div class="promo">Buy now</div>
+<main><h1>Processing</h1><p>Pages are cleaned and converted to markdown by the engine.</p><img src="/img/diagram.png"><a href="/docs/next">Next</a></main>
+<section class="extra"><p>Related reading</p></section>
+<footer>Footer text</footer></body></html>`
+
+func crawlOne(t *testing.T, cfg Config, body string) *models.CrawlResult {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	cfg.RateLimit.Enabled = false
+	cfg.Robots.Enabled = false
+	cfg.Scope.MaxDepth = 0
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, []string{srv.URL + "/guide"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	var got []*models.CrawlResult
+	for r := range results {
+		got = append(got, r)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one result, got %d", len(got))
+	}
+	return got[0]
+}
+
+func TestEngineProcessesContent(t *testing.T) {
+	r := crawlOne(t, Defaults(), processingTestPage)
+	if !r.Success || r.Page == nil {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	p := r.Page
+	if p.Title != "Processing guide" || p.Metadata.Author != "Ariadne" || p.Metadata.Description != "How pages are processed" {
+		t.Fatalf("metadata not extracted: title=%q meta=%+v", p.Title, p.Metadata)
+	}
+	if !strings.Contains(p.Markdown, "# Processing") || strings.Contains(p.Markdown, "Menu") || strings.Contains(p.Markdown, "Footer") {
+		t.Fatalf("unexpected markdown %q", p.Markdown)
+	}
+	if !strings.Contains(p.Markdown, "/docs/next)") || !strings.HasPrefix(p.Images[0], "http://") {
+		t.Fatalf("relative URLs not resolved: %q %v", p.Markdown, p.Images)
+	}
+	if p.CleanedText != "Processing Pages are cleaned and converted to markdown by the engine. Next" || p.Metadata.WordCount != 12 {
+		t.Fatalf("unexpected text %q (%d words)", p.CleanedText, p.Metadata.WordCount)
+	}
+	if p.Quality == nil || p.Quality.Score <= 0 {
+		t.Fatalf("expected a quality score, got %+v", p.Quality)
+	}
+	if p.Metadata.Headers["Content-Type"] == "" {
+		t.Fatalf("fetch headers lost during processing: %v", p.Metadata.Headers)
+	}
+}
+
+func TestEngineProcessingSelectors(t *testing.T) {
+	cfg := Defaults()
+	cfg.Processing.ContentSelectors = []string{"section.extra"}
+	r := crawlOne(t, cfg, processingTestPage)
+	if !r.Success || r.Page.CleanedText != "Related reading" {
+		t.Fatalf("expected the configured selector to win, got %+v", r.Page)
+	}
+
+	cfg = Defaults()
+	cfg.Processing.ContentSelectors = []string{".missing"}
+	cfg.Processing.RemoveSelectors = []string{"main", ".promo"}
+	r = crawlOne(t, cfg, processingTestPage)
+	if !r.Success || r.Page.CleanedText != "Related reading" {
+		t.Fatalf("expected the cleaned body without removed elements, got %q", r.Page.CleanedText)
+	}
+
+	cfg = Defaults()
+	cfg.Processing.Enabled = false
+	r = crawlOne(t, cfg, processingTestPage)
+	if !r.Success || r.Page.Markdown != "" || r.Page.Content != processingTestPage {
+		t.Fatalf("expected pages to pass through with processing disabled")
+	}
+}
+
+func TestEngineProcessingMinQuality(t *testing.T) {
+	cfg := Defaults()
+	cfg.Processing.MinQuality = 0.9
+	r := crawlOne(t, cfg, `<html><body><p>Too short</p></body></html>`)
+	if r.Success || r.Stage != "processing" || r.Error == nil || r.Error.Code != models.ErrorCodeProcessing {
+		t.Fatalf("expected a processing failure, got %+v", r)
+	}
+	if !strings.Contains(r.Error.Error(), "content quality") {
+		t.Fatalf("unexpected error %v", r.Error)
+	}
+}
+
+func TestEngineProcessingReadability(t *testing.T) {
+	cfg := Defaults()
+	cfg.Processing.Extractor = "readability"
+	r := crawlOne(t, cfg, `<html><head><title>Notes</title></head><body>
+<div id="menu-links"><a href="/a">First link</a> <a href="/b">Second link</a> <a href="/c">Third link</a></div>
+<div id="story"><p>Field notes from the coast, written down carefully, one observation at a time.</p><p>The tide came in early, and the gulls followed it, circling over the rocks until dusk.</p></div>
+</body></html>`)
+	if !r.Success || r.Page == nil || r.Page.Quality == nil {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if q := r.Page.Quality; q.Extractor != "readability" || q.Confidence <= 0 {
+		t.Fatalf("expected a readability extraction with confidence, got %+v", q)
+	}
+	if text := r.Page.CleanedText; strings.Contains(text, "link") || !strings.HasPrefix(text, "Field notes") {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	cfg.Processing.Extractor = "magic"
+	if _, err := New(cfg); err == nil {
+		t.Fatalf("expected an error for an unknown extractor")
+	}
+}
+
+func TestEngineProcessingSchemas(t *testing.T) {
+	cfg := Defaults()
+	cfg.Processing.Schemas = []ExtractionSchema{{Domain: "127.0.0.1", Fields: []ExtractionField{
+		{Name: "title", Selector: "h1", Required: true},
+		{Name: "price", Selector: ".price", Type: "number"},
+		{Name: "sizes", Selector: ".sizes li", Type: "list", Items: "integer"},
+		{Name: "sku", Selector: ".sku", Required: true},
+	}}}
+	page := `<html><body><main><h1>Trail shoe</h1><p class="price">€89.90</p><ul class="sizes"><li>41</li><li>42</li></ul></main></body></html>`
+	r := crawlOne(t, cfg, page)
+	if !r.Success || r.Page == nil || r.Page.Quality == nil {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	want := map[string]any{"title": "Trail shoe", "price": 89.9, "sizes": []any{int64(41), int64(42)}}
+	if !reflect.DeepEqual(r.Page.Extracted, want) {
+		t.Fatalf("record %#v, want %#v", r.Page.Extracted, want)
+	}
+	if errs := r.Page.Quality.SchemaErrors; len(errs) != 1 || errs[0] != "sku: required field missing" {
+		t.Fatalf("unexpected schema errors %q", errs)
+	}
+
+	cfg.Processing.Schemas[0].Strict = true
+	r = crawlOne(t, cfg, page)
+	if r.Success || r.Stage != "processing" || r.Error == nil || !strings.Contains(r.Error.Error(), "sku: required field missing") {
+		t.Fatalf("expected a strict schema failure, got %+v", r)
+	}
+
+	for _, schemas := range [][]ExtractionSchema{
+		{{Domain: "example.com", Fields: []ExtractionField{{Name: "a", Type: "money"}}}},
+		{{Fields: []ExtractionField{{Name: "a"}}}},
+		{{Domain: "example.com", Fields: []ExtractionField{{Name: "a"}}}, {Domain: "Example.com", Fields: []ExtractionField{{Name: "b"}}}},
+	} {
+		cfg.Processing.Schemas = schemas
+		if _, err := New(cfg); err == nil {
+			t.Errorf("expected an error for schemas %+v", schemas)
+		}
+	}
+}
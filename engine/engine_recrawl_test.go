@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func TestEngineConditionalGetReusesUnchangedPages(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var version atomic.Int32 // bumps the ETag of /c between runs
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("ETag", `"root"`)
+			if r.Header.Get("If-None-Match") == `"root"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = fmt.Fprint(w, `<html><head><title>Root</title></head><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a></body></html>`)
+		case "/a":
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = fmt.Fprint(w, "<html><body>a</body></html>")
+		case "/c":
+			etag := fmt.Sprintf(`"c%d"`, version.Load())
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = fmt.Fprint(w, "<html><body>c</body></html>")
+		default:
+			_, _ = fmt.Fprint(w, "<html><body>no validators</body></html>")
+		}
+	}))
+	defer srv.Close()
+	checkpoint := filepath.Join(t.TempDir(), "crawl", "checkpoint.log")
+
+	run := func() (map[string]*engmodels.CrawlResult, Snapshot) {
+		t.Helper()
+		cfg := Defaults()
+		cfg.RateLimit.Enabled = false
+		cfg.Robots.Enabled = false
+		cfg.Scope.MaxDepth = 1
+		cfg.CheckpointPath = checkpoint
+		cfg.Recrawl.ConditionalGet = true
+		eng, err := New(cfg)
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results, err := eng.Start(ctx, []string{srv.URL + "/"})
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		got := map[string]*engmodels.CrawlResult{}
+		for r := range results {
+			if !r.Success {
+				t.Fatalf("unexpected failure for %s: %v", r.URL, r.Error)
+			}
+			got[r.URL] = r
+		}
+		snap := eng.Snapshot()
+		if err := eng.Stop(); err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+		return got, snap
+	}
+
+	first, snap := run()
+	if len(first) != 4 || first[srv.URL+"/"].Unchanged {
+		t.Fatalf("unexpected first run results: %v", first)
+	}
+	if snap.Recrawl == nil || *snap.Recrawl != (RecrawlSnapshot{New: 4, Tracked: 3}) {
+		t.Fatalf("unexpected first run recrawl snapshot: %+v", snap.Recrawl)
+	}
+
+	version.Add(1)
+	second, snap := run()
+	if len(second) != 4 {
+		t.Fatalf("links of an unchanged page must still be followed, got %v", second)
+	}
+	root := second[srv.URL+"/"]
+	if !root.Unchanged || root.Page == nil || root.Page.Title != "Root" {
+		t.Fatalf("expected root reused from the previous run, got %+v", root)
+	}
+	if !second[srv.URL+"/a"].Unchanged || second[srv.URL+"/b"].Unchanged || second[srv.URL+"/c"].Unchanged {
+		t.Fatalf("unexpected unchanged flags: a=%v b=%v c=%v", second[srv.URL+"/a"].Unchanged, second[srv.URL+"/b"].Unchanged, second[srv.URL+"/c"].Unchanged)
+	}
+	if *snap.Recrawl != (RecrawlSnapshot{New: 1, Refetched: 1, Unchanged: 2, Tracked: 3}) {
+		t.Fatalf("unexpected second run recrawl snapshot: %+v", snap.Recrawl)
+	}
+}
+
+func TestEngineConditionalGetRequiresCheckpoint(t *testing.T) {
+	cfg := Defaults()
+	cfg.Recrawl.ConditionalGet = true
+	if _, err := New(cfg); err == nil {
+		t.Fatalf("expected an error without a checkpoint path")
+	}
+}
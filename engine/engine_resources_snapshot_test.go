@@ -28,6 +28,13 @@ func TestEngineResourceSnapshotPresence(t *testing.T) {
 			},
 			expectRes: true,
 		},
+		{
+			name: "enabled-cache-max-bytes", // byte budget alone enables the manager
+			mutate: func(c *Config) {
+				c.Resources = ResourcesConfig{CacheMaxBytes: 1 << 20}
+			},
+			expectRes: true,
+		},
 		{
 			name: "enabled-checkpoint-only", // enabling via checkpoint path (no cache/inflight)
 			mutate: func(c *Config) {
@@ -58,7 +65,7 @@ func TestEngineResourceSnapshotPresence(t *testing.T) {
 			}
 			if tc.expectRes && snap.Resources != nil {
 				rs := snap.Resources
-				if rs.CacheEntries != 0 || rs.SpillFiles != 0 || rs.InFlight != 0 || rs.CheckpointQueued != 0 {
+				if rs.CacheEntries != 0 || rs.CacheBytes != 0 || rs.SpillFiles != 0 || rs.SpillBytes != 0 || rs.Evictions != 0 || rs.InFlight != 0 || rs.CheckpointQueued != 0 {
 					t.Fatalf("expected zeroed counters, got %+v", *rs)
 				}
 			}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngineScopeRulesReportRejections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/docs/" {
+			_, _ = fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/private/x">p</a><a href="/blog/">b</a><a href="https://cdn.invalid/x">c</a>`)
+			return
+		}
+		_, _ = fmt.Fprint(w, "<html><body>leaf</body></html>")
+	}))
+	defer srv.Close()
+
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	cfg.Robots.Enabled = false
+	cfg.Scope.MaxDepth = 1
+	cfg.Scope.PathPrefixes = []string{"/docs/"}
+	cfg.Scope.Exclude = []string{"/docs/private/**"}
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	var mu sync.Mutex
+	events := map[string]string{}
+	eng.RegisterEventObserver(func(ev TelemetryEvent) {
+		if ev.Type != "scope_reject" {
+			return
+		}
+		mu.Lock()
+		events[fmt.Sprint(ev.Fields["url"])] = ev.Labels["rule"]
+		mu.Unlock()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, []string{srv.URL + "/docs/"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	var crawled []string
+	for r := range results {
+		crawled = append(crawled, r.URL)
+	}
+	if len(crawled) != 2 {
+		t.Fatalf("expected the seed and /docs/a, got %v", crawled)
+	}
+	snap := eng.Snapshot()
+	want := map[string]int{ScopeRuleOffsite: 1, ScopeRulePathPrefix: 1, ScopeRuleExclude + ":/docs/private/**": 1}
+	if snap.Scope == nil || snap.Scope.Total != 3 || len(snap.Scope.Rejected) != len(want) {
+		t.Fatalf("unexpected scope snapshot: %+v", snap.Scope)
+	}
+	for rule, n := range want {
+		if snap.Scope.Rejected[rule] != n {
+			t.Fatalf("rule %s: got %d rejections, want %d (%v)", rule, snap.Scope.Rejected[rule], n, snap.Scope.Rejected)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if events[srv.URL+"/blog/"] != ScopeRulePathPrefix || events["https://cdn.invalid/x"] != ScopeRuleOffsite || len(events) != 3 {
+		t.Fatalf("unexpected scope_reject events: %v", events)
+	}
+}
+
+func TestEngineRejectsInvalidScopePattern(t *testing.T) {
+	cfg := Defaults()
+	cfg.Scope.Include = []string{"re:["}
+	if _, err := New(cfg); err == nil {
+		t.Fatalf("expected an error for an invalid include pattern")
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEngineSitemapSeeds(t *testing.T) {
+	var base string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = fmt.Fprintf(w, "User-agent: *\nSitemap: %s/index.xml\n", base)
+		case "/index.xml":
+			_, _ = fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/pages.xml</loc></sitemap></sitemapindex>`, base)
+		case "/pages.xml":
+			_, _ = fmt.Fprintf(w, `<urlset><url><loc>%[1]s/a</loc></url><url><loc>%[1]s/b</loc><priority>0.9</priority></url><url><loc>http://elsewhere.invalid/c</loc></url></urlset>`, base)
+		case "/broken.xml":
+			_, _ = fmt.Fprint(w, `<urlset><url>`)
+		default:
+			_, _ = fmt.Fprint(w, "<html><body>page</body></html>")
+		}
+	}))
+	defer srv.Close()
+	base = srv.URL
+
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	cfg.Scope.MaxDepth = 0
+	cfg.Sitemap = SitemapConfig{Discover: true, URLs: []string{base + "/broken.xml"}}
+	eng, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, []string{base + "/"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	got := map[string]bool{}
+	for r := range results {
+		got[r.URL] = r.Success
+	}
+	for _, p := range []string{"/", "/a", "/b"} {
+		if !got[base+p] {
+			t.Fatalf("expected successful result for %s, got %v", p, got)
+		}
+	}
+	if _, ok := got["http://elsewhere.invalid/c"]; ok {
+		t.Fatalf("sitemap entries outside the seed hosts must not be crawled")
+	}
+	if rejected := eng.Snapshot().Scope.Rejected[ScopeRuleOffsite]; rejected != 1 {
+		t.Fatalf("expected the offsite sitemap entry counted as rejected, got %d", rejected)
+	}
+	sm := eng.Snapshot().Sitemap
+	if sm == nil || sm.URLs != 3 || sm.Sitemaps != 2 || len(sm.Errors) != 1 {
+		t.Fatalf("unexpected sitemap snapshot: %+v", sm)
+	}
+}
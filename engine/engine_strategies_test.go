@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+type stubFetcher struct{}
+
+func (stubFetcher) Fetch(ctx context.Context, raw string) (*engmodels.Page, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &engmodels.Page{URL: u, Title: "stub", Content: "<p>stub</p>"}, nil
+}
+
+type tagProcessor struct{ tag string }
+
+func (p tagProcessor) Process(ctx context.Context, page *engmodels.Page) (*engmodels.Page, error) {
+	if strings.Contains(page.URL.Path, "reject") {
+		return nil, errors.New("rejected by " + p.tag)
+	}
+	page.Title += "+" + p.tag
+	return page, nil
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	titles  []string
+	flushed bool
+	closed  bool
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+func (s *recordingSink) Write(ctx context.Context, page *engmodels.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles = append(s.titles, page.Title)
+	return nil
+}
+func (s *recordingSink) Flush(ctx context.Context) error { s.flushed = true; return nil }
+func (s *recordingSink) Close(ctx context.Context) error { s.closed = true; return nil }
+
+func TestNewWithStrategiesRunsInjectedComponents(t *testing.T) {
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	sink := &recordingSink{}
+	eng, err := NewWithStrategies(cfg, EngineStrategies{
+		Fetcher:     stubFetcher{},
+		Processors:  []Processor{tagProcessor{"a"}, tagProcessor{"b"}},
+		OutputSinks: []OutputSink{sink},
+	})
+	if err != nil {
+		t.Fatalf("NewWithStrategies: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := eng.Start(ctx, []string{"https://stub.test/ok", "https://stub.test/reject"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	var ok, failed int
+	for r := range results {
+		if r.Success {
+			ok++
+			if r.Page.Title != "stub+a+b" {
+				t.Fatalf("expected processors applied in order, got %q", r.Page.Title)
+			}
+			continue
+		}
+		failed++
+		if r.Stage != "processing" || !strings.Contains(r.Error.Error(), "rejected by a") {
+			t.Fatalf("unexpected failure: stage=%s err=%v", r.Stage, r.Error)
+		}
+	}
+	if ok != 1 || failed != 1 {
+		t.Fatalf("expected 1 success and 1 failure, got %d/%d", ok, failed)
+	}
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if len(sink.titles) != 1 || sink.titles[0] != "stub+a+b" {
+		t.Fatalf("sink should receive only the processed page, got %v", sink.titles)
+	}
+	if !sink.flushed || !sink.closed {
+		t.Fatalf("expected sink flushed and closed on Stop")
+	}
+}
+
+func TestNewWithStrategiesRejectsNilEntries(t *testing.T) {
+	cfg := Defaults()
+	if _, err := NewWithStrategies(cfg, EngineStrategies{Processors: []Processor{nil}}); err == nil {
+		t.Fatalf("expected nil processor to be rejected")
+	}
+	if _, err := NewWithStrategies(cfg, EngineStrategies{OutputSinks: []OutputSink{&recordingSink{}, nil}}); err == nil {
+		t.Fatalf("expected nil sink to be rejected")
+	}
+}
+
+type failingSink struct{ recordingSink }
+
+func (s *failingSink) Write(ctx context.Context, page *engmodels.Page) error {
+	return errors.New("disk full")
+}
+
+func TestOutputSinkErrorFailsResult(t *testing.T) {
+	cfg := Defaults()
+	cfg.RateLimit.Enabled = false
+	eng, err := NewWithStrategies(cfg, EngineStrategies{Fetcher: stubFetcher{}, OutputSinks: []OutputSink{&failingSink{}}})
+	if err != nil {
+		t.Fatalf("NewWithStrategies: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, _ := eng.Start(ctx, []string{"https://stub.test/x"})
+	for r := range results {
+		if r.Success || r.Stage != "output" || !strings.Contains(r.Error.Error(), "output sink recording: disk full") {
+			t.Fatalf("expected output stage failure, got %+v", r)
+		}
+	}
+}
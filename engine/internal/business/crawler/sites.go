@@ -5,12 +5,16 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/99souls/ariadne/engine/internal/processor"
 )
 
 // ContentExtractionRules defines rules for extracting content from a site
 type ContentExtractionRules struct {
 	Selectors    []string
 	ExcludeRules []string
+	// Schema declares the fields extracted from the site's pages into Page.Extracted
+	Schema *processor.Schema
 }
 
 // SiteRateLimitRules defines site-specific rate limiting rules
@@ -100,7 +104,7 @@ func NewSiteRuleEvaluator(manager *SitePolicyManager) *SiteRuleEvaluator {
 
 // GetApplicablePolicy finds the most specific policy for a URL
 func (sre *SiteRuleEvaluator) GetApplicablePolicy(u *url.URL) *SiteSpecificPolicy {
-	domain := u.Host
+	domain := u.Hostname()
 	
 	// First try exact match
 	if policy := sre.manager.GetSitePolicy(domain); policy != nil {
@@ -130,6 +134,12 @@ func (sre *SiteRuleEvaluator) GetContentExtractionRules(u *url.URL) ContentExtra
 	return ContentExtractionRules{}
 }
 
+// SchemaFor returns the extraction schema of the most specific policy for a URL,
+// making the evaluator a processor.SchemaSource
+func (sre *SiteRuleEvaluator) SchemaFor(u *url.URL) *processor.Schema {
+	return sre.GetContentExtractionRules(u).Schema
+}
+
 // GetRateLimitRules returns rate limiting rules for a URL
 func (sre *SiteRuleEvaluator) GetRateLimitRules(u *url.URL) SiteRateLimitRules {
 	policy := sre.GetApplicablePolicy(u)
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/99souls/ariadne/engine/internal/processor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -155,6 +156,21 @@ func TestSiteRuleEvaluator(t *testing.T) {
 		assert.Empty(t, rules.Selectors)
 		assert.Empty(t, rules.ExcludeRules)
 	})
+
+	t.Run("schema for site with port", func(t *testing.T) {
+		schema := &processor.Schema{Fields: []processor.Field{{Name: "title", Selector: "h1"}}}
+		err := manager.AddSitePolicy("shop.example.com", &SiteSpecificPolicy{
+			Domain:  "shop.example.com",
+			Content: ContentExtractionRules{Schema: schema},
+		})
+		require.NoError(t, err)
+
+		shopURL, _ := url.Parse("http://shop.example.com:8080/item/1")
+		assert.Same(t, schema, evaluator.SchemaFor(shopURL))
+
+		newsURL, _ := url.Parse("https://news.example.com/article/123")
+		assert.Nil(t, evaluator.SchemaFor(newsURL))
+	})
 }
 
 func TestSitePatternMatching(t *testing.T) {
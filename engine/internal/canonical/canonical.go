@@ -0,0 +1,253 @@
+// Package canonical normalizes URLs into the keys used for crawl de-duplication,
+// checkpoints and cache lookups so that trivially different spellings of the same
+// resource map to one entry.
+package canonical
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams are stripped when Config.StripTrackingParams is set and no
+// explicit list is supplied. A trailing '*' matches a parameter name prefix.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga"}
+
+// Config controls the optional canonicalization rules. Scheme/host case, default
+// ports, fragments, dot segments, percent-encoding and query order are always
+// normalized.
+type Config struct {
+	// StripTrackingParams removes TrackingParams from the query.
+	StripTrackingParams bool
+	// TrackingParams overrides DefaultTrackingParams (names, or prefixes ending in '*').
+	TrackingParams []string
+	// KeepTrailingSlash preserves a trailing '/' on non-root paths instead of trimming it.
+	KeepTrailingSlash bool
+}
+
+// Canonicalizer applies a Config. It is immutable and safe for concurrent use.
+type Canonicalizer struct {
+	keepSlash bool
+	exact     map[string]struct{}
+	prefixes  []string
+}
+
+// New creates a Canonicalizer for cfg.
+func New(cfg Config) *Canonicalizer {
+	c := &Canonicalizer{keepSlash: cfg.KeepTrailingSlash, exact: map[string]struct{}{}}
+	if cfg.StripTrackingParams {
+		params := cfg.TrackingParams
+		if params == nil {
+			params = DefaultTrackingParams
+		}
+		for _, p := range params {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if strings.HasSuffix(p, "*") {
+				c.prefixes = append(c.prefixes, strings.TrimSuffix(p, "*"))
+			} else if p != "" {
+				c.exact[p] = struct{}{}
+			}
+		}
+	}
+	return c
+}
+
+// Default is the canonicalizer used when none is configured (tracking params kept).
+var Default = New(Config{})
+
+// URL returns the canonical form of u as a new URL. Opaque and relative URLs are
+// returned with only the fragment removed.
+func (c *Canonicalizer) URL(u *url.URL) *url.URL {
+	out := *u
+	out.Fragment, out.RawFragment = "", ""
+	if u.Opaque != "" || u.Host == "" {
+		return &out
+	}
+	out.Scheme = strings.ToLower(u.Scheme)
+	out.Host = hostPort(out.Scheme, u.Host)
+	p := removeDotSegments(normalizeEscapes(u.EscapedPath(), false))
+	if p == "" {
+		p = "/"
+	}
+	if !c.keepSlash && len(p) > 1 && strings.HasSuffix(p, "/") {
+		p = strings.TrimRight(p, "/")
+		if p == "" {
+			p = "/"
+		}
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		out.Path, out.RawPath = unescaped, p
+	} else {
+		out.Path, out.RawPath = p, ""
+	}
+	out.RawQuery = c.query(u.RawQuery)
+	out.ForceQuery = false
+	return &out
+}
+
+// String canonicalizes raw.
+func (c *Canonicalizer) String(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	return c.URL(u).String(), nil
+}
+
+// Key returns the canonical form of raw, or raw itself when it does not parse.
+func (c *Canonicalizer) Key(raw string) string {
+	if s, err := c.String(raw); err == nil {
+		return s
+	}
+	return raw
+}
+
+// Host returns the canonical host of raw (lowercase, default port removed) or "".
+func Host(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return hostPort(strings.ToLower(u.Scheme), u.Host)
+}
+
+func (c *Canonicalizer) query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	pairs := strings.FieldsFunc(raw, func(r rune) bool { return r == '&' || r == ';' })
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		key, value, hasValue := strings.Cut(pair, "=")
+		key = normalizeEscapes(key, true)
+		if key == "" || c.tracking(key) {
+			continue
+		}
+		if hasValue {
+			kept = append(kept, key+"="+normalizeEscapes(value, true))
+		} else {
+			kept = append(kept, key)
+		}
+	}
+	sort.Strings(kept)
+	return strings.Join(kept, "&")
+}
+
+func (c *Canonicalizer) tracking(escapedKey string) bool {
+	name, err := url.QueryUnescape(escapedKey)
+	if err != nil {
+		name = escapedKey
+	}
+	name = strings.ToLower(name)
+	if _, ok := c.exact[name]; ok {
+		return true
+	}
+	for _, p := range c.prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostPort(scheme, hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]"), ""
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// normalizeEscapes decodes percent-escapes of unreserved characters, upper-cases the
+// remaining escape hex digits and escapes bytes that must not appear literally.
+// In query components '+' is left as is.
+func normalizeEscapes(s string, query bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			v := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(v) {
+				b.WriteByte(v)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(hex[v>>4])
+				b.WriteByte(hex[v&0x0f])
+			}
+			i += 2
+			continue
+		}
+		if ch <= 0x20 || ch >= 0x7f || ch == '%' || (query && ch == '#') {
+			b.WriteByte('%')
+			b.WriteByte(hex[ch>>4])
+			b.WriteByte(hex[ch&0x0f])
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// removeDotSegments implements RFC 3986 section 5.2.4.
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	out := make([]string, 0, len(segments))
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch seg {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+	res := strings.Join(out, "/")
+	if strings.HasPrefix(p, "/") && !strings.HasPrefix(res, "/") {
+		res = "/" + res
+	}
+	return res
+}
+
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,36 @@
+package canonical
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	strip := New(Config{StripTrackingParams: true})
+	cases := []struct {
+		c        *Canonicalizer
+		in, want string
+	}{
+		{Default, "HTTP://Example.COM:80/a/./b/../c/?b=2&a=1#frag", "http://example.com/a/c?a=1&b=2"},
+		{Default, "https://example.com:443", "https://example.com/"},
+		{Default, "https://example.com:8443/x", "https://example.com:8443/x"},
+		{Default, "https://example.com/%7euser/%2f%e2%82%ac", "https://example.com/~user/%2F%E2%82%AC"},
+		{Default, "https://example.com/list?page=2", "https://example.com/list?page=2"},
+		{Default, "https://example.com/a b", "https://example.com/a%20b"},
+		{Default, "https://example.com/?utm_source=x&q=1", "https://example.com/?q=1&utm_source=x"},
+		{strip, "https://example.com/?utm_source=x&UTM_Medium=y&fbclid=z&q=1", "https://example.com/?q=1"},
+		{strip, "https://example.com/docs/?gclid=1", "https://example.com/docs"},
+		{New(Config{KeepTrailingSlash: true}), "https://example.com/docs/", "https://example.com/docs/"},
+		{New(Config{StripTrackingParams: true, TrackingParams: []string{"ref"}}), "https://example.com/?ref=a&utm_x=1", "https://example.com/?utm_x=1"},
+		{Default, "http://[::1]:80/x", "http://[::1]/x"},
+	}
+	for _, tc := range cases {
+		got, err := tc.c.String(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("canonicalize %q = %q (%v), want %q", tc.in, got, err, tc.want)
+		}
+		if again := tc.c.Key(got); again != got {
+			t.Errorf("canonicalization not idempotent: %q -> %q", got, again)
+		}
+	}
+	if Host("HTTPS://Docs.Example.com:443/x") != "docs.example.com" || Host("::bad") != "" {
+		t.Fatalf("unexpected Host results")
+	}
+}
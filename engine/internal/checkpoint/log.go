@@ -0,0 +1,515 @@
+// Package checkpoint implements the crawl checkpoint: a write-ahead log recording, per
+// canonical URL key, its admission to the frontier (with link depth), the start of
+// every fetch attempt and its final success or failure. Replaying the log rebuilds
+// the frontier of an interrupted crawl: the URLs still queued, the URLs in flight
+// (to be fetched again) and the finished URLs to skip.
+//
+// The file is a version header followed by JSON lines. Appends are buffered and made
+// durable in batches, every Options.Interval or as soon as batchRecords are waiting,
+// so a crash loses at most the last batch; a torn trailing line is ignored on replay.
+// The log is compacted (rewritten from its live state, one or two records per URL)
+// when opened and whenever it grew to twice the records its state needs.
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the format written by this package. Files without a version header are
+// read as version 1: one finished URL per line, keyed with Options.Key.
+const Version = 2
+
+const (
+	defaultInterval = 50 * time.Millisecond
+	// batchRecords buffered records trigger a sync before the interval elapses.
+	batchRecords = 256
+	// compactMinRecords keeps small logs from being rewritten over and over.
+	compactMinRecords = 4096
+)
+
+// Op is the kind of a log record.
+type Op string
+
+const (
+	OpEnqueue Op = "enqueue"
+	OpStart   Op = "start"
+	OpSuccess Op = "success"
+	OpFailure Op = "failure"
+)
+
+type header struct {
+	Version int `json:"version"`
+}
+
+type record struct {
+	Op  Op     `json:"op"`
+	Key string `json:"key"`
+	URL string `json:"url,omitempty"`
+	// Depth is the link depth (enqueue); Attempt is the attempts made before the
+	// record (enqueue), the attempt started (start) or the attempts made (failure).
+	Depth   int `json:"depth,omitempty"`
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// Entry is a URL the log holds as queued or in flight.
+type Entry struct {
+	Key   string
+	URL   string
+	Depth int
+	// Attempts is the number of fetch attempts started for the URL.
+	Attempts int
+	// InFlight is set once a fetch started and no final result was recorded.
+	InFlight bool
+
+	seq uint64
+}
+
+// Options configures a Log.
+type Options struct {
+	// Path of the log file; its directory is created if missing.
+	Path string
+	// Interval between batched syncs (default 50ms).
+	Interval time.Duration
+	// Resume replays the existing log; otherwise it is discarded.
+	Resume bool
+	// Key maps the raw URLs of a version 1 log to the keys version 2 records use (the
+	// crawl's canonical URL key); nil keeps them as they are.
+	Key func(rawURL string) string
+}
+
+// Stats counts the URLs per status and the log's own activity.
+type Stats struct {
+	Queued    int
+	InFlight  int
+	Succeeded int
+	Failed    int
+	// Records is the number of records in the file; Unsynced those not yet synced.
+	Records     int
+	Unsynced    int
+	Compactions int
+	// Errors counts failed writes, syncs and compactions.
+	Errors int
+}
+
+// Log is the checkpoint write-ahead log. It is safe for concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	opts     Options
+	f        *os.File
+	w        *bufio.Writer
+	open     map[string]*Entry
+	finished map[string]bool // key -> succeeded
+	seq      uint64
+	stats    Stats
+	err      error
+	closed   bool
+
+	kick     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// Open opens the log at opts.Path, replaying it when opts.Resume is set, and starts
+// the background sync loop.
+func Open(opts Options) (*Log, error) {
+	if opts.Path == "" {
+		return nil, errors.New("checkpoint: Path is required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	l := &Log{opts: opts, open: make(map[string]*Entry), finished: make(map[string]bool), kick: make(chan struct{}, 1), stop: make(chan struct{})}
+	if opts.Resume {
+		if err := l.load(); err != nil {
+			return nil, err
+		}
+	}
+	// Rewrite the replayed state: this drops a torn tail, upgrades a version 1 file
+	// and starts a fresh log when not resuming.
+	if err := l.compactLocked(); err != nil {
+		return nil, err
+	}
+	l.stats.Compactions = 0
+	l.wg.Add(1)
+	go l.syncLoop()
+	return l, nil
+}
+
+func (l *Log) load() error {
+	f, err := os.Open(l.opts.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	first, legacy := true, false
+	for sc.Scan() {
+		line := sc.Bytes()
+		if first {
+			first = false
+			var h header
+			if json.Unmarshal(line, &h) == nil && h.Version > 0 {
+				if h.Version != Version {
+					return fmt.Errorf("checkpoint: unsupported version %d in %s", h.Version, l.opts.Path)
+				}
+				continue
+			}
+			legacy = true
+		}
+		if legacy {
+			if raw := strings.TrimSpace(string(line)); raw != "" {
+				key := raw
+				if l.opts.Key != nil {
+					key = l.opts.Key(raw)
+				}
+				l.apply(record{Op: OpSuccess, Key: key})
+			}
+			continue
+		}
+		var r record
+		if json.Unmarshal(line, &r) != nil || r.Key == "" {
+			continue // torn write
+		}
+		l.apply(r)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// apply updates the in-memory state with r.
+func (l *Log) apply(r record) {
+	switch r.Op {
+	case OpEnqueue:
+		delete(l.finished, r.Key)
+		e := l.open[r.Key]
+		if e == nil {
+			l.seq++
+			e = &Entry{Key: r.Key, seq: l.seq}
+			l.open[r.Key] = e
+		}
+		e.URL, e.Depth, e.InFlight = r.URL, r.Depth, false
+		e.Attempts = max(e.Attempts, r.Attempt)
+	case OpStart:
+		if e := l.open[r.Key]; e != nil {
+			e.InFlight = true
+			e.Attempts = max(e.Attempts, r.Attempt+1)
+		}
+	case OpSuccess, OpFailure:
+		delete(l.open, r.Key)
+		l.finished[r.Key] = r.Op == OpSuccess
+	}
+}
+
+// Enqueue records that rawURL (canonical key key) was admitted at depth after attempts
+// earlier fetch attempts.
+func (l *Log) Enqueue(key, rawURL string, depth, attempts int) {
+	l.append(record{Op: OpEnqueue, Key: key, URL: rawURL, Depth: depth, Attempt: attempts})
+}
+
+// Start records the start of fetch attempt (zero-based) for key.
+func (l *Log) Start(key string, attempt int) {
+	l.append(record{Op: OpStart, Key: key, Attempt: attempt})
+}
+
+// Finish records the final result for key; attempts is the number of fetches made.
+func (l *Log) Finish(key string, success bool, attempts int) {
+	r := record{Op: OpFailure, Key: key, Attempt: attempts}
+	if success {
+		r = record{Op: OpSuccess, Key: key}
+	}
+	l.append(r)
+}
+
+func (l *Log) append(r record) {
+	if r.Key == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.w == nil {
+		return
+	}
+	l.apply(r)
+	if err := l.writeLocked(r); err != nil {
+		l.failLocked(fmt.Errorf("checkpoint: %w", err))
+		return
+	}
+	l.stats.Records++
+	if l.stats.Unsynced++; l.stats.Unsynced == batchRecords {
+		select {
+		case l.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *Log) writeLocked(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = l.w.Write(data)
+	return err
+}
+
+func (l *Log) failLocked(err error) {
+	l.stats.Errors++
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// Finished reports whether a final result was recorded for key.
+func (l *Log) Finished(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.finished[key]
+	return ok
+}
+
+// FinishedKeys returns the keys with a final result.
+func (l *Log) FinishedKeys() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]string, 0, len(l.finished))
+	for k := range l.finished {
+		out = append(out, k)
+	}
+	return out
+}
+
+// Frontier returns the URLs queued or in flight, in the order they were first admitted.
+func (l *Log) Frontier() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.frontierLocked()
+}
+
+func (l *Log) frontierLocked() []Entry {
+	out := make([]Entry, 0, len(l.open))
+	for _, e := range l.open {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+	return out
+}
+
+// Stats returns the current counters.
+func (l *Log) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.stats
+	for _, e := range l.open {
+		if e.InFlight {
+			st.InFlight++
+		} else {
+			st.Queued++
+		}
+	}
+	for _, ok := range l.finished {
+		if ok {
+			st.Succeeded++
+		} else {
+			st.Failed++
+		}
+	}
+	return st
+}
+
+// Sync makes the records appended so far durable and returns the first error the log
+// ran into, if any.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.w == nil {
+		return l.err
+	}
+	l.syncLocked()
+	return l.err
+}
+
+func (l *Log) syncLocked() {
+	if l.stats.Unsynced == 0 {
+		return
+	}
+	if err := l.w.Flush(); err != nil {
+		l.failLocked(fmt.Errorf("checkpoint: %w", err))
+		return
+	}
+	if err := l.f.Sync(); err != nil {
+		l.failLocked(fmt.Errorf("checkpoint: %w", err))
+		return
+	}
+	l.stats.Unsynced = 0
+}
+
+// compactDue reports whether the file holds at least twice the records the state needs.
+func (l *Log) compactDue() bool {
+	return l.stats.Records >= compactMinRecords && l.stats.Records >= 2*l.liveRecordsLocked()
+}
+
+func (l *Log) liveRecordsLocked() int {
+	n := len(l.finished)
+	for _, e := range l.open {
+		n++
+		if e.InFlight {
+			n++
+		}
+	}
+	return n
+}
+
+// compactLocked replaces the file with the records of the current state.
+func (l *Log) compactLocked() error {
+	tmp := l.opts.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	w := bufio.NewWriterSize(f, 64<<10)
+	prev := l.w
+	l.w = w
+	n, err := l.writeStateLocked()
+	l.w = prev
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, l.opts.Path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("checkpoint: compact: %w", err)
+	}
+	if l.f != nil {
+		_ = l.f.Close()
+	}
+	l.f, err = os.OpenFile(l.opts.Path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		l.f, l.w = nil, nil
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	l.w = bufio.NewWriterSize(l.f, 64<<10)
+	l.stats.Records, l.stats.Unsynced = n, 0
+	l.stats.Compactions++
+	return nil
+}
+
+func (l *Log) writeStateLocked() (int, error) {
+	if err := l.writeLocked(header{Version: Version}); err != nil {
+		return 0, err
+	}
+	n := 0
+	keys := make([]string, 0, len(l.finished))
+	for k := range l.finished {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r := record{Op: OpFailure, Key: k}
+		if l.finished[k] {
+			r.Op = OpSuccess
+		}
+		if err := l.writeLocked(r); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, e := range l.frontierLocked() {
+		attempts := e.Attempts
+		if e.InFlight {
+			attempts-- // restored by the start record
+		}
+		if err := l.writeLocked(record{Op: OpEnqueue, Key: e.Key, URL: e.URL, Depth: e.Depth, Attempt: attempts}); err != nil {
+			return n, err
+		}
+		n++
+		if e.InFlight {
+			if err := l.writeLocked(record{Op: OpStart, Key: e.Key, Attempt: attempts}); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (l *Log) syncLoop() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(l.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		case <-l.kick:
+		}
+		l.mu.Lock()
+		if l.w == nil {
+			l.mu.Unlock()
+			continue
+		}
+		l.syncLocked()
+		if l.compactDue() {
+			if err := l.compactLocked(); err != nil {
+				l.failLocked(err)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Close syncs and compacts the log and releases the file. Records appended afterwards
+// are ignored.
+func (l *Log) Close() error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	l.wg.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return l.err
+	}
+	l.closed = true
+	if l.w == nil {
+		return l.err
+	}
+	l.syncLocked()
+	if l.err == nil && l.stats.Records > l.liveRecordsLocked() {
+		if err := l.compactLocked(); err != nil {
+			l.failLocked(err)
+		}
+	}
+	if l.f != nil {
+		if err := l.f.Close(); err != nil {
+			l.failLocked(fmt.Errorf("checkpoint: %w", err))
+		}
+	}
+	return l.err
+}
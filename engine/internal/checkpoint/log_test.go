@@ -0,0 +1,158 @@
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+)
+
+func TestLogResumeRebuildsFrontier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "checkpoint.log")
+	l, err := Open(Options{Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	l.Enqueue("https://a.test/", "https://a.test/", 0, 0)
+	l.Enqueue("https://a.test/x", "https://a.test/x", 1, 0)
+	l.Enqueue("https://a.test/y", "https://a.test/y?utm=1", 1, 0)
+	l.Enqueue("https://a.test/z", "https://a.test/z", 2, 0)
+	l.Start("https://a.test/", 0)
+	l.Finish("https://a.test/", true, 1)
+	l.Start("https://a.test/x", 0)
+	l.Start("https://a.test/x", 1) // retry still in flight
+	l.Start("https://a.test/z", 0)
+	l.Finish("https://a.test/z", false, 1)
+	if err := l.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	// Crash: the file is left as synced, with a torn record at its end.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"op":"success","ke`)
+	_ = f.Close()
+
+	r, err := Open(Options{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	got := r.Frontier()
+	want := []Entry{{Key: "https://a.test/x", URL: "https://a.test/x", Depth: 1, Attempts: 2, InFlight: true}, {Key: "https://a.test/y", URL: "https://a.test/y?utm=1", Depth: 1}}
+	if len(got) != len(want) {
+		t.Fatalf("expected frontier %+v, got %+v", want, got)
+	}
+	for i := range want {
+		got[i].seq = 0
+		if got[i] != want[i] {
+			t.Fatalf("frontier[%d]: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if !r.Finished("https://a.test/") || !r.Finished("https://a.test/z") || r.Finished("https://a.test/y") {
+		t.Fatalf("unexpected finished set %v", r.FinishedKeys())
+	}
+	st := r.Stats()
+	if st.Queued != 1 || st.InFlight != 1 || st.Succeeded != 1 || st.Failed != 1 || st.Records != 5 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestLogWithoutResumeStartsFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.log")
+	if err := os.WriteFile(path, []byte("https://a.test/1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(Options{Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if l.Finished("https://a.test/1") {
+		t.Fatalf("a log opened without Resume must not replay old records")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(data)) != `{"version":2}` {
+		t.Fatalf("expected an empty version 2 log, got %q", data)
+	}
+}
+
+func TestLogReadsVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.log")
+	if err := os.WriteFile(path, []byte("https://a.test/1\n\nhttps://a.test/2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(Options{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	if st := l.Stats(); st.Succeeded != 2 || !l.Finished("https://a.test/2") {
+		t.Fatalf("expected both version 1 keys finished, got %+v", st)
+	}
+	// Version 1 lines are raw URLs; they get the keys the crawl derives.
+	canon := filepath.Join(t.TempDir(), "canonical.log")
+	if err := os.WriteFile(canon, []byte("https://example.com/docs/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lc, err := Open(Options{Path: canon, Resume: true, Key: canonical.Default.Key})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = lc.Close() }()
+	if key := canonical.Default.Key("https://example.com/docs/"); key == "https://example.com/docs/" || !lc.Finished(key) {
+		t.Fatalf("expected the version 1 URL finished under its canonical key %q, got %v", key, lc.FinishedKeys())
+	}
+	if err := os.WriteFile(path+".v3", []byte(`{"version":3}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(Options{Path: path + ".v3", Resume: true}); err == nil {
+		t.Fatalf("expected an error for an unknown version")
+	}
+}
+
+func TestLogCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.log")
+	l, err := Open(Options{Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < compactMinRecords; i++ {
+		key := fmt.Sprintf("https://a.test/%d", i)
+		l.Enqueue(key, key, 1, 0)
+		for attempt := 0; attempt < 3; attempt++ {
+			l.Start(key, attempt)
+		}
+		if i%2 == 0 {
+			l.Finish(key, true, 1)
+		}
+	}
+	// The sync loop compacts once the log holds twice the records its state needs.
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Stats().Compactions == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the log to be compacted, stats %+v", l.Stats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	l.Finish("https://a.test/1", false, 3)
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	r, err := Open(Options{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	// Finished URLs keep one record, in-flight ones two.
+	if st := r.Stats(); st.Succeeded != compactMinRecords/2 || st.Failed != 1 || st.InFlight != compactMinRecords/2-1 || st.Records != compactMinRecords/2*3-1 {
+		t.Fatalf("state lost across compaction: %+v", st)
+	}
+}
@@ -47,6 +47,7 @@ func NewCollyFetcher(policy FetchPolicy) (*CollyFetcher, error) {
 	if policy.UserAgent != "" {
 		c.UserAgent = policy.UserAgent
 	}
+	c.IgnoreRobotsTxt = !policy.RespectRobots
 
 	// Set up rate limiting
 	if err := c.Limit(&colly.LimitRule{
@@ -234,6 +235,7 @@ func (f *CollyFetcher) Configure(policy FetchPolicy) error {
 	if policy.UserAgent != "" {
 		f.collector.UserAgent = policy.UserAgent
 	}
+	f.collector.IgnoreRobotsTxt = !policy.RespectRobots
 
 	// Update rate limiting
 	if err := f.collector.Limit(&colly.LimitRule{
@@ -271,16 +273,5 @@ func (f *CollyFetcher) Stats() FetcherStats {
 
 // isAllowedURL checks if a URL is allowed based on the policy
 func (f *CollyFetcher) isAllowedURL(u *url.URL) bool {
-	if len(f.policy.AllowedDomains) == 0 {
-		return true // No restrictions
-	}
-
-	hostname := u.Hostname()
-	for _, allowed := range f.policy.AllowedDomains {
-		if hostname == allowed || strings.HasSuffix(hostname, "."+allowed) {
-			return true
-		}
-	}
-
-	return false
+	return isAllowedHost(f.policy.AllowedDomains, u)
 }
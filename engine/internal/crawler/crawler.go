@@ -8,6 +8,7 @@ import (
     "sync"
     "time"
 
+    "github.com/99souls/ariadne/engine/internal/canonical"
     "github.com/99souls/ariadne/engine/models"
     "github.com/gocolly/colly/v2"
     "github.com/gocolly/colly/v2/debug"
@@ -31,6 +32,7 @@ func New(config *models.ScraperConfig) *Crawler {
     _ = c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 1, Delay: config.RequestDelay})
     c.SetRequestTimeout(config.Timeout)
     c.UserAgent = config.UserAgent
+    c.IgnoreRobotsTxt = !config.RespectRobots
     crawler := &Crawler{config: config, collector: c, queue: make(chan string, 1000), results: make(chan *models.CrawlResult, 100), stats: &models.CrawlStats{StartTime: time.Now()}}
     crawler.setupCallbacks()
     return crawler
@@ -80,7 +82,7 @@ func (c *Crawler) processLink(link string, base *url.URL) {
     select { case c.queue <- linkURL.String(): default: log.Printf("Queue full, dropping URL: %s", linkURL.String()) }
 }
 
-func (c *Crawler) normalizeURL(u *url.URL) string { normalized := *u; normalized.Fragment = ""; normalized.RawQuery = ""; return normalized.String() }
+func (c *Crawler) normalizeURL(u *url.URL) string { return canonical.Default.URL(u).String() }
 func (c *Crawler) isAllowedURL(u *url.URL) bool { for _, domain := range c.config.AllowedDomains { if u.Host == domain || strings.HasSuffix(u.Host, "."+domain) { return true } }; return false }
 func (c *Crawler) Start(startURL string) error { log.Printf("Starting crawl from: %s", startURL); c.queue <- startURL; go c.processQueue(); return nil }
 func (c *Crawler) processQueue() { for url := range c.queue { if c.shouldStop() { break }; if err := c.collector.Visit(url); err != nil { log.Printf("Failed to visit %s: %v", url, err) } } }
@@ -0,0 +1,364 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/robots"
+	"github.com/99souls/ariadne/engine/models"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxBodyBytes caps the number of response bytes read per page. Larger bodies are
+// truncated rather than failing the fetch.
+const maxBodyBytes = 16 << 20
+
+// HTTPError reports a completed request that returned a non-2xx status.
+// RetryAfter carries the server supplied Retry-After delay (zero when absent).
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("fetch %s: HTTP %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Temporary reports whether the status is worth retrying (408, 429 and 5xx).
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NotModified reports a 304 answer to a conditional request.
+func (e *HTTPError) NotModified() bool { return e.StatusCode == http.StatusNotModified }
+
+// Validators are cache validators from a previous response, sent on revisits as
+// If-None-Match / If-Modified-Since.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// HTTPFetcher implements Fetcher on top of net/http. Unlike CollyFetcher it does not
+// register per-request callbacks, so one instance is safe for concurrent use by the
+// pipeline extraction workers.
+type HTTPFetcher struct {
+	mu     sync.RWMutex
+	policy FetchPolicy
+	client *http.Client
+	robots *robots.Checker // non-nil when policy.RespectRobots
+	stats  fetcherStats
+}
+
+// NewHTTPFetcher creates a net/http based fetcher with the given policy.
+func NewHTTPFetcher(policy FetchPolicy) (*HTTPFetcher, error) {
+	if err := validateFetchPolicy(policy); err != nil {
+		return nil, fmt.Errorf("invalid fetch policy: %w", err)
+	}
+	f := &HTTPFetcher{}
+	f.apply(policy)
+	return f, nil
+}
+
+func (f *HTTPFetcher) apply(policy FetchPolicy) {
+	client := &http.Client{Timeout: policy.Timeout}
+	if !policy.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	var checker *robots.Checker
+	if policy.RespectRobots {
+		checker = robots.NewChecker(robots.Config{UserAgent: policy.UserAgent})
+	}
+	f.mu.Lock()
+	f.policy = policy
+	f.client = client
+	f.robots = checker
+	f.mu.Unlock()
+}
+
+// Fetch retrieves a single page. With RespectRobots set, URLs excluded by robots.txt
+// fail with an error wrapping robots.ErrDisallowed. A non-nil result is returned alongside an *HTTPError
+// when the server answered with a non-2xx status so callers can inspect headers.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*FetchResult, error) {
+	return f.FetchConditional(ctx, rawURL, Validators{})
+}
+
+// FetchConditional is Fetch with cache validators: a page the server reports as not
+// modified yields an *HTTPError whose NotModified method returns true.
+func (f *HTTPFetcher) FetchConditional(ctx context.Context, rawURL string, v Validators) (*FetchResult, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	f.mu.RLock()
+	policy, client, checker := f.policy, f.client, f.robots
+	f.mu.RUnlock()
+	if !isAllowedHost(policy.AllowedDomains, u) {
+		return nil, fmt.Errorf("URL not in allowed domains: %s", u.String())
+	}
+	if checker != nil {
+		if err := checker.Check(ctx, u).Err(rawURL); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request %q: %w", rawURL, err)
+	}
+	if policy.UserAgent != "" {
+		req.Header.Set("User-Agent", policy.UserAgent)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		atomic.AddInt64(&f.stats.requestsFailed, 1)
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	atomic.AddInt64(&f.stats.totalLatency, int64(time.Since(start)))
+	if err != nil {
+		atomic.AddInt64(&f.stats.requestsFailed, 1)
+		return nil, fmt.Errorf("read body %q: %w", rawURL, err)
+	}
+	atomic.AddInt64(&f.stats.bytesDownloaded, int64(len(body)))
+
+	result := &FetchResult{
+		URL:      resp.Request.URL,
+		Content:  body,
+		Headers:  make(map[string]string, len(resp.Header)),
+		Status:   resp.StatusCode,
+		Metadata: make(map[string]interface{}),
+	}
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			result.Headers[key] = values[0]
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		atomic.AddInt64(&f.stats.requestsCompleted, 1)
+		return result, &HTTPError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		atomic.AddInt64(&f.stats.requestsFailed, 1)
+		return result, &HTTPError{URL: rawURL, StatusCode: resp.StatusCode, RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+	atomic.AddInt64(&f.stats.requestsCompleted, 1)
+
+	if isHTML(resp.Header.Get("Content-Type")) {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err == nil {
+			if title := strings.TrimSpace(doc.Find("title").First().Text()); title != "" {
+				result.Metadata["title"] = title
+			}
+			if desc, ok := doc.Find("meta[name='description']").Attr("content"); ok && desc != "" {
+				result.Metadata["description"] = strings.TrimSpace(desc)
+			}
+			if href, ok := doc.Find("link[rel~='canonical'][href]").First().Attr("href"); ok {
+				if canon, err := result.URL.Parse(strings.TrimSpace(href)); err == nil && (canon.Scheme == "http" || canon.Scheme == "https") {
+					canon.Fragment = ""
+					result.Metadata["canonical"] = canon.String()
+				}
+			}
+			result.Links = f.collectLinks(doc, result.URL, policy)
+		}
+	}
+	return result, nil
+}
+
+// Discover extracts links from HTML content.
+func (f *HTTPFetcher) Discover(ctx context.Context, content []byte, baseURL *url.URL) ([]*url.URL, error) {
+	if len(content) == 0 {
+		return []*url.URL{}, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrHTMLParsingFailed, err)
+	}
+	f.mu.RLock()
+	policy := f.policy
+	f.mu.RUnlock()
+	return f.collectLinks(doc, baseURL, policy), nil
+}
+
+func (f *HTTPFetcher) collectLinks(doc *goquery.Document, base *url.URL, policy FetchPolicy) []*url.URL {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if b, err := base.Parse(href); err == nil {
+			base = b
+		}
+	}
+	var links []*url.URL
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+		link, err := base.Parse(href)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return
+		}
+		link.Fragment = ""
+		if isAllowedHost(policy.AllowedDomains, link) {
+			links = append(links, link)
+		}
+	})
+	atomic.AddInt64(&f.stats.linksDiscovered, int64(len(links)))
+	return links
+}
+
+// Configure updates the fetcher's policy.
+func (f *HTTPFetcher) Configure(policy FetchPolicy) error {
+	if err := validateFetchPolicy(policy); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	f.apply(policy)
+	return nil
+}
+
+// Stats returns current fetch statistics.
+func (f *HTTPFetcher) Stats() FetcherStats {
+	completed := atomic.LoadInt64(&f.stats.requestsCompleted)
+	failed := atomic.LoadInt64(&f.stats.requestsFailed)
+	var avg time.Duration
+	if total := completed + failed; total > 0 {
+		avg = time.Duration(atomic.LoadInt64(&f.stats.totalLatency) / total)
+	}
+	return FetcherStats{
+		RequestsCompleted: completed,
+		RequestsFailed:    failed,
+		LinksDiscovered:   atomic.LoadInt64(&f.stats.linksDiscovered),
+		BytesDownloaded:   atomic.LoadInt64(&f.stats.bytesDownloaded),
+		AverageLatency:    avg,
+	}
+}
+
+// PageFetcher adapts a Fetcher to the page-level contract consumed by the engine
+// pipeline. Non-2xx responses surface as *HTTPError.
+type PageFetcher struct {
+	fetcher Fetcher
+}
+
+// NewPageFetcher wraps f.
+func NewPageFetcher(f Fetcher) *PageFetcher { return &PageFetcher{fetcher: f} }
+
+// Fetch retrieves rawURL and converts the result into a models.Page.
+func (p *PageFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	res, err := p.fetcher.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("fetcher returned no result")
+	}
+	return PageFromResult(res), nil
+}
+
+// FetchConditional revalidates rawURL with v when the wrapped fetcher supports
+// conditional requests (HTTPFetcher does) and falls back to a plain Fetch otherwise.
+// A not-modified answer surfaces as an *HTTPError with status 304.
+func (p *PageFetcher) FetchConditional(ctx context.Context, rawURL string, v Validators) (*models.Page, error) {
+	cf, ok := p.fetcher.(interface {
+		FetchConditional(context.Context, string, Validators) (*FetchResult, error)
+	})
+	if !ok {
+		return p.Fetch(ctx, rawURL)
+	}
+	res, err := cf.FetchConditional(ctx, rawURL, v)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("fetcher returned no result")
+	}
+	return PageFromResult(res), nil
+}
+
+// PageFromResult builds a models.Page from a successful FetchResult.
+func PageFromResult(res *FetchResult) *models.Page {
+	page := &models.Page{URL: res.URL, Content: string(res.Content), Links: res.Links, CrawledAt: time.Now()}
+	if title, ok := res.Metadata["title"].(string); ok {
+		page.Title = title
+	}
+	if desc, ok := res.Metadata["description"].(string); ok {
+		page.Metadata.Description = desc
+	}
+	if canon, ok := res.Metadata["canonical"].(string); ok {
+		page.Metadata.Canonical = canon
+	}
+	if len(res.Headers) > 0 {
+		page.Metadata.Headers = make(map[string]string, len(res.Headers))
+		for k, v := range res.Headers {
+			page.Metadata.Headers[k] = v
+		}
+	}
+	return page
+}
+
+// ParseRetryAfter interprets a Retry-After header value (delta seconds or HTTP date)
+// relative to now. Invalid or past values yield zero.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.Contains(contentType, "html")
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func isAllowedHost(allowed []string, u *url.URL) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	hostname := u.Hostname()
+	for _, domain := range allowed {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
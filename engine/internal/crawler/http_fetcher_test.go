@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			if r.Header.Get("User-Agent") != "Test Agent" {
+				t.Errorf("expected policy user agent, got %q", r.Header.Get("User-Agent"))
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title> Home </title><meta name="description" content="d"></head>
+				<body><a href="/a#frag">A</a><a href="mailto:x@y">m</a><a href="#top">t</a></body></html>`))
+		case "/moved":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` || r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`<html><body>versioned</body></html>`))
+		case "/busy":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f, err := NewHTTPFetcher(FetchPolicy{UserAgent: "Test Agent", Timeout: 5 * time.Second, FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("new fetcher: %v", err)
+	}
+	var _ Fetcher = f
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		res, err := f.Fetch(ctx, srv.URL+"/moved")
+		if err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+		if res.Status != http.StatusOK || res.URL.Path != "/" {
+			t.Fatalf("expected redirect to be followed, got status=%d url=%s", res.Status, res.URL)
+		}
+		if res.Metadata["title"] != "Home" || res.Metadata["description"] != "d" {
+			t.Fatalf("unexpected metadata: %v", res.Metadata)
+		}
+		if len(res.Links) != 1 || res.Links[0].String() != srv.URL+"/a" {
+			t.Fatalf("unexpected links: %v", res.Links)
+		}
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		res, err := f.Fetch(ctx, srv.URL+"/busy")
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("expected HTTPError, got %v", err)
+		}
+		if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.RetryAfter != 7*time.Second || !httpErr.Temporary() {
+			t.Fatalf("unexpected error: %+v", httpErr)
+		}
+		if res == nil || res.Headers["Retry-After"] != "7" {
+			t.Fatalf("expected result with headers alongside HTTP error")
+		}
+		if (&HTTPError{StatusCode: http.StatusNotFound}).Temporary() {
+			t.Fatalf("404 must not be temporary")
+		}
+	})
+
+	t.Run("page adapter", func(t *testing.T) {
+		page, err := NewPageFetcher(f).Fetch(ctx, srv.URL+"/")
+		if err != nil {
+			t.Fatalf("page fetch: %v", err)
+		}
+		if page.Title != "Home" || page.Metadata.Description != "d" || page.CrawledAt.IsZero() || len(page.Links) != 1 {
+			t.Fatalf("unexpected page: %+v", page)
+		}
+		if page.Metadata.Headers["Content-Type"] == "" {
+			t.Fatalf("expected response headers on page metadata")
+		}
+		if _, err := NewPageFetcher(f).Fetch(ctx, srv.URL+"/nope"); err == nil {
+			t.Fatalf("expected error for 404")
+		}
+	})
+
+	t.Run("conditional", func(t *testing.T) {
+		pf := NewPageFetcher(f)
+		page, err := pf.FetchConditional(ctx, srv.URL+"/etag", Validators{})
+		if err != nil || page.Metadata.Headers["Etag"] != `"v1"` {
+			t.Fatalf("expected full response with ETag, got %v %v", page, err)
+		}
+		for _, v := range []Validators{{ETag: `"v1"`}, {LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}} {
+			_, err = pf.FetchConditional(ctx, srv.URL+"/etag", v)
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) || !httpErr.NotModified() || httpErr.Temporary() {
+				t.Fatalf("expected not-modified error for %+v, got %v", v, err)
+			}
+		}
+	})
+
+	stats := f.Stats()
+	if stats.RequestsCompleted < 2 || stats.RequestsFailed < 2 || stats.BytesDownloaded == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 Jan 2025 12:00:30 GMT": 30 * time.Second,
+		"Wed, 01 Jan 2025 11:00:00 GMT": 0,
+	}
+	for in, want := range cases {
+		if got := ParseRetryAfter(in, now); got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package deadletter persists URLs that exhausted their fetch attempts so a later run
+// can re-drive them. Each entry keeps the failure classification, attempt count, last
+// status and the first/last failure times.
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// Entry is one dead-lettered URL.
+type Entry struct {
+	URL        string           `json:"url"`
+	Depth      int              `json:"depth"`
+	Stage      string           `json:"stage"`
+	Code       models.ErrorCode `json:"code"`
+	StatusCode int              `json:"status_code,omitempty"`
+	Attempts   int              `json:"attempts"`
+	Error      string           `json:"error"`
+	// Failures counts the runs in which the URL failed.
+	Failures      int       `json:"failures"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+type record struct {
+	Key      string `json:"key"`
+	Resolved bool   `json:"resolved,omitempty"`
+	*Entry
+}
+
+// Filter selects entries. Empty fields match everything; Domains match the URL host
+// and its subdomains.
+type Filter struct {
+	Codes   []models.ErrorCode
+	Domains []string
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if len(f.Codes) > 0 && !containsCode(f.Codes, e.Code) {
+		return false
+	}
+	if len(f.Domains) == 0 {
+		return true
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range f.Domains {
+		d = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*."), ".")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCode(codes []models.ErrorCode, c models.ErrorCode) bool {
+	for _, want := range codes {
+		if want == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is an append-only JSON lines file of dead letters keyed by canonical URL,
+// loaded into memory on Open (last record per key wins, resolved records remove the
+// key) and compacted on Close. Every record is fsynced before Add or Resolve returns.
+// It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Entry
+	f       *os.File
+	appends int
+}
+
+// Open loads the store at path, creating it if missing. A torn trailing line left by
+// a crash is ignored.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("deadletter: %w", err)
+	}
+	entries, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("deadletter: %w", err)
+	}
+	return &Store{path: path, entries: entries, f: f}, nil
+}
+
+// Read returns the entries of the store at path that match f, sorted by URL, without
+// opening it for writing. A missing file holds no entries.
+func Read(path string, f Filter) ([]Entry, error) {
+	entries, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	return selectEntries(entries, f), nil
+}
+
+func load(path string) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deadletter: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var r record
+		if json.Unmarshal(sc.Bytes(), &r) != nil || r.Key == "" {
+			continue
+		}
+		if r.Resolved || r.Entry == nil {
+			delete(entries, r.Key)
+		} else {
+			entries[r.Key] = *r.Entry
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("deadletter: read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func selectEntries(entries map[string]Entry, f Filter) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].URL < out[j].URL })
+	return out
+}
+
+// Add records a failure of key. A key already dead-lettered keeps its first failure
+// time and has its failure count incremented.
+func (s *Store) Add(key string, e Entry) error {
+	if e.LastFailedAt.IsZero() {
+		e.LastFailedAt = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("deadletter: store closed")
+	}
+	e.FirstFailedAt, e.Failures = e.LastFailedAt, 1
+	if prev, ok := s.entries[key]; ok {
+		e.FirstFailedAt, e.Failures = prev.FirstFailedAt, prev.Failures+1
+	}
+	if err := s.appendLocked(record{Key: key, Entry: &e}); err != nil {
+		return err
+	}
+	s.entries[key] = e
+	return nil
+}
+
+// Resolve removes key after a successful crawl. It reports whether key was
+// dead-lettered.
+func (s *Store) Resolve(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; !ok {
+		return false, nil
+	}
+	if s.f == nil {
+		return false, errors.New("deadletter: store closed")
+	}
+	if err := s.appendLocked(record{Key: key, Resolved: true}); err != nil {
+		return false, err
+	}
+	delete(s.entries, key)
+	return true, nil
+}
+
+func (s *Store) appendLocked(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("deadletter: %w", err)
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("deadletter: %w", err)
+	}
+	// Dead letters are rare; syncing each one keeps a crash from losing any.
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("deadletter: %w", err)
+	}
+	s.appends++
+	return nil
+}
+
+// Entries returns the entries matching f, sorted by URL.
+func (s *Store) Entries(f Filter) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return selectEntries(s.entries, f)
+}
+
+// Len returns the number of dead-lettered URLs.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close compacts the file to one record per URL when it holds superseded records and
+// releases it.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	if err != nil || s.appends == 0 {
+		return err
+	}
+	return s.compactLocked()
+}
+
+func (s *Store) compactLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("deadletter: compact: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for key, e := range s.entries {
+		if err := enc.Encode(record{Key: key, Entry: &e}); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("deadletter: compact: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("deadletter: compact: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("deadletter: compact: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("deadletter: compact: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("deadletter: compact: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package deadletter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+func TestStoreTracksFailuresAndResolves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "dead-letters.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	must(s.Add("https://a.test/x", Entry{URL: "https://a.test/x", Depth: 1, Code: models.ErrorCodeHTTP5xx, StatusCode: 503, Attempts: 3, LastFailedAt: first}))
+	must(s.Add("https://a.test/x", Entry{URL: "https://a.test/x", Depth: 1, Code: models.ErrorCodeHTTP5xx, StatusCode: 502, Attempts: 3}))
+	must(s.Add("https://docs.b.test/y", Entry{URL: "https://docs.b.test/y", Code: models.ErrorCodeTimeout, Attempts: 3}))
+	must(s.Add("https://a.test/fixed", Entry{URL: "https://a.test/fixed", Code: models.ErrorCodeDNS, Attempts: 1}))
+	if ok, err := s.Resolve("https://a.test/fixed"); err != nil || !ok {
+		t.Fatalf("resolve: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := s.Resolve("https://a.test/unknown"); ok {
+		t.Fatalf("resolving an unknown key must report false")
+	}
+	must(s.Close())
+
+	// Simulate a crash mid-append on top of the compacted file.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"key":"https://a.test/torn","ur`)
+	_ = f.Close()
+
+	all, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(all) != 2 || all[0].URL != "https://a.test/x" || all[1].URL != "https://docs.b.test/y" {
+		t.Fatalf("unexpected entries: %+v", all)
+	}
+	x := all[0]
+	if x.Failures != 2 || !x.FirstFailedAt.Equal(first) || !x.LastFailedAt.After(first) || x.StatusCode != 502 || x.Depth != 1 {
+		t.Fatalf("unexpected merged entry: %+v", x)
+	}
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Fatalf("expected compacted file with two records (+ torn tail), got %d lines", n)
+	}
+
+	for _, tc := range []struct {
+		f    Filter
+		want int
+	}{
+		{Filter{Codes: []models.ErrorCode{models.ErrorCodeHTTP5xx}}, 1},
+		{Filter{Domains: []string{"b.test"}}, 1},
+		{Filter{Domains: []string{"a.test"}, Codes: []models.ErrorCode{models.ErrorCodeTimeout}}, 0},
+		{Filter{Domains: []string{"*.B.test", "c.test"}}, 1},
+	} {
+		if got, _ := Read(path, tc.f); len(got) != tc.want {
+			t.Fatalf("filter %+v: got %d entries, want %d", tc.f, len(got), tc.want)
+		}
+	}
+	if got, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"), Filter{}); err != nil || len(got) != 0 {
+		t.Fatalf("missing file: %v %v", got, err)
+	}
+}
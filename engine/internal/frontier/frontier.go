@@ -0,0 +1,375 @@
+// Package frontier implements a disk-backed crawl frontier. Pending URLs are kept in
+// an append-only, segmented log per priority band and only a bounded read-ahead window
+// per band is held in memory, so a frontier holding millions of URLs stays small. A
+// compact index records the consumed position of every band; together with the
+// de-duplication set, which is also kept on disk, it lets a crawl resume after a
+// restart.
+//
+// Durability: Close persists an exact index. After a crash the logs are rescanned from
+// the last persisted positions (torn trailing records are truncated), so URLs handed
+// out since the last Sync may be delivered again and pushes still buffered in memory
+// are lost.
+package frontier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+)
+
+const (
+	indexVersion = 1
+	indexFile    = "index.json"
+
+	defaultPriorities   = 4
+	defaultSegmentBytes = 16 << 20
+	defaultReadAhead    = 256
+)
+
+// ErrClosed is returned by operations on a closed Queue.
+var ErrClosed = errors.New("frontier: closed")
+
+// Item is a queued URL.
+type Item struct {
+	URL   string
+	Depth int
+	// Priority selects the band (0..Options.Priorities-1, clamped); higher bands are
+	// dequeued first, FIFO within a band.
+	Priority int
+}
+
+// Options configures a Queue.
+type Options struct {
+	// Dir holds the segment logs, index and de-duplication set. It is created if missing.
+	Dir string
+	// Priorities is the number of priority bands (default 4).
+	Priorities int
+	// SegmentBytes is the size at which a band's log rolls over to a new segment
+	// (default 16MiB). Fully consumed segments are deleted.
+	SegmentBytes int64
+	// ReadAhead bounds how many pending items per band are held in memory (default 256).
+	ReadAhead int
+}
+
+// HostQueue is the per-host view of pending items.
+type HostQueue struct {
+	Host    string `json:"host"`
+	Pending int    `json:"pending"`
+}
+
+// Queue is a persistent priority queue of crawl items with a de-duplication set. It is
+// safe for concurrent use.
+type Queue struct {
+	mu      sync.Mutex
+	opts    Options
+	bands   []*band
+	hosts   map[string]int
+	pending int
+	pushed  int
+	seen    *seenSet
+	closed  bool
+}
+
+type indexState struct {
+	Version int            `json:"version"`
+	Clean   bool           `json:"clean"`
+	Bands   []bandState    `json:"bands"`
+	Pending int            `json:"pending"`
+	Pushed  int            `json:"pushed"`
+	Hosts   map[string]int `json:"hosts,omitempty"`
+}
+
+// Open opens or creates the queue stored in opts.Dir.
+func Open(opts Options) (*Queue, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("frontier: Dir is required")
+	}
+	if opts.Priorities <= 0 {
+		opts.Priorities = defaultPriorities
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaultSegmentBytes
+	}
+	if opts.ReadAhead <= 0 {
+		opts.ReadAhead = defaultReadAhead
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("frontier: %w", err)
+	}
+	var idx indexState
+	if data, err := os.ReadFile(filepath.Join(opts.Dir, indexFile)); err == nil {
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return nil, fmt.Errorf("frontier: index: %w", err)
+		}
+		if idx.Version != indexVersion {
+			return nil, fmt.Errorf("frontier: unsupported index version %d", idx.Version)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("frontier: index: %w", err)
+	}
+	// A changed band count would silently reorder work; refuse instead.
+	if len(idx.Bands) > 0 && len(idx.Bands) != opts.Priorities {
+		return nil, fmt.Errorf("frontier: %s was created with %d priorities, not %d", opts.Dir, len(idx.Bands), opts.Priorities)
+	}
+
+	q := &Queue{opts: opts, hosts: make(map[string]int)}
+	for i := 0; i < opts.Priorities; i++ {
+		var st bandState
+		if i < len(idx.Bands) {
+			st = idx.Bands[i]
+		}
+		b, err := openBand(opts, i, st)
+		if err != nil {
+			q.closeFiles()
+			return nil, err
+		}
+		q.bands = append(q.bands, b)
+	}
+	if idx.Clean {
+		q.pending, q.pushed = idx.Pending, idx.Pushed
+		for h, n := range idx.Hosts {
+			q.hosts[h] = n
+		}
+		for _, b := range q.bands {
+			b.pending = b.state.Pending
+		}
+	} else {
+		// Unclean shutdown (or first open): recount from the logs.
+		for _, b := range q.bands {
+			if err := b.scan(func(it Item) { q.hosts[canonical.Host(it.URL)]++ }); err != nil {
+				q.closeFiles()
+				return nil, err
+			}
+			q.pending += b.pending
+		}
+		q.pushed = idx.Pushed
+		if q.pushed < q.pending {
+			q.pushed = q.pending
+		}
+	}
+	seen, err := openSeen(opts.Dir, idx.Clean)
+	if err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+	q.seen = seen
+	// Until the next clean Close a crash must trigger a rescan.
+	if err := q.writeIndexLocked(false); err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Push appends it to its priority band.
+func (q *Queue) Push(it Item) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	b := q.bands[q.band(it.Priority)]
+	if err := b.append(it); err != nil {
+		return err
+	}
+	q.pending++
+	q.pushed++
+	q.hosts[canonical.Host(it.URL)]++
+	return nil
+}
+
+// Pop removes and returns the next item: the oldest item of the highest non-empty band.
+func (q *Queue) Pop() (Item, bool, error) {
+	return q.PopFunc(nil)
+}
+
+// PopFunc removes and returns the first item, in priority order, for which accept
+// returns true. Only items inside each band's read-ahead window are considered, so an
+// item may be skipped until enough earlier items of its band have been consumed. A nil
+// accept takes the next item.
+func (q *Queue) PopFunc(accept func(Item) bool) (Item, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Item{}, false, ErrClosed
+	}
+	for i := len(q.bands) - 1; i >= 0; i-- {
+		b := q.bands[i]
+		if b.pending == 0 {
+			continue
+		}
+		if err := b.fill(); err != nil {
+			return Item{}, false, err
+		}
+		if it, ok := b.take(accept); ok {
+			q.pending--
+			host := canonical.Host(it.URL)
+			if q.hosts[host]--; q.hosts[host] <= 0 {
+				delete(q.hosts, host)
+			}
+			return it, true, nil
+		}
+	}
+	return Item{}, false, nil
+}
+
+// Len returns the number of pending items.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+// Pushed returns the number of items ever pushed to this queue, across restarts.
+func (q *Queue) Pushed() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pushed
+}
+
+// Hosts returns the pending count per host, largest first.
+func (q *Queue) Hosts() []HostQueue {
+	q.mu.Lock()
+	out := make([]HostQueue, 0, len(q.hosts))
+	for h, n := range q.hosts {
+		out = append(out, HostQueue{Host: h, Pending: n})
+	}
+	q.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pending != out[j].Pending {
+			return out[i].Pending > out[j].Pending
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// MarkSeen records key in the de-duplication set and reports whether it was new.
+func (q *Queue) MarkSeen(key string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false, ErrClosed
+	}
+	return q.seen.add(key)
+}
+
+// Seen reports whether key was marked. It reports false once the queue is closed or
+// if the set cannot be read.
+func (q *Queue) Seen(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	ok, _ := q.seen.contains(key)
+	return ok
+}
+
+// Sync flushes buffered pushes and persists the consumed positions so that a crash
+// loses at most the work handed out after this call.
+func (q *Queue) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if err := q.flushLocked(); err != nil {
+		return err
+	}
+	return q.writeIndexLocked(false)
+}
+
+// Close flushes all state and releases file handles. Items popped before Close are
+// considered consumed; pending items are delivered again after Open.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	err := q.flushLocked()
+	if err == nil {
+		err = q.seen.seal()
+	}
+	if err == nil {
+		err = q.writeIndexLocked(true)
+	}
+	return errors.Join(err, q.closeFiles())
+}
+
+func (q *Queue) band(priority int) int {
+	switch {
+	case priority < 0:
+		return 0
+	case priority >= len(q.bands):
+		return len(q.bands) - 1
+	default:
+		return priority
+	}
+}
+
+func (q *Queue) flushLocked() error {
+	for _, b := range q.bands {
+		if err := b.flush(true); err != nil {
+			return err
+		}
+	}
+	return q.seen.sync()
+}
+
+func (q *Queue) writeIndexLocked(clean bool) error {
+	idx := indexState{Version: indexVersion, Clean: clean, Pending: q.pending, Pushed: q.pushed}
+	for _, b := range q.bands {
+		idx.Bands = append(idx.Bands, b.snapshot())
+	}
+	if clean {
+		idx.Hosts = q.hosts
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("frontier: index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(q.opts.Dir, indexFile), data)
+}
+
+func (q *Queue) closeFiles() error {
+	var errs []error
+	for _, b := range q.bands {
+		errs = append(errs, b.close())
+	}
+	if q.seen != nil {
+		errs = append(errs, q.seen.close())
+	}
+	return errors.Join(errs...)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package frontier
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustOpen(t *testing.T, opts Options) *Queue {
+	t.Helper()
+	q, err := Open(opts)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return q
+}
+
+func mustPush(t *testing.T, q *Queue, items ...Item) {
+	t.Helper()
+	for _, it := range items {
+		if err := q.Push(it); err != nil {
+			t.Fatalf("push %s: %v", it.URL, err)
+		}
+	}
+}
+
+func popURLs(t *testing.T, q *Queue, n int) []string {
+	t.Helper()
+	var out []string
+	for i := 0; i < n; i++ {
+		it, ok, err := q.Pop()
+		if err != nil {
+			t.Fatalf("pop: %v", err)
+		}
+		if !ok {
+			break
+		}
+		out = append(out, it.URL)
+	}
+	return out
+}
+
+func TestPriorityOrderAndHostView(t *testing.T) {
+	q := mustOpen(t, Options{Dir: t.TempDir(), Priorities: 3})
+	defer func() { _ = q.Close() }()
+	mustPush(t, q,
+		Item{URL: "https://a.test/low", Priority: 0},
+		Item{URL: "https://a.test/high1", Priority: 2, Depth: 1},
+		Item{URL: "https://b.test/mid", Priority: 1},
+		Item{URL: "https://B.test:443/high2", Priority: 7}, // clamped to the top band
+	)
+	hosts := q.Hosts()
+	if len(hosts) != 2 || hosts[0] != (HostQueue{Host: "a.test", Pending: 2}) || hosts[1] != (HostQueue{Host: "b.test", Pending: 2}) {
+		t.Fatalf("unexpected host view: %+v", hosts)
+	}
+	it, ok, err := q.PopFunc(func(it Item) bool { return it.URL != "https://a.test/high1" })
+	if err != nil || !ok || it.URL != "https://B.test:443/high2" || it.Priority != 2 {
+		t.Fatalf("PopFunc should skip rejected items, got %+v ok=%v err=%v", it, ok, err)
+	}
+	got := popURLs(t, q, 10)
+	want := []string{"https://a.test/high1", "https://b.test/mid", "https://a.test/low"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("pop order = %v, want %v", got, want)
+	}
+	if q.Len() != 0 || len(q.Hosts()) != 0 {
+		t.Fatalf("expected empty queue, len=%d hosts=%v", q.Len(), q.Hosts())
+	}
+}
+
+func TestReopenResumesAndSegmentsAreReclaimed(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Dir: dir, SegmentBytes: 256, ReadAhead: 8}
+	q := mustOpen(t, opts)
+	for i := 0; i < 200; i++ {
+		mustPush(t, q, Item{URL: fmt.Sprintf("https://a.test/p/%03d", i), Depth: i % 3, Priority: 1})
+	}
+	if ok, _ := q.MarkSeen("https://a.test/p/000"); !ok {
+		t.Fatalf("first MarkSeen should report new")
+	}
+	first := popURLs(t, q, 120)
+	if len(first) != 120 || first[119] != "https://a.test/p/119" {
+		t.Fatalf("unexpected first pops: %d", len(first))
+	}
+	// Out-of-order consumption inside the window must survive the restart too.
+	if it, ok, _ := q.PopFunc(func(it Item) bool { return it.URL == "https://a.test/p/122" }); !ok || it.Depth != 2 {
+		t.Fatalf("expected PopFunc to take p/122, got %+v", it)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "band-1-*.log"))
+	if len(segs) > 11 {
+		t.Fatalf("consumed segments should be deleted, %d remain", len(segs))
+	}
+
+	q = mustOpen(t, opts)
+	defer func() { _ = q.Close() }()
+	if q.Len() != 79 || q.Pushed() != 200 || !q.Seen("https://a.test/p/000") || q.Seen("https://a.test/p/001") {
+		t.Fatalf("unexpected state after reopen: len=%d pushed=%d", q.Len(), q.Pushed())
+	}
+	rest := popURLs(t, q, 1000)
+	if len(rest) != 79 || rest[0] != "https://a.test/p/120" || rest[2] != "https://a.test/p/123" || rest[78] != "https://a.test/p/199" {
+		t.Fatalf("unexpected resumed order: %v", rest)
+	}
+}
+
+func TestCrashRecoveryRescansAndTruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Dir: dir, Priorities: 2}
+	q := mustOpen(t, opts)
+	for i := 0; i < 10; i++ {
+		mustPush(t, q, Item{URL: fmt.Sprintf("https://h%d.test/", i%2), Priority: i % 2})
+	}
+	popURLs(t, q, 3)
+	mustPush(t, q, Item{URL: "https://late.test/"})
+	if err := q.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	popURLs(t, q, 2) // handed out after the last Sync: redelivered after a crash
+	// Simulate a crash mid-append: leave the handles open and add a torn record.
+	seg := filepath.Join(dir, "band-0-00000001.log")
+	f, err := os.OpenFile(seg, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{42, 0, 0, 0, 1, 2})
+	_ = f.Close()
+	before, _ := os.Stat(seg)
+
+	q2 := mustOpen(t, opts)
+	defer func() { _ = q2.Close() }()
+	after, _ := os.Stat(seg)
+	if after.Size() != before.Size()-6 {
+		t.Fatalf("torn record not truncated: %d -> %d", before.Size(), after.Size())
+	}
+	if q2.Len() != 8 {
+		t.Fatalf("expected 6 pending plus 2 redelivered items, got %d", q2.Len())
+	}
+	mustPush(t, q2, Item{URL: "https://after.test/"})
+	if got := popURLs(t, q2, 100); len(got) != 9 || got[len(got)-1] != "https://after.test/" {
+		t.Fatalf("unexpected recovered items: %v", got)
+	}
+	_ = q.closeFiles()
+}
+
+func TestReadAheadBoundsMemory(t *testing.T) {
+	q := mustOpen(t, Options{Dir: t.TempDir(), ReadAhead: 16, SegmentBytes: 4 << 10})
+	defer func() { _ = q.Close() }()
+	const n = 20000
+	for i := 0; i < n; i++ {
+		mustPush(t, q, Item{URL: fmt.Sprintf("https://big.test/%d", i), Priority: i % 4})
+	}
+	for i := 0; i < n; i++ {
+		if _, ok, err := q.Pop(); !ok || err != nil {
+			t.Fatalf("pop %d: ok=%v err=%v", i, ok, err)
+		}
+		for _, b := range q.bands {
+			if len(b.window) > 16 {
+				t.Fatalf("band %d window grew to %d", b.id, len(b.window))
+			}
+		}
+	}
+	if _, ok, _ := q.Pop(); ok {
+		t.Fatalf("expected drained queue")
+	}
+}
+
+func TestOpenRejectsChangedPriorities(t *testing.T) {
+	dir := t.TempDir()
+	q := mustOpen(t, Options{Dir: dir, Priorities: 2})
+	_ = q.Close()
+	if _, err := Open(Options{Dir: dir, Priorities: 3}); err == nil {
+		t.Fatalf("expected error when reopening with a different band count")
+	}
+}
+
+func TestSeenSetGrowsOnDiskAndSurvivesCrash(t *testing.T) {
+	dir := t.TempDir()
+	q := mustOpen(t, Options{Dir: dir})
+	const n = 3 * minSeenSlots
+	for i := 0; i < n; i++ {
+		if ok, err := q.MarkSeen(fmt.Sprintf("https://a.test/%d", i)); !ok || err != nil {
+			t.Fatalf("MarkSeen %d: ok=%v err=%v", i, ok, err)
+		}
+	}
+	if q.seen.slots <= minSeenSlots || q.seen.count != n {
+		t.Fatalf("table should have grown: slots=%d count=%d", q.seen.slots, q.seen.count)
+	}
+	// Crash with the table header stale: the table must be rebuilt from the key log.
+	q2 := mustOpen(t, Options{Dir: dir})
+	defer func() { _ = q2.Close() }()
+	for _, i := range []int{0, 1, n / 2, n - 1} {
+		if ok, err := q2.MarkSeen(fmt.Sprintf("https://a.test/%d", i)); ok || err != nil {
+			t.Fatalf("key %d should still be seen: ok=%v err=%v", i, ok, err)
+		}
+	}
+	if q2.Seen(fmt.Sprintf("https://a.test/%d", n)) {
+		t.Fatalf("unmarked key reported as seen")
+	}
+	_ = q.closeFiles()
+}
+
+func TestSeenSetComparesKeysOnHashCollision(t *testing.T) {
+	defer func(h func(string) uint64) { hashKey = h }(hashKey)
+	hashKey = func(string) uint64 { return 7 }
+
+	dir := t.TempDir()
+	q := mustOpen(t, Options{Dir: dir})
+	for i := 0; i < 50; i++ {
+		if ok, err := q.MarkSeen(fmt.Sprintf("https://a.test/%d", i)); !ok || err != nil {
+			t.Fatalf("colliding key %d dropped as a duplicate: ok=%v err=%v", i, ok, err)
+		}
+	}
+	if ok, _ := q.MarkSeen("https://a.test/7"); ok {
+		t.Fatalf("repeated key reported as new")
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	q = mustOpen(t, Options{Dir: dir})
+	defer func() { _ = q.Close() }()
+	if !q.Seen("https://a.test/49") || q.Seen("https://a.test/50") {
+		t.Fatalf("unexpected membership after a clean reopen")
+	}
+}
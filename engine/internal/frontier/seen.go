@@ -0,0 +1,340 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The de-duplication set lives on disk in two files: seen.keys, an append-only log of
+// the keys (uint32 length, uint32 CRC-32 of the key, key bytes), and seen.idx, an
+// open-addressing hash table of 16-byte slots (uint64 key hash, uint64 log offset + 1;
+// zero marks an empty slot) behind a 16-byte header holding the key count and the log
+// size it covers. Only the file handles are kept in memory. A hash hit is confirmed
+// against the key in the log, so colliding keys are never mistaken for each other.
+const (
+	seenKeysFile  = "seen.keys"
+	seenTableFile = "seen.idx"
+
+	seenHeaderBytes = 16
+	seenSlotBytes   = 16
+	minSeenSlots    = 1 << 12
+	seenCopySlots   = 4 << 10
+)
+
+// hashKey is a variable so tests can force collisions.
+var hashKey = func(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+type seenSet struct {
+	dir   string
+	keys  *os.File
+	table *os.File
+	size  int64 // bytes of whole records in keys
+	slots uint64
+	count uint64
+}
+
+// openSeen opens the set in dir. The table is trusted only if the previous shutdown
+// was clean and it covers the whole key log; otherwise it is rebuilt from the log,
+// truncating a torn trailing record.
+func openSeen(dir string, clean bool) (*seenSet, error) {
+	keys, err := os.OpenFile(filepath.Join(dir, seenKeysFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("frontier: seen: %w", err)
+	}
+	s := &seenSet{dir: dir, keys: keys}
+	if clean {
+		if err := s.openTable(); err != nil {
+			_ = s.close()
+			return nil, err
+		}
+		if s.table != nil {
+			return s, nil
+		}
+	}
+	if err := s.rebuild(); err != nil {
+		_ = s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openTable opens an existing table, leaving s.table nil if it is missing or does not
+// match the key log.
+func (s *seenSet) openTable() error {
+	fi, err := s.keys.Stat()
+	if err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, seenTableFile), os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	tfi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	var hdr [seenHeaderBytes]byte
+	if _, err := f.ReadAt(hdr[:], 0); err != nil {
+		_ = f.Close()
+		return nil
+	}
+	slots := uint64(tfi.Size()-seenHeaderBytes) / seenSlotBytes
+	count, size := binary.LittleEndian.Uint64(hdr[0:]), int64(binary.LittleEndian.Uint64(hdr[8:]))
+	if size != fi.Size() || slots < minSeenSlots || slots&(slots-1) != 0 || count >= slots {
+		_ = f.Close()
+		return nil
+	}
+	s.table, s.slots, s.count, s.size = f, slots, count, size
+	return nil
+}
+
+func (s *seenSet) rebuild() error {
+	if s.table != nil {
+		_ = s.table.Close()
+		s.table = nil
+	}
+	table, err := createTable(filepath.Join(s.dir, seenTableFile), minSeenSlots)
+	if err != nil {
+		return err
+	}
+	s.table, s.slots, s.count, s.size = table, minSeenSlots, 0, 0
+	if _, err := s.keys.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	br := bufio.NewReader(s.keys)
+	for {
+		key, n, err := readSeenRecord(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if terr := s.keys.Truncate(s.size); terr != nil {
+				return fmt.Errorf("frontier: seen: truncate torn record: %w", terr)
+			}
+			break
+		}
+		if err := s.insert(hashKey(key), s.size); err != nil {
+			return err
+		}
+		s.size += n
+	}
+	return nil
+}
+
+// contains reports whether key is in the set.
+func (s *seenSet) contains(key string) (bool, error) {
+	return s.lookup(key, hashKey(key))
+}
+
+// add inserts key and reports whether it was new.
+func (s *seenSet) add(key string) (bool, error) {
+	if len(key) > maxRecordBytes {
+		return false, fmt.Errorf("frontier: seen: key too long (%d bytes)", len(key))
+	}
+	h := hashKey(key)
+	found, err := s.lookup(key, h)
+	if found || err != nil {
+		return false, err
+	}
+	rec := make([]byte, 8+len(key))
+	binary.LittleEndian.PutUint32(rec[0:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(rec[4:], crc32.ChecksumIEEE([]byte(key)))
+	copy(rec[8:], key)
+	if _, err := s.keys.WriteAt(rec, s.size); err != nil {
+		return false, fmt.Errorf("frontier: seen: %w", err)
+	}
+	if err := s.insert(h, s.size); err != nil {
+		return false, err
+	}
+	s.size += int64(len(rec))
+	return true, nil
+}
+
+// lookup walks h's probe sequence up to the first empty slot, comparing the stored key
+// on every hash match.
+func (s *seenSet) lookup(key string, h uint64) (bool, error) {
+	var slot [seenSlotBytes]byte
+	var rec []byte
+	mask := s.slots - 1
+	for i := h & mask; ; i = (i + 1) & mask {
+		if _, err := s.table.ReadAt(slot[:], slotOffset(i)); err != nil {
+			return false, fmt.Errorf("frontier: seen: %w", err)
+		}
+		ref := binary.LittleEndian.Uint64(slot[8:])
+		if ref == 0 {
+			return false, nil
+		}
+		if binary.LittleEndian.Uint64(slot[0:]) != h {
+			continue
+		}
+		if rec == nil {
+			rec = make([]byte, 8+len(key))
+		}
+		n, err := s.keys.ReadAt(rec, int64(ref-1))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("frontier: seen: %w", err)
+		}
+		if n == len(rec) && binary.LittleEndian.Uint32(rec) == uint32(len(key)) && string(rec[8:]) == key {
+			return true, nil
+		}
+	}
+}
+
+// insert records a key of hash h stored at off, growing the table first if it is
+// three quarters full. The key must not be present yet.
+func (s *seenSet) insert(h uint64, off int64) error {
+	if (s.count+1)*4 > s.slots*3 {
+		if err := s.grow(); err != nil {
+			return err
+		}
+	}
+	if err := placeSlot(s.table, s.slots, h, uint64(off)+1); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// grow rehashes the table into one twice its size.
+func (s *seenSet) grow() error {
+	path := filepath.Join(s.dir, seenTableFile)
+	slots := s.slots * 2
+	next, err := createTable(path+".tmp", slots)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, seenCopySlots*seenSlotBytes)
+	for first := uint64(0); first < s.slots; first += seenCopySlots {
+		chunk := buf[:min(seenCopySlots, s.slots-first)*seenSlotBytes]
+		if _, err := s.table.ReadAt(chunk, slotOffset(first)); err != nil {
+			_ = next.Close()
+			return fmt.Errorf("frontier: seen: %w", err)
+		}
+		for i := 0; i < len(chunk); i += seenSlotBytes {
+			ref := binary.LittleEndian.Uint64(chunk[i+8:])
+			if ref == 0 {
+				continue
+			}
+			if err := placeSlot(next, slots, binary.LittleEndian.Uint64(chunk[i:]), ref); err != nil {
+				_ = next.Close()
+				return err
+			}
+		}
+	}
+	_ = s.table.Close()
+	// Keep using the new table even if it cannot be renamed into place; a missing
+	// table is rebuilt on the next Open.
+	s.table, s.slots = next, slots
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	return nil
+}
+
+// sync flushes both files to stable storage.
+func (s *seenSet) sync() error {
+	if err := s.keys.Sync(); err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	if err := s.table.Sync(); err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	return nil
+}
+
+// seal records the count and covered log size so the next clean Open can reuse the
+// table as is.
+func (s *seenSet) seal() error {
+	var hdr [seenHeaderBytes]byte
+	binary.LittleEndian.PutUint64(hdr[0:], s.count)
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(s.size))
+	if _, err := s.table.WriteAt(hdr[:], 0); err != nil {
+		return fmt.Errorf("frontier: seen: %w", err)
+	}
+	return s.sync()
+}
+
+func (s *seenSet) close() error {
+	var errs []error
+	if s.table != nil {
+		errs = append(errs, s.table.Close())
+	}
+	return errors.Join(append(errs, s.keys.Close())...)
+}
+
+// createTable creates an empty table of slots slots at path, replacing any file there.
+// The header stays zeroed, which never matches a key log, until seal.
+func createTable(path string, slots uint64) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("frontier: seen: %w", err)
+	}
+	if err := f.Truncate(int64(slotOffset(slots))); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("frontier: seen: %w", err)
+	}
+	return f, nil
+}
+
+// placeSlot writes (h, ref) into the first empty slot of h's probe sequence.
+func placeSlot(f *os.File, slots, h, ref uint64) error {
+	var slot [seenSlotBytes]byte
+	mask := slots - 1
+	for i := h & mask; ; i = (i + 1) & mask {
+		if _, err := f.ReadAt(slot[:], slotOffset(i)); err != nil {
+			return fmt.Errorf("frontier: seen: %w", err)
+		}
+		if binary.LittleEndian.Uint64(slot[8:]) != 0 {
+			continue
+		}
+		binary.LittleEndian.PutUint64(slot[0:], h)
+		binary.LittleEndian.PutUint64(slot[8:], ref)
+		if _, err := f.WriteAt(slot[:], slotOffset(i)); err != nil {
+			return fmt.Errorf("frontier: seen: %w", err)
+		}
+		return nil
+	}
+}
+
+func slotOffset(i uint64) int64 {
+	return seenHeaderBytes + int64(i)*seenSlotBytes
+}
+
+// readSeenRecord decodes one key record, returning its encoded size. io.EOF signals a
+// clean end; any other error a torn or corrupt record.
+func readSeenRecord(r *bufio.Reader) (string, int64, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.EOF {
+			return "", 0, io.EOF
+		}
+		return "", 0, errCorrupt
+	}
+	n := binary.LittleEndian.Uint32(hdr[0:])
+	if n > maxRecordBytes {
+		return "", 0, errCorrupt
+	}
+	key := make([]byte, n)
+	if _, err := io.ReadFull(r, key); err != nil {
+		return "", 0, errCorrupt
+	}
+	if crc32.ChecksumIEEE(key) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return "", 0, errCorrupt
+	}
+	return string(key), int64(len(hdr)) + int64(n), nil
+}
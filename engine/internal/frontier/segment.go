@@ -0,0 +1,413 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Record layout: uint32 payload length, uint32 CRC-32 (IEEE) of the payload, then the
+// payload: uvarint depth followed by the URL bytes.
+const (
+	headerBytes    = 8
+	maxRecordBytes = 1 << 20
+)
+
+var errCorrupt = errors.New("frontier: corrupt record")
+
+// position addresses a record by segment sequence number and byte offset.
+type position struct {
+	Segment int   `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// bandState is the persisted part of a band.
+type bandState struct {
+	// Head is the first record not yet consumed; everything before it is done.
+	Head position `json:"head"`
+	// Taken lists records past Head that were consumed out of order (PopFunc).
+	Taken   []position `json:"taken,omitempty"`
+	Pending int        `json:"pending"`
+}
+
+type slot struct {
+	item  Item
+	start position
+	taken bool
+}
+
+// band is one priority level: a FIFO over a sequence of segment files plus an
+// in-memory window of records read ahead from the head.
+type band struct {
+	id        int
+	opts      Options
+	state     bandState
+	segments  []int // ascending sequence numbers of existing segment files
+	head      position
+	next      position              // start of the first record not yet read into window
+	taken     map[position]struct{} // consumed records not yet reached by the reader
+	window    []slot
+	untaken   int
+	pending   int
+	exhausted bool // the reader hit the end of the newest segment since the last append
+
+	w     *os.File
+	wbuf  *bufio.Writer
+	wseg  int
+	wsize int64
+	r     *os.File
+	rseg  int
+}
+
+func openBand(opts Options, id int, st bandState) (*band, error) {
+	b := &band{id: id, opts: opts, state: st, head: st.Head, taken: make(map[position]struct{})}
+	matches, err := filepath.Glob(filepath.Join(opts.Dir, fmt.Sprintf("band-%d-*.log", id)))
+	if err != nil {
+		return nil, fmt.Errorf("frontier: %w", err)
+	}
+	for _, m := range matches {
+		var seq int
+		if _, err := fmt.Sscanf(filepath.Base(m), fmt.Sprintf("band-%d-%%d.log", id), &seq); err == nil && seq > 0 {
+			b.segments = append(b.segments, seq)
+		}
+	}
+	sort.Ints(b.segments)
+	// Position the head on the first surviving segment at or after the persisted one;
+	// a segment deleted before the index caught up was fully consumed.
+	switch i := sort.SearchInts(b.segments, b.head.Segment); {
+	case i == len(b.segments):
+		last := 0
+		if len(b.segments) > 0 {
+			last = b.segments[len(b.segments)-1]
+		}
+		if b.head.Segment <= last {
+			b.head = position{Segment: last + 1}
+		}
+	case b.segments[i] != b.head.Segment:
+		b.head = position{Segment: b.segments[i]}
+	}
+	if b.head.Segment <= 0 {
+		b.head = position{Segment: 1}
+	}
+	b.removeBefore(b.head.Segment)
+	for _, p := range st.Taken {
+		b.taken[p] = struct{}{}
+	}
+	b.next = b.head
+	return b, nil
+}
+
+func (b *band) path(seq int) string {
+	return filepath.Join(b.opts.Dir, fmt.Sprintf("band-%d-%08d.log", b.id, seq))
+}
+
+// scan recounts pending records from the head, calling fn for each. A damaged
+// trailing record in the newest segment (a torn write) is truncated.
+func (b *band) scan(fn func(Item)) error {
+	b.pending = 0
+	for i, seq := range b.segments {
+		var off int64
+		if seq == b.head.Segment {
+			off = b.head.Offset
+		}
+		f, err := os.Open(b.path(seq))
+		if err != nil {
+			return fmt.Errorf("frontier: %w", err)
+		}
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("frontier: %w", err)
+		}
+		br := bufio.NewReader(f)
+		for {
+			it, n, err := readRecord(br)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				if i == len(b.segments)-1 {
+					if terr := os.Truncate(b.path(seq), off); terr != nil {
+						_ = f.Close()
+						return fmt.Errorf("frontier: truncate torn record: %w", terr)
+					}
+				}
+				break
+			}
+			if _, done := b.taken[position{Segment: seq, Offset: off}]; !done {
+				it.Priority = b.id
+				b.pending++
+				fn(it)
+			}
+			off += n
+		}
+		_ = f.Close()
+	}
+	return nil
+}
+
+func (b *band) append(it Item) error {
+	payload := binary.AppendUvarint(nil, uint64(max(it.Depth, 0)))
+	payload = append(payload, it.URL...)
+	if len(payload) > maxRecordBytes {
+		return fmt.Errorf("frontier: URL too long (%d bytes)", len(it.URL))
+	}
+	size := int64(headerBytes + len(payload))
+	if b.w == nil {
+		if err := b.openWriter(); err != nil {
+			return err
+		}
+	}
+	if b.wsize > 0 && b.wsize+size > b.opts.SegmentBytes {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+	var hdr [headerBytes]byte
+	binary.LittleEndian.PutUint32(hdr[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(hdr[4:], crc32.ChecksumIEEE(payload))
+	if _, err := b.wbuf.Write(hdr[:]); err != nil {
+		return fmt.Errorf("frontier: append: %w", err)
+	}
+	if _, err := b.wbuf.Write(payload); err != nil {
+		return fmt.Errorf("frontier: append: %w", err)
+	}
+	b.wsize += size
+	b.pending++
+	b.exhausted = false
+	return nil
+}
+
+func (b *band) openWriter() error {
+	seq := b.head.Segment
+	if n := len(b.segments); n > 0 && b.segments[n-1] > seq {
+		seq = b.segments[n-1]
+	}
+	f, err := os.OpenFile(b.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if n := len(b.segments); n == 0 || b.segments[n-1] != seq {
+		b.segments = append(b.segments, seq)
+	}
+	b.w, b.wbuf, b.wseg, b.wsize = f, bufio.NewWriterSize(f, 64<<10), seq, fi.Size()
+	return nil
+}
+
+func (b *band) rotate() error {
+	if err := b.wbuf.Flush(); err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if err := b.w.Close(); err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	b.w = nil
+	b.segments = append(b.segments, b.wseg+1)
+	return b.openWriter()
+}
+
+// fill tops up the read-ahead window once it runs low.
+func (b *band) fill() error {
+	if b.untaken > 0 && b.untaken >= b.opts.ReadAhead/2 || b.exhausted {
+		return nil
+	}
+	if b.w != nil {
+		if err := b.wbuf.Flush(); err != nil {
+			return fmt.Errorf("frontier: %w", err)
+		}
+	}
+	defer b.compact()
+read:
+	for b.untaken < b.opts.ReadAhead {
+		if len(b.segments) == 0 || b.next.Segment > b.segments[len(b.segments)-1] {
+			b.exhausted = true
+			break
+		}
+		if b.r == nil || b.rseg != b.next.Segment {
+			if err := b.openReader(b.next.Segment); err != nil {
+				return err
+			}
+		}
+		if _, err := b.r.Seek(b.next.Offset, io.SeekStart); err != nil {
+			return fmt.Errorf("frontier: %w", err)
+		}
+		br := bufio.NewReader(b.r)
+		for b.untaken < b.opts.ReadAhead {
+			it, n, err := readRecord(br)
+			if err != nil {
+				// End of segment (a damaged record also ends it): move on unless this is
+				// the newest segment, which may still grow.
+				if b.next.Segment == b.segments[len(b.segments)-1] {
+					b.exhausted = true
+					break read
+				}
+				b.next = position{Segment: b.nextSegment(b.next.Segment)}
+				break
+			}
+			start := b.next
+			b.next.Offset += n
+			it.Priority = b.id
+			if _, done := b.taken[start]; done {
+				delete(b.taken, start)
+				b.window = append(b.window, slot{start: start, taken: true})
+				continue
+			}
+			b.window = append(b.window, slot{item: it, start: start})
+			b.untaken++
+		}
+	}
+	return nil
+}
+
+func (b *band) openReader(seq int) error {
+	if b.r != nil {
+		_ = b.r.Close()
+		b.r = nil
+	}
+	f, err := os.Open(b.path(seq))
+	if err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	b.r, b.rseg = f, seq
+	return nil
+}
+
+func (b *band) nextSegment(seq int) int {
+	i := sort.SearchInts(b.segments, seq+1)
+	if i == len(b.segments) {
+		return seq + 1
+	}
+	return b.segments[i]
+}
+
+// take consumes the first window item accepted by accept (nil accepts any).
+func (b *band) take(accept func(Item) bool) (Item, bool) {
+	for i := range b.window {
+		s := &b.window[i]
+		if s.taken || (accept != nil && !accept(s.item)) {
+			continue
+		}
+		it := s.item
+		s.taken, s.item = true, Item{}
+		b.untaken--
+		b.pending--
+		b.compact()
+		return it, true
+	}
+	return Item{}, false
+}
+
+// compact drops the consumed window prefix, advances the head and deletes segments
+// that are entirely behind it.
+func (b *band) compact() {
+	i := 0
+	for i < len(b.window) && b.window[i].taken {
+		i++
+	}
+	if i > 0 {
+		b.window = append(b.window[:0], b.window[i:]...)
+	}
+	if len(b.window) > 0 {
+		b.head = b.window[0].start
+	} else {
+		b.head = b.next
+	}
+	if len(b.segments) > 0 && b.segments[0] < b.head.Segment {
+		b.removeBefore(b.head.Segment)
+	}
+}
+
+func (b *band) removeBefore(seq int) {
+	keep := b.segments[:0]
+	for _, s := range b.segments {
+		if s >= seq || (b.w != nil && s == b.wseg) {
+			keep = append(keep, s)
+			continue
+		}
+		if b.r != nil && b.rseg == s {
+			_ = b.r.Close()
+			b.r = nil
+		}
+		_ = os.Remove(b.path(s))
+	}
+	b.segments = keep
+}
+
+func (b *band) snapshot() bandState {
+	st := bandState{Head: b.head, Pending: b.pending}
+	for _, s := range b.window {
+		if s.taken {
+			st.Taken = append(st.Taken, s.start)
+		}
+	}
+	for p := range b.taken {
+		st.Taken = append(st.Taken, p)
+	}
+	return st
+}
+
+func (b *band) flush(sync bool) error {
+	if b.wbuf == nil {
+		return nil
+	}
+	if err := b.wbuf.Flush(); err != nil {
+		return fmt.Errorf("frontier: %w", err)
+	}
+	if sync {
+		if err := b.w.Sync(); err != nil {
+			return fmt.Errorf("frontier: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *band) close() error {
+	var errs []error
+	if b.w != nil {
+		errs = append(errs, b.wbuf.Flush(), b.w.Close())
+		b.w, b.wbuf = nil, nil
+	}
+	if b.r != nil {
+		errs = append(errs, b.r.Close())
+		b.r = nil
+	}
+	return errors.Join(errs...)
+}
+
+// readRecord decodes one record, returning its encoded size. io.EOF signals a clean
+// end; any other error a torn or corrupt record.
+func readRecord(r *bufio.Reader) (Item, int64, error) {
+	var hdr [headerBytes]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.EOF {
+			return Item{}, 0, io.EOF
+		}
+		return Item{}, 0, errCorrupt
+	}
+	n := binary.LittleEndian.Uint32(hdr[0:])
+	if n == 0 || n > maxRecordBytes {
+		return Item{}, 0, errCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return Item{}, 0, errCorrupt
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return Item{}, 0, errCorrupt
+	}
+	depth, k := binary.Uvarint(payload)
+	if k <= 0 {
+		return Item{}, 0, errCorrupt
+	}
+	return Item{URL: string(payload[k:]), Depth: int(depth)}, int64(headerBytes) + int64(n), nil
+}
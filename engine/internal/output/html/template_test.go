@@ -157,7 +157,7 @@ func TestHTMLTemplateRendererWrite(t *testing.T) {
 				URL: mustURL("https://example.com/failed"),
 			},
 			Success: false,
-			Error:   errors.New("Connection timeout"),
+			Error:   models.NewCrawlError("https://example.com/failed", "extraction", errors.New("Connection timeout")),
 		}
 
 		err := renderer.Write(result)
@@ -329,7 +329,7 @@ func TestHTMLTemplateRendererIntegration(t *testing.T) {
 		err := renderer.Write(&models.CrawlResult{
 			Page:    &models.Page{URL: mustURL("https://example.com/broken")},
 			Success: false,
-			Error:   errors.New("404 Not Found"),
+			Error:   models.NewCrawlError("https://example.com/broken", "extraction", errors.New("404 Not Found")),
 		})
 		if err != nil {
 			t.Fatalf("Failed to write failed page: %v", err)
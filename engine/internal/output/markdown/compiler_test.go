@@ -111,7 +111,7 @@ func TestMarkdownCompilerWrite(t *testing.T) {
 		result := &models.CrawlResult{
 			URL:     "https://example.com/failed",
 			Success: false,
-			Error:   fmt.Errorf("404 not found"),
+			Error:   models.NewCrawlError("https://example.com/failed", "extraction", fmt.Errorf("404 not found")),
 		}
 
 		err := compiler.Write(result)
@@ -316,7 +316,7 @@ func TestMarkdownCompilerIntegration(t *testing.T) {
 		failedResult := &models.CrawlResult{
 			URL:     "https://wiki.example.com/broken-link",
 			Success: false,
-			Error:   fmt.Errorf("404 page not found"),
+			Error:   models.NewCrawlError("https://wiki.example.com/broken-link", "extraction", fmt.Errorf("404 page not found")),
 		}
 		err := compiler.Write(failedResult)
 		if err != nil {
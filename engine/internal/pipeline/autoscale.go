@@ -0,0 +1,265 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage names used for worker pools, StageStatus and scaling decisions.
+const (
+	StageDiscovery  = "discovery"
+	StageExtraction = "extraction"
+	StageProcessing = "processing"
+	StageOutput     = "output"
+)
+
+var poolStages = [...]string{StageDiscovery, StageExtraction, StageProcessing, StageOutput}
+
+// AutoscaleConfig lets the pipeline resize its stage worker pools while it runs.
+type AutoscaleConfig struct {
+	// Interval between scaling decisions (default 1s). Queue depth and worker activity
+	// are sampled autoscaleSamples times per interval.
+	Interval time.Duration
+	// Bounds holds the worker range per stage; stages without bounds keep their
+	// configured size.
+	Bounds map[string]WorkerBounds
+	// MaxInFlight caps the extraction pool (the resource manager's fetch slots);
+	// zero means no cap.
+	MaxInFlight int
+	// OnScale, when set, is told about every decision.
+	OnScale func(ScaleDecision)
+}
+
+// WorkerBounds is the range a stage's pool is kept in.
+type WorkerBounds struct{ Min, Max int }
+
+// ScaleDecision records one resize of a stage's worker pool together with the signals
+// it was based on, averaged over the interval.
+type ScaleDecision struct {
+	Stage  string    `json:"stage"`
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+	// QueueDepth is the average number of items waiting for the stage and BusyRatio
+	// the average share of its workers handling an item.
+	QueueDepth float64 `json:"queue_depth"`
+	BusyRatio  float64 `json:"busy_ratio"`
+}
+
+const (
+	autoscaleSamples = 8
+	// maxScaleHistory bounds PipelineMetrics.Scaling.
+	maxScaleHistory = 16
+	// A stage grows when its workers are busier than scaleUpBusy with items queued,
+	// and shrinks when they are idler than scaleDownBusy with nothing queued.
+	scaleUpBusy   = 0.75
+	scaleDownBusy = 0.25
+)
+
+// workerPool runs one stage's workers. Each worker gets its own context, derived
+// from the pipeline's, which the pool cancels to retire it; a retired worker finishes
+// the item it is handling first.
+type workerPool struct {
+	name string
+	p    *Pipeline
+	// run is the worker loop; it returns when wctx is done or its input closed.
+	run func(wctx context.Context, act *activity)
+	wg  *sync.WaitGroup
+
+	mu      sync.Mutex
+	workers []*poolWorker // workers not asked to retire, oldest first
+	alive   int
+	started bool
+	// active counts workers handling an item.
+	active atomic.Int32
+}
+
+type poolWorker struct{ cancel context.CancelFunc }
+
+// activity tracks whether one worker is handling an item.
+type activity struct {
+	pool *workerPool
+	busy bool
+}
+
+func (a *activity) set(busy bool) {
+	if busy == a.busy {
+		return
+	}
+	a.busy = busy
+	if busy {
+		a.pool.active.Add(1)
+	} else {
+		a.pool.active.Add(-1)
+	}
+}
+
+func (p *Pipeline) newWorkerPool(name string, wg *sync.WaitGroup, run func(context.Context, *activity)) *workerPool {
+	wp := &workerPool{name: name, p: p, run: run, wg: wg}
+	p.pools[name] = wp
+	return wp
+}
+
+// size returns the number of workers not asked to retire.
+func (wp *workerPool) size() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return len(wp.workers)
+}
+
+// resize grows or shrinks the pool to n workers and returns the previous size. Once
+// the pipeline is stopping (or every worker exited) the pool no longer grows, so the
+// stage's WaitGroup is never re-armed after reaching zero.
+func (wp *workerPool) resize(n int) int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	from := len(wp.workers)
+	for len(wp.workers) > n {
+		last := wp.workers[len(wp.workers)-1]
+		wp.workers[len(wp.workers)-1] = nil
+		wp.workers = wp.workers[:len(wp.workers)-1]
+		last.cancel()
+	}
+	if len(wp.workers) < n && (wp.p.ctx.Err() != nil || (wp.started && wp.alive == 0)) {
+		return from
+	}
+	wp.started = true
+	for len(wp.workers) < n {
+		wctx, cancel := context.WithCancel(wp.p.ctx)
+		w := &poolWorker{cancel: cancel}
+		wp.workers = append(wp.workers, w)
+		wp.alive++
+		wp.p.wg.Add(1)
+		wp.wg.Add(1)
+		go func() {
+			defer wp.p.wg.Done()
+			defer wp.wg.Done()
+			act := &activity{pool: wp}
+			defer wp.exited(w, act)
+			wp.run(wctx, act)
+		}()
+	}
+	return from
+}
+
+// exited records a worker's exit. Workers leaving because the pipeline stops stay
+// counted in size, which keeps reporting the final pool size.
+func (wp *workerPool) exited(w *poolWorker, act *activity) {
+	act.set(false)
+	w.cancel()
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.alive--
+}
+
+// queueDepth returns the number of items waiting for stage.
+func (p *Pipeline) queueDepth(stage string) int {
+	switch stage {
+	case StageDiscovery:
+		return len(p.urlQueue)
+	case StageExtraction:
+		return p.scheduler.ready()
+	case StageProcessing:
+		return len(p.processingQueue)
+	case StageOutput:
+		return len(p.outputQueue)
+	}
+	return 0
+}
+
+// autoscale periodically resizes the pools with bounds: a stage grows by a quarter
+// (at least one worker) while its workers are busy and items queue up, and shrinks by
+// one while they idle with nothing queued. Extraction counts only the tasks of hosts
+// not held back by the limiter; with a limiter lacking TryAcquire, whose throttled
+// requests wait inside workers, it does not grow while the limiter is throttling.
+func (p *Pipeline) autoscale(cfg AutoscaleConfig) {
+	defer p.wg.Done()
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval / autoscaleSamples)
+	defer ticker.Stop()
+	var queued, busy [len(poolStages)]float64
+	samples := 0
+	throttled := p.limiterThrottled()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for i, stage := range poolStages {
+			wp := p.pools[stage]
+			queued[i] += float64(p.queueDepth(stage))
+			if n := wp.size(); n > 0 {
+				busy[i] += min(float64(wp.active.Load())/float64(n), 1)
+			}
+		}
+		if samples++; samples < autoscaleSamples {
+			continue
+		}
+		now := p.limiterThrottled()
+		throttling := now > throttled && p.scheduler.limiter == nil
+		throttled = now
+		for i, stage := range poolStages {
+			bounds, ok := cfg.Bounds[stage]
+			if !ok {
+				continue
+			}
+			bounds.Min = max(bounds.Min, 1)
+			bounds.Max = max(bounds.Max, bounds.Min)
+			if stage == StageExtraction && cfg.MaxInFlight > 0 && bounds.Max > cfg.MaxInFlight {
+				bounds.Max = cfg.MaxInFlight
+				bounds.Min = min(bounds.Min, bounds.Max)
+			}
+			d := ScaleDecision{Stage: stage, QueueDepth: queued[i] / float64(samples), BusyRatio: busy[i] / float64(samples)}
+			p.scaleStage(cfg, bounds, d, throttling && stage == StageExtraction)
+		}
+		queued, busy, samples = [len(poolStages)]float64{}, [len(poolStages)]float64{}, 0
+	}
+}
+
+// scaleStage applies one decision for d.Stage (filled in with the averaged signals).
+func (p *Pipeline) scaleStage(cfg AutoscaleConfig, bounds WorkerBounds, d ScaleDecision, throttling bool) {
+	wp := p.pools[d.Stage]
+	size := wp.size()
+	to := size
+	switch {
+	case size < bounds.Min:
+		to, d.Reason = bounds.Min, "below_min"
+	case size > bounds.Max:
+		to, d.Reason = bounds.Max, "above_max"
+	case d.BusyRatio >= scaleUpBusy && d.QueueDepth >= 1 && size < bounds.Max && !throttling:
+		to, d.Reason = min(size+max(size/4, 1), bounds.Max), "busy"
+	case d.BusyRatio < scaleDownBusy && d.QueueDepth < 1 && size > bounds.Min:
+		to, d.Reason = size-1, "idle"
+	}
+	if to == size {
+		return
+	}
+	d.From = wp.resize(to)
+	d.To, d.At = wp.size(), time.Now()
+	if d.To == d.From {
+		return // stopping
+	}
+	p.mutex.Lock()
+	p.scaling = append(p.scaling, d)
+	if len(p.scaling) > maxScaleHistory {
+		p.scaling = append(p.scaling[:0], p.scaling[len(p.scaling)-maxScaleHistory:]...)
+	}
+	p.mutex.Unlock()
+	if cfg.OnScale != nil {
+		cfg.OnScale(d)
+	}
+}
+
+func (p *Pipeline) limiterThrottled() int64 {
+	if p.limiter == nil {
+		return 0
+	}
+	return p.limiter.Snapshot().Throttled
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// sleepyFetcher delays every page by delay.
+type sleepyFetcher struct{ delay time.Duration }
+
+func (f sleepyFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(f.delay):
+	}
+	return instantFetcher{}.Fetch(ctx, rawURL)
+}
+
+func TestPipelineAutoscale(t *testing.T) {
+	var mu sync.Mutex
+	var decisions []ScaleDecision
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 4, OutputWorkers: 1, BufferSize: 8, Fetcher: sleepyFetcher{delay: 15 * time.Millisecond}, Processors: []Processor{passProcessor{}},
+		Autoscale: &AutoscaleConfig{Interval: 40 * time.Millisecond, MaxInFlight: 4, Bounds: map[string]WorkerBounds{StageExtraction: {Min: 1, Max: 8}, StageProcessing: {Min: 1, Max: 4}},
+			OnScale: func(d ScaleDecision) {
+				mu.Lock()
+				decisions = append(decisions, d)
+				mu.Unlock()
+			}}}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	var urls []string
+	for i := 0; i < 80; i++ {
+		urls = append(urls, fmt.Sprintf("https://host%d.test/%d", i%10, i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	maxExtraction := 0
+	n := 0
+	for range pl.ProcessURLs(ctx, urls) {
+		n++
+		maxExtraction = max(maxExtraction, pl.StageStatus(StageExtraction).Workers)
+	}
+	if n != len(urls) {
+		t.Fatalf("expected %d results, got %d", len(urls), n)
+	}
+	if maxExtraction != 4 {
+		t.Fatalf("expected extraction to grow up to MaxInFlight (4), peaked at %d", maxExtraction)
+	}
+	m := pl.Metrics()
+	if m.Workers[StageProcessing] >= 4 || m.Workers[StageDiscovery] != 1 {
+		t.Fatalf("expected processing to shrink and discovery to stay fixed, got %v", m.Workers)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	reasons := map[string]bool{}
+	for _, d := range decisions {
+		reasons[d.Stage+":"+d.Reason] = true
+		if d.To > 4 && d.Stage == StageExtraction {
+			t.Fatalf("extraction scaled beyond MaxInFlight: %+v", d)
+		}
+	}
+	if !reasons["extraction:busy"] || !reasons["processing:idle"] {
+		t.Fatalf("unexpected decisions: %+v", decisions)
+	}
+	if len(m.Scaling) != min(len(decisions), maxScaleHistory) {
+		t.Fatalf("expected %d recorded decisions, got %d", min(len(decisions), maxScaleHistory), len(m.Scaling))
+	}
+}
@@ -2,18 +2,19 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 )
 
 // Ensures bounded channels + slower extraction introduce noticeable latency.
 func TestPipelineBackpressure(t *testing.T) {
-	cfg := &PipelineConfig{DiscoveryWorkers: 2, ExtractionWorkers: 1, ProcessingWorkers: 2, OutputWorkers: 2, BufferSize: 5}
+	cfg := &PipelineConfig{DiscoveryWorkers: 2, ExtractionWorkers: 1, ProcessingWorkers: 2, OutputWorkers: 2, BufferSize: 5, Fetcher: simulatedFetcher{}}
 	pl := NewPipeline(cfg)
 	defer pl.Stop()
 	urls := make([]string, 25)
 	for i := range urls {
-		urls[i] = "https://example.com/page" + time.Now().Add(time.Duration(i)*time.Nanosecond).Format("150405.000000")
+		urls[i] = fmt.Sprintf("https://example.com/page%d", i)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+	"github.com/99souls/ariadne/engine/models"
+)
+
+func TestPipelineCanonicalCollapse(t *testing.T) {
+	var base string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = fmt.Fprint(w, `<a href="/list?page=2">2</a><a href="/list?page=3&utm_source=x">3</a><a href="/print">p</a>`)
+		case "/print", "/article":
+			_, _ = fmt.Fprintf(w, `<html><head><link rel="canonical" href="%s/article"></head><body>article</body></html>`, base)
+		case "/amp":
+			_, _ = fmt.Fprint(w, `<html><head><link rel="canonical" href="/article"></head><body>article</body></html>`)
+		default:
+			_, _ = fmt.Fprint(w, `<html><body>list</body></html>`)
+		}
+	}))
+	defer srv.Close()
+	base = srv.URL
+
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 8, MaxDepth: 1,
+		Canonicalizer: canonical.New(canonical.Config{StripTrackingParams: true})}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	byURL := map[string]*models.CrawlResult{}
+	// The tracking-param variant of the seed must be de-duplicated against it.
+	for r := range pl.ProcessURLs(ctx, []string{base + "/", base + "/?utm_campaign=x", base + "/amp"}) {
+		byURL[r.URL] = r
+	}
+	for _, u := range []string{"/list?page=2", "/list?page=3&utm_source=x"} {
+		if r := byURL[base+u]; r == nil || !r.Success {
+			t.Fatalf("expected distinct query pages to be crawled, missing %s in %v", u, keys(byURL))
+		}
+	}
+	if _, ok := byURL[base+"/?utm_campaign=x"]; ok {
+		t.Fatalf("tracking-param variant should have been de-duplicated")
+	}
+	article := byURL[base+"/article"]
+	if article == nil || article.Stage != "output" || article.Page.URL.String() != base+"/article" {
+		t.Fatalf("expected first alias to be collapsed onto the canonical URL, got %+v (all: %v)", article, keys(byURL))
+	}
+	dupes := 0
+	for _, r := range byURL {
+		if r.Stage == "duplicate" {
+			dupes++
+			if r.Page.Metadata.Canonical != base+"/article" {
+				t.Fatalf("duplicate should carry its canonical URL, got %q", r.Page.Metadata.Canonical)
+			}
+		}
+	}
+	if dupes != 1 || len(byURL) != 5 {
+		t.Fatalf("expected one duplicate among 5 results, got %d in %v", dupes, keys(byURL))
+	}
+	if len(article.Page.Aliases) == 0 {
+		t.Fatalf("expected canonical page to record aliases")
+	}
+}
+
+func keys(m map[string]*models.CrawlResult) []string {
+	out := make([]string, 0, len(m))
+	for k, r := range m {
+		out = append(out, k+"@"+r.Stage)
+	}
+	return out
+}
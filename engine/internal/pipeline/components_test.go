@@ -2,12 +2,14 @@ package pipeline
 
 import (
 	"testing"
+
+	"github.com/99souls/ariadne/engine/internal/crawler"
 )
 
 // Migrated test: URL validation and component sanity checks
 func TestPipelineComponents(t *testing.T) {
 	t.Run("URL validation should work", func(t *testing.T) {
-		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2}
+		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, Fetcher: simulatedFetcher{}}
 		pipeline := NewPipeline(config)
 		defer pipeline.Stop()
 		if !pipeline.isValidURL("https://example.com/test") {
@@ -22,11 +24,11 @@ func TestPipelineComponents(t *testing.T) {
 	})
 
 	t.Run("content extraction should work", func(t *testing.T) {
-		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2}
+		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, Fetcher: simulatedFetcher{}}
 		pipeline := NewPipeline(config)
 		defer pipeline.Stop()
-		page := pipeline.extractContent("https://example.com/test")
-		if page == nil {
+		page, _, err := pipeline.extractContent("https://example.com/test", crawler.Validators{})
+		if err != nil || page == nil {
 			t.Error("Content extraction should return a page")
 			return
 		}
@@ -36,10 +38,10 @@ func TestPipelineComponents(t *testing.T) {
 	})
 
 	t.Run("content processing should work", func(t *testing.T) {
-		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2}
+		config := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, Fetcher: simulatedFetcher{}}
 		pipeline := NewPipeline(config)
 		defer pipeline.Stop()
-		page := pipeline.extractContent("https://example.com/test")
+		page, _, _ := pipeline.extractContent("https://example.com/test", crawler.Validators{})
 		result := pipeline.processContent(page)
 		if result == nil {
 			t.Error("Content processing should return a result")
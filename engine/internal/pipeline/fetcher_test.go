@@ -0,0 +1,230 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+	"github.com/99souls/ariadne/engine/internal/crawler"
+	"github.com/99souls/ariadne/engine/internal/deadletter"
+	engratelimit "github.com/99souls/ariadne/engine/internal/ratelimit"
+	intresources "github.com/99souls/ariadne/engine/internal/resources"
+	"github.com/99souls/ariadne/engine/internal/testutil/httpmock"
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// simulatedFetcher reproduces the timing of the former simulated extraction stage so
+// orchestration tests (backpressure, shutdown, metrics) stay hermetic.
+type simulatedFetcher struct{}
+
+func (simulatedFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	delay := 10 * time.Millisecond
+	if strings.Contains(rawURL, "slow") {
+		delay = 50 * time.Millisecond
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(delay):
+	}
+	if strings.Contains(rawURL, "fail-extraction") {
+		return nil, errors.New("simulated extraction failure")
+	}
+	page := &models.Page{Title: "Test Page", Content: "<h1>Test Content</h1>"}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		page.URL = parsed
+	}
+	return page, nil
+}
+
+// recordingLimiter captures feedback per domain.
+type recordingLimiter struct {
+	mu       sync.Mutex
+	feedback []engratelimit.Feedback
+}
+
+func (r *recordingLimiter) Acquire(ctx context.Context, domain string) (engratelimit.Permit, error) {
+	return permitStub{}, ctx.Err()
+}
+func (r *recordingLimiter) Feedback(domain string, fb engratelimit.Feedback) {
+	r.mu.Lock()
+	r.feedback = append(r.feedback, fb)
+	r.mu.Unlock()
+}
+func (r *recordingLimiter) Snapshot() engratelimit.LimiterSnapshot {
+	return engratelimit.LimiterSnapshot{}
+}
+func (r *recordingLimiter) all() []engratelimit.Feedback {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]engratelimit.Feedback(nil), r.feedback...)
+}
+
+func TestPipelineFetchesOverHTTP(t *testing.T) {
+	srv := httpmock.NewServer([]httpmock.RouteSpec{
+		{Pattern: "/ok", Body: `<html><head><title>Real Page</title></head><body><a href="/next">n</a></body></html>`, Headers: map[string]string{"Content-Type": "text/html"}},
+		{Pattern: "/missing", Status: http.StatusNotFound, Body: "gone"},
+	})
+	defer srv.Close()
+
+	lim := &recordingLimiter{}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RateLimiter: lim, RetryMaxAttempts: 3, RetryBaseDelay: time.Millisecond}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	byURL := map[string]*models.CrawlResult{}
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL() + "/ok", srv.URL() + "/missing"}) {
+		byURL[r.URL] = r
+	}
+	ok := byURL[srv.URL()+"/ok"]
+	if ok == nil || !ok.Success || ok.Page == nil {
+		t.Fatalf("expected successful result for /ok, got %+v", ok)
+	}
+	if ok.Page.Title != "Real Page" || len(ok.Page.Links) != 1 {
+		t.Fatalf("unexpected page: title=%q links=%v", ok.Page.Title, ok.Page.Links)
+	}
+	missing := byURL[srv.URL()+"/missing"]
+	if missing == nil || missing.Success {
+		t.Fatalf("expected failure result for /missing, got %+v", missing)
+	}
+	var httpErr *crawler.HTTPError
+	if !errors.As(missing.Error, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected wrapped 404 HTTPError, got %v", missing.Error)
+	}
+	if ce := missing.Error; ce.Code != models.ErrorCodeHTTP4xx || ce.StatusCode != http.StatusNotFound || ce.Retryable || ce.Attempts != 1 || !errors.Is(ce, models.ErrHTTPError) {
+		t.Fatalf("unexpected classification: %+v", ce)
+	}
+
+	statuses := map[int]int{}
+	for _, fb := range lim.all() {
+		statuses[fb.StatusCode]++
+		if fb.Latency <= 0 {
+			t.Fatalf("expected measured latency, got %v", fb.Latency)
+		}
+		if fb.Err != nil {
+			t.Fatalf("HTTP responses must not be reported as transport errors: %v", fb.Err)
+		}
+	}
+	if statuses[http.StatusOK] != 1 || statuses[http.StatusNotFound] != 1 {
+		t.Fatalf("expected one 200 and one (non-retried) 404 feedback, got %v", statuses)
+	}
+}
+
+func TestPipelineRetryAfterFeedback(t *testing.T) {
+	srv := httpmock.NewServer([]httpmock.RouteSpec{
+		{Pattern: "/busy", Status: http.StatusServiceUnavailable, Headers: map[string]string{"Retry-After": "1"}},
+	})
+	defer srv.Close()
+
+	lim := &recordingLimiter{}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RateLimiter: lim, RetryMaxAttempts: 1}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL() + "/busy"}) {
+		if r.Success {
+			t.Fatalf("expected failure for 503")
+		}
+		if r.Error.Code != models.ErrorCodeHTTP5xx || r.Error.StatusCode != http.StatusServiceUnavailable || !r.Error.Retryable {
+			t.Fatalf("unexpected classification: %+v", r.Error)
+		}
+	}
+	fbs := lim.all()
+	if len(fbs) != 1 {
+		t.Fatalf("expected single feedback, got %d", len(fbs))
+	}
+	if fbs[0].StatusCode != http.StatusServiceUnavailable || fbs[0].RetryAfter != time.Second {
+		t.Fatalf("unexpected feedback: %+v", fbs[0])
+	}
+}
+
+func TestPipelineTransportErrorFeedback(t *testing.T) {
+	srv := httpmock.NewServer(nil)
+	target := srv.URL() + "/down"
+	srv.Close() // connection refused from here on
+
+	lim := &recordingLimiter{}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RateLimiter: lim, RetryMaxAttempts: 2, RetryBaseDelay: time.Millisecond, RetryMaxDelay: 2 * time.Millisecond}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var results int
+	for r := range pl.ProcessURLs(ctx, []string{target}) {
+		results++
+		if r.Success || !strings.Contains(r.Error.Error(), "failed after 2 attempts") {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+		if r.Error.Code != models.ErrorCodeConnect || !r.Error.Retryable || r.Error.Attempts != 2 {
+			t.Fatalf("unexpected classification: %+v", r.Error)
+		}
+	}
+	if results != 1 {
+		t.Fatalf("expected 1 result, got %d", results)
+	}
+	fbs := lim.all()
+	if len(fbs) != 2 {
+		t.Fatalf("expected feedback per attempt, got %d", len(fbs))
+	}
+	for _, fb := range fbs {
+		if fb.Err == nil || fb.StatusCode != 0 {
+			t.Fatalf("expected transport error feedback, got %+v", fb)
+		}
+	}
+}
+
+func TestPipelineCacheLookupFailureIsNotDeadLettered(t *testing.T) {
+	spill := t.TempDir()
+	rm, err := intresources.NewManager(intresources.Config{CacheCapacity: 1, SpillDirectory: spill, SpillFormat: intresources.SpillJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rm.Close() }()
+	const u = "https://a.test/"
+	_ = rm.StorePage(canonical.Default.Key(u), &models.Page{Title: "cached"})
+	_ = rm.StorePage("other", &models.Page{Title: "other"}) // spills u
+	files, _ := filepath.Glob(filepath.Join(spill, "spill-*"))
+	if len(files) == 0 {
+		t.Fatal("expected a spill file")
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := deadletter.Open(filepath.Join(t.TempDir(), "dead-letters.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, Fetcher: instantFetcher{}, ResourceManager: rm, DeadLetters: store}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var n int
+	for r := range pl.ProcessURLs(ctx, []string{u}) {
+		n++
+		if r.Success || r.Stage != "extraction" || !strings.Contains(r.Error.Error(), "cache lookup failed") {
+			t.Fatalf("expected a cache lookup failure, got %+v", r)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("expected one result, got %d", n)
+	}
+	// A local cache fault says nothing about the URL, so it is not dead-lettered.
+	if letters := store.Entries(deadletter.Filter{}); len(letters) != 0 {
+		t.Fatalf("expected no dead letters, got %+v", letters)
+	}
+}
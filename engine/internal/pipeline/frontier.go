@@ -0,0 +1,631 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+	"github.com/99souls/ariadne/engine/internal/checkpoint"
+	intfrontier "github.com/99souls/ariadne/engine/internal/frontier"
+	"github.com/99souls/ariadne/engine/internal/scope"
+)
+
+// priorityBands is the number of scheduling bands. Shallower URLs go to higher bands
+// so the crawl proceeds breadth-first regardless of discovery order.
+const priorityBands = 4
+
+func priorityFor(depth int) int {
+	if depth >= priorityBands-1 {
+		return 0
+	}
+	return priorityBands - 1 - depth
+}
+
+// sitemapBand maps a sitemap <priority> (0..1) to a band: 0.75 and above is scheduled
+// with the seeds, the default 0.5 with links one hop away and below 0.25 with links
+// two hops away.
+func sitemapBand(priority float64) int {
+	return min(max(1+int(priority*2+0.5), 1), priorityBands-1)
+}
+
+// crawlTask is a URL admitted to the crawl together with its link depth (seeds are 0)
+// and the fetch attempts an interrupted run already made for it. Sitemap entries carry
+// their <priority>, which picks their band instead of the depth.
+type crawlTask struct {
+	url      string
+	depth    int
+	attempt  int
+	sitemap  bool
+	priority float64
+}
+
+func (t crawlTask) band() int {
+	if t.sitemap {
+		return sitemapBand(t.priority)
+	}
+	return priorityFor(t.depth)
+}
+
+// frontier owns the set of URLs admitted to a crawl. It de-duplicates, enforces the
+// depth and page budgets and the scope rules (link following is restricted to the
+// seed hosts unless allowed domains are configured), records which rule rejected
+// each URL and counts outstanding work so the pipeline can finish once the last admitted URL has
+// produced its result (rather than after a fixed number of seeds).
+//
+// Pending URLs and the de-duplication set live in memory unless a disk-backed store
+// is configured, in which case they are delegated to it and a restarted pipeline
+// resumes the URLs the previous run left pending.
+type frontier struct {
+	mu       sync.Mutex
+	queue    [priorityBands][]crawlTask
+	seen     map[string]struct{}
+	store    *intfrontier.Queue
+	hosts    map[string]struct{}
+	queued   map[string]int // pending URLs per host (in-memory queue only)
+	maxDepth int
+	maxPages int
+	admitted int
+	pending  int
+	sealed   bool
+	notify   chan struct{}
+	canon    *canonical.Canonicalizer
+	// storeErrors counts failed store operations; the affected URL is dropped.
+	storeErrors int
+	// aliases maps a canonical key to URLs that declared it via rel=canonical.
+	aliases map[string][]string
+
+	scope *scope.Rules
+	// hostPages and hostBytes track per-host budgets for this run.
+	hostPages map[string]int
+	hostBytes map[string]int64
+	// rejected counts rejections per rule; onReject, when set, is told about each one
+	// (outside the lock).
+	rejected map[string]int
+	onReject func(rawURL, rule string)
+
+	// paused stops next from handing out queued URLs. cancels holds the patterns
+	// cancelled during the run; dropped holds cancelled URLs removed from the in-memory
+	// queue that still have to be reported.
+	paused  bool
+	cancels []scope.Pattern
+	dropped []droppedTask
+
+	// checkpoint, when set, records every admitted URL.
+	checkpoint *checkpoint.Log
+}
+
+// droppedTask is an admitted URL dropped before dispatch by rule.
+type droppedTask struct {
+	crawlTask
+	rule string
+}
+
+func newFrontier(maxDepth, maxPages int, canon *canonical.Canonicalizer, store *intfrontier.Queue) *frontier {
+	if canon == nil {
+		canon = canonical.Default
+	}
+	f := &frontier{seen: make(map[string]struct{}), store: store, hosts: make(map[string]struct{}), queued: make(map[string]int), aliases: make(map[string][]string), hostPages: make(map[string]int), hostBytes: make(map[string]int64), rejected: make(map[string]int), maxDepth: maxDepth, maxPages: maxPages, notify: make(chan struct{}, 1), canon: canon}
+	if store != nil {
+		f.pending, f.admitted = store.Len(), store.Pushed()
+	}
+	return f
+}
+
+// seed admits a seed URL at depth (zero for crawl seeds) and registers its host as
+// followable. Seeds are subject to the scope rules except the seed-host restriction;
+// invalid seeds are still admitted so that discovery reports them as failed results.
+// Nothing is admitted once the run has finished.
+func (f *frontier) seed(raw string, depth int) bool {
+	f.mu.Lock()
+	if f.sealed && f.pending == 0 {
+		f.mu.Unlock()
+		return false // run already finished
+	}
+	host := canonical.Host(raw)
+	if host != "" {
+		f.hosts[host] = struct{}{}
+	}
+	var rule string
+	ok := false
+	if u, err := url.Parse(raw); err == nil && host != "" {
+		if rule = f.ruleLocked(u, host); rule == "" {
+			ok, rule = f.admitLocked(crawlTask{url: raw, depth: depth}, host)
+		}
+	} else {
+		ok, _ = f.admitLocked(crawlTask{url: raw, depth: depth}, host)
+	}
+	f.rejectLocked(rule)
+	f.mu.Unlock()
+	f.notifyReject(raw, rule)
+	return ok
+}
+
+// seedSitemap admits a sitemap entry at depth zero. Unlike seeds, entries do not make
+// their host followable: they are held to the same scope as discovered links.
+func (f *frontier) seedSitemap(raw string, priority float64) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false // the sitemap loader only returns absolute URLs
+	}
+	u.Fragment = ""
+	raw = u.String()
+	f.mu.Lock()
+	var ok bool
+	var rule string
+	switch {
+	case f.sealed && f.pending == 0:
+	case u.Scheme != "http" && u.Scheme != "https":
+		rule = scope.RuleScheme
+	default:
+		host := canonical.Host(raw)
+		if rule = f.hostRuleLocked(u, host); rule == "" {
+			ok, rule = f.admitLocked(crawlTask{url: raw, sitemap: true, priority: priority}, host)
+		}
+	}
+	f.rejectLocked(rule)
+	f.mu.Unlock()
+	f.notifyReject(raw, rule)
+	return ok
+}
+
+// follow admits a discovered link one level below its parent. Links already seen are
+// ignored; links beyond MaxDepth, outside the crawl's hosts or domains, rejected by a
+// scope rule or over a page budget are counted as rejections of that rule.
+func (f *frontier) follow(link *url.URL, parentDepth int) bool {
+	if link == nil {
+		return false
+	}
+	u := *link
+	u.Fragment = ""
+	raw := u.String()
+	f.mu.Lock()
+	ok, rule := f.followLocked(&u, raw, parentDepth+1)
+	f.rejectLocked(rule)
+	f.mu.Unlock()
+	f.notifyReject(raw, rule)
+	return ok
+}
+
+func (f *frontier) followLocked(u *url.URL, raw string, depth int) (bool, string) {
+	if f.sealed && f.pending == 0 {
+		return false, "" // run already finished
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, scope.RuleScheme
+	}
+	if f.seenLocked(f.canon.Key(raw)) {
+		return false, ""
+	}
+	if depth > f.maxDepth {
+		return false, scope.RuleMaxDepth
+	}
+	host := canonical.Host(raw)
+	if rule := f.hostRuleLocked(u, host); rule != "" {
+		return false, rule
+	}
+	return f.admitLocked(crawlTask{url: raw, depth: depth}, host)
+}
+
+// hostRuleLocked applies the seed-host restriction (unless allowed domains replace it)
+// and the static scope rules.
+func (f *frontier) hostRuleLocked(u *url.URL, host string) string {
+	if !f.scope.HasAllowedDomains() {
+		if _, ok := f.hosts[host]; !ok {
+			return scope.RuleOffsite
+		}
+	}
+	return f.ruleLocked(u, host)
+}
+
+// ruleLocked applies the static scope rules and the patterns cancelled so far.
+func (f *frontier) ruleLocked(u *url.URL, host string) string {
+	if rule := f.scope.Check(u, host); rule != "" {
+		return rule
+	}
+	return f.cancelRuleLocked(u)
+}
+
+func (f *frontier) cancelRuleLocked(u *url.URL) string {
+	for _, p := range f.cancels {
+		if p.Match(u) {
+			return scope.RuleCancelled + ":" + p.String()
+		}
+	}
+	return ""
+}
+
+// cancel rejects URLs matching pat for the rest of the run. Matching URLs queued in
+// memory are removed at once and handed back by next for reporting; their number is
+// returned. A disk-backed queue cannot drop entries in place, so its matching URLs
+// are dropped (and not counted here) as they are dequeued.
+func (f *frontier) cancel(pat scope.Pattern) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancels = append(f.cancels, pat)
+	rule := scope.RuleCancelled + ":" + pat.String()
+	n := 0
+	for band, q := range f.queue {
+		kept := q[:0]
+		for _, t := range q {
+			u, err := url.Parse(t.url)
+			if err != nil || !pat.Match(u) {
+				kept = append(kept, t)
+				continue
+			}
+			host := canonical.Host(t.url)
+			if f.queued[host]--; f.queued[host] <= 0 {
+				delete(f.queued, host)
+			}
+			f.dropped = append(f.dropped, droppedTask{crawlTask: t, rule: rule})
+			f.rejectLocked(rule)
+			n++
+		}
+		clear(q[len(kept):])
+		f.queue[band] = kept
+	}
+	if n > 0 {
+		f.wakeLocked()
+	}
+	return n
+}
+
+// setPaused pauses or resumes dispatch.
+func (f *frontier) setPaused(paused bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = paused
+	if !paused {
+		f.wakeLocked()
+	}
+}
+
+func (f *frontier) isPaused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.paused
+}
+
+// finished reports whether seeding ended and every admitted URL produced a result.
+func (f *frontier) finished() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sealed && f.pending == 0
+}
+
+func (f *frontier) wakeLocked() {
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+}
+
+// admitLocked enqueues t unless it was seen or a page budget is exhausted, in which
+// case the rejecting rule is returned.
+func (f *frontier) admitLocked(t crawlTask, host string) (bool, string) {
+	key := f.canon.Key(t.url)
+	if f.seenLocked(key) {
+		return false, ""
+	}
+	return f.pushLocked(t, host, key)
+}
+
+func (f *frontier) pushLocked(t crawlTask, host, key string) (bool, string) {
+	if f.maxPages > 0 && f.admitted >= f.maxPages {
+		return false, scope.RuleMaxPages
+	}
+	if rule := f.budgetRuleLocked(host); rule != "" {
+		return false, rule
+	}
+	if !f.markSeenLocked(key) {
+		return false, ""
+	}
+	if f.store != nil {
+		if err := f.store.Push(intfrontier.Item{URL: t.url, Depth: t.depth, Priority: t.band()}); err != nil {
+			f.storeErrors++
+			return false, ""
+		}
+	} else {
+		band := t.band()
+		f.queue[band] = append(f.queue[band], t)
+		f.queued[host]++
+	}
+	if f.checkpoint != nil {
+		f.checkpoint.Enqueue(key, t.url, t.depth, t.attempt)
+	}
+	f.admitted++
+	f.pending++
+	f.hostPages[host]++
+	f.wakeLocked()
+	return true, ""
+}
+
+// restore carries the crawl recorded by log over to this run: URLs it finished are
+// marked seen (and count against MaxPages), and URLs it left queued or in flight are
+// admitted again at their depth, keeping their attempt count. A disk-backed store
+// already resumes its own queue, so only the in-flight URLs, which it handed out, are
+// re-admitted then, bypassing its de-duplication set (the store does not keep attempt
+// counts). Their hosts become followable like seed hosts.
+func (f *frontier) restore(log *checkpoint.Log) (finished, requeued int) {
+	keys := log.FinishedKeys()
+	entries := log.Frontier()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		if !f.seenLocked(key) && f.markSeenLocked(key) && f.store == nil {
+			f.admitted++
+		}
+	}
+	for _, e := range entries {
+		if f.store != nil && !e.InFlight {
+			continue
+		}
+		host := canonical.Host(e.URL)
+		if host != "" {
+			f.hosts[host] = struct{}{}
+		}
+		var ok bool
+		if f.store != nil {
+			ok, _ = f.pushLocked(crawlTask{url: e.URL, depth: e.Depth, attempt: e.Attempts}, host, e.Key)
+		} else {
+			ok, _ = f.admitLocked(crawlTask{url: e.URL, depth: e.Depth, attempt: e.Attempts}, host)
+		}
+		if ok {
+			requeued++
+		}
+	}
+	return len(keys), requeued
+}
+
+// budgetRuleLocked reports the per-host budget host has exhausted, if any.
+func (f *frontier) budgetRuleLocked(host string) string {
+	if f.scope == nil {
+		return ""
+	}
+	if f.scope.MaxPagesPerHost > 0 && f.hostPages[host] >= f.scope.MaxPagesPerHost {
+		return scope.RuleMaxPagesPerHost
+	}
+	if f.scope.MaxBytesPerHost > 0 && f.hostBytes[host] >= f.scope.MaxBytesPerHost {
+		return scope.RuleMaxBytesPerHost
+	}
+	return ""
+}
+
+// addBytes charges n fetched bytes to host's byte budget.
+func (f *frontier) addBytes(host string, n int) {
+	if f.scope == nil || f.scope.MaxBytesPerHost <= 0 || n <= 0 {
+		return
+	}
+	f.mu.Lock()
+	f.hostBytes[host] += int64(n)
+	f.mu.Unlock()
+}
+
+// dispatchRule reports the rule that drops an already admitted URL before it is
+// fetched: only the byte budget, which can run out while URLs are queued.
+func (f *frontier) dispatchRule(raw string) string {
+	if f.scope == nil || f.scope.MaxBytesPerHost <= 0 {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hostBytes[canonical.Host(raw)] < f.scope.MaxBytesPerHost {
+		return ""
+	}
+	f.rejectLocked(scope.RuleMaxBytesPerHost)
+	return scope.RuleMaxBytesPerHost
+}
+
+func (f *frontier) rejectLocked(rule string) {
+	if rule != "" {
+		f.rejected[rule]++
+	}
+}
+
+func (f *frontier) notifyReject(raw, rule string) {
+	if rule != "" && f.onReject != nil {
+		f.onReject(raw, rule)
+	}
+}
+
+// rejections returns a copy of the per-rule rejection counts.
+func (f *frontier) rejections() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.rejected) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(f.rejected))
+	for k, v := range f.rejected {
+		out[k] = v
+	}
+	return out
+}
+
+// seal marks seeding complete. It reports true when nothing is outstanding, i.e. the
+// run is already finished.
+func (f *frontier) seal() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sealed = true
+	return f.pending == 0
+}
+
+// complete records a terminal result for one admitted URL and reports whether the
+// frontier has drained.
+func (f *frontier) complete() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pending > 0 {
+		f.pending--
+	}
+	return f.sealed && f.pending == 0
+}
+
+func (f *frontier) seenLocked(key string) bool {
+	if f.store != nil {
+		return f.store.Seen(key)
+	}
+	_, ok := f.seen[key]
+	return ok
+}
+
+// markSeenLocked records key; false means the store failed and the URL is dropped.
+func (f *frontier) markSeenLocked(key string) bool {
+	if f.store != nil {
+		if _, err := f.store.MarkSeen(key); err != nil {
+			f.storeErrors++
+			return false
+		}
+		return true
+	}
+	f.seen[key] = struct{}{}
+	return true
+}
+
+// popLocked removes the next task, highest band first. ok is false when nothing is
+// queued or the store failed (a store failure ends the run).
+func (f *frontier) popLocked() (t crawlTask, ok, failed bool) {
+	if f.store != nil {
+		it, ok, err := f.store.Pop()
+		if err != nil {
+			f.storeErrors++
+			return crawlTask{}, false, true
+		}
+		return crawlTask{url: it.URL, depth: it.Depth}, ok, false
+	}
+	for band := priorityBands - 1; band >= 0; band-- {
+		if q := f.queue[band]; len(q) > 0 {
+			t = q[0]
+			q[0] = crawlTask{}
+			f.queue[band] = q[1:]
+			host := canonical.Host(t.url)
+			if f.queued[host]--; f.queued[host] <= 0 {
+				delete(f.queued, host)
+			}
+			return t, true, false
+		}
+	}
+	return crawlTask{}, false, false
+}
+
+// next blocks until a task is available or ctx is done. A non-empty rule means the
+// task was cancelled and must be reported as rejected instead of fetched; cancelled
+// tasks are handed out even while dispatch is paused.
+func (f *frontier) next(ctx context.Context) (t crawlTask, rule string, ok bool) {
+	for {
+		f.mu.Lock()
+		if len(f.dropped) > 0 {
+			d := f.dropped[0]
+			f.dropped[0] = droppedTask{}
+			f.dropped = f.dropped[1:]
+			f.mu.Unlock()
+			return d.crawlTask, d.rule, true
+		}
+		var failed bool
+		if !f.paused {
+			t, ok, failed = f.popLocked()
+		}
+		if ok && f.store != nil && len(f.cancels) > 0 {
+			if u, err := url.Parse(t.url); err == nil {
+				rule = f.cancelRuleLocked(u)
+				f.rejectLocked(rule)
+			}
+		}
+		f.mu.Unlock()
+		if ok {
+			return t, rule, true
+		}
+		if failed {
+			return crawlTask{}, "", false
+		}
+		select {
+		case <-ctx.Done():
+			return crawlTask{}, "", false
+		case <-f.notify:
+		}
+	}
+}
+
+// sync makes the disk-backed store durable; a failure is counted as a store error.
+func (f *frontier) sync() {
+	if f.store == nil {
+		return
+	}
+	if err := f.store.Sync(); err != nil && !errors.Is(err, intfrontier.ErrClosed) {
+		f.mu.Lock()
+		f.storeErrors++
+		f.mu.Unlock()
+	}
+}
+
+// stats returns admitted and outstanding counts.
+func (f *frontier) stats() (admitted, pending, queued, storeErrors int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.store != nil {
+		queued = f.store.Len()
+	} else {
+		for _, q := range f.queue {
+			queued += len(q)
+		}
+	}
+	return f.admitted, f.pending, queued, f.storeErrors
+}
+
+// hostQueues returns the queued (not yet dispatched) URL count per host, largest first.
+func (f *frontier) hostQueues() []intfrontier.HostQueue {
+	if f.store != nil {
+		return f.store.Hosts()
+	}
+	f.mu.Lock()
+	out := make([]intfrontier.HostQueue, 0, len(f.queued))
+	for h, n := range f.queued {
+		out = append(out, intfrontier.HostQueue{Host: h, Pending: n})
+	}
+	f.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pending != out[j].Pending {
+			return out[i].Pending > out[j].Pending
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// claimCanonical resolves a page fetched from pageURL that declares canonicalURL.
+// When the canonical URL has not been admitted yet the page becomes its
+// representative (the canonical URL is marked seen so it is never fetched) and
+// adopted is true. Otherwise pageURL is recorded as an alias of the canonical page
+// and the caller reports the page as a duplicate. Canonical URLs out of scope are
+// ignored (ok is false).
+func (f *frontier) claimCanonical(pageURL, canonicalURL string) (adopted, ok bool) {
+	host := canonical.Host(canonicalURL)
+	key := f.canon.Key(canonicalURL)
+	cu, err := url.Parse(canonicalURL)
+	if err != nil {
+		return false, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hostRuleLocked(cu, host) != "" || key == f.canon.Key(pageURL) {
+		return false, false
+	}
+	f.aliases[key] = append(f.aliases[key], pageURL)
+	if f.seenLocked(key) || !f.markSeenLocked(key) {
+		return false, true
+	}
+	return true, true
+}
+
+// aliasesOf returns the URLs recorded as aliases of raw's canonical key so far.
+func (f *frontier) aliasesOf(raw string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if a := f.aliases[f.canon.Key(raw)]; len(a) > 0 {
+		return append([]string(nil), a...)
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+package pipeline
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/canonical"
+	"github.com/99souls/ariadne/engine/internal/checkpoint"
+	intfrontier "github.com/99souls/ariadne/engine/internal/frontier"
+	"github.com/99souls/ariadne/engine/internal/scope"
+	"github.com/99souls/ariadne/engine/internal/testutil/httpmock"
+	"github.com/99souls/ariadne/engine/models"
+)
+
+func linkSite() *httpmock.MockServer {
+	page := func(links ...string) string {
+		var b strings.Builder
+		b.WriteString("<html><body>")
+		for _, l := range links {
+			b.WriteString(`<a href="` + l + `">x</a>`)
+		}
+		b.WriteString("</body></html>")
+		return b.String()
+	}
+	return httpmock.NewServer([]httpmock.RouteSpec{
+		{Pattern: "^/$", Regex: true, Body: page("/a", "/b#frag", "http://elsewhere.invalid/x")},
+		{Pattern: "^/a$", Regex: true, Body: page("/c", "/b", "/")},
+		{Pattern: "^/b$", Regex: true, Body: page()},
+		{Pattern: "^/c$", Regex: true, Body: page("/d")},
+		{Pattern: "^/d$", Regex: true, Body: page()},
+	})
+}
+
+func crawlPaths(t *testing.T, cfg *PipelineConfig, seed string) []string {
+	t.Helper()
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var paths []string
+	for r := range pl.ProcessURLs(ctx, []string{seed}) {
+		if !r.Success {
+			t.Fatalf("unexpected failure for %s: %v", r.URL, r.Error)
+		}
+		u, _ := url.Parse(r.URL)
+		paths = append(paths, u.Path)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("crawl did not finish before deadline")
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestPipelineFollowsLinksToMaxDepth(t *testing.T) {
+	srv := linkSite()
+	defer srv.Close()
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, MaxDepth: 2}
+	got := crawlPaths(t, cfg, srv.URL()+"/")
+	want := []string{"/", "/a", "/b", "/c"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+}
+
+func TestPipelineSeedOnlyWithoutDepth(t *testing.T) {
+	srv := linkSite()
+	defer srv.Close()
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2}
+	if got := crawlPaths(t, cfg, srv.URL()+"/"); len(got) != 1 {
+		t.Fatalf("expected only the seed, got %v", got)
+	}
+}
+
+func TestPipelineMaxPagesBudget(t *testing.T) {
+	srv := linkSite()
+	defer srv.Close()
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, MaxDepth: 5, MaxPages: 3}
+	got := crawlPaths(t, cfg, srv.URL()+"/")
+	if len(got) != 3 {
+		t.Fatalf("expected 3 pages under budget, got %v", got)
+	}
+}
+
+func TestFrontierAccounting(t *testing.T) {
+	f := newFrontier(1, 0, nil, nil)
+	if !f.seed("https://Example.com/a#x", 0) || f.seed("https://example.com/a", 0) {
+		t.Fatalf("expected case/fragment-insensitive de-duplication of seeds")
+	}
+	if f.seal() {
+		t.Fatalf("frontier with pending work must not report drained")
+	}
+	child, _ := url.Parse("https://example.com/b")
+	other, _ := url.Parse("https://other.example/b")
+	if f.follow(other, 0) {
+		t.Fatalf("links outside seed hosts must not be followed")
+	}
+	if !f.follow(child, 0) || f.follow(child, 0) {
+		t.Fatalf("expected child admitted exactly once")
+	}
+	if f.follow(child, 1) {
+		t.Fatalf("links beyond max depth must be ignored")
+	}
+	if f.complete() {
+		t.Fatalf("one URL still pending")
+	}
+	if !f.complete() {
+		t.Fatalf("expected drained after last completion")
+	}
+	if admitted, pending, _, _ := f.stats(); admitted != 2 || pending != 0 {
+		t.Fatalf("unexpected stats admitted=%d pending=%d", admitted, pending)
+	}
+}
+
+func TestPipelineResumesDiskFrontier(t *testing.T) {
+	srv := linkSite()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	// State left by an interrupted run: the seed was crawled, two links still pending.
+	q, err := intfrontier.Open(intfrontier.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/", "/a", "/b"} {
+		if _, err := q.MarkSeen(canonical.Default.Key(srv.URL() + p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"/a", "/b"} {
+		if err := q.Push(intfrontier.Item{URL: srv.URL() + p, Depth: 1, Priority: priorityFor(1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = intfrontier.Open(intfrontier.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = q.Close() }()
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, MaxDepth: 2, Frontier: q}
+	got := crawlPaths(t, cfg, srv.URL()+"/")
+	if want := "/a,/b,/c"; strings.Join(got, ",") != want {
+		t.Fatalf("expected resumed crawl %s, got %v", want, got)
+	}
+	if q.Len() != 0 || q.Pushed() != 3 {
+		t.Fatalf("expected drained store with 3 pushes, got len=%d pushed=%d", q.Len(), q.Pushed())
+	}
+}
+
+// holdFetcher holds fetches of hold until release is closed.
+type holdFetcher struct {
+	hold    string
+	release chan struct{}
+}
+
+func (f holdFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	if rawURL == f.hold {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return instantFetcher{}.Fetch(ctx, rawURL)
+}
+
+func TestPipelineSyncsDiskFrontierForCrashRecovery(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frontier")
+	q, err := intfrontier.Open(intfrontier.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = q.Close() }()
+	seeds := []string{"https://a.test/", "https://b.test/", "https://c.test/", "https://hold.test/"}
+	fetcher := holdFetcher{hold: "https://hold.test/", release: make(chan struct{})}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, Fetcher: fetcher, Processors: []Processor{passProcessor{}}, Frontier: q, FrontierSyncInterval: 10 * time.Millisecond}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results := pl.ProcessURLs(ctx, seeds)
+	for i := 0; i < 3; i++ {
+		<-results
+	}
+
+	// Kill the run without closing the store: a copy of the directory is what a crashed
+	// process leaves behind. Every seed has been handed out, so once the frontier has
+	// synced none of them may be delivered again.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		crashed := filepath.Join(t.TempDir(), "crashed")
+		copyDir(t, dir, crashed)
+		q2, err := intfrontier.Open(intfrontier.Options{Dir: crashed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending, seen := q2.Len(), 0
+		for _, u := range seeds {
+			if q2.Seen(canonical.Default.Key(u)) {
+				seen++
+			}
+		}
+		_ = q2.Close()
+		if pending == 0 && seen == len(seeds) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("crash image still has %d pending URLs and %d/%d seen seeds", pending, seen, len(seeds))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(fetcher.release)
+	for range results {
+	}
+}
+
+// copyDir copies the regular files of src into a new directory dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPipelineScopeRejections(t *testing.T) {
+	srv := linkSite()
+	defer srv.Close()
+	rules, err := scope.Compile(scope.Config{Exclude: []string{"/c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	reported := map[string]string{}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 2, MaxDepth: 2, Scope: rules, ScopeRejected: func(rawURL, rule string) {
+		mu.Lock()
+		reported[rawURL] = rule
+		mu.Unlock()
+	}}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n := 0
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL() + "/"}) {
+		if !r.Success {
+			t.Fatalf("unexpected failure for %s: %v", r.URL, r.Error)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("expected /, /a and /b, got %d results", n)
+	}
+	m := pl.Metrics()
+	if len(m.URLsRejected) != 2 || m.URLsRejected[scope.RuleOffsite] != 1 || m.URLsRejected["exclude:/c"] != 1 {
+		t.Fatalf("unexpected rejection counts: %v", m.URLsRejected)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if reported["http://elsewhere.invalid/x"] != scope.RuleOffsite || reported[srv.URL()+"/c"] != "exclude:/c" {
+		t.Fatalf("unexpected reported rejections: %v", reported)
+	}
+}
+
+func TestFrontierHostBudgets(t *testing.T) {
+	f := newFrontier(2, 0, nil, nil)
+	f.scope = &scope.Rules{MaxPagesPerHost: 2, MaxBytesPerHost: 100}
+	f.seed("https://example.com/", 0)
+	a, _ := url.Parse("https://example.com/a")
+	b, _ := url.Parse("https://example.com/b")
+	if !f.follow(a, 0) || f.follow(b, 0) {
+		t.Fatalf("expected exactly two pages admitted for the host")
+	}
+	if rule := f.dispatchRule("https://example.com/a"); rule != "" {
+		t.Fatalf("byte budget not yet exhausted, got %q", rule)
+	}
+	f.addBytes("example.com", 150)
+	if rule := f.dispatchRule("https://example.com/a"); rule != scope.RuleMaxBytesPerHost {
+		t.Fatalf("expected byte budget rejection at dispatch, got %q", rule)
+	}
+	if got := f.rejections(); got[scope.RuleMaxPagesPerHost] != 1 || got[scope.RuleMaxBytesPerHost] != 1 {
+		t.Fatalf("unexpected rejection counts: %v", got)
+	}
+}
+
+func TestFrontierSitemapEntries(t *testing.T) {
+	f := newFrontier(1, 0, nil, nil)
+	f.seed("https://example.com/", 0)
+	f.seedSitemap("https://example.com/low", 0.1)
+	f.seedSitemap("https://example.com/default", 0.5)
+	f.seedSitemap("https://example.com/high", 1)
+	if f.seedSitemap("https://elsewhere.test/page", 1) {
+		t.Fatalf("sitemap entries must stay within the seed hosts")
+	}
+	link, _ := url.Parse("https://example.com/link")
+	f.follow(link, 0)
+	other, _ := url.Parse("https://elsewhere.test/linked")
+	if f.follow(other, 0) {
+		t.Fatalf("a sitemap entry must not make its host followable")
+	}
+	if got := f.rejections(); got[scope.RuleOffsite] != 2 {
+		t.Fatalf("expected both offsite URLs rejected, got %v", got)
+	}
+	var order []string
+	for {
+		task, ok, _ := f.popLocked()
+		if !ok {
+			break
+		}
+		order = append(order, strings.TrimPrefix(task.url, "https://example.com"))
+	}
+	if got, want := strings.Join(order, " "), "/ /high /default /link /low"; got != want {
+		t.Fatalf("expected sitemap priority to order the queue as %q, got %q", want, got)
+	}
+}
+
+func TestFrontierPauseAndCancel(t *testing.T) {
+	f := newFrontier(2, 0, nil, nil)
+	for _, u := range []string{"https://example.com/a", "https://example.com/skip/1", "https://example.com/skip/2"} {
+		f.seed(u, 0)
+	}
+	f.seal()
+	f.setPaused(true)
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, ok := f.next(short); ok {
+		t.Fatalf("a paused frontier must not dispatch")
+	}
+	pat, err := scope.CompilePattern("/skip/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := f.cancel(pat); n != 2 {
+		t.Fatalf("expected two queued URLs cancelled, got %d", n)
+	}
+	for i := 0; i < 2; i++ {
+		task, rule, ok := f.next(context.Background())
+		if !ok || rule != "cancelled:/skip/*" || !strings.Contains(task.url, "/skip/") {
+			t.Fatalf("expected cancelled tasks while paused, got %+v %q %v", task, rule, ok)
+		}
+	}
+	link, _ := url.Parse("https://example.com/skip/3")
+	if f.follow(link, 0) {
+		t.Fatalf("links matching a cancelled pattern must be rejected")
+	}
+	f.setPaused(false)
+	if task, rule, ok := f.next(context.Background()); !ok || rule != "" || task.url != "https://example.com/a" {
+		t.Fatalf("expected /a after resume, got %+v %q", task, rule)
+	}
+	if got := f.rejections()["cancelled:/skip/*"]; got != 3 {
+		t.Fatalf("expected three cancelled rejections, got %d", got)
+	}
+	f.complete()
+	f.complete()
+	if !f.complete() || !f.finished() || f.seed("https://example.com/late", 0) {
+		t.Fatalf("a finished frontier must not admit seeds")
+	}
+}
+
+func TestFrontierRestoreWithDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	log, err := checkpoint.Open(checkpoint.Options{Path: dir + "/checkpoint.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = log.Close() }()
+	store, err := intfrontier.Open(intfrontier.Options{Dir: dir + "/frontier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	// The previous run: / finished, /b was in flight (second attempt) and /c is still
+	// held by the store.
+	prev := newFrontier(2, 0, nil, store)
+	prev.checkpoint = log
+	prev.seed("https://example.com/", 0)
+	for _, u := range []string{"https://example.com/b", "https://example.com/c"} {
+		link, _ := url.Parse(u)
+		prev.follow(link, 0)
+	}
+	for i := 0; i < 2; i++ {
+		task, _, _ := prev.next(context.Background())
+		key := canonical.Default.Key(task.url)
+		if task.url == "https://example.com/b" {
+			log.Start(key, 0)
+			log.Start(key, 1)
+		} else {
+			log.Finish(key, true, 1)
+		}
+	}
+
+	f := newFrontier(2, 0, nil, store)
+	f.checkpoint = log
+	if finished, requeued := f.restore(log); finished != 1 || requeued != 1 {
+		t.Fatalf("expected one finished and one re-admitted URL, got %d and %d", finished, requeued)
+	}
+	got := map[string]crawlTask{}
+	for i := 0; i < 2; i++ {
+		task, _, ok := f.next(context.Background())
+		if !ok {
+			t.Fatalf("expected the in-flight and the stored URL")
+		}
+		got[task.url] = task
+	}
+	if b := got["https://example.com/b"]; b.depth != 1 || got["https://example.com/c"].url == "" {
+		t.Fatalf("unexpected restored tasks: %+v", got)
+	}
+	if f.seed("https://example.com/", 0) {
+		t.Fatalf("a finished URL must not be admitted again")
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func TestPipelineGracefulShutdown(t *testing.T) {
-	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 10}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 10, Fetcher: simulatedFetcher{}}
 	pl := NewPipeline(cfg)
 	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
 	ctx, cancel := context.WithCancel(context.Background())
@@ -7,7 +7,7 @@ import (
 )
 
 func TestPipelineMetricsSnapshot(t *testing.T) {
-	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 8}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 8, Fetcher: simulatedFetcher{}}
 	pl := NewPipeline(cfg)
 	defer pl.Stop()
 	urls := []string{"https://example.com/m1", "https://example.com/m2"}
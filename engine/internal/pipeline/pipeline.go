@@ -7,14 +7,25 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	crawlerPolicies "github.com/99souls/ariadne/engine/internal/business/crawler"
+	"github.com/99souls/ariadne/engine/internal/canonical"
+	"github.com/99souls/ariadne/engine/internal/checkpoint"
+	"github.com/99souls/ariadne/engine/internal/crawler"
+	"github.com/99souls/ariadne/engine/internal/deadletter"
+	intfrontier "github.com/99souls/ariadne/engine/internal/frontier"
+	"github.com/99souls/ariadne/engine/internal/processor"
 	intrat "github.com/99souls/ariadne/engine/internal/ratelimit"
 	intresources "github.com/99souls/ariadne/engine/internal/resources"
+	"github.com/99souls/ariadne/engine/internal/revisit"
+	"github.com/99souls/ariadne/engine/internal/robots"
+	"github.com/99souls/ariadne/engine/internal/runs"
+	"github.com/99souls/ariadne/engine/internal/scope"
 	"github.com/99souls/ariadne/engine/models"
 )
 
@@ -27,20 +38,151 @@ type PipelineConfig struct {
 	OutputWorkers     int `yaml:"output_workers" json:"output_workers"`
 	BufferSize        int `yaml:"buffer_size" json:"buffer_size"`
 
+	// RateLimiter and ResourceManager may be shared by several pipelines; the caller
+	// owns them and closes them after Stop.
 	RateLimiter      intrat.RateLimiter    `yaml:"-" json:"-"`
 	RetryBaseDelay   time.Duration         `yaml:"retry_base_delay" json:"retry_base_delay"`
 	RetryMaxDelay    time.Duration         `yaml:"retry_max_delay" json:"retry_max_delay"`
 	RetryMaxAttempts int                   `yaml:"retry_max_attempts" json:"retry_max_attempts"`
 	ResourceManager  *intresources.Manager `yaml:"-" json:"-"`
 
+	// Autoscale, when non-nil, resizes the stage worker pools within bounds while the
+	// pipeline runs; the worker counts above are the starting sizes.
+	Autoscale *AutoscaleConfig `yaml:"-" json:"-"`
+
+	// MaxDepth bounds link following: links found on a page at depth d are crawled at
+	// d+1 while d+1 <= MaxDepth (seeds are depth 0, so 0 disables following). Only
+	// links on seed hosts are followed unless Scope allows other domains. MaxPages caps
+	// admitted URLs (0 = unlimited).
+	MaxDepth int `yaml:"max_depth" json:"max_depth"`
+	MaxPages int `yaml:"max_pages" json:"max_pages"`
+	// Scope, when non-nil, applies domain, path and pattern rules and per-host budgets
+	// to admitted URLs. URLs already admitted when their host exhausts its byte budget
+	// fail at stage "scope" without being fetched.
+	Scope *scope.Rules `yaml:"-" json:"-"`
+	// ScopeRejected, when set, is called with every URL the frontier rejects and the
+	// rule that rejected it (see scope.Rule*). It must not block.
+	ScopeRejected func(rawURL, rule string) `yaml:"-" json:"-"`
+
+	// Frontier, when non-nil, holds pending URLs and the de-duplication set on disk
+	// instead of in memory; URLs it already holds are resumed. The caller owns the
+	// queue and closes it after Stop. While the pipeline runs the queue is synced
+	// every FrontierSyncInterval (default 1s), so a crash re-delivers only the URLs
+	// handed out since the last sync.
+	Frontier             *intfrontier.Queue `yaml:"-" json:"-"`
+	FrontierSyncInterval time.Duration      `yaml:"-" json:"-"`
+
+	// Revisit, when non-nil, enables conditional GET revalidation: URLs with recorded
+	// validators are fetched with If-None-Match / If-Modified-Since and a 304 reuses
+	// the processed page kept by ResourceManager (which needs a spill directory), emitting
+	// a result marked Unchanged. The caller owns the store and closes it after Stop.
+	Revisit *revisit.Store `yaml:"-" json:"-"`
+
+	// DeadLetters, when non-nil, records every URL whose extraction fails for good
+	// (after RetryMaxAttempts) and removes URLs that later produce a successful result.
+	// The caller owns the store and closes it after Stop.
+	DeadLetters *deadletter.Store `yaml:"-" json:"-"`
+
+	// Checkpoint, when non-nil, records every admitted URL, the start of each fetch
+	// attempt and each URL's final result so that RestoreCheckpoint can resume an
+	// interrupted crawl. The caller owns the log and closes it after Stop.
+	Checkpoint *checkpoint.Log `yaml:"-" json:"-"`
+
+	// Fetcher retrieves pages for the extraction stage. Nil selects a net/http fetcher
+	// configured from UserAgent and RequestTimeout.
+	Fetcher        Fetcher       `yaml:"-" json:"-"`
+	UserAgent      string        `yaml:"user_agent" json:"user_agent"`
+	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout"`
+
+	// Canonicalizer derives the URL keys used for frontier de-duplication, cache
+	// lookups and checkpoints. Nil selects canonical.Default.
+	Canonicalizer *canonical.Canonicalizer `yaml:"-" json:"-"`
+	// IgnoreRelCanonical disables collapsing pages onto their declared
+	// <link rel="canonical"> URL.
+	IgnoreRelCanonical bool `yaml:"ignore_rel_canonical" json:"ignore_rel_canonical"`
+
+	// Robots enables robots.txt compliance when non-nil. URLs excluded by robots.txt
+	// fail at stage "robots" without being fetched; while robots.txt is unavailable
+	// (5xx or transport failure) they are retried like failed fetches. Crawl-delay
+	// spaces the host's requests and caps its rate limiter fill rate. A nil
+	// Robots.Fetch routes robots.txt through Fetcher.
+	Robots *robots.Config `yaml:"-" json:"-"`
+
+	// Content enables the built-in content processing of every fetched page:
+	// main-content extraction, cleanup, relative URL resolution, metadata and image
+	// extraction, markdown conversion and validation (Page.Quality). Nil passes pages
+	// through as fetched.
+	Content *ContentConfig `yaml:"-" json:"-"`
+	// Processors run in order on every fetched page in the processing stage, after
+	// the built-in content processing. Entries must be non-nil.
+	Processors []Processor `yaml:"-" json:"-"`
+	// OutputSinks receive every successfully processed page in the output stage, in
+	// order, before the result is emitted. Results are still delivered on the channel.
+	OutputSinks []OutputSink `yaml:"-" json:"-"`
+
 	// AssetProcessingHook allows the engine to inject page mutation logic after extraction
 	// but before result emission (e.g., asset strategy rewrite). Optional.
 	AssetProcessingHook func(ctx context.Context, page *models.Page) (*models.Page, error) `yaml:"-" json:"-"`
 }
 
+// ContentConfig configures the built-in content processing.
+type ContentConfig struct {
+	// ContentSelectors are CSS selectors tried in order to locate the main content;
+	// empty uses main, article, .content, #content, .post, .entry, .article-content.
+	// Without a match the cleaned body is used.
+	ContentSelectors []string
+	// Extractor selects the main content strategy: "selector" (default) uses
+	// ContentSelectors, "readability" scores text and link density instead.
+	Extractor string
+	// RemoveSelectors are removed in addition to the built-in noise (scripts, styles,
+	// navigation, headers, footers, sidebars, ads and comments).
+	RemoveSelectors []string
+	// MinQuality fails pages whose quality score is below it; zero keeps every page.
+	MinQuality float64
+	// Schemas maps a domain (subdomains included) to the extraction schema whose
+	// record is stored in Page.Extracted.
+	Schemas map[string]*processor.Schema
+}
+
+// Fetcher retrieves a single page for the extraction stage. Implementations report
+// non-2xx responses as *crawler.HTTPError so the status code and Retry-After reach the
+// rate limiter.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*models.Page, error)
+}
+
+// Processor transforms a fetched page. A non-nil error fails the page at the
+// processing stage.
+type Processor interface {
+	Process(ctx context.Context, page *models.Page) (*models.Page, error)
+}
+
+// OutputSink consumes processed pages. Flush/Close are owned by the embedding engine.
+type OutputSink interface {
+	Name() string
+	Write(ctx context.Context, page *models.Page) error
+}
+
+const (
+	defaultUserAgent      = "Ariadne/1.0 (+https://github.com/99souls/ariadne)"
+	defaultRequestTimeout = 30 * time.Second
+	defaultFrontierSync   = time.Second
+)
+
 type extractionTask struct {
 	url     string
 	attempt int
+	depth   int
+}
+
+// pageTask carries a fetched page and its link depth into the processing stage. key is
+// the canonical key of the fetched URL; unchanged marks a previously processed page
+// revalidated with a 304, which skips processing.
+type pageTask struct {
+	page      *models.Page
+	depth     int
+	key       string
+	unchanged bool
 }
 
 type StageStatus struct {
@@ -55,18 +197,41 @@ type StageMetrics struct {
 	AvgTime   time.Duration `json:"avg_time"`
 }
 type PipelineMetrics struct {
-	TotalProcessed int                     `json:"total_processed"`
-	TotalFailed    int                     `json:"total_failed"`
-	StartTime      time.Time               `json:"start_time"`
-	Duration       time.Duration           `json:"duration"`
-	StageMetrics   map[string]StageMetrics `json:"stage_metrics"`
+	TotalProcessed int `json:"total_processed"`
+	TotalFailed    int `json:"total_failed"`
+	URLsAdmitted   int `json:"urls_admitted"`
+	URLsPending    int `json:"urls_pending"`
+	URLsQueued     int `json:"urls_queued"`
+	FrontierErrors int `json:"frontier_errors,omitempty"`
+	// URLsRejected counts URLs kept out of the crawl per scope rule.
+	URLsRejected map[string]int `json:"urls_rejected,omitempty"`
+	// Revalidation outcomes, counted only when PipelineConfig.Revisit is set: pages
+	// fetched without validators, fetched in full despite validators, and answered 304.
+	PagesNew       int `json:"pages_new,omitempty"`
+	PagesRefetched int `json:"pages_refetched,omitempty"`
+	PagesUnchanged int `json:"pages_unchanged,omitempty"`
+	RevisitErrors  int `json:"revisit_errors,omitempty"`
+	// CacheErrors counts fetched pages the resource manager failed to cache.
+	CacheErrors int `json:"cache_errors,omitempty"`
+	// Dead-letter activity, counted only when PipelineConfig.DeadLetters is set: URLs
+	// recorded, URLs removed after succeeding, and store write failures.
+	DeadLettered      int `json:"dead_lettered,omitempty"`
+	DeadLetterCleared int `json:"dead_letter_cleared,omitempty"`
+	DeadLetterErrors  int `json:"dead_letter_errors,omitempty"`
+	// Workers is the current worker count per stage; Scaling lists the most recent
+	// autoscaler decisions, oldest first.
+	Workers      map[string]int          `json:"workers,omitempty"`
+	Scaling      []ScaleDecision         `json:"scaling,omitempty"`
+	StartTime    time.Time               `json:"start_time"`
+	Duration     time.Duration           `json:"duration"`
+	StageMetrics map[string]StageMetrics `json:"stage_metrics"`
 }
 
 type Pipeline struct {
 	config                                            *PipelineConfig
-	urlQueue                                          chan string
-	extractionQueue                                   chan extractionTask
-	processingQueue                                   chan *models.Page
+	urlQueue                                          chan crawlTask
+	scheduler                                         *hostScheduler
+	processingQueue                                   chan pageTask
 	outputQueue                                       chan *models.CrawlResult
 	resultsInternal                                   chan *models.CrawlResult
 	results                                           chan *models.CrawlResult
@@ -77,12 +242,16 @@ type Pipeline struct {
 	metrics                                           *PipelineMetrics
 	stageStatus                                       map[string]*StageStatus
 	closeResultsOnce                                  sync.Once
-	expectedResults                                   int64
-	resultCount                                       int64
+	frontier                                          *frontier
 	discoveryWG, extractionWG, processingWG, outputWG sync.WaitGroup
+	pools                                             map[string]*workerPool
+	scaling                                           []ScaleDecision
 	retryWG                                           sync.WaitGroup
 	limiter                                           intrat.RateLimiter
+	fetcher                                           Fetcher
+	robots                                            *robots.Checker
 	resourceManager                                   *intresources.Manager
+	content                                           *processor.ContentProcessor
 	randMu                                            sync.Mutex
 	rand                                              *rand.Rand
 }
@@ -98,33 +267,226 @@ func NewPipeline(config *PipelineConfig) *Pipeline {
 	if config.RetryMaxAttempts <= 0 {
 		config.RetryMaxAttempts = 3
 	}
+	if config.Fetcher == nil {
+		config.Fetcher = newDefaultFetcher(config)
+	}
+	if err := validateStrategies(config); err != nil {
+		panic(fmt.Sprintf("pipeline: %v", err)) // callers validate first; see ValidateStrategies
+	}
+	if config.Canonicalizer == nil {
+		config.Canonicalizer = canonical.Default
+	}
 	randGen := rand.New(rand.NewSource(time.Now().UnixNano()))
-	p := &Pipeline{config: config, ctx: ctx, cancel: cancel, urlQueue: make(chan string, config.BufferSize), extractionQueue: make(chan extractionTask, config.BufferSize), processingQueue: make(chan *models.Page, config.BufferSize), outputQueue: make(chan *models.CrawlResult, config.BufferSize), resultsInternal: make(chan *models.CrawlResult, config.BufferSize), results: make(chan *models.CrawlResult, config.BufferSize), metrics: &PipelineMetrics{StartTime: time.Now(), StageMetrics: make(map[string]StageMetrics)}, stageStatus: make(map[string]*StageStatus), limiter: config.RateLimiter, resourceManager: config.ResourceManager, rand: randGen}
+	p := &Pipeline{config: config, fetcher: config.Fetcher, ctx: ctx, cancel: cancel, urlQueue: make(chan crawlTask, config.BufferSize), scheduler: newHostScheduler(config.BufferSize*schedulerSlotsPerBuffer, config.RateLimiter), processingQueue: make(chan pageTask, config.BufferSize), outputQueue: make(chan *models.CrawlResult, config.BufferSize), resultsInternal: make(chan *models.CrawlResult, config.BufferSize), results: make(chan *models.CrawlResult, config.BufferSize), metrics: &PipelineMetrics{StartTime: time.Now(), StageMetrics: make(map[string]StageMetrics)}, stageStatus: make(map[string]*StageStatus), pools: make(map[string]*workerPool), limiter: config.RateLimiter, resourceManager: config.ResourceManager, rand: randGen, frontier: newFrontier(config.MaxDepth, config.MaxPages, config.Canonicalizer, config.Frontier)}
+	p.frontier.scope, p.frontier.onReject = config.Scope, config.ScopeRejected
+	p.frontier.checkpoint = config.Checkpoint
+	if cc := config.Content; cc != nil {
+		extractor, _ := processor.NewExtractor(cc.Extractor, cc.ContentSelectors) // validated above
+		p.content = &processor.ContentProcessor{ContentSelectors: cc.ContentSelectors, RemoveSelectors: cc.RemoveSelectors, Extractor: extractor}
+		if len(cc.Schemas) > 0 {
+			sites := crawlerPolicies.NewSitePolicyManager()
+			for domain, schema := range cc.Schemas {
+				_ = sites.AddSitePolicy(domain, &crawlerPolicies.SiteSpecificPolicy{Domain: domain, Content: crawlerPolicies.ContentExtractionRules{Schema: schema}}) // never fails
+			}
+			p.content.Schemas = crawlerPolicies.NewSiteRuleEvaluator(sites)
+		}
+	}
+	if config.Robots != nil {
+		rc := *config.Robots
+		if rc.UserAgent == "" {
+			rc.UserAgent = config.UserAgent
+		}
+		if rc.UserAgent == "" {
+			rc.UserAgent = defaultUserAgent
+		}
+		if rc.Fetch == nil {
+			rc.Fetch = rawFetchFunc(config.Fetcher)
+		}
+		p.robots = robots.NewChecker(rc)
+	}
 	p.initStageStatus()
 	p.startStages()
 	p.startResultAggregator()
+	if config.Autoscale != nil {
+		p.wg.Add(1)
+		go p.autoscale(*config.Autoscale)
+	}
+	if config.Frontier != nil {
+		p.wg.Add(1)
+		go p.syncFrontier(config.FrontierSyncInterval)
+	}
 	return p
 }
 
+// syncFrontier periodically makes the disk-backed frontier durable until the pipeline
+// stops.
+func (p *Pipeline) syncFrontier(interval time.Duration) {
+	defer p.wg.Done()
+	if interval <= 0 {
+		interval = defaultFrontierSync
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.frontier.sync()
+		}
+	}
+}
+
+// newDefaultFetcher builds the net/http backed fetcher used when no Fetcher is configured.
+func newDefaultFetcher(config *PipelineConfig) Fetcher {
+	policy := crawler.FetchPolicy{UserAgent: config.UserAgent, Timeout: config.RequestTimeout, FollowRedirects: true}
+	if policy.UserAgent == "" {
+		policy.UserAgent = defaultUserAgent
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = defaultRequestTimeout
+	}
+	hf, err := crawler.NewHTTPFetcher(policy)
+	if err != nil {
+		panic(fmt.Sprintf("pipeline: default fetcher: %v", err)) // unreachable: policy normalized above
+	}
+	return crawler.NewPageFetcher(hf)
+}
+
+// ValidateStrategies reports nil processor or sink entries so constructors can fail
+// fast instead of panicking on first use inside a stage worker.
+func ValidateStrategies(config *PipelineConfig) error { return validateStrategies(config) }
+
+func validateStrategies(config *PipelineConfig) error {
+	for i, proc := range config.Processors {
+		if proc == nil {
+			return fmt.Errorf("nil processor at index %d", i)
+		}
+	}
+	for i, sink := range config.OutputSinks {
+		if sink == nil {
+			return fmt.Errorf("nil output sink at index %d", i)
+		}
+	}
+	if cc := config.Content; cc != nil {
+		if _, err := processor.NewExtractor(cc.Extractor, cc.ContentSelectors); err != nil {
+			return err
+		}
+		for domain, schema := range cc.Schemas {
+			if domain == "" {
+				return fmt.Errorf("extraction schema without domain")
+			}
+			if schema == nil {
+				return fmt.Errorf("nil extraction schema for %s", domain)
+			}
+			if err := schema.Validate(); err != nil {
+				return fmt.Errorf("extraction schema for %s: %w", domain, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RawFetch retrieves rawURL through the configured Fetcher, returning the status and
+// body. Auxiliary documents (robots.txt, sitemaps) use it so they share the fetcher's
+// transport, user agent and any embedder supplied behavior.
+func (p *Pipeline) RawFetch(ctx context.Context, rawURL string) (int, []byte, error) {
+	return rawFetchFunc(p.fetcher)(ctx, rawURL)
+}
+
+func rawFetchFunc(f Fetcher) robots.FetchFunc {
+	return func(ctx context.Context, robotsURL string) (int, []byte, error) {
+		page, err := f.Fetch(ctx, robotsURL)
+		if err != nil {
+			var httpErr *crawler.HTTPError
+			if errors.As(err, &httpErr) {
+				return httpErr.StatusCode, nil, nil
+			}
+			return 0, nil, err
+		}
+		if page == nil {
+			return http.StatusOK, nil, nil
+		}
+		return http.StatusOK, []byte(page.Content), nil
+	}
+}
+
 func (p *Pipeline) Config() *PipelineConfig { return p.config }
+
+// StageStatus returns a copy of the stage's status with its current worker count and
+// queue depth.
 func (p *Pipeline) StageStatus(stageName string) *StageStatus {
 	p.mutex.RLock()
-	defer p.mutex.RUnlock()
-	if s, ok := p.stageStatus[stageName]; ok {
-		return s
+	s, ok := p.stageStatus[stageName]
+	var st StageStatus
+	if ok {
+		st = *s
+	}
+	p.mutex.RUnlock()
+	if !ok {
+		return &StageStatus{Name: stageName, Active: false}
+	}
+	if wp := p.pools[stageName]; wp != nil {
+		st.Workers = wp.size()
 	}
-	return &StageStatus{Name: stageName, Active: false}
+	st.Queue = p.queueDepth(stageName)
+	return &st
 }
+
+// ProcessURLs admits the seed URLs to the frontier and returns the results channel.
+// Links discovered on processed pages are fed back into discovery (bounded by
+// MaxDepth/MaxPages); the channel closes once every admitted URL produced a result.
 func (p *Pipeline) ProcessURLs(ctx context.Context, urls []string) <-chan *models.CrawlResult {
-	atomic.StoreInt64(&p.expectedResults, int64(len(urls)))
-	atomic.StoreInt64(&p.resultCount, 0)
+	seeds := make([]Seed, 0, len(urls))
+	for _, u := range urls {
+		seeds = append(seeds, Seed{URL: u})
+	}
+	return p.ProcessSeeds(ctx, seeds)
+}
+
+// Seed is a URL admitted at a given link depth, e.g. a dead letter replayed at the
+// depth it was first discovered. Sitemap seeds are sitemap entries: they are held to
+// the scope of the other seeds (their hosts are not followed unless a seed's is, or
+// the scope allows their domain) and Priority, the entry's <priority>, orders them
+// against the seeds and discovered links.
+type Seed struct {
+	URL      string
+	Depth    int
+	Sitemap  bool
+	Priority float64
+}
+
+// ProcessSeeds is ProcessURLs for seeds with explicit depths: links found on a seed
+// at depth d are followed while d+1 <= MaxDepth.
+func (p *Pipeline) ProcessSeeds(ctx context.Context, seeds []Seed) <-chan *models.CrawlResult {
+	for _, s := range seeds {
+		if !s.Sitemap {
+			p.frontier.seed(s.URL, s.Depth)
+		}
+	}
+	for _, s := range seeds { // after the seeds, whose hosts they may be on
+		if s.Sitemap {
+			p.frontier.seedSitemap(s.URL, s.Priority)
+		}
+	}
+	if p.frontier.seal() {
+		p.cancel()
+		return p.results
+	}
 	processCtx, processCancel := context.WithCancel(ctx)
 	go func() {
 		defer processCancel()
-		defer close(p.urlQueue)
-		for _, u := range urls {
+		for {
+			task, rule, ok := p.frontier.next(processCtx)
+			if !ok {
+				return
+			}
+			if rule != "" {
+				p.rejectAdmitted(task.url, rule)
+				continue
+			}
 			select {
-			case p.urlQueue <- u:
+			case p.urlQueue <- task:
 			case <-processCtx.Done():
 				return
 			case <-p.ctx.Done():
@@ -132,18 +494,98 @@ func (p *Pipeline) ProcessURLs(ctx context.Context, urls []string) <-chan *model
 			}
 		}
 	}()
+	go func() {
+		defer processCancel()
+		select {
+		case <-processCtx.Done(): // caller cancelled: stop the whole run
+			p.cancel()
+		case <-p.ctx.Done():
+		}
+	}()
 	return p.results
 }
 
+// RestoreCheckpoint resumes the crawl recorded by PipelineConfig.Checkpoint: URLs it
+// finished are skipped for the rest of the run and URLs it left queued or in flight
+// are admitted again at their recorded depth, continuing their attempt count. It must
+// be called before ProcessURLs and returns the number of finished and re-admitted URLs.
+func (p *Pipeline) RestoreCheckpoint() (finished, requeued int) {
+	if p.config.Checkpoint == nil {
+		return 0, 0
+	}
+	return p.frontier.restore(p.config.Checkpoint)
+}
+
+// ErrFinished is returned by Enqueue once every admitted URL has produced its result.
+var ErrFinished = errors.New("pipeline: crawl already finished")
+
+// Enqueue admits urls as depth-0 seeds of the running crawl and returns how many were
+// admitted (URLs already seen or out of scope are not).
+func (p *Pipeline) Enqueue(urls []string) (int, error) {
+	if p.frontier.finished() || p.ctx.Err() != nil {
+		return 0, ErrFinished
+	}
+	n := 0
+	for _, u := range urls {
+		if p.frontier.seed(u, 0) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Pause stops handing queued URLs to the workers: the frontier stops dispatching and
+// the host scheduler holds the tasks it already queued, so no fetch starts until
+// Resume. Fetches in progress complete normally and limiter state is kept.
+func (p *Pipeline) Pause() {
+	p.frontier.setPaused(true)
+	p.scheduler.setPaused(true)
+}
+
+// Resume restarts dispatch after Pause.
+func (p *Pipeline) Resume() {
+	p.scheduler.setPaused(false)
+	p.frontier.setPaused(false)
+}
+
+// Paused reports whether dispatch is paused.
+func (p *Pipeline) Paused() bool { return p.frontier.isPaused() }
+
+// Finished reports whether every admitted URL has produced its result.
+func (p *Pipeline) Finished() bool { return p.frontier.finished() }
+
+// CancelURLs drops queued URLs matching pattern (Scope include/exclude syntax) and
+// rejects matching URLs for the rest of the run. Dropped URLs fail at stage "scope"
+// with rule "cancelled:<pattern>". It returns the number of queued URLs dropped at
+// once; a disk-backed frontier drops its matches as they are dequeued instead.
+func (p *Pipeline) CancelURLs(pattern string) (int, error) {
+	pat, err := scope.CompilePattern(pattern)
+	if err != nil {
+		return 0, err
+	}
+	return p.frontier.cancel(pat), nil
+}
+
 // Metrics returns a snapshot copy of current aggregate metrics (duration updated).
 func (p *Pipeline) Metrics() *PipelineMetrics {
 	p.mutex.RLock()
 	defer p.mutex.RUnlock()
 	cp := *p.metrics
 	cp.Duration = time.Since(cp.StartTime)
+	cp.URLsAdmitted, cp.URLsPending, cp.URLsQueued, cp.FrontierErrors = p.frontier.stats()
+	cp.URLsRejected = p.frontier.rejections()
+	cp.Scaling = append([]ScaleDecision(nil), p.scaling...)
+	cp.Workers = make(map[string]int, len(p.pools))
+	for name, wp := range p.pools {
+		cp.Workers[name] = wp.size()
+	}
 	return &cp
 }
 
+// FrontierHosts returns the per-host view of URLs waiting in the frontier (admitted
+// but not yet dispatched to workers), largest queue first.
+func (p *Pipeline) FrontierHosts() []intfrontier.HostQueue { return p.frontier.hostQueues() }
+
 // SetMetricsForTest injects synthetic counters for tests (not for production use).
 func (p *Pipeline) SetMetricsForTest(m *PipelineMetrics) {
 	if p == nil || m == nil {
@@ -164,37 +606,28 @@ func (p *Pipeline) Stop() {
 	}
 	p.mutex.Unlock()
 	p.closeResults()
-	if closable, ok := p.limiter.(interface{ Close() error }); ok {
-		_ = closable.Close()
-	}
 }
 
 func (p *Pipeline) startStages() {
-	p.discoveryWG.Add(p.config.DiscoveryWorkers)
-	for i := 0; i < p.config.DiscoveryWorkers; i++ {
-		p.wg.Add(1)
-		go p.discoveryWorker()
-	}
-	go func() { p.discoveryWG.Wait(); <-p.ctx.Done(); p.retryWG.Wait(); close(p.extractionQueue) }()
-	p.extractionWG.Add(p.config.ExtractionWorkers)
-	for i := 0; i < p.config.ExtractionWorkers; i++ {
-		p.wg.Add(1)
-		go p.extractionWorker()
-	}
+	p.startPool(StageDiscovery, &p.discoveryWG, p.config.DiscoveryWorkers, p.discoveryWorker)
+	go func() { p.discoveryWG.Wait(); <-p.ctx.Done(); p.retryWG.Wait(); p.scheduler.close() }()
+	p.startPool(StageExtraction, &p.extractionWG, p.config.ExtractionWorkers, p.extractionWorker)
 	go func() { p.extractionWG.Wait(); close(p.processingQueue) }()
-	p.processingWG.Add(p.config.ProcessingWorkers)
-	for i := 0; i < p.config.ProcessingWorkers; i++ {
-		p.wg.Add(1)
-		go p.processingWorker()
-	}
+	p.startPool(StageProcessing, &p.processingWG, p.config.ProcessingWorkers, p.processingWorker)
 	go func() { p.processingWG.Wait(); close(p.outputQueue) }()
-	p.outputWG.Add(p.config.OutputWorkers)
-	for i := 0; i < p.config.OutputWorkers; i++ {
-		p.wg.Add(1)
-		go p.outputWorker()
-	}
+	p.startPool(StageOutput, &p.outputWG, p.config.OutputWorkers, p.outputWorker)
 	go func() { p.outputWG.Wait(); close(p.resultsInternal) }()
 }
+
+// startPool starts a stage with n workers, kept within the stage's autoscale bounds.
+func (p *Pipeline) startPool(stage string, wg *sync.WaitGroup, n int, run func(context.Context, *activity)) {
+	if as := p.config.Autoscale; as != nil {
+		if b, ok := as.Bounds[stage]; ok {
+			n = min(max(n, b.Min, 1), max(b.Max, b.Min, 1))
+		}
+	}
+	p.newWorkerPool(stage, wg, run).resize(n)
+}
 func (p *Pipeline) startResultAggregator() { p.wg.Add(1); go p.monitorResults() }
 func (p *Pipeline) monitorResults() {
 	defer p.wg.Done()
@@ -214,9 +647,7 @@ func (p *Pipeline) monitorResults() {
 				p.closeResults()
 				return
 			}
-			newCount := atomic.AddInt64(&p.resultCount, 1)
-			expected := atomic.LoadInt64(&p.expectedResults)
-			if expected > 0 && newCount >= expected {
+			if p.frontier.complete() {
 				p.cancel()
 				p.drainResultsInternal()
 				p.closeResults()
@@ -252,52 +683,55 @@ func (p *Pipeline) deliverResult(result *models.CrawlResult) bool {
 	case <-p.ctx.Done():
 		return false
 	case p.resultsInternal <- result:
-		if p.resourceManager != nil && result != nil {
-			checkpointURL := result.URL
-			if checkpointURL == "" && result.Page != nil && result.Page.URL != nil {
-				checkpointURL = result.Page.URL.String()
-			}
-			if checkpointURL != "" {
-				p.resourceManager.Checkpoint(checkpointURL)
-			}
+		if result != nil && result.Success {
+			p.clearDeadLetter(result)
 		}
+		p.checkpointResult(result)
 		return true
 	}
 }
-func (p *Pipeline) forwardToProcessing(page *models.Page, fromCache bool) bool {
-	if page == nil {
-		return false
+
+// checkpointResult records the final result of result's URL (and of the aliases of a
+// collapsed page) in the checkpoint log.
+func (p *Pipeline) checkpointResult(result *models.CrawlResult) {
+	log := p.config.Checkpoint
+	if log == nil || result == nil {
+		return
 	}
-	select {
-	case p.processingQueue <- page:
-		if fromCache {
-			p.updateStageMetrics("cache", true)
-		} else {
-			p.updateStageMetrics("extraction", true)
+	u := result.URL
+	if u == "" {
+		u = pageURL(result.Page)
+	}
+	attempts := 0
+	if result.Error != nil {
+		attempts = result.Error.Attempts
+	}
+	if u != "" {
+		log.Finish(p.config.Canonicalizer.Key(u), result.Success, attempts)
+	}
+	if result.Page != nil && result.Stage != "duplicate" {
+		for _, alias := range result.Page.Aliases {
+			log.Finish(p.config.Canonicalizer.Key(alias), result.Success, attempts)
 		}
-		return true
-	case <-p.ctx.Done():
-		return false
 	}
 }
-func (p *Pipeline) enqueueExtraction(u string, attempt int) bool {
-	task := extractionTask{url: u, attempt: attempt}
-	var sent bool
-	defer func() {
-		if r := recover(); r != nil {
-			sent = false
-		}
-	}()
+func (p *Pipeline) forwardToProcessing(task pageTask, stage string) bool {
+	if task.page == nil {
+		return false
+	}
 	select {
+	case p.processingQueue <- task:
+		p.updateStageMetrics(stage, true)
+		return true
 	case <-p.ctx.Done():
 		return false
-	case p.extractionQueue <- task:
-		sent = true
 	}
-	return sent
 }
-func (p *Pipeline) scheduleRetry(u string, attempt int, delay time.Duration) {
-	if p.config.RetryMaxAttempts > 0 && attempt >= p.config.RetryMaxAttempts {
+func (p *Pipeline) enqueueExtraction(task extractionTask) bool {
+	return p.scheduler.push(p.ctx, task)
+}
+func (p *Pipeline) scheduleRetry(task extractionTask, delay time.Duration) {
+	if p.config.RetryMaxAttempts > 0 && task.attempt >= p.config.RetryMaxAttempts {
 		return
 	}
 	if err := p.ctx.Err(); err != nil {
@@ -324,7 +758,7 @@ func (p *Pipeline) scheduleRetry(u string, attempt int, delay time.Duration) {
 		if err := p.ctx.Err(); err != nil {
 			return
 		}
-		p.enqueueExtraction(u, attempt)
+		p.dispatch(task) // checks robots.txt again
 	}()
 }
 func (p *Pipeline) shouldRetry(task extractionTask) bool {
@@ -370,201 +804,508 @@ func (p *Pipeline) acquirePermit(task extractionTask, domain string) (intrat.Per
 	}
 	return permit, nil
 }
-func extractDomain(raw string) string {
-	u, err := url.Parse(raw)
+
+// robotsAllow applies the robots.txt policy to task, emitting a "robots" stage
+// failure for excluded URLs and forwarding Crawl-delay to the scheduler and the rate
+// limiter. URLs denied because robots.txt is unavailable are retried once it is
+// fetched again, while attempts remain.
+func (p *Pipeline) robotsAllow(task extractionTask, domain string) bool {
+	if p.robots == nil {
+		return true
+	}
+	u, err := url.Parse(task.url)
 	if err != nil {
-		return ""
+		return true // extraction reports the invalid URL
 	}
-	return strings.ToLower(u.Host)
+	decision := p.robots.Check(p.ctx, u)
+	if decision.CrawlDelay > 0 && domain != "" {
+		p.scheduler.setCrawlDelay(domain, decision.CrawlDelay)
+		if capped, ok := p.limiter.(interface{ SetDomainCeiling(string, float64) }); ok {
+			capped.SetDomainCeiling(domain, 1/decision.CrawlDelay.Seconds())
+		}
+	}
+	if decision.Allowed {
+		return true
+	}
+	if p.ctx.Err() != nil {
+		return false
+	}
+	if decision.Temporary() && p.shouldRetry(task) {
+		p.scheduleRetry(extractionTask{url: task.url, attempt: task.attempt + 1, depth: task.depth}, max(decision.RetryAfter, p.backoffDelay(task.attempt+1)))
+		return false
+	}
+	p.updateStageMetrics("robots", false)
+	if decision.Temporary() {
+		// robots.txt stayed unavailable through every attempt: dead-letter the URL so a
+		// later replay can re-drive it.
+		p.failTask(task, "robots", decision.Err(task.url), task.attempt+1)
+		return false
+	}
+	p.sendFailure(task.url, "robots", decision.Err(task.url), 0, false)
+	return false
 }
-func (p *Pipeline) discoveryWorker() {
-	defer p.wg.Done()
-	defer p.discoveryWG.Done()
+
+// scopeAllow drops rawURL with a "scope" stage failure when its host ran out of byte
+// budget after the URL was admitted.
+func (p *Pipeline) scopeAllow(rawURL string) bool {
+	rule := p.frontier.dispatchRule(rawURL)
+	if rule == "" {
+		return true
+	}
+	p.rejectAdmitted(rawURL, rule)
+	return false
+}
+
+// rejectAdmitted reports an admitted URL dropped by rule before it was fetched.
+func (p *Pipeline) rejectAdmitted(rawURL, rule string) {
+	p.frontier.notifyReject(rawURL, rule)
+	p.updateStageMetrics("scope", false)
+	p.sendFailure(rawURL, "scope", scope.Err(rawURL, rule), 0, false)
+}
+
+func extractDomain(raw string) string { return canonical.Host(raw) }
+func (p *Pipeline) discoveryWorker(wctx context.Context, act *activity) {
 	for {
+		act.set(false)
 		select {
-		case u, ok := <-p.urlQueue:
+		case t, ok := <-p.urlQueue:
 			if !ok {
 				return
 			}
+			act.set(true)
+			u := t.url
 			if p.isValidURL(u) {
-				if p.enqueueExtraction(u, 0) {
-					p.updateStageMetrics("discovery", true)
-				} else {
+				p.updateStageMetrics("discovery", true)
+				if !p.dispatch(extractionTask{url: u, depth: t.depth, attempt: t.attempt}) {
 					return
 				}
 			} else {
 				p.updateStageMetrics("discovery", false)
 				p.sendErrorResult(u, "discovery", "invalid URL", false)
 			}
-		case <-p.ctx.Done():
+		case <-wctx.Done():
 			return
 		}
 	}
 }
-func (p *Pipeline) extractionWorker() {
-	defer p.wg.Done()
-	defer p.extractionWG.Done()
+
+// dispatch runs the checks that need no rate limiter permit (scope budgets, robots.txt,
+// cache) and hands task to the per-host scheduler. It returns false once the pipeline
+// stopped.
+func (p *Pipeline) dispatch(task extractionTask) bool {
+	if !p.scopeAllow(task.url) || !p.robotsAllow(task, extractDomain(task.url)) {
+		return true
+	}
+	if manager := p.resourceManager; manager != nil {
+		key := p.config.Canonicalizer.Key(task.url)
+		cachedPage, hit, err := manager.GetPage(key)
+		if err != nil {
+			p.updateStageMetrics("extraction", false)
+			p.sendFailure(task.url, "extraction", fmt.Errorf("cache lookup failed: %w", err), task.attempt, false)
+			return true
+		}
+		if hit && cachedPage != nil {
+			return p.forwardToProcessing(pageTask{page: cachedPage, depth: task.depth, key: key}, "cache")
+		}
+	}
+	return p.enqueueExtraction(task)
+}
+
+// extractionWorker fetches the tasks the scheduler hands out, which (with a limiter
+// supporting TryAcquire) come with their host's permit already granted.
+func (p *Pipeline) extractionWorker(wctx context.Context, act *activity) {
 	for {
-		select {
-		case task, ok := <-p.extractionQueue:
-			if !ok {
+		act.set(false)
+		sc, ok := p.scheduler.next(wctx)
+		if !ok {
+			return
+		}
+		act.set(true)
+		task := sc.task
+		domain := extractDomain(task.url)
+		if !p.scopeAllow(task.url) {
+			if sc.permit != nil {
+				sc.permit.Release()
+			}
+			continue
+		}
+		key := p.config.Canonicalizer.Key(task.url)
+		manager := p.resourceManager
+		permit, err := sc.permit, sc.err
+		if !sc.acquired {
+			permit, err = p.acquirePermit(task, domain)
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
 				return
 			}
-			domain := extractDomain(task.url)
-			manager := p.resourceManager
-			if manager != nil {
-				cachedPage, hit, err := manager.GetPage(task.url)
-				if err != nil {
-					p.updateStageMetrics("extraction", false)
-					p.sendErrorResult(task.url, "extraction", fmt.Sprintf("cache lookup failed: %v", err), false)
-					continue
-				}
-				if hit && cachedPage != nil {
-					if !p.forwardToProcessing(cachedPage, true) {
-						return
-					}
-					continue
-				}
+			p.updateStageMetrics("extraction", false)
+			if errors.Is(err, intrat.ErrCircuitOpen) && p.shouldRetry(task) {
+				delay := p.backoffDelay(task.attempt + 1)
+				p.scheduleRetry(extractionTask{url: task.url, attempt: task.attempt + 1, depth: task.depth}, delay)
+				continue
 			}
-			permit, err := p.acquirePermit(task, domain)
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
+			p.failExtraction(task, err, task.attempt)
+			continue
+		}
+		var slotAcquired bool
+		if manager != nil {
+			if acquireErr := manager.Acquire(p.ctx); acquireErr != nil {
+				if permit != nil {
+					permit.Release()
+				}
+				if errors.Is(acquireErr, context.Canceled) {
 					return
 				}
 				p.updateStageMetrics("extraction", false)
-				if errors.Is(err, intrat.ErrCircuitOpen) && p.shouldRetry(task) {
-					delay := p.backoffDelay(task.attempt + 1)
-					p.scheduleRetry(task.url, task.attempt+1, delay)
-					continue
-				}
-				p.sendErrorResult(task.url, "extraction", err.Error(), false)
+				p.failExtraction(task, acquireErr, task.attempt)
 				continue
 			}
-			var slotAcquired bool
-			if manager != nil {
-				if acquireErr := manager.Acquire(p.ctx); acquireErr != nil {
-					if permit != nil {
-						permit.Release()
-					}
-					if errors.Is(acquireErr, context.Canceled) {
-						return
-					}
-					p.updateStageMetrics("extraction", false)
-					p.sendErrorResult(task.url, "extraction", acquireErr.Error(), false)
-					continue
-				}
-				slotAcquired = true
-			}
-			start := time.Now()
-			page := p.extractContent(task.url)
-			latency := time.Since(start)
-			if permit != nil {
-				permit.Release()
-			}
-			releaseSlot := func() {
-				if slotAcquired && manager != nil {
-					manager.Release()
-					slotAcquired = false
+			slotAcquired = true
+		}
+		if log := p.config.Checkpoint; log != nil {
+			log.Start(key, task.attempt)
+		}
+		validators, revalidate := p.validatorsFor(key)
+		page, feedback, fetchErr := p.extractContent(task.url, validators)
+		var unchanged bool
+		if revalidate && isNotModified(fetchErr) {
+			if prev := p.loadProcessed(key); prev != nil {
+				if u, err := url.Parse(task.url); err == nil {
+					prev.URL = u
 				}
+				page, fetchErr, unchanged = prev, nil, true
+			} else {
+				// The stored page is gone, so the 304 is of no use: fetch in full.
+				page, feedback, fetchErr = p.extractContent(task.url, crawler.Validators{})
 			}
-			if page != nil {
-				if manager != nil {
-					if err := manager.StorePage(task.url, page); err != nil {
-						releaseSlot()
-						p.updateStageMetrics("extraction", false)
-						p.sendErrorResult(task.url, "extraction", fmt.Sprintf("cache store failed: %v", err), false)
-						continue
-					}
-				}
-				releaseSlot()
-				if p.limiter != nil && domain != "" {
-					p.limiter.Feedback(domain, intrat.Feedback{StatusCode: 200, Latency: latency})
-				}
-				if !p.forwardToProcessing(page, false) {
-					return
-				}
+		}
+		if permit != nil {
+			permit.Release()
+		}
+		releaseSlot := func() {
+			if slotAcquired && manager != nil {
+				manager.Release()
+				slotAcquired = false
+			}
+		}
+		if fetchErr != nil && p.ctx.Err() != nil {
+			releaseSlot()
+			return
+		}
+		if p.limiter != nil && domain != "" {
+			p.limiter.Feedback(domain, feedback)
+		}
+		if fetchErr == nil && unchanged {
+			releaseSlot()
+			p.countRevisit(&p.metrics.PagesUnchanged)
+			if !p.forwardToProcessing(pageTask{page: page, depth: task.depth, key: key, unchanged: true}, "extraction") {
+				return
+			}
+		} else if fetchErr == nil {
+			p.frontier.addBytes(domain, len(page.Content))
+			if revalidate {
+				p.countRevisit(&p.metrics.PagesRefetched)
 			} else {
-				releaseSlot()
-				if p.limiter != nil && domain != "" {
-					p.limiter.Feedback(domain, intrat.Feedback{StatusCode: 503, Latency: latency, Err: errors.New("extraction failed")})
+				p.countRevisit(&p.metrics.PagesNew)
+			}
+			if manager != nil {
+				if err := manager.StorePage(key, page); err != nil {
+					// Only caching failed: the fetched page is still processed.
+					p.mutex.Lock()
+					p.metrics.CacheErrors++
+					p.mutex.Unlock()
 				}
-				p.updateStageMetrics("extraction", false)
-				if p.shouldRetry(task) {
-					delay := p.backoffDelay(task.attempt + 1)
-					p.scheduleRetry(task.url, task.attempt+1, delay)
-					continue
+			}
+			releaseSlot()
+			if !p.forwardToProcessing(pageTask{page: page, depth: task.depth, key: key}, "extraction") {
+				return
+			}
+		} else {
+			releaseSlot()
+			p.updateStageMetrics("extraction", false)
+			if isRetryableFetchError(fetchErr) && p.shouldRetry(task) {
+				delay := p.backoffDelay(task.attempt + 1)
+				if feedback.RetryAfter > delay {
+					delay = min(feedback.RetryAfter, p.config.RetryMaxDelay)
 				}
-				p.sendErrorResult(task.url, "extraction", fmt.Sprintf("failed after %d attempts", task.attempt+1), false)
+				p.scheduleRetry(extractionTask{url: task.url, attempt: task.attempt + 1, depth: task.depth}, delay)
+				continue
 			}
-		case <-p.ctx.Done():
-			return
+			p.failExtraction(task, fmt.Errorf("failed after %d attempts: %w", task.attempt+1, fetchErr), task.attempt+1)
 		}
 	}
 }
-func (p *Pipeline) processingWorker() {
-	defer p.wg.Done()
-	defer p.processingWG.Done()
+func (p *Pipeline) processingWorker(wctx context.Context, act *activity) {
 	for {
+		act.set(false)
 		select {
-		case page, ok := <-p.processingQueue:
+		case task, ok := <-p.processingQueue:
 			if !ok {
 				return
 			}
-			result := p.processContent(page)
+			act.set(true)
+			if dup := p.collapseCanonical(task.page); dup != nil {
+				p.deliverResult(dup)
+				p.updateStageMetrics("duplicate", true)
+				continue
+			}
+			var result *models.CrawlResult
+			if task.unchanged {
+				result = &models.CrawlResult{URL: pageURL(task.page), Page: task.page, Success: true, Stage: "processing", Unchanged: true}
+			} else {
+				result = p.processContent(task.page)
+				if !result.Success {
+					p.deliverResult(result)
+					p.updateStageMetrics("processing", false)
+					continue
+				}
+				p.rememberPage(task.key, result.Page)
+			}
+			p.followLinks(result.Page, task.depth)
 			select {
 			case p.outputQueue <- result:
 				p.updateStageMetrics("processing", result.Success)
 			case <-p.ctx.Done():
 				return
 			}
-		case <-p.ctx.Done():
+		case <-wctx.Done():
 			return
 		}
 	}
 }
-func (p *Pipeline) outputWorker() {
-	defer p.wg.Done()
-	defer p.outputWG.Done()
+func (p *Pipeline) outputWorker(wctx context.Context, act *activity) {
 	for {
+		act.set(false)
 		select {
 		case result, ok := <-p.outputQueue:
 			if !ok {
 				return
 			}
+			act.set(true)
 			result.Stage = "output"
+			if err := p.writeSinks(result.Page); err != nil {
+				result = &models.CrawlResult{URL: result.URL, Page: result.Page, Error: models.NewCrawlError(result.URL, "output", err), Stage: "output"}
+			}
 			if !p.deliverResult(result) {
 				return
 			}
 			p.updateStageMetrics("output", result.Success)
-		case <-p.ctx.Done():
+		case <-wctx.Done():
 			return
 		}
 	}
 }
-func (p *Pipeline) isValidURL(u string) bool { return u != "" && u != "invalid-url" }
-func (p *Pipeline) extractContent(rawURL string) *models.Page {
-	if strings.Contains(rawURL, "fail-extraction") {
-		time.Sleep(5 * time.Millisecond)
+
+// collapseCanonical applies rel=canonical de-duplication to a fetched page. A page
+// whose canonical URL is new is rewritten to that URL (keeping its fetch URL as an
+// alias); a page whose canonical URL was already admitted is returned as a
+// "duplicate" result that skips processing, sinks and link following. Aliases seen
+// before the canonical page is processed are attached to it.
+func (p *Pipeline) collapseCanonical(page *models.Page) *models.CrawlResult {
+	if page == nil || page.URL == nil {
 		return nil
 	}
-	if strings.Contains(rawURL, "slow") {
-		time.Sleep(50 * time.Millisecond)
+	fetched := page.URL.String()
+	declared := page.Metadata.Canonical
+	if declared != "" && !p.config.IgnoreRelCanonical {
+		adopted, ok := p.frontier.claimCanonical(fetched, declared)
+		if ok && !adopted {
+			return &models.CrawlResult{URL: fetched, Page: page, Success: true, Stage: "duplicate"}
+		}
+		if adopted {
+			if cu, err := url.Parse(declared); err == nil {
+				page.URL = cu
+			}
+		}
+	}
+	page.Aliases = p.frontier.aliasesOf(page.URL.String())
+	return nil
+}
+
+// writeSinks hands a processed page to every configured sink, stopping at the first error.
+func (p *Pipeline) writeSinks(page *models.Page) error {
+	for _, sink := range p.config.OutputSinks {
+		if err := sink.Write(p.ctx, page); err != nil {
+			return fmt.Errorf("output sink %s: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
+
+// followLinks admits a processed page's links to the frontier. It runs before the
+// page's own result is emitted so the outstanding count never drops to zero while
+// children are still being admitted.
+func (p *Pipeline) followLinks(page *models.Page, depth int) {
+	if page == nil {
+		return
+	}
+	for _, link := range page.Links {
+		p.frontier.follow(link, depth)
+	}
+}
+
+func (p *Pipeline) isValidURL(u string) bool { return u != "" && u != "invalid-url" }
+
+// extractContent fetches rawURL through the configured Fetcher and derives the
+// rate limiter feedback from the outcome. Non-2xx responses carry their status code
+// (and Retry-After) without being reported as transport errors so that, e.g., a 404
+// does not count against the host's circuit breaker.
+func (p *Pipeline) extractContent(rawURL string, v crawler.Validators) (*models.Page, intrat.Feedback, error) {
+	start := time.Now()
+	var page *models.Page
+	var err error
+	if cf, ok := p.fetcher.(conditionalFetcher); ok && (v.ETag != "" || v.LastModified != "") {
+		page, err = cf.FetchConditional(p.ctx, rawURL, v)
 	} else {
-		time.Sleep(10 * time.Millisecond)
+		page, err = p.fetcher.Fetch(p.ctx, rawURL)
+	}
+	feedback := intrat.Feedback{Latency: time.Since(start)}
+	if err != nil {
+		var httpErr *crawler.HTTPError
+		if errors.As(err, &httpErr) {
+			feedback.StatusCode = httpErr.StatusCode
+			feedback.RetryAfter = httpErr.RetryAfter
+		} else {
+			feedback.Err = err
+		}
+		return nil, feedback, err
 	}
-	page := &models.Page{Title: "Test Page", Content: "<h1>Test Content</h1>"}
-	if parsed, err := url.Parse(rawURL); err == nil {
-		page.URL = parsed
+	if page == nil {
+		err = errors.New("fetcher returned no page")
+		feedback.Err = err
+		return nil, feedback, err
+	}
+	feedback.StatusCode = http.StatusOK
+	if page.URL == nil {
+		if parsed, perr := url.Parse(rawURL); perr == nil {
+			page.URL = parsed
+		}
+	}
+	if page.CrawledAt.IsZero() {
+		page.CrawledAt = time.Now()
+	}
+	return page, feedback, nil
+}
+
+// conditionalFetcher is implemented by fetchers that can revalidate a page with
+// recorded validators (crawler.PageFetcher). Others always fetch in full.
+type conditionalFetcher interface {
+	FetchConditional(ctx context.Context, rawURL string, v crawler.Validators) (*models.Page, error)
+}
+
+func isNotModified(err error) bool {
+	var httpErr *crawler.HTTPError
+	return errors.As(err, &httpErr) && httpErr.NotModified()
+}
+
+// validatorsFor returns the validators recorded for key by an earlier run.
+func (p *Pipeline) validatorsFor(key string) (crawler.Validators, bool) {
+	if p.config.Revisit == nil {
+		return crawler.Validators{}, false
+	}
+	v, ok := p.config.Revisit.Get(key)
+	if !ok {
+		return crawler.Validators{}, false
+	}
+	return crawler.Validators{ETag: v.ETag, LastModified: v.LastModified}, true
+}
+
+// loadProcessed returns the processed page kept for key, or nil when there is none.
+func (p *Pipeline) loadProcessed(key string) *models.Page {
+	if p.resourceManager == nil {
+		return nil
+	}
+	page, ok, err := p.resourceManager.LoadProcessed(key)
+	if err != nil {
+		p.countRevisit(&p.metrics.RevisitErrors)
+		return nil
+	}
+	if !ok {
+		return nil
 	}
-	page.CrawledAt = time.Now()
 	return page
 }
+
+// rememberPage keeps a freshly processed page and its response validators so the next
+// run can revalidate it. Pages without validators drop any stale record.
+func (p *Pipeline) rememberPage(key string, page *models.Page) {
+	store := p.config.Revisit
+	if store == nil || key == "" || page == nil || p.resourceManager == nil {
+		return
+	}
+	v := revisit.Validators{ETag: headerValue(page.Metadata.Headers, "ETag"), LastModified: headerValue(page.Metadata.Headers, "Last-Modified")}
+	if v.Empty() {
+		if _, ok := store.Get(key); ok {
+			if err := store.Put(key, v); err != nil {
+				p.countRevisit(&p.metrics.RevisitErrors)
+			}
+		}
+		return
+	}
+	if err := p.resourceManager.StoreProcessed(key, page); err != nil {
+		p.countRevisit(&p.metrics.RevisitErrors)
+		return
+	}
+	if err := store.Put(key, v); err != nil {
+		p.countRevisit(&p.metrics.RevisitErrors)
+	}
+}
+
+func (p *Pipeline) countRevisit(counter *int) {
+	if p.config.Revisit == nil {
+		return
+	}
+	p.mutex.Lock()
+	*counter++
+	p.mutex.Unlock()
+}
+
+func headerValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// isRetryableFetchError reports whether a failed fetch is worth another attempt:
+// transport failures and temporary HTTP statuses (408, 429, 5xx) are, other HTTP
+// statuses are final.
+func isRetryableFetchError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *crawler.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Temporary()
+	}
+	return true
+}
 func (p *Pipeline) processContent(page *models.Page) *models.CrawlResult {
-	time.Sleep(5 * time.Millisecond)
 	var processedPage *models.Page
 	if page != nil {
 		page.ProcessedAt = time.Now()
 		processedPage = page
+		if err := p.extractPageContent(page); err != nil {
+			u := pageURL(page)
+			return &models.CrawlResult{URL: u, Page: page, Error: models.NewCrawlError(u, "processing", err), Stage: "processing"}
+		}
+		for _, proc := range p.config.Processors {
+			out, err := proc.Process(p.ctx, processedPage)
+			if err == nil && out == nil {
+				err = fmt.Errorf("processor %T returned no page", proc)
+			}
+			if err != nil {
+				u := pageURL(processedPage)
+				return &models.CrawlResult{URL: u, Page: processedPage, Error: models.NewCrawlError(u, "processing", err), Stage: "processing"}
+			}
+			processedPage = out
+		}
 		if p.config.AssetProcessingHook != nil {
 			ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
 			mutated, err := p.config.AssetProcessingHook(ctx, processedPage)
@@ -573,29 +1314,138 @@ func (p *Pipeline) processContent(page *models.Page) *models.CrawlResult {
 				processedPage = mutated
 			}
 		}
+		processedPage.ContentHash = runs.ContentHash(processedPage.CleanedText, processedPage.Markdown)
+	}
+	return &models.CrawlResult{URL: pageURL(processedPage), Page: processedPage, Success: true, Stage: "processing"}
+}
+
+// extractPageContent runs the built-in content processing on page, failing pages that
+// score below ContentConfig.MinQuality. Pages without content pass through.
+func (p *Pipeline) extractPageContent(page *models.Page) error {
+	if p.content == nil || strings.TrimSpace(page.Content) == "" {
+		return nil
 	}
-	resultURL := ""
-	if processedPage != nil && processedPage.URL != nil {
-		resultURL = processedPage.URL.String()
+	if err := p.content.ProcessPage(page, pageURL(page)); err != nil {
+		return err
 	}
-	return &models.CrawlResult{URL: resultURL, Page: processedPage, Success: true, Stage: "processing"}
+	if minQuality := p.config.Content.MinQuality; minQuality > 0 && page.Quality != nil && page.Quality.Score < minQuality {
+		return fmt.Errorf("content quality %.2f below minimum %.2f: %s", page.Quality.Score, minQuality, strings.Join(page.Quality.Issues, ", "))
+	}
+	return nil
+}
+
+func pageURL(page *models.Page) string {
+	if page == nil || page.URL == nil {
+		return ""
+	}
+	return page.URL.String()
 }
 func (p *Pipeline) sendErrorResult(u, stage, msg string, retry bool) {
-	result := &models.CrawlResult{URL: u, Error: models.NewCrawlError(u, stage, errors.New(msg)), Success: false, Stage: stage, Retry: retry}
+	p.sendFailure(u, stage, errors.New(msg), 0, retry)
+}
+
+// sendFailure delivers a failed result wrapping err so callers can inspect the cause.
+// attempts is the number of fetches made for the URL.
+func (p *Pipeline) sendFailure(u, stage string, err error, attempts int, retry bool) {
+	ce := newFailure(u, stage, err, attempts)
+	result := &models.CrawlResult{URL: u, Error: ce, Success: false, Stage: stage, Retry: retry}
 	p.deliverResult(result)
 }
+
+func newFailure(u, stage string, err error, attempts int) *models.CrawlError {
+	ce := models.NewCrawlError(u, stage, err)
+	ce.Attempts = attempts
+	classifyFetchError(ce)
+	return ce
+}
+
+// failExtraction delivers the final failure of task and records it as a dead letter.
+func (p *Pipeline) failExtraction(task extractionTask, err error, attempts int) {
+	p.failTask(task, "extraction", err, attempts)
+}
+
+// failTask delivers the final failure of task at stage and records it as a dead
+// letter.
+func (p *Pipeline) failTask(task extractionTask, stage string, err error, attempts int) {
+	ce := newFailure(task.url, stage, err, attempts)
+	if store := p.config.DeadLetters; store != nil {
+		err := store.Add(p.config.Canonicalizer.Key(task.url), deadletter.Entry{
+			URL: task.url, Depth: task.depth, Stage: ce.Stage, Code: ce.Code, StatusCode: ce.StatusCode,
+			Attempts: attempts, Error: ce.Error(),
+		})
+		if err != nil {
+			p.countDeadLetter(&p.metrics.DeadLetterErrors)
+		} else {
+			p.countDeadLetter(&p.metrics.DeadLettered)
+		}
+	}
+	p.deliverResult(&models.CrawlResult{URL: task.url, Error: ce, Success: false, Stage: stage})
+}
+
+// clearDeadLetter removes the URL of a successful result (and its aliases) from the
+// dead-letter store.
+func (p *Pipeline) clearDeadLetter(result *models.CrawlResult) {
+	store := p.config.DeadLetters
+	if store == nil {
+		return
+	}
+	urls := []string{result.URL}
+	if result.Page != nil {
+		urls = append(urls, pageURL(result.Page))
+		urls = append(urls, result.Page.Aliases...)
+	}
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		ok, err := store.Resolve(p.config.Canonicalizer.Key(u))
+		switch {
+		case err != nil:
+			p.countDeadLetter(&p.metrics.DeadLetterErrors)
+		case ok:
+			p.countDeadLetter(&p.metrics.DeadLetterCleared)
+		}
+	}
+}
+
+func (p *Pipeline) countDeadLetter(counter *int) {
+	p.mutex.Lock()
+	*counter++
+	p.mutex.Unlock()
+}
+
+// classifyFetchError refines the classification of ce with the pipeline's own error
+// types, which the models package cannot see.
+func classifyFetchError(ce *models.CrawlError) {
+	var httpErr *crawler.HTTPError
+	switch {
+	case errors.As(ce.Err, &httpErr):
+		ce.StatusCode = httpErr.StatusCode
+		ce.Code = models.ErrorCodeHTTP4xx
+		if httpErr.StatusCode >= 500 {
+			ce.Code = models.ErrorCodeHTTP5xx
+		}
+		ce.Retryable = httpErr.Temporary()
+	case errors.Is(ce.Err, intrat.ErrCircuitOpen):
+		ce.Code, ce.Retryable = models.ErrorCodeCircuitOpen, true
+	case errors.Is(ce.Err, robots.ErrDisallowed):
+		ce.Code, ce.Retryable = models.ErrorCodeRobotsDenied, errors.Is(ce.Err, robots.ErrUnavailable)
+	case errors.Is(ce.Err, scope.ErrOutOfScope):
+		ce.Code, ce.Retryable = models.ErrorCodeOutOfScope, false
+	}
+}
 func (p *Pipeline) updateStageMetrics(stage string, success bool) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	m := p.metrics.StageMetrics[stage]
 	if success {
 		m.Processed++
-		if stage != "cache" {
+		if stage != "cache" && stage != "robots" && stage != "duplicate" {
 			p.metrics.TotalProcessed++
 		}
 	} else {
 		m.Failed++
-		if stage != "cache" {
+		if stage != "cache" && stage != "robots" && stage != "scope" {
 			p.metrics.TotalFailed++
 		}
 	}
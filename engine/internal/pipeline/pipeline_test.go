@@ -7,7 +7,7 @@ import (
 )
 
 func TestPipelineDataFlow_Migrated(t *testing.T){
-    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:10}
+    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:10, Fetcher: simulatedFetcher{}}
     p := NewPipeline(config); defer p.Stop()
     urls := []string{"https://example.com/page1","https://example.com/page2"}
     ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second); defer cancel()
@@ -43,7 +43,7 @@ func (permitStub) Release() {}
 
 func TestPipelineRateLimiterIntegration(t *testing.T) {
 	lim := newStubLimiter()
-	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RateLimiter: lim, RetryBaseDelay: 1 * time.Millisecond, RetryMaxDelay: 2 * time.Millisecond, RetryMaxAttempts: 3}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, Fetcher: simulatedFetcher{}, RateLimiter: lim, RetryBaseDelay: 1 * time.Millisecond, RetryMaxDelay: 2 * time.Millisecond, RetryMaxAttempts: 3}
 	pl := NewPipeline(cfg)
 	defer pl.Stop()
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -6,7 +6,7 @@ import (
 )
 
 func TestPipelineResultCounting_Migrated(t *testing.T){
-    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:2}
+    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:2, Fetcher: simulatedFetcher{}}
     p := NewPipeline(config); defer p.Stop()
     urls := []string{"https://example.com/test"}
     ctx := context.Background()
@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/deadletter"
+	engratelimit "github.com/99souls/ariadne/engine/internal/ratelimit"
+	"github.com/99souls/ariadne/engine/internal/robots"
+	"github.com/99souls/ariadne/engine/internal/testutil/httpmock"
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// ceilingLimiter records crawl-delay ceilings on top of recordingLimiter.
+type ceilingLimiter struct {
+	recordingLimiter
+	mu       sync.Mutex
+	ceilings map[string]float64
+}
+
+func (c *ceilingLimiter) SetDomainCeiling(domain string, rps float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ceilings == nil {
+		c.ceilings = map[string]float64{}
+	}
+	c.ceilings[domain] = rps
+}
+
+var _ engratelimit.RateLimiter = (*ceilingLimiter)(nil)
+
+func TestPipelineRobotsExclusion(t *testing.T) {
+	srv := httpmock.NewServer([]httpmock.RouteSpec{
+		{Pattern: "^/robots.txt$", Regex: true, Body: "User-agent: *\nDisallow: /private\nCrawl-delay: 4\n"},
+		{Pattern: "^/(public|private)$", Regex: true, Body: "<html><body>ok</body></html>"},
+	})
+	defer srv.Close()
+
+	lim := &ceilingLimiter{}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RateLimiter: lim, Robots: &robots.Config{}}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stages := map[string]string{}
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL() + "/public", srv.URL() + "/private"}) {
+		stages[r.URL] = r.Stage
+		if r.Stage == "robots" && (r.Success || !errors.Is(r.Error, robots.ErrDisallowed) || r.Error.Code != models.ErrorCodeRobotsDenied || !errors.Is(r.Error, models.ErrURLNotAllowed)) {
+			t.Fatalf("expected robots exclusion error, got %+v", r)
+		}
+	}
+	if stages[srv.URL()+"/public"] != "output" || stages[srv.URL()+"/private"] != "robots" {
+		t.Fatalf("unexpected stages: %v", stages)
+	}
+	if len(lim.all()) != 1 {
+		t.Fatalf("excluded URL must not be fetched, got %d fetch feedbacks", len(lim.all()))
+	}
+	u, _ := url.Parse(srv.URL())
+	lim.mu.Lock()
+	got := lim.ceilings[u.Host]
+	lim.mu.Unlock()
+	if got != 0.25 {
+		t.Fatalf("expected crawl-delay ceiling 0.25 rps, got %v", got)
+	}
+	if m := pl.Metrics(); m.TotalFailed != 0 {
+		t.Fatalf("robots exclusions must not count as failures, got %d", m.TotalFailed)
+	}
+}
+
+func TestPipelineRobotsUnavailableIsRetried(t *testing.T) {
+	var robotsCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			if robotsCalls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("User-agent: *\nAllow: /\n"))
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer srv.Close()
+
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RetryMaxAttempts: 3, RetryBaseDelay: time.Millisecond, Robots: &robots.Config{ErrorTTL: 50 * time.Millisecond}}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var results []*models.CrawlResult
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL + "/page"}) {
+		results = append(results, r)
+	}
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected the page fetched once robots.txt recovered, got %+v", results)
+	}
+	if n := robotsCalls.Load(); n != 2 {
+		t.Fatalf("expected robots.txt refetched after ErrorTTL, got %d fetches", n)
+	}
+
+	// Without attempts left the denial is reported, flagged as retryable, and
+	// dead-lettered.
+	robotsCalls.Store(0)
+	store, err := deadletter.Open(filepath.Join(t.TempDir(), "dead-letters.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	cfg = &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 1, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, RetryMaxAttempts: 1, Robots: &robots.Config{}, DeadLetters: store}
+	pl2 := NewPipeline(cfg)
+	defer pl2.Stop()
+	var n int
+	for r := range pl2.ProcessURLs(ctx, []string{srv.URL + "/page"}) {
+		n++
+		if r.Success || r.Stage != "robots" || r.Error.Code != models.ErrorCodeRobotsDenied || !r.Error.Retryable || !errors.Is(r.Error, robots.ErrUnavailable) || r.Error.Attempts != 1 {
+			t.Fatalf("expected a retryable robots failure, got %+v", r)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("expected one result, got %d", n)
+	}
+	letters := store.Entries(deadletter.Filter{Codes: []models.ErrorCode{models.ErrorCodeRobotsDenied}})
+	if len(letters) != 1 || letters[0].URL != srv.URL+"/page" || letters[0].Attempts != 1 {
+		t.Fatalf("expected the URL dead-lettered after its last attempt, got %+v", letters)
+	}
+}
+
+func TestPipelineCrawlDelayWithoutRateLimiter(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nCrawl-delay: 0.1\n"))
+			return
+		}
+		mu.Lock()
+		fetched = append(fetched, time.Now())
+		mu.Unlock()
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer srv.Close()
+
+	cfg := &PipelineConfig{DiscoveryWorkers: 2, ExtractionWorkers: 4, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 8, Robots: &robots.Config{}}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for r := range pl.ProcessURLs(ctx, []string{srv.URL + "/1", srv.URL + "/2", srv.URL + "/3"}) {
+		if !r.Success {
+			t.Fatalf("unexpected failure %+v", r)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fetched) != 3 {
+		t.Fatalf("expected 3 fetches, got %d", len(fetched))
+	}
+	for i := 1; i < len(fetched); i++ {
+		if gap := fetched[i].Sub(fetched[i-1]); gap < 90*time.Millisecond {
+			t.Fatalf("Crawl-delay ignored without a rate limiter: fetches %d and %d %v apart", i-1, i, gap)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	intrat "github.com/99souls/ariadne/engine/internal/ratelimit"
+)
+
+// tryAcquirer is implemented by rate limiters that can grant a permit without
+// blocking (intrat.AdaptiveRateLimiter). With other limiters the scheduler treats every
+// host as ready and workers wait in Acquire as before.
+type tryAcquirer interface {
+	TryAcquire(domain string) (intrat.Permit, time.Duration, error)
+}
+
+// hostScheduler holds the tasks waiting for an extraction worker in one ready queue
+// per host and hands them out round-robin, skipping hosts the rate limiter is holding
+// back. A throttled host therefore waits in its own queue instead of tying up workers
+// while other hosts sit idle.
+//
+// Tasks of ready hosts leave almost at once, so the queued tasks are mostly those of
+// throttled hosts. At most capacity tasks are queued; push blocks beyond that unless
+// the task's host has nothing queued, so every host with pending work stays eligible.
+type hostScheduler struct {
+	mu       sync.Mutex
+	hosts    map[string]*hostQueue
+	ring     []*hostQueue // hosts with queued tasks, in round-robin order
+	cursor   int          // ring index tried first by the next call to next
+	size     int
+	capacity int
+	closed   bool
+	// paused holds every queued task back until resumed (Pipeline.Pause).
+	paused bool
+	// changed is closed and replaced whenever a task is queued or taken, or the
+	// scheduler is closed.
+	changed chan struct{}
+	limiter tryAcquirer
+	// paced holds the robots.txt Crawl-delay of hosts that declared one and when each
+	// was last handed out; it outlives the host queues so the delay spans bursts.
+	paced map[string]*hostPace
+}
+
+type hostPace struct {
+	delay time.Duration
+	last  time.Time
+}
+
+// schedulerSlotsPerBuffer sizes the scheduler relative to PipelineConfig.BufferSize.
+// The scheduler must hold the backlog of throttled hosts: while it is full a discovery
+// worker blocks on the next throttled task and the ready hosts behind it wait.
+const schedulerSlotsPerBuffer = 64
+
+type hostQueue struct {
+	host  string
+	tasks []extractionTask
+	// readyAt is when the limiter said the host may be requested again.
+	readyAt time.Time
+}
+
+// scheduled is a task handed to a worker. When acquired is set the scheduler already
+// asked the limiter: permit (possibly nil) is the worker's to release and err is the
+// limiter's refusal, e.g. an open circuit.
+type scheduled struct {
+	task     extractionTask
+	permit   intrat.Permit
+	err      error
+	acquired bool
+}
+
+func newHostScheduler(capacity int, limiter intrat.RateLimiter) *hostScheduler {
+	s := &hostScheduler{hosts: make(map[string]*hostQueue), capacity: max(capacity, 1), changed: make(chan struct{}), paced: make(map[string]*hostPace)}
+	s.limiter, _ = limiter.(tryAcquirer)
+	return s
+}
+
+func (s *hostScheduler) signalLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// push queues t on its host's ready queue. It returns false when ctx is done or the
+// scheduler was closed.
+func (s *hostScheduler) push(ctx context.Context, t extractionTask) bool {
+	host := extractDomain(t.url)
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return false
+		}
+		q := s.hosts[host]
+		if q == nil || s.size < s.capacity {
+			if q == nil {
+				q = &hostQueue{host: host}
+				s.hosts[host] = q
+				s.ring = append(s.ring, q)
+			}
+			q.tasks = append(q.tasks, t)
+			s.size++
+			s.signalLocked()
+			s.mu.Unlock()
+			return true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+// setCrawlDelay spaces the tasks handed out for host by at least delay, whatever the
+// rate limiter allows; zero removes the delay.
+func (s *hostScheduler) setCrawlDelay(host string, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pace := s.paced[host]
+	switch {
+	case delay <= 0:
+		delete(s.paced, host)
+	case pace == nil:
+		s.paced[host] = &hostPace{delay: delay}
+	default:
+		pace.delay = delay
+	}
+}
+
+// setPaused stops or restarts handing out tasks; push keeps queuing while paused.
+func (s *hostScheduler) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused != paused {
+		s.paused = paused
+		s.signalLocked()
+	}
+}
+
+// next blocks until a task whose host may be requested now is queued and the
+// scheduler is not paused. Hosts are visited round-robin from where the previous call
+// left off; a host the limiter or its Crawl-delay holds back is skipped until its wait
+// elapsed. ok is false once ctx is done or the scheduler was closed and drained.
+func (s *hostScheduler) next(ctx context.Context) (sc scheduled, ok bool) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		if ctx.Err() != nil {
+			return scheduled{}, false
+		}
+		s.mu.Lock()
+		if s.closed && s.size == 0 {
+			s.mu.Unlock()
+			return scheduled{}, false
+		}
+		now := time.Now()
+		var wake time.Time
+		for i := 0; i < len(s.ring) && !s.paused; i++ {
+			idx := (s.cursor + i) % len(s.ring)
+			q := s.ring[idx]
+			if now.Before(q.readyAt) {
+				if wake.IsZero() || q.readyAt.Before(wake) {
+					wake = q.readyAt
+				}
+				continue
+			}
+			pace := s.paced[q.host]
+			if pace != nil && now.Before(pace.last.Add(pace.delay)) {
+				q.readyAt = pace.last.Add(pace.delay)
+				if wake.IsZero() || q.readyAt.Before(wake) {
+					wake = q.readyAt
+				}
+				continue
+			}
+			var sc scheduled
+			if s.limiter != nil && q.host != "" {
+				permit, wait, err := s.limiter.TryAcquire(q.host)
+				if err == nil && wait > 0 {
+					q.readyAt = now.Add(wait)
+					if wake.IsZero() || q.readyAt.Before(wake) {
+						wake = q.readyAt
+					}
+					continue
+				}
+				sc.permit, sc.err, sc.acquired = permit, err, true
+			}
+			if pace != nil {
+				pace.last = now
+			}
+			sc.task = s.popLocked(idx)
+			s.mu.Unlock()
+			return sc, true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		var expired <-chan time.Time
+		if !wake.IsZero() {
+			if timer == nil {
+				timer = time.NewTimer(time.Until(wake))
+			} else {
+				timer.Reset(time.Until(wake))
+			}
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return scheduled{}, false
+		case <-changed:
+		case <-expired:
+		}
+	}
+}
+
+// popLocked takes the head of ring[idx] and moves the cursor to the host after it.
+func (s *hostScheduler) popLocked(idx int) extractionTask {
+	q := s.ring[idx]
+	t := q.tasks[0]
+	q.tasks[0] = extractionTask{}
+	q.tasks = q.tasks[1:]
+	s.size--
+	if len(q.tasks) == 0 {
+		delete(s.hosts, q.host)
+		copy(s.ring[idx:], s.ring[idx+1:])
+		s.ring[len(s.ring)-1] = nil
+		s.ring = s.ring[:len(s.ring)-1]
+		s.cursor = idx
+	} else {
+		s.cursor = idx + 1
+	}
+	if len(s.ring) > 0 {
+		s.cursor %= len(s.ring)
+	} else {
+		s.cursor = 0
+	}
+	s.signalLocked()
+	return t
+}
+
+// close makes next return false once the queued tasks are taken and push fail.
+func (s *hostScheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.signalLocked()
+	}
+}
+
+// queued returns the number of queued tasks.
+func (s *hostScheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// ready returns the number of queued tasks whose host the limiter is not known to
+// hold back.
+func (s *hostScheduler) ready() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now, n := time.Now(), 0
+	for _, q := range s.ring {
+		if !now.Before(q.readyAt) {
+			n += len(q.tasks)
+		}
+	}
+	return n
+}
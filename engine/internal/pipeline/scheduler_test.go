@@ -0,0 +1,288 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	engratelimit "github.com/99souls/ariadne/engine/internal/ratelimit"
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// pacedLimiter grants one request per gap to the throttled host and lets every other
+// host through.
+type pacedLimiter struct {
+	recordingLimiter
+	throttled string
+	gap       time.Duration
+	mu        sync.Mutex
+	next      time.Time
+}
+
+func (l *pacedLimiter) TryAcquire(domain string) (engratelimit.Permit, time.Duration, error) {
+	if domain != l.throttled {
+		return permitStub{}, 0, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.next) {
+		return nil, l.next.Sub(now), nil
+	}
+	l.next = now.Add(l.gap)
+	return permitStub{}, 0, nil
+}
+
+func (l *pacedLimiter) Acquire(ctx context.Context, domain string) (engratelimit.Permit, error) {
+	for {
+		permit, wait, err := l.TryAcquire(domain)
+		if err != nil || wait <= 0 {
+			return permit, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// blockingLimiter hides TryAcquire so workers wait inside Acquire.
+type blockingLimiter struct{ engratelimit.RateLimiter }
+
+// instantFetcher returns a page without any delay.
+type instantFetcher struct{}
+
+func (instantFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Page{URL: u, Title: u.Path, Content: "<p>" + u.Path + "</p>"}, nil
+}
+
+// passProcessor returns pages unchanged (skipping the simulated processing delay).
+type passProcessor struct{}
+
+func (passProcessor) Process(ctx context.Context, page *models.Page) (*models.Page, error) {
+	return page, nil
+}
+
+func TestHostSchedulerRoundRobin(t *testing.T) {
+	s := newHostScheduler(8, &pacedLimiter{throttled: "c.test", gap: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, u := range []string{"https://a.test/1", "https://a.test/2", "https://a.test/3", "https://b.test/1", "https://c.test/1", "https://c.test/2"} {
+		if !s.push(ctx, extractionTask{url: u}) {
+			t.Fatalf("push %s failed", u)
+		}
+	}
+	var got []string
+	for i := 0; i < 5; i++ {
+		sc, ok := s.next(ctx)
+		if !ok || !sc.acquired || sc.permit == nil {
+			t.Fatalf("next %d: ok=%v %+v", i, ok, sc)
+		}
+		got = append(got, strings.TrimPrefix(sc.task.url, "https://"))
+	}
+	if want := "a.test/1 b.test/1 c.test/1 a.test/2 a.test/3"; strings.Join(got, " ") != want {
+		t.Fatalf("expected order %q, got %q", want, strings.Join(got, " "))
+	}
+	short, stop := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer stop()
+	if sc, ok := s.next(short); ok {
+		t.Fatalf("the throttled host must not be handed out, got %+v", sc)
+	}
+	if n := s.queued(); n != 1 {
+		t.Fatalf("expected the throttled task to stay queued, got %d", n)
+	}
+	s.close()
+	if s.push(ctx, extractionTask{url: "https://a.test/4"}) {
+		t.Fatalf("push after close must fail")
+	}
+}
+
+func TestHostSchedulerCapacity(t *testing.T) {
+	s := newHostScheduler(2, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, u := range []string{"https://a.test/1", "https://a.test/2", "https://b.test/1"} {
+		if !s.push(ctx, extractionTask{url: u}) {
+			t.Fatalf("push %s failed", u)
+		}
+	}
+	short, stop := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer stop()
+	if s.push(short, extractionTask{url: "https://a.test/3"}) {
+		t.Fatalf("push beyond capacity for a queued host must block")
+	}
+	pushed := make(chan bool)
+	go func() { pushed <- s.push(ctx, extractionTask{url: "https://a.test/3"}) }()
+	if sc, ok := s.next(ctx); !ok || sc.acquired {
+		t.Fatalf("next: ok=%v %+v", ok, sc)
+	}
+	if sc, ok := s.next(ctx); !ok || sc.task.url != "https://b.test/1" {
+		t.Fatalf("expected b.test next, got %+v", sc)
+	}
+	if !<-pushed {
+		t.Fatalf("push must resume once tasks were taken")
+	}
+}
+
+func TestHostSchedulerCrawlDelayWithoutLimiter(t *testing.T) {
+	s := newHostScheduler(8, nil)
+	s.setCrawlDelay("slow.test", 60*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, u := range []string{"https://slow.test/1", "https://slow.test/2", "https://fast.test/1", "https://slow.test/3"} {
+		if !s.push(ctx, extractionTask{url: u}) {
+			t.Fatalf("push %s failed", u)
+		}
+	}
+	var slow []time.Time
+	var order []string
+	for i := 0; i < 4; i++ {
+		sc, ok := s.next(ctx)
+		if !ok {
+			t.Fatalf("next %d failed", i)
+		}
+		order = append(order, strings.TrimPrefix(sc.task.url, "https://"))
+		if strings.HasPrefix(sc.task.url, "https://slow.test") {
+			slow = append(slow, time.Now())
+		}
+	}
+	if order[1] != "fast.test/1" {
+		t.Fatalf("a paced host must not hold back others, got %q", order)
+	}
+	for i := 1; i < len(slow); i++ {
+		if gap := slow[i].Sub(slow[i-1]); gap < 60*time.Millisecond {
+			t.Fatalf("slow.test requests %d and %d only %v apart", i-1, i, gap)
+		}
+	}
+}
+
+// gatedFetcher counts fetches and holds each one until release is closed.
+type gatedFetcher struct {
+	started atomic.Int32
+	release chan struct{}
+}
+
+func (f *gatedFetcher) Fetch(ctx context.Context, rawURL string) (*models.Page, error) {
+	f.started.Add(1)
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return instantFetcher{}.Fetch(ctx, rawURL)
+}
+
+func TestPipelinePauseStopsNewFetches(t *testing.T) {
+	fetcher := &gatedFetcher{release: make(chan struct{})}
+	cfg := &PipelineConfig{DiscoveryWorkers: 2, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 16, Fetcher: fetcher}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	var urls []string
+	for i := 0; i < 12; i++ {
+		urls = append(urls, fmt.Sprintf("https://host%d.test/", i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results := pl.ProcessURLs(ctx, urls)
+	for fetcher.started.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	pl.Pause()
+	// Let discovery hand the buffered URLs to the scheduler, then finish the two
+	// fetches in progress: nothing queued may be fetched until Resume.
+	time.Sleep(30 * time.Millisecond)
+	close(fetcher.release)
+	time.Sleep(100 * time.Millisecond)
+	if n := fetcher.started.Load(); n != 2 {
+		t.Fatalf("expected no fetch to start after Pause, %d started", n)
+	}
+	pl.Resume()
+	n := 0
+	for r := range results {
+		if !r.Success {
+			t.Fatalf("unexpected failure: %+v", r)
+		}
+		n++
+	}
+	if n != len(urls) {
+		t.Fatalf("expected %d results after Resume, got %d", len(urls), n)
+	}
+}
+
+func TestPipelineThrottledHostDoesNotStarveWorkers(t *testing.T) {
+	lim := &pacedLimiter{throttled: "throttled.test", gap: time.Hour}
+	cfg := &PipelineConfig{DiscoveryWorkers: 1, ExtractionWorkers: 2, ProcessingWorkers: 1, OutputWorkers: 1, BufferSize: 4, Fetcher: instantFetcher{}, RateLimiter: lim}
+	pl := NewPipeline(cfg)
+	defer pl.Stop()
+	var urls []string
+	for i := 0; i < 4; i++ {
+		urls = append(urls, fmt.Sprintf("https://throttled.test/%d", i))
+		urls = append(urls, fmt.Sprintf("https://fast%d.test/", i), fmt.Sprintf("https://fast%d.test/more", i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	results := pl.ProcessURLs(ctx, urls)
+	fast := 0
+	for r := range results {
+		if !r.Success {
+			t.Fatalf("unexpected failure: %+v", r)
+		}
+		if !strings.Contains(r.URL, "throttled.test") {
+			fast++
+		}
+		if fast == 8 {
+			break
+		}
+	}
+	if fast != 8 {
+		t.Fatalf("expected every fast host URL to complete while the throttled host waits, got %d", fast)
+	}
+}
+
+// BenchmarkPipelineThrottledHost crawls 8 fast hosts next to one host limited to a
+// request every 2ms, with workers that only take ready hosts (fair) and with workers
+// waiting inside the limiter (blocking). fast-pages/s is the fast hosts' throughput up
+// to their last result; ns/op is bounded below by the throttled host either way.
+func BenchmarkPipelineThrottledHost(b *testing.B) {
+	var urls []string
+	for i := 0; i < 64; i++ {
+		urls = append(urls, fmt.Sprintf("https://throttled.test/%d", i))
+		for h := 0; h < 4; h++ {
+			urls = append(urls, fmt.Sprintf("https://fast%d.test/%d", (i+h)%8, i))
+		}
+	}
+	for _, mode := range []string{"fair", "blocking"} {
+		b.Run(mode, func(b *testing.B) {
+			var fast int
+			var fastTime time.Duration
+			for i := 0; i < b.N; i++ {
+				var lim engratelimit.RateLimiter = &pacedLimiter{throttled: "throttled.test", gap: 2 * time.Millisecond}
+				if mode == "blocking" {
+					lim = blockingLimiter{lim}
+				}
+				pl := NewPipeline(&PipelineConfig{DiscoveryWorkers: 2, ExtractionWorkers: 4, ProcessingWorkers: 2, OutputWorkers: 2, BufferSize: 16, Fetcher: instantFetcher{}, Processors: []Processor{passProcessor{}}, RateLimiter: lim})
+				start := time.Now()
+				var last time.Duration
+				for r := range pl.ProcessURLs(context.Background(), urls) {
+					if !strings.Contains(r.URL, "throttled.test") {
+						fast++
+						last = time.Since(start)
+					}
+				}
+				fastTime += last
+				pl.Stop()
+			}
+			b.ReportMetric(float64(fast)/fastTime.Seconds(), "fast-pages/s")
+		})
+	}
+}
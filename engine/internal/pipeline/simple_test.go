@@ -7,7 +7,7 @@ import (
 )
 
 func TestSimplePipeline_Migrated(t *testing.T){
-    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:2}
+    config := &PipelineConfig{DiscoveryWorkers:1, ExtractionWorkers:1, ProcessingWorkers:1, OutputWorkers:1, BufferSize:2, Fetcher: simulatedFetcher{}}
     p := NewPipeline(config); defer p.Stop()
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second); defer cancel()
     results := p.ProcessURLs(ctx, []string{"https://example.com/test"})
@@ -0,0 +1,261 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/99souls/ariadne/engine/models"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Document is a page parsed once for ProcessPage. Steps transform Root in place and
+// record what they find; ProcessPage serializes the content once all steps ran.
+type Document struct {
+	Root *goquery.Document
+	// Base resolves relative URLs.
+	Base *url.URL
+	// Content holds the main content nodes once a step extracted them.
+	Content    []*html.Node
+	Extraction Extraction
+	Title      string
+	Meta       models.PageMeta
+	Images     []string
+	// Extracted is the record of the page's schema and SchemaErrors its violations.
+	Extracted    map[string]any
+	SchemaErrors []string
+}
+
+// NewDocument parses src for processing relative to baseURL.
+func NewDocument(src, baseURL string) (*Document, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	root, err := goquery.NewDocumentFromReader(strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Root: root, Base: base}, nil
+}
+
+// ContentNodes returns Content, or the children of the body before a step extracted
+// the main content.
+func (d *Document) ContentNodes() []*html.Node {
+	if d.Content != nil {
+		return d.Content
+	}
+	return bodyNodes(d.Root)
+}
+
+// Step is one stage of ProcessPage: a DOM transform or an extractor reading the
+// document into its fields.
+type Step interface {
+	Name() string
+	Apply(d *Document) error
+}
+
+type stepFunc struct {
+	name string
+	fn   func(*Document) error
+}
+
+func (s stepFunc) Name() string            { return s.name }
+func (s stepFunc) Apply(d *Document) error { return s.fn(d) }
+
+// StepFunc returns a Step named name running fn.
+func StepFunc(name string, fn func(*Document) error) Step { return stepFunc{name: name, fn: fn} }
+
+// DefaultSteps returns the steps ProcessPage runs when Steps is nil, in order: read
+// the title and metadata, extract the fields of the page's schema, remove noise,
+// resolve relative URLs, extract the main content and collect its images.
+func (cp *ContentProcessor) DefaultSteps() []Step {
+	return []Step{
+		StepFunc("metadata", extractMetadata),
+		StepFunc("fields", cp.extractFields),
+		StepFunc("clean", cp.clean),
+		StepFunc("resolve urls", resolveURLs),
+		StepFunc("extract", cp.extract),
+		StepFunc("images", extractImages),
+	}
+}
+
+var (
+	noiseTags      = []string{"script", "style", "nav", "footer", "aside", "header"}
+	noiseSelectors = []string{".advertisement", ".ad", ".ads", ".sidebar", ".nav", ".navigation", ".footer", ".header", "#comments", ".comments"}
+)
+
+// clean removes comments, noise elements, RemoveSelectors and tracking pixels.
+func (cp *ContentProcessor) clean(d *Document) error {
+	removeComments(d.Root.Get(0))
+	for _, tag := range noiseTags {
+		d.Root.Find(tag).Remove()
+	}
+	for _, selector := range noiseSelectors {
+		d.Root.Find(selector).Remove()
+	}
+	for _, selector := range cp.RemoveSelectors {
+		d.Root.Find(selector).Remove()
+	}
+	d.Root.Find("img[width='1'][height='1']").Remove()
+	return nil
+}
+
+func removeComments(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else {
+			removeComments(c)
+		}
+		c = next
+	}
+}
+
+// resolveURLs makes link targets and image sources absolute against Base.
+func resolveURLs(d *Document) error {
+	d.Root.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if strings.HasPrefix(href, "http") || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "mailto:") {
+			return
+		}
+		if abs, err := d.Base.Parse(href); err == nil {
+			s.SetAttr("href", abs.String())
+		}
+	})
+	d.Root.Find("img[src]").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "data:") {
+			return
+		}
+		if abs, err := d.Base.Parse(src); err == nil {
+			s.SetAttr("src", abs.String())
+		}
+	})
+	return nil
+}
+
+// extract runs Extractor (a SelectorExtractor over ContentSelectors when nil); when
+// it fails the whole body is the content.
+func (cp *ContentProcessor) extract(d *Document) error {
+	extractor := cp.Extractor
+	if extractor == nil {
+		extractor = &SelectorExtractor{Selectors: cp.ContentSelectors}
+	}
+	ext, err := extractor.Extract(d.Root)
+	if err != nil {
+		ext = Extraction{Nodes: bodyNodes(d.Root), Extractor: extractor.Name()}
+	}
+	d.Extraction, d.Content = ext, ext.Nodes
+	if d.Content == nil {
+		d.Content = []*html.Node{}
+	}
+	return nil
+}
+
+// extractImages collects the absolute sources of the content's images, skipping data
+// URLs and tracking pixels.
+func extractImages(d *Document) error {
+	d.Images = nil
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+			if src := imageSource(n, d.Base); src != "" {
+				d.Images = append(d.Images, src)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range d.ContentNodes() {
+		walk(n)
+	}
+	return nil
+}
+
+func imageSource(img *html.Node, base *url.URL) string {
+	src := attr(img, "src")
+	if src == "" || strings.HasPrefix(src, "data:") || (attr(img, "width") == "1" && attr(img, "height") == "1") {
+		return ""
+	}
+	if strings.HasPrefix(src, "http") {
+		return src
+	}
+	abs, err := base.Parse(src)
+	if err != nil {
+		return ""
+	}
+	return abs.String()
+}
+
+func bodyNodes(doc *goquery.Document) []*html.Node {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		return nil
+	}
+	return childNodes(body.Get(0))
+}
+
+func childNodes(n *html.Node) []*html.Node {
+	nodes := []*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+// renderNodes serializes nodes, trimming surrounding whitespace.
+func renderNodes(nodes []*html.Node) (string, error) {
+	var b strings.Builder
+	for _, n := range nodes {
+		if err := html.Render(&b, n); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// blockElements separate words in nodeText.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true, atom.Dd: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Hr: true, atom.Li: true,
+	atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true,
+	atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// nodeText returns the text of nodes with whitespace runs collapsed; block elements
+// separate words.
+func nodeText(nodes ...*html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if n.DataAtom == atom.Script || n.DataAtom == atom.Style {
+				return
+			}
+		}
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			b.WriteByte(' ')
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,455 @@
+package processor
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractMetadata reads the title (falling back to the first h1), description,
+// keywords, Open Graph and Twitter cards, schema.org JSON-LD and microdata, the
+// author, publish and modified dates, canonical URL, hreflang alternates and language.
+// Where sources disagree structured data wins over meta tags.
+func extractMetadata(d *Document) error {
+	doc := d.Root
+	meta := models.PageMeta{}
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	if title == "" {
+		title = strings.TrimSpace(doc.Find("h1").First().Text())
+	}
+	tags := metaTags(doc)
+	meta.Description = first(tags["description"], tags["og:description"], tags["twitter:description"])
+	if keywords := tags["keywords"]; keywords != "" {
+		for _, k := range strings.Split(keywords, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				meta.Keywords = append(meta.Keywords, k)
+			}
+		}
+	}
+	meta.OpenGraph = models.OpenGraphMeta{Title: tags["og:title"], Description: tags["og:description"], Image: resolve(d, tags["og:image"]), URL: tags["og:url"], Type: tags["og:type"], SiteName: tags["og:site_name"], Locale: tags["og:locale"]}
+	meta.Twitter = models.TwitterCardMeta{Card: tags["twitter:card"], Site: tags["twitter:site"], Creator: tags["twitter:creator"], Title: tags["twitter:title"], Description: tags["twitter:description"], Image: resolve(d, first(tags["twitter:image"], tags["twitter:image:src"]))}
+	meta.Structured = structuredData(d)
+
+	var article models.ArticleData
+	if meta.Structured != nil && len(meta.Structured.Articles) > 0 {
+		article = meta.Structured.Articles[0]
+	}
+	meta.Author = first(strings.Join(article.Authors, ", "), tags["author"], tags["article:author"], tags["dc.creator"], tags["parsely-author"], relAuthor(doc))
+	meta.PublishDate = firstDate(article.DatePublished, parseDate(tags["article:published_time"]), parseDate(tags["datepublished"]),
+		parseDate(tags["date"]), parseDate(tags["pubdate"]), parseDate(tags["publish-date"]), parseDate(tags["dc.date.issued"]),
+		parseDate(tags["dcterms.created"]), parseDate(doc.Find("time[pubdate]").First().AttrOr("datetime", "")))
+	meta.ModifiedDate = firstDate(article.DateModified, parseDate(tags["article:modified_time"]), parseDate(tags["og:updated_time"]),
+		parseDate(tags["datemodified"]), parseDate(tags["last-modified"]), parseDate(tags["dcterms.modified"]))
+	if canonical, ok := doc.Find("link[rel~='canonical']").First().Attr("href"); ok {
+		meta.Canonical = resolve(d, strings.TrimSpace(canonical))
+	}
+	doc.Find("link[rel~='alternate'][hreflang][href]").Each(func(_ int, s *goquery.Selection) {
+		href := resolve(d, strings.TrimSpace(s.AttrOr("href", "")))
+		if lang := strings.TrimSpace(s.AttrOr("hreflang", "")); lang != "" && href != "" {
+			meta.Alternates = append(meta.Alternates, models.AlternateLink{Lang: normalizeLanguage(lang), URL: href})
+		}
+	})
+	lang, _ := doc.Find("html").First().Attr("lang")
+	meta.Language = normalizeLanguage(first(lang, tags["content-language"], tags["og:locale"]))
+	d.Title, d.Meta = title, meta
+	return nil
+}
+
+// metaTags maps the lower-cased name, property, itemprop or http-equiv of each <meta>
+// to its trimmed content; the first occurrence wins.
+func metaTags(doc *goquery.Document) map[string]string {
+	tags := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		for _, key := range []string{"name", "property", "itemprop", "http-equiv"} {
+			if k := strings.ToLower(strings.TrimSpace(s.AttrOr(key, ""))); k != "" {
+				if _, seen := tags[k]; !seen {
+					tags[k] = content
+				}
+			}
+		}
+	})
+	return tags
+}
+
+func relAuthor(doc *goquery.Document) string {
+	return strings.TrimSpace(doc.Find("a[rel~='author']").First().Text())
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstDate(dates ...time.Time) time.Time {
+	for _, t := range dates {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// resolve makes ref absolute against the document's base URL.
+func resolve(d *Document, ref string) string {
+	if ref == "" || d.Base == nil {
+		return ref
+	}
+	abs, err := d.Base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return abs.String()
+}
+
+// dateLayouts are tried in order by parseDate.
+var dateLayouts = []string{
+	time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02T15:04Z07:00", "2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02", "2006/01/02", "20060102",
+	time.RFC1123, time.RFC1123Z, time.RFC850, time.ANSIC,
+	"January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006", "January 2006",
+}
+
+// parseDate normalizes the date formats pages use to UTC; unknown formats give the
+// zero time.
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// normalizeLanguage turns language tags such as "en_us" or "EN-gb" into "en-US" and
+// "en-GB".
+func normalizeLanguage(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	if parts[0] == "" {
+		return ""
+	}
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// structuredData collects the schema.org items of the page's JSON-LD scripts and
+// microdata and builds the typed views of those it recognizes.
+func structuredData(d *Document) *models.StructuredData {
+	var items []map[string]any
+	d.Root.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &v); err == nil {
+			items = append(items, jsonLDItems(v)...)
+		}
+	})
+	d.Root.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, nested := s.Attr("itemprop"); !nested {
+			items = append(items, microdataItem(d, s.Get(0)))
+		}
+	})
+	if len(items) == 0 {
+		return nil
+	}
+	sd := &models.StructuredData{Raw: items}
+	var walk func(map[string]any)
+	walk = func(item map[string]any) {
+		for _, t := range schemaTypes(item) {
+			switch t {
+			case "Article", "NewsArticle", "BlogPosting", "TechArticle", "ScholarlyArticle", "Report", "LiveBlogPosting":
+				sd.Articles = append(sd.Articles, articleData(t, item, d))
+			case "Product":
+				sd.Products = append(sd.Products, productData(item, d))
+			case "FAQPage":
+				for _, q := range values(item["mainEntity"]) {
+					if qm, ok := q.(map[string]any); ok {
+						if question := text(qm["name"]); question != "" {
+							sd.FAQ = append(sd.FAQ, models.FAQEntry{Question: question, Answer: text(values(qm["acceptedAnswer"]))})
+						}
+					}
+				}
+			case "BreadcrumbList":
+				sd.Breadcrumbs = append(sd.Breadcrumbs, breadcrumbList(item, d))
+			case "WebPage":
+				// Pages commonly nest their article or breadcrumbs.
+				for _, key := range []string{"mainEntity", "breadcrumb"} {
+					for _, v := range values(item[key]) {
+						if m, ok := v.(map[string]any); ok {
+							walk(m)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, item := range items {
+		walk(item)
+	}
+	return sd
+}
+
+// jsonLDItems flattens a decoded JSON-LD document (an object, an array or a @graph)
+// into its top-level items.
+func jsonLDItems(v any) []map[string]any {
+	switch x := v.(type) {
+	case []any:
+		var items []map[string]any
+		for _, e := range x {
+			items = append(items, jsonLDItems(e)...)
+		}
+		return items
+	case map[string]any:
+		if graph, ok := x["@graph"]; ok {
+			return jsonLDItems(graph)
+		}
+		return []map[string]any{x}
+	}
+	return nil
+}
+
+// microdataItem decodes the itemscope element n: "@type" is the last path segment of
+// its itemtype, and each itemprop below it (outside nested items) adds a value, a
+// nested item or a list of values.
+func microdataItem(d *Document, n *html.Node) map[string]any {
+	item := make(map[string]any)
+	if itemType := strings.Fields(attr(n, "itemtype")); len(itemType) > 0 {
+		item["@type"] = itemType[0][strings.LastIndex(itemType[0], "/")+1:]
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			_, scope := attrLookup(c, "itemscope")
+			if props := strings.Fields(attr(c, "itemprop")); len(props) > 0 {
+				var v any
+				if scope {
+					v = microdataItem(d, c)
+				} else {
+					v = microdataValue(d, c)
+				}
+				for _, prop := range props {
+					switch prev := item[prop].(type) {
+					case nil:
+						item[prop] = v
+					case []any:
+						item[prop] = append(prev, v)
+					default:
+						item[prop] = []any{prev, v}
+					}
+				}
+			}
+			if !scope {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return item
+}
+
+func microdataValue(d *Document, n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		return strings.TrimSpace(attr(n, "content"))
+	case atom.A, atom.Link, atom.Area:
+		return resolve(d, strings.TrimSpace(attr(n, "href")))
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Embed, atom.Iframe:
+		return resolve(d, strings.TrimSpace(attr(n, "src")))
+	case atom.Time:
+		if v, ok := attrLookup(n, "datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	case atom.Data, atom.Meter:
+		if v, ok := attrLookup(n, "value"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	if v, ok := attrLookup(n, "content"); ok {
+		return strings.TrimSpace(v)
+	}
+	return nodeText(n)
+}
+
+func attrLookup(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// schemaTypes returns the "@type" of item (a string or a list) without any
+// "schema:" or URL prefix.
+func schemaTypes(item map[string]any) []string {
+	var types []string
+	for _, v := range values(item["@type"]) {
+		if t, ok := v.(string); ok {
+			types = append(types, t[strings.LastIndexAny(t, "/:")+1:])
+		}
+	}
+	return types
+}
+
+// values returns v as a list: lists as they are, anything else as its only element.
+func values(v any) []any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return x
+	}
+	return []any{v}
+}
+
+// text reads a schema.org value loosely: strings and numbers as they are, items by
+// their name (or text, or @id, or url), lists by their first element.
+func text(v any) string {
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case []any:
+		for _, e := range x {
+			if s := text(e); s != "" {
+				return s
+			}
+		}
+	case map[string]any:
+		for _, key := range []string{"name", "text", "@id", "url", "contentUrl", "@value"} {
+			if s := text(x[key]); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func texts(v any) []string {
+	var out []string
+	for _, e := range values(v) {
+		if s := text(e); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func number(v any) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case []any, map[string]any:
+		return number(text(x))
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f
+	}
+	return 0
+}
+
+func articleData(typ string, item map[string]any, d *Document) models.ArticleData {
+	return models.ArticleData{
+		Type:          typ,
+		Headline:      first(text(item["headline"]), text(item["name"])),
+		Description:   text(item["description"]),
+		Authors:       texts(item["author"]),
+		Publisher:     text(item["publisher"]),
+		DatePublished: parseDate(text(item["datePublished"])),
+		DateModified:  parseDate(text(item["dateModified"])),
+		Image:         resolve(d, text(item["image"])),
+		URL:           resolve(d, first(text(item["url"]), text(item["mainEntityOfPage"]))),
+	}
+}
+
+func productData(item map[string]any, d *Document) models.ProductData {
+	p := models.ProductData{
+		Name:        text(item["name"]),
+		Description: text(item["description"]),
+		Brand:       text(item["brand"]),
+		SKU:         first(text(item["sku"]), text(item["mpn"]), text(item["gtin13"])),
+		Image:       resolve(d, text(item["image"])),
+	}
+	for _, o := range values(item["offers"]) {
+		om, ok := o.(map[string]any)
+		if !ok {
+			continue
+		}
+		// AggregateOffer lists its own offers; otherwise it stands for them.
+		if nested := values(om["offers"]); len(nested) > 0 {
+			for _, n := range nested {
+				if nm, ok := n.(map[string]any); ok {
+					p.Offers = append(p.Offers, offerData(nm, d))
+				}
+			}
+			continue
+		}
+		p.Offers = append(p.Offers, offerData(om, d))
+	}
+	if rating, ok := item["aggregateRating"].(map[string]any); ok {
+		p.RatingValue = number(rating["ratingValue"])
+		p.ReviewCount = int(number(first(text(rating["reviewCount"]), text(rating["ratingCount"]))))
+	}
+	return p
+}
+
+func offerData(o map[string]any, d *Document) models.OfferData {
+	availability := text(o["availability"])
+	return models.OfferData{
+		Price:         first(text(o["price"]), text(o["lowPrice"])),
+		PriceCurrency: text(o["priceCurrency"]),
+		Availability:  availability[strings.LastIndex(availability, "/")+1:],
+		URL:           resolve(d, text(o["url"])),
+	}
+}
+
+func breadcrumbList(item map[string]any, d *Document) models.BreadcrumbList {
+	var list models.BreadcrumbList
+	for i, e := range values(item["itemListElement"]) {
+		em, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		crumb := models.Breadcrumb{Position: int(number(em["position"])), Name: text(em["name"])}
+		if crumb.Position == 0 {
+			crumb.Position = i + 1
+		}
+		switch target := em["item"].(type) {
+		case string:
+			crumb.URL = resolve(d, strings.TrimSpace(target))
+		case map[string]any:
+			crumb.URL = resolve(d, first(text(target["@id"]), text(target["url"])))
+			crumb.Name = first(crumb.Name, text(target["name"]))
+		}
+		list.Items = append(list.Items, crumb)
+	}
+	sort.SliceStable(list.Items, func(i, j int) bool { return list.Items[i].Position < list.Items[j].Position })
+	return list
+}
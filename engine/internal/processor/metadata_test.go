@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+func TestProcessPageRichMetadata(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "metadata", "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &models.Page{Content: string(src), Metadata: models.PageMeta{Headers: map[string]string{"Last-Modified": "Tue, 05 Mar 2024 11:00:00 GMT"}}}
+	if err := NewContentProcessor().ProcessPage(p, "https://example.com/guides/cast-iron?ref=feed"); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	m := p.Metadata
+
+	if m.Author != "Ada Lovelace, Grace Hopper" {
+		t.Errorf("author from JSON-LD expected, got %q", m.Author)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !m.PublishDate.Equal(want) {
+		t.Errorf("publish date %v, want %v", m.PublishDate, want)
+	}
+	if want := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC); !m.ModifiedDate.Equal(want) {
+		t.Errorf("modified date %v, want %v", m.ModifiedDate, want)
+	}
+	if !reflect.DeepEqual(m.Keywords, []string{"cast iron", "cooking", "seasoning"}) {
+		t.Errorf("keywords %q", m.Keywords)
+	}
+	if m.Canonical != "https://example.com/guides/cast-iron" || m.Language != "en-GB" {
+		t.Errorf("canonical %q language %q", m.Canonical, m.Language)
+	}
+	wantAlt := []models.AlternateLink{{Lang: "de-DE", URL: "https://example.com/de/guides/cast-iron"}, {Lang: "x-default", URL: "https://example.com/guides/cast-iron"}}
+	if !reflect.DeepEqual(m.Alternates, wantAlt) {
+		t.Errorf("alternates %+v", m.Alternates)
+	}
+	if og := m.OpenGraph; og.Title != "Restoring cast iron" || og.Image != "https://example.com/img/pan.jpg" || og.SiteName != "Kitchen Notes" || og.Locale != "en_GB" {
+		t.Errorf("open graph %+v", og)
+	}
+	if tw := m.Twitter; tw.Card != "summary_large_image" || tw.Site != "@kitchennotes" || tw.Creator != "@ada" || tw.Image != "https://cdn.example.com/pan-wide.jpg" {
+		t.Errorf("twitter card %+v", tw)
+	}
+
+	sd := m.Structured
+	if sd == nil {
+		t.Fatalf("expected structured data")
+	}
+	if len(sd.Raw) != 4 {
+		t.Errorf("expected 3 JSON-LD items and 1 microdata item (the malformed script skipped), got %d", len(sd.Raw))
+	}
+	if len(sd.Articles) != 1 || sd.Articles[0].Type != "BlogPosting" || sd.Articles[0].Publisher != "Kitchen Notes" || sd.Articles[0].URL != "https://example.com/guides/cast-iron" {
+		t.Errorf("articles %+v", sd.Articles)
+	}
+	wantCrumbs := []models.BreadcrumbList{{Items: []models.Breadcrumb{{Position: 1, Name: "Home", URL: "https://example.com/"}, {Position: 2, Name: "Guides", URL: "https://example.com/guides"}}}}
+	if !reflect.DeepEqual(sd.Breadcrumbs, wantCrumbs) {
+		t.Errorf("breadcrumbs %+v", sd.Breadcrumbs)
+	}
+	if len(sd.FAQ) != 2 || sd.FAQ[0] != (models.FAQEntry{Question: "Can I use soap?", Answer: "Yes, a little mild soap is fine."}) {
+		t.Errorf("faq %+v", sd.FAQ)
+	}
+	wantProduct := models.ProductData{Name: "Pre-seasoned skillet", Brand: "Ironworks", SKU: "SK-26", Image: "https://example.com/img/skillet.jpg",
+		Offers: []models.OfferData{{Price: "39.99", PriceCurrency: "GBP", Availability: "InStock"}}, RatingValue: 4.6, ReviewCount: 128}
+	if len(sd.Products) != 1 || !reflect.DeepEqual(sd.Products[0], wantProduct) {
+		t.Errorf("products %+v", sd.Products)
+	}
+}
+
+func TestMetadataFallbacks(t *testing.T) {
+	p := &models.Page{
+		Content: `<html><head><title>Plain page</title><meta name="date" content="March 7, 2024"><meta http-equiv="content-language" content="fr"></head>
+<body><p>By <a rel="author" href="/people/jo">Jo Martin</a></p><time datetime="2020-01-01">old</time></body></html>`,
+		Metadata: models.PageMeta{Canonical: "https://example.com/fetched", Headers: map[string]string{"Last-Modified": "Fri, 08 Mar 2024 09:00:00 GMT", "Content-Language": "de"}},
+	}
+	if err := NewContentProcessor().ProcessPage(p, "https://example.com/plain"); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	m := p.Metadata
+	if m.Author != "Jo Martin" || m.Language != "fr" || m.Canonical != "https://example.com/fetched" || m.Structured != nil {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+	if !m.PublishDate.Equal(time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)) || !m.ModifiedDate.Equal(time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("dates %v / %v", m.PublishDate, m.ModifiedDate)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for in, want := range map[string]string{
+		"2024-03-01T08:00:00+01:00":     "2024-03-01T07:00:00Z",
+		"2024-03-01T08:00:00.5Z":        "2024-03-01T08:00:00.5Z",
+		"2024-03-01T08:00:00+0100":      "2024-03-01T07:00:00Z",
+		"2024-03-01 08:00:00":           "2024-03-01T08:00:00Z",
+		"2024/03/01":                    "2024-03-01T00:00:00Z",
+		"Fri, 01 Mar 2024 08:00:00 GMT": "2024-03-01T08:00:00Z",
+		"1 March 2024":                  "2024-03-01T00:00:00Z",
+		"yesterday":                     "0001-01-01T00:00:00Z",
+	} {
+		if got := parseDate(in).Format(time.RFC3339Nano); got != want {
+			t.Errorf("parseDate(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package processor
 
 import (
     "fmt"
-    "net/url"
     "regexp"
     "strings"
+    "sync"
     "time"
 
 
@@ -15,116 +15,98 @@ import (
     "github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
     "github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
     "github.com/PuerkitoBio/goquery"
+    "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
 )
 
 // (Asset pipeline dependencies removed during internalization; will be reintroduced later if needed)
 
+// defaultContentSelectors locate the main content when ContentProcessor.ContentSelectors is empty.
+var defaultContentSelectors = []string{"main", "article", ".content", "#content", ".post", ".entry", ".article-content"}
+
 // ContentProcessor handles HTML content cleaning and processing
-type ContentProcessor struct{}
+type ContentProcessor struct {
+    // ContentSelectors are tried in order by ProcessPage to find the main content
+    // (default defaultContentSelectors); without a match the cleaned body is used.
+    ContentSelectors []string
+    // RemoveSelectors are removed by RemoveUnwantedElements in addition to the built-in noise.
+    RemoveSelectors []string
+    // Extractor locates the main content in ProcessPage; nil uses a SelectorExtractor
+    // over ContentSelectors.
+    Extractor ContentExtractor
+    // Steps run in order over the parsed page in ProcessPage; nil uses DefaultSteps.
+    Steps []Step
+    // Schemas selects the extraction schema filling Page.Extracted; nil extracts no fields.
+    Schemas SchemaSource
+}
 
 func NewContentProcessor() *ContentProcessor { return &ContentProcessor{} }
 
+// The helpers below each parse their input; ProcessPage parses a page once and runs
+// the same transforms as steps over it.
+
 // ExtractContent extracts main content using selectors
 func (cp *ContentProcessor) ExtractContent(html string, selectors []string) (string, error) {
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-    if err != nil { return "", err }
-    for _, selector := range selectors {
-        selection := doc.Find(selector)
-        if selection.Length() > 0 {
-            content, err := selection.Html(); if err != nil { continue }
-            return strings.TrimSpace(content), nil
-        }
-    }
-    bodySelection := doc.Find("body")
-    if bodySelection.Length() > 0 {
-        bodySelection.Find("script, style, nav, footer, aside, header").Remove()
-        bodySelection.Find(".advertisement, .ad, .ads").Remove()
-        bodySelection.Find("img[width='1'][height='1']").Remove()
-        bodyContent, err := bodySelection.Html(); if err != nil { return "", fmt.Errorf("could not extract any content: %w", err) }
-        return strings.TrimSpace(bodyContent), nil
-    }
-    return "", fmt.Errorf("could not extract any content: no body found")
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); if err != nil { return "", err }
+    nodes, _, err := selectContent(doc, selectors); if err != nil { return "", err }
+    return renderNodes(nodes)
 }
 
 func (cp *ContentProcessor) RemoveUnwantedElements(html string) (string, error) {
-    re := regexp.MustCompile(`<!--[\s\S]*?-->`)
-    html = re.ReplaceAllString(html, "")
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); if err != nil { return "", err }
-    unwantedTags := []string{"script", "style", "nav", "footer", "aside", "header"}
-    for _, tag := range unwantedTags { doc.Find(tag).Remove() }
-    unwantedSelectors := []string{".advertisement", ".ad", ".ads", ".sidebar", ".nav", ".navigation", ".footer", ".header", "#comments", ".comments"}
-    for _, selector := range unwantedSelectors { doc.Find(selector).Remove() }
-    doc.Find("img[width='1'][height='1']").Remove()
-    bodyContent := doc.Find("body")
-    if bodyContent.Length() > 0 { result, err := bodyContent.Html(); if err != nil { return "", err }; return result, nil }
-    result, err := doc.Html(); if err != nil { return "", err }; return result, nil
+    d, err := NewDocument(html, ""); if err != nil { return "", err }
+    if err := cp.clean(d); err != nil { return "", err }
+    if body := d.Root.Find("body"); body.Length() > 0 { return body.Html() }
+    return d.Root.Html()
 }
 
 func (cp *ContentProcessor) ConvertRelativeURLs(html, baseURL string) (string, error) {
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); if err != nil { return "", err }
-    base, err := url.Parse(baseURL); if err != nil { return "", fmt.Errorf("invalid base URL: %w", err) }
-    doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
-        href, exists := s.Attr("href"); if !exists { return }
-        if strings.HasPrefix(href, "http") || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "mailto:") { return }
-        absoluteURL, err := base.Parse(href); if err != nil { return }
-        s.SetAttr("href", absoluteURL.String())
-    })
-    doc.Find("img[src]").Each(func(i int, s *goquery.Selection) {
-        src, exists := s.Attr("src"); if !exists { return }
-        if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "data:") { return }
-        absoluteURL, err := base.Parse(src); if err != nil { return }
-        s.SetAttr("src", absoluteURL.String())
-    })
-    result, err := doc.Html(); if err != nil { return "", err }
-    return result, nil
+    d, err := NewDocument(html, baseURL); if err != nil { return "", err }
+    if err := resolveURLs(d); err != nil { return "", err }
+    return d.Root.Html()
 }
 
 func (cp *ContentProcessor) ExtractMetadata(html string) (string, *models.PageMeta, error) {
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); if err != nil { return "", nil, err }
-    meta := &models.PageMeta{}
-    title := strings.TrimSpace(doc.Find("title").Text())
-    if title == "" { title = strings.TrimSpace(doc.Find("h1").First().Text()) }
-    description, _ := doc.Find("meta[name='description']").Attr("content")
-    if description == "" { description, _ = doc.Find("meta[property='og:description']").Attr("content") }
-    meta.Description = strings.TrimSpace(description)
-    keywords, _ := doc.Find("meta[name='keywords']").Attr("content")
-    if keywords != "" { keywordList := strings.Split(keywords, ","); for i, k := range keywordList { keywordList[i] = strings.TrimSpace(k) }; meta.Keywords = keywordList }
-    author, _ := doc.Find("meta[name='author']").Attr("content"); meta.Author = strings.TrimSpace(author)
-    ogTitle, _ := doc.Find("meta[property='og:title']").Attr("content")
-    ogDesc, _ := doc.Find("meta[property='og:description']").Attr("content")
-    ogImage, _ := doc.Find("meta[property='og:image']").Attr("content")
-    ogURL, _ := doc.Find("meta[property='og:url']").Attr("content")
-    ogType, _ := doc.Find("meta[property='og:type']").Attr("content")
-    meta.OpenGraph = models.OpenGraphMeta{Title: strings.TrimSpace(ogTitle), Description: strings.TrimSpace(ogDesc), Image: strings.TrimSpace(ogImage), URL: strings.TrimSpace(ogURL), Type: strings.TrimSpace(ogType)}
-    return title, meta, nil
+    d, err := NewDocument(html, ""); if err != nil { return "", nil, err }
+    if err := extractMetadata(d); err != nil { return "", nil, err }
+    return d.Title, &d.Meta, nil
 }
 
 func (cp *ContentProcessor) ExtractImages(html, baseURL string) ([]string, error) {
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); if err != nil { return nil, err }
-    base, err := url.Parse(baseURL); if err != nil { return nil, fmt.Errorf("invalid base URL: %w", err) }
-    var images []string
-    doc.Find("img[src]").Each(func(i int, s *goquery.Selection) {
-        src, exists := s.Attr("src"); if !exists || src == "" { return }
-        if strings.HasPrefix(src, "data:") || (s.AttrOr("width", "") == "1" && s.AttrOr("height", "") == "1") { return }
-        if !strings.HasPrefix(src, "http") { absoluteURL, err := base.Parse(src); if err != nil { return }; src = absoluteURL.String() }
-        images = append(images, src)
-    })
-    return images, nil
+    d, err := NewDocument(html, baseURL); if err != nil { return nil, err }
+    if err := extractImages(d); err != nil { return nil, err }
+    return d.Images, nil
 }
 
+// ProcessPage parses page.Content once, runs Steps (default DefaultSteps) over it and
+// serializes the extracted content into Content, CleanedText and Markdown.
 func (cp *ContentProcessor) ProcessPage(page *models.Page, baseURL string) error {
     if page == nil { return fmt.Errorf("page cannot be nil") }
     if strings.TrimSpace(page.Content) == "" { return fmt.Errorf("page content is empty") }
     if strings.HasPrefix(strings.TrimSpace(page.Content), "<<") { return fmt.Errorf("content appears to be malformed HTML") }
-    cleaned, err := cp.RemoveUnwantedElements(page.Content); if err != nil { return fmt.Errorf("failed to clean content: %w", err) }
-    withAbsolute, err := cp.ConvertRelativeURLs(cleaned, baseURL); if err != nil { return fmt.Errorf("failed to convert URLs: %w", err) }
-    selectors := []string{"main", "article", ".content", "#content", ".post", ".entry", ".article-content"}
-    extracted, err := cp.ExtractContent(withAbsolute, selectors); if err != nil { extracted = withAbsolute }
-    converter := NewHTMLToMarkdownConverter(); markdown, err := converter.Convert(extracted); if err != nil { return fmt.Errorf("failed to convert to markdown: %w", err) }
-    title, meta, err := cp.ExtractMetadata(page.Content); if err != nil { meta = &models.PageMeta{}; title = "" }
-    images, err := cp.ExtractImages(extracted, baseURL); if err != nil { images = []string{} }
-    cleanText := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(extracted, " "); words := strings.Fields(cleanText); meta.WordCount = len(words)
-    page.Content = extracted; page.Markdown = markdown; page.Title = title; page.Images = images; page.Metadata = *meta; page.ProcessedAt = time.Now()
+    d, err := NewDocument(page.Content, baseURL); if err != nil { return fmt.Errorf("failed to parse content: %w", err) }
+    steps := cp.Steps; if steps == nil { steps = cp.DefaultSteps() }
+    for _, step := range steps { if err := step.Apply(d); err != nil { return fmt.Errorf("%s step failed: %w", step.Name(), err) } }
+    nodes := d.ContentNodes()
+    extracted, err := renderNodes(nodes); if err != nil { return fmt.Errorf("failed to render content: %w", err) }
+    cleanText := nodeText(nodes...)
+    var markdown string
+    // Converting last: the converter takes the nodes out of the document.
+    if extracted != "" { markdown, err = NewHTMLToMarkdownConverter().ConvertNodes(nodes); if err != nil { return fmt.Errorf("failed to convert to markdown: %w", err) } }
+    title, meta := d.Title, d.Meta
+    // Keep what the fetcher learned about the page (title, HTTP headers, rel=canonical).
+    if title == "" { title = page.Title }
+    if meta.Description == "" { meta.Description = page.Metadata.Description }
+    meta.Headers = page.Metadata.Headers
+    if page.Metadata.Canonical != "" { meta.Canonical = page.Metadata.Canonical }
+    if meta.PublishDate.IsZero() { meta.PublishDate = page.Metadata.PublishDate }
+    if meta.ModifiedDate.IsZero() { meta.ModifiedDate = parseDate(meta.Headers["Last-Modified"]) }
+    if meta.Language == "" { meta.Language = normalizeLanguage(strings.Split(meta.Headers["Content-Language"], ",")[0]) }
+    meta.WordCount = len(strings.Fields(cleanText))
+    page.Content = extracted; page.CleanedText = cleanText; page.Markdown = markdown; page.Title = title; page.Images = d.Images; page.Metadata = meta; page.ProcessedAt = time.Now()
+    v := NewContentValidator().ValidateContent(page)
+    page.Extracted = d.Extracted
+    page.Quality = &models.ContentQuality{Score: v.Score, Issues: v.Issues, Extractor: d.Extraction.Extractor, Confidence: d.Extraction.Confidence, SchemaErrors: d.SchemaErrors}
+    if len(v.Issues) == 0 { page.Quality.Issues = nil }
     return nil
 }
 
@@ -147,23 +129,41 @@ func (wp *WorkerPool) ProcessPages(pages []*models.Page, baseURL string) <-chan
             processor := NewContentProcessor(); err := processor.ProcessPage(page, baseURL)
             resultURL := ""; if page != nil && page.URL != nil { resultURL = page.URL.String() }
             res := &models.CrawlResult{ URL: resultURL, Page: page, Success: err == nil, Stage: "processing" }
-            if err != nil { res.Error = err }
+            if err != nil { res.Error = models.NewCrawlError(resultURL, "processing", err) }
             results <- res
         }
     }()
     return results
 }
 
+// markdownConverter is shared: a converter holds no per-conversion state.
+var markdownConverter = sync.OnceValue(func() *converter.Converter {
+    return converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin(), table.NewTablePlugin()))
+})
+
+var (
+    htmlComment = regexp.MustCompile(`<!--[\s\S]*?-->`)
+    blankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
 func (c *HTMLToMarkdownConverter) Convert(html string) (string, error) {
     if strings.TrimSpace(html) == "" { return "", fmt.Errorf("HTML content is empty") }
-    conv := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin(), table.NewTablePlugin()))
-    markdown, err := conv.ConvertString(html); if err != nil { return "", fmt.Errorf("conversion failed: %w", err) }
+    markdown, err := markdownConverter().ConvertString(html); if err != nil { return "", fmt.Errorf("conversion failed: %w", err) }
     cleaned := cleanMarkdown(markdown); return cleaned, nil
 }
 
+// ConvertNodes converts parsed content, moving nodes into a detached container.
+func (c *HTMLToMarkdownConverter) ConvertNodes(nodes []*html.Node) (string, error) {
+    if len(nodes) == 0 { return "", fmt.Errorf("HTML content is empty") }
+    container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+    for _, n := range nodes { if n.Parent != nil { n.Parent.RemoveChild(n) }; container.AppendChild(n) }
+    markdown, err := markdownConverter().ConvertNode(container); if err != nil { return "", fmt.Errorf("conversion failed: %w", err) }
+    return cleanMarkdown(string(markdown)), nil
+}
+
 func cleanMarkdown(markdown string) string {
-    re := regexp.MustCompile(`<!--[\s\S]*?-->`); cleaned := re.ReplaceAllString(markdown, "")
-    re = regexp.MustCompile(`\n{3,}`); cleaned = re.ReplaceAllString(cleaned, "\n\n")
+    cleaned := htmlComment.ReplaceAllString(markdown, "")
+    cleaned = blankLines.ReplaceAllString(cleaned, "\n\n")
     cleaned = strings.ReplaceAll(cleaned, "\\n", "\n")
     cleaned = strings.ReplaceAll(cleaned, `\"`, `"`)
     lines := strings.Split(cleaned, "\n")
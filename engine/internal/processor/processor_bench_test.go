@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// longArticle builds a documentation-sized page: head metadata, scripts, a navigation
+// tree, an article of sections with paragraphs, images, code and a table, a sidebar
+// and a footer.
+func longArticle() string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><title>Operating the crawler at scale</title>
+<meta name="description" content="A field guide to running large crawls"><meta name="keywords" content="crawler, scale, operations">
+<meta name="author" content="Ariadne"><meta property="og:title" content="Operating the crawler"><meta property="og:image" content="/img/cover.png">
+<link rel="stylesheet" href="/css/site.css"><style>body{font-family:sans-serif}.sidebar{float:right}</style>
+<script>window.analytics=window.analytics||[];analytics.push(["page"]);</script></head><body>
+<header class="site-header"><a href="/" class="logo">Docs</a><nav><ul>`)
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, `<li><a href="/docs/section-%d">Section %d</a></li>`, i, i)
+	}
+	b.WriteString(`</ul></nav></header><div class="layout"><main><article><h1>Operating the crawler at scale</h1>`)
+	for s := 0; s < 12; s++ {
+		fmt.Fprintf(&b, `<h2 id="part-%d">Part %d</h2>`, s, s)
+		for p := 0; p < 5; p++ {
+			fmt.Fprintf(&b, `<p>Paragraph %d of part %d explains how workers, queues and rate limits interact, with a <a href="../ref/%d">reference</a>, some <em>emphasis</em> and <code>inline code</code>, so that operators can reason about throughput, memory and politeness.</p>`, p, s, p)
+		}
+		fmt.Fprintf(&b, `<img src="/img/diagram-%d.png" alt="Diagram %d"><img src="/pixel.gif" width="1" height="1">`, s, s)
+		b.WriteString(`<pre><code>ariadne -seeds https://example.com -max-depth 3 -workers 8</code></pre>`)
+		b.WriteString(`<table><tr><th>Setting</th><th>Value</th></tr><tr><td>workers</td><td>8</td></tr><tr><td>depth</td><td>3</td></tr></table>`)
+	}
+	b.WriteString(`</article></main><aside class="sidebar"><h3>On this page</h3><ul>`)
+	for s := 0; s < 12; s++ {
+		fmt.Fprintf(&b, `<li><a href="#part-%d">Part %d</a></li>`, s, s)
+	}
+	b.WriteString(`</ul><div class="ad">Advertisement</div></aside></div><!-- tracking --><footer class="footer"><p>Copyright Example Docs</p><a href="/privacy">Privacy</a></footer>
+<script src="/js/app.js"></script></body></html>`)
+	return b.String()
+}
+
+// BenchmarkProcessPage measures ProcessPage per page over the extraction corpus and a
+// long documentation page, for both extractors.
+func BenchmarkProcessPage(b *testing.B) {
+	pages := map[string]string{"long-article": longArticle()}
+	fixtures, _ := filepath.Glob(filepath.Join("testdata", "extract", "*.html"))
+	for _, fixture := range fixtures {
+		src, err := os.ReadFile(fixture)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pages[strings.TrimSuffix(filepath.Base(fixture), ".html")] = string(src)
+	}
+	for _, extractor := range []string{ExtractorSelector, ExtractorReadability} {
+		ex, err := NewExtractor(extractor, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cp := &ContentProcessor{Extractor: ex}
+		for _, name := range []string{"article", "div-soup", "docs", "long-article"} {
+			src := pages[name]
+			b.Run(extractor+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(src)))
+				for i := 0; i < b.N; i++ {
+					page := &models.Page{Content: src}
+					if err := cp.ProcessPage(page, "https://example.com/docs/guide"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -21,3 +21,49 @@ func TestProcessPageSmoke(t *testing.T) {
 		t.Fatalf("expected word count")
 	}
 }
+
+// TestProcessPageMatchesHelpers checks the single-parse steps against the string
+// helpers chained as ProcessPage used to.
+func TestProcessPageMatchesHelpers(t *testing.T) {
+	cp := NewContentProcessor()
+	src := longArticle()
+	base := "https://example.com/docs/guide"
+	cleaned, err := cp.RemoveUnwantedElements(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := cp.ConvertRelativeURLs(cleaned, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := cp.ExtractContent(resolved, defaultContentSelectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown, err := NewHTMLToMarkdownConverter().Convert(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := cp.ExtractImages(content, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, _, err := cp.ExtractMetadata(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &models.Page{Content: src}
+	if err := cp.ProcessPage(p, base); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if p.Content != content {
+		t.Fatalf("content differs:\n%s\n---\n%s", p.Content, content)
+	}
+	if p.Markdown != markdown {
+		t.Fatalf("markdown differs:\n%s\n---\n%s", p.Markdown, markdown)
+	}
+	if p.Title != title || len(p.Images) != len(images) || p.Images[0] != "https://example.com/img/diagram-0.png" {
+		t.Fatalf("unexpected title %q or images %v (helpers: %v)", p.Title, p.Images, images)
+	}
+}
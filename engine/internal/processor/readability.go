@@ -0,0 +1,312 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Extractor names accepted by NewExtractor.
+const (
+	ExtractorSelector    = "selector"
+	ExtractorReadability = "readability"
+)
+
+// Extraction is the main content an extractor located in a document.
+type Extraction struct {
+	// Nodes are the content in document order; rendered together they form the page's
+	// main content.
+	Nodes     []*html.Node
+	Extractor string
+	// Confidence rates from 0 to 1 how likely Nodes are the page's main content.
+	Confidence float64
+}
+
+// ContentExtractor locates the main content of a parsed document.
+type ContentExtractor interface {
+	Name() string
+	// Extract may modify doc, which ProcessPage no longer needs beyond the content.
+	Extract(doc *goquery.Document) (Extraction, error)
+}
+
+// NewExtractor returns the extractor called name ("" selects ExtractorSelector).
+// selectors configure the selector extractor.
+func NewExtractor(name string, selectors []string) (ContentExtractor, error) {
+	switch name {
+	case "", ExtractorSelector:
+		return &SelectorExtractor{Selectors: selectors}, nil
+	case ExtractorReadability:
+		return &ReadabilityExtractor{}, nil
+	}
+	return nil, fmt.Errorf("unknown content extractor %q", name)
+}
+
+// SelectorExtractor returns the children of the first element matching one of
+// Selectors (default defaultContentSelectors), falling back to the body without noise
+// elements. Its confidence is 1 for a selector match and 0 for the fallback.
+type SelectorExtractor struct{ Selectors []string }
+
+func (e *SelectorExtractor) Name() string { return ExtractorSelector }
+
+func (e *SelectorExtractor) Extract(doc *goquery.Document) (Extraction, error) {
+	selectors := e.Selectors
+	if len(selectors) == 0 {
+		selectors = defaultContentSelectors
+	}
+	nodes, matched, err := selectContent(doc, selectors)
+	if err != nil {
+		return Extraction{}, err
+	}
+	ext := Extraction{Nodes: nodes, Extractor: ExtractorSelector}
+	if matched {
+		ext.Confidence = 1
+	}
+	return ext, nil
+}
+
+// selectContent returns the children of the first element matching one of selectors,
+// or of the body without noise elements.
+func selectContent(doc *goquery.Document, selectors []string) (nodes []*html.Node, matched bool, err error) {
+	for _, selector := range selectors {
+		if sel := doc.Find(selector).First(); sel.Length() > 0 {
+			return childNodes(sel.Get(0)), true, nil
+		}
+	}
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		return nil, false, fmt.Errorf("could not extract any content: no body found")
+	}
+	body.Find("script, style, nav, footer, aside, header").Remove()
+	body.Find(".advertisement, .ad, .ads").Remove()
+	body.Find("img[width='1'][height='1']").Remove()
+	return childNodes(body.Get(0)), false, nil
+}
+
+// ReadabilityExtractor scores block elements the way Arc90's Readability does: every
+// paragraph-like element with enough text adds points (one, plus one per comma and per
+// 100 characters, at most three) to its parent and half as many to its grandparent.
+// Candidates start from a tag weight and class/id hints and are discounted by their
+// link density. The best candidate is merged with siblings that score close to it or
+// read like prose. Elements whose class or id suggest boilerplate are dropped first
+// unless they also suggest content. Confidence grows with the top score and with its
+// margin over the best candidate outside the extracted content.
+type ReadabilityExtractor struct{}
+
+func (e *ReadabilityExtractor) Name() string { return ExtractorReadability }
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|widget`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveHint       = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeHint       = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	sentenceEnd        = regexp.MustCompile(`\.( |$)`)
+)
+
+const (
+	minParagraphChars = 25
+	// confidenceScore is the candidate score at which the score alone no longer limits
+	// the confidence.
+	confidenceScore = 30.0
+)
+
+// blockChildren make a <div> a container rather than a paragraph.
+var blockChildren = map[atom.Atom]bool{
+	atom.A: false, atom.Blockquote: true, atom.Dl: true, atom.Div: true, atom.Img: false, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Section: true, atom.Article: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+func (e *ReadabilityExtractor) Extract(doc *goquery.Document) (Extraction, error) {
+	doc.Find("script, style, noscript, iframe, form, svg").Remove()
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		return Extraction{}, fmt.Errorf("could not extract any content: no body found")
+	}
+	removeUnlikely(body)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, points float64) {
+		if n == nil || n.Type != html.ElementNode || n.DataAtom == atom.Html {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += points
+	}
+	body.Find("p, pre, td, div, section, blockquote, li").Each(func(_ int, s *goquery.Selection) {
+		n := s.Nodes[0]
+		if (n.DataAtom == atom.Div || n.DataAtom == atom.Section || n.DataAtom == atom.Li) && hasBlockChild(n) {
+			return
+		}
+		text := nodeText(n)
+		if utf8.RuneCountInString(text) < minParagraphChars {
+			return
+		}
+		points := 1 + float64(strings.Count(text, ",")) + math.Min(math.Floor(float64(utf8.RuneCountInString(text))/100), 3)
+		addScore(n.Parent, points)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, points/2)
+		}
+	})
+	if len(candidates) == 0 {
+		return Extraction{Nodes: childNodes(body.Get(0)), Extractor: ExtractorReadability}, nil
+	}
+	var top *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+	kept := mergeSiblings(top, scores)
+	// The runner-up is the best candidate outside the extracted content and its
+	// ancestry, which always shares part of the top score.
+	var second *html.Node
+	for _, n := range candidates {
+		if contains(n, top) || slices.ContainsFunc(kept, func(k *html.Node) bool { return contains(k, n) }) {
+			continue
+		}
+		if second == nil || scores[n] > scores[second] {
+			second = n
+		}
+	}
+	margin := 1.0
+	if second != nil && scores[top] > 0 {
+		margin = 1 - math.Max(scores[second], 0)/scores[top]
+	}
+	confidence := math.Min(math.Max(scores[top], 0)/confidenceScore, 1) * (0.5 + 0.5*margin)
+	return Extraction{Nodes: kept, Extractor: ExtractorReadability, Confidence: math.Round(confidence*100) / 100}, nil
+}
+
+// removeUnlikely drops elements whose class or id suggest boilerplate.
+func removeUnlikely(body *goquery.Selection) {
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		n := s.Nodes[0]
+		if n.DataAtom == atom.Body || n.DataAtom == atom.A || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			return
+		}
+		hints := attr(n, "class") + " " + attr(n, "id")
+		if unlikelyCandidates.MatchString(hints) && !maybeCandidate.MatchString(hints) {
+			s.Remove()
+		}
+	})
+}
+
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	return score + classWeight(n)
+}
+
+// classWeight scores the class and id hints of n: +25 for each suggesting content and
+// -25 for each suggesting boilerplate.
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHint.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(hint) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// mergeSiblings returns the top candidate with the siblings that score at least a fifth
+// of it (siblings sharing its class get that bonus) or read like prose: long paragraphs
+// with few links, or short ones without links ending a sentence.
+func mergeSiblings(top *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+	threshold := math.Max(10, scores[top]*0.2)
+	var kept []*html.Node
+	for sib := top.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode {
+			continue
+		}
+		keep := sib == top
+		if !keep {
+			bonus := 0.0
+			if class := attr(top, "class"); class != "" && attr(sib, "class") == class {
+				bonus = scores[top] * 0.2
+			}
+			if score, ok := scores[sib]; ok && score+bonus >= threshold {
+				keep = true
+			} else if sib.DataAtom == atom.P {
+				text := nodeText(sib)
+				chars := utf8.RuneCountInString(text)
+				density := linkDensity(sib)
+				keep = (chars > 80 && density < 0.25) || (chars > 0 && chars <= 80 && density == 0 && sentenceEnd.MatchString(text))
+			}
+		}
+		if keep {
+			kept = append(kept, sib)
+		}
+	}
+	return kept
+}
+
+// contains reports whether n is ancestor or equal to m.
+func contains(n, m *html.Node) bool {
+	for ; m != nil; m = m.Parent {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockChildren[c.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+// linkDensity is the share of n's text inside links.
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(nodeText(n))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	goquery.NewDocumentFromNode(n).Find("a").Each(func(_ int, s *goquery.Selection) {
+		linked += utf8.RuneCountInString(nodeText(s.Nodes[0]))
+	})
+	return math.Min(float64(linked)/float64(total), 1)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// tokenF1 is the F1 score of the words of got against those of want.
+func tokenF1(got, want string) float64 {
+	counts := make(map[string]int)
+	wantWords := strings.Fields(want)
+	for _, w := range wantWords {
+		counts[w]++
+	}
+	gotWords := strings.Fields(got)
+	var common int
+	for _, w := range gotWords {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(gotWords))
+	recall := float64(common) / float64(len(wantWords))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// TestExtractorsGoldenCorpus runs both extractors over testdata/extract, where each
+// page has a .golden file holding the text of its main content, and compares them.
+func TestExtractorsGoldenCorpus(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "extract", "*.html"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	totals := make(map[string]float64)
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+		src, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden, err := os.ReadFile(strings.TrimSuffix(fixture, ".html") + ".golden")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, extractor := range []string{ExtractorSelector, ExtractorReadability} {
+			ex, err := NewExtractor(extractor, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page := &models.Page{Content: string(src)}
+			if err := (&ContentProcessor{Extractor: ex}).ProcessPage(page, "https://example.com"); err != nil {
+				t.Fatalf("%s/%s: %v", name, extractor, err)
+			}
+			f1 := tokenF1(page.CleanedText, string(golden))
+			totals[extractor] += f1
+			t.Logf("%-15s %-12s f1=%.2f confidence=%.2f", name, extractor, f1, page.Quality.Confidence)
+			if page.Quality.Extractor != extractor {
+				t.Fatalf("%s: quality reports extractor %q, want %q", name, page.Quality.Extractor, extractor)
+			}
+			if extractor == ExtractorReadability && f1 < 0.9 {
+				t.Errorf("%s: readability f1 %.2f below 0.9, text %q", name, f1, page.CleanedText)
+			}
+		}
+	}
+	n := float64(len(fixtures))
+	t.Logf("mean f1: selector=%.2f readability=%.2f", totals[ExtractorSelector]/n, totals[ExtractorReadability]/n)
+	if totals[ExtractorReadability] <= totals[ExtractorSelector] {
+		t.Fatalf("readability (%.2f) should beat selector (%.2f) on the corpus", totals[ExtractorReadability]/n, totals[ExtractorSelector]/n)
+	}
+}
+
+func TestReadabilityConfidence(t *testing.T) {
+	ex := &ReadabilityExtractor{}
+	extract := func(src string) (Extraction, error) {
+		d, err := NewDocument(src, "")
+		if err != nil {
+			return Extraction{}, err
+		}
+		return ex.Extract(d.Root)
+	}
+	article, err := extract(`<html><body><div id="post"><p>` + strings.Repeat("Readable prose, with commas, goes here. ", 12) + `</p><p>` + strings.Repeat("More of the same story, told at length. ", 12) + `</p></div><div><a href="/a">A link</a></div></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thin, err := extract(`<html><body><div><p>Only a short paragraph sits here alone.</p></div></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Confidence < 0.9 || thin.Confidence >= article.Confidence || thin.Confidence <= 0 {
+		t.Fatalf("unexpected confidences: article %.2f, thin %.2f", article.Confidence, thin.Confidence)
+	}
+	links, err := extract(`<html><body><div><a href="/1">Nothing but a list of links to other pages</a></div></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links.Confidence != 0 {
+		t.Fatalf("expected no confidence without paragraphs, got %.2f", links.Confidence)
+	}
+	if _, err := NewExtractor("magic", nil); err == nil {
+		t.Fatalf("expected an error for an unknown extractor")
+	}
+}
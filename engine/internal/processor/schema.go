@@ -0,0 +1,282 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// Field types accepted by Field.Type and Field.Items.
+const (
+	FieldString  = "string"
+	FieldNumber  = "number"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+	FieldDate    = "date"
+	FieldList    = "list"
+	FieldObject  = "object"
+)
+
+// Schema declares the fields to pull out of a site's pages into a record
+// (Page.Extracted). Records are validated against it: missing required fields and
+// values that cannot be coerced to their type are reported as violations.
+type Schema struct {
+	Fields []Field
+	// Strict fails pages whose record has violations instead of reporting them in
+	// Page.Quality.SchemaErrors.
+	Strict bool
+
+	once      sync.Once
+	err       error
+	selectors map[string]cascadia.Selector
+	patterns  map[string]*regexp.Regexp
+}
+
+// Field maps a record field to a value in the page.
+type Field struct {
+	Name string
+	// Selector is a CSS selector relative to the enclosing object (the page at the top
+	// level); empty uses the enclosing element itself.
+	Selector string
+	// Attr reads an attribute (href and src are resolved against the page URL) instead
+	// of the element's text.
+	Attr string
+	// Pattern is a regular expression applied to the raw value; its first group (or
+	// the whole match) is kept. A value that does not match is missing.
+	Pattern string
+	// Type coerces the value: string (default), number, integer, boolean, date
+	// (normalized to UTC), list (every match, each coerced to Items) or object (the
+	// first match holding Fields).
+	Type string
+	// Items is the element type of a list (default string); lists of objects use
+	// Fields for each element.
+	Items    string
+	Fields   []Field
+	Required bool
+}
+
+// SchemaSource selects the schema applied to a page, nil for none.
+type SchemaSource interface {
+	SchemaFor(u *url.URL) *Schema
+}
+
+// Validate reports the first error in the schema: a missing or duplicate name, an
+// unknown type, or an invalid selector or pattern.
+func (s *Schema) Validate() error {
+	s.once.Do(func() {
+		s.selectors, s.patterns = make(map[string]cascadia.Selector), make(map[string]*regexp.Regexp)
+		s.err = s.compile(s.Fields, "")
+	})
+	return s.err
+}
+
+func (s *Schema) compile(fields []Field, prefix string) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		path := prefix + f.Name
+		if f.Name == "" {
+			return fmt.Errorf("schema: field without name in %q", strings.TrimSuffix(prefix, "."))
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("schema: duplicate field %q", path)
+		}
+		seen[f.Name] = true
+		if f.Selector != "" {
+			sel, err := cascadia.Compile(f.Selector)
+			if err != nil {
+				return fmt.Errorf("schema: field %q: invalid selector %q: %w", path, f.Selector, err)
+			}
+			s.selectors[f.Selector] = sel
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return fmt.Errorf("schema: field %q: invalid pattern: %w", path, err)
+			}
+			s.patterns[f.Pattern] = re
+		}
+		elem := f.Type
+		switch f.Type {
+		case "", FieldString, FieldNumber, FieldInteger, FieldBoolean, FieldDate, FieldObject:
+			if f.Items != "" {
+				return fmt.Errorf("schema: field %q: items only apply to lists", path)
+			}
+		case FieldList:
+			elem = f.Items
+			switch f.Items {
+			case "", FieldString, FieldNumber, FieldInteger, FieldBoolean, FieldDate, FieldObject:
+			default:
+				return fmt.Errorf("schema: field %q: unknown item type %q", path, f.Items)
+			}
+		default:
+			return fmt.Errorf("schema: field %q: unknown type %q", path, f.Type)
+		}
+		if (elem == FieldObject) != (len(f.Fields) > 0) {
+			return fmt.Errorf("schema: field %q: fields are required for, and only allowed on, objects", path)
+		}
+		if err := s.compile(f.Fields, path+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract builds the record of the page root, resolving URLs against base. It returns
+// the violations found; fields that are missing or invalid are left out.
+func (s *Schema) Extract(root *goquery.Selection, base *url.URL) (map[string]any, []string) {
+	if err := s.Validate(); err != nil {
+		return nil, []string{err.Error()}
+	}
+	x := &schemaExtraction{schema: s, base: base}
+	return x.object(root, s.Fields, ""), x.violations
+}
+
+type schemaExtraction struct {
+	schema     *Schema
+	base       *url.URL
+	violations []string
+}
+
+func (x *schemaExtraction) object(scope *goquery.Selection, fields []Field, prefix string) map[string]any {
+	record := make(map[string]any)
+	for _, f := range fields {
+		path := prefix + f.Name
+		matches := scope
+		if f.Selector != "" {
+			matches = scope.FindMatcher(x.schema.selectors[f.Selector])
+		}
+		var v any
+		if f.Type == FieldList {
+			var items []any
+			matches.Each(func(i int, m *goquery.Selection) {
+				if item, ok := x.value(m, f, f.Items, fmt.Sprintf("%s[%d]", path, i)); ok {
+					items = append(items, item)
+				}
+			})
+			if len(items) > 0 {
+				v = items
+			}
+		} else if matches.Length() > 0 {
+			if value, ok := x.value(matches.First(), f, f.Type, path); ok {
+				v = value
+			}
+		}
+		if v == nil {
+			if f.Required {
+				x.violations = append(x.violations, fmt.Sprintf("%s: required field missing", path))
+			}
+			continue
+		}
+		record[f.Name] = v
+	}
+	return record
+}
+
+// value reads and coerces one match of f to typ; missing values and coercion failures
+// (reported as violations) return false.
+func (x *schemaExtraction) value(m *goquery.Selection, f Field, typ, path string) (any, bool) {
+	if typ == FieldObject {
+		record := x.object(m, f.Fields, path+".")
+		return record, len(record) > 0
+	}
+	var raw string
+	if f.Attr != "" {
+		v, ok := m.Attr(f.Attr)
+		if !ok {
+			return nil, false
+		}
+		raw = strings.TrimSpace(v)
+		if (f.Attr == "href" || f.Attr == "src") && raw != "" && x.base != nil {
+			if abs, err := x.base.Parse(raw); err == nil {
+				raw = abs.String()
+			}
+		}
+	} else {
+		raw = nodeText(m.Nodes...)
+	}
+	if f.Pattern != "" {
+		match := x.schema.patterns[f.Pattern].FindStringSubmatch(raw)
+		if match == nil {
+			return nil, false
+		}
+		raw = match[0]
+		if len(match) > 1 {
+			raw = match[1]
+		}
+		raw = strings.TrimSpace(raw)
+	}
+	if raw == "" {
+		return nil, false
+	}
+	v, err := coerce(raw, typ)
+	if err != nil {
+		x.violations = append(x.violations, fmt.Sprintf("%s: %v", path, err))
+		return nil, false
+	}
+	return v, true
+}
+
+var numberPattern = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+
+// coerce converts raw to typ. Numbers are read from the first number in raw, so
+// currency symbols and units are ignored and thousands separators are dropped.
+func coerce(raw, typ string) (any, error) {
+	switch typ {
+	case "", FieldString:
+		return raw, nil
+	case FieldNumber, FieldInteger:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(numberPattern.FindString(raw), ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as %s", raw, typ)
+		}
+		if typ == FieldInteger {
+			if n != float64(int64(n)) {
+				return nil, fmt.Errorf("cannot parse %q as integer", raw)
+			}
+			return int64(n), nil
+		}
+		return n, nil
+	case FieldBoolean:
+		switch strings.ToLower(raw) {
+		case "yes", "y", "on":
+			return true, nil
+		case "no", "n", "off":
+			return false, nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as boolean", raw)
+		}
+		return b, nil
+	case FieldDate:
+		t := parseDate(raw)
+		if t.IsZero() {
+			return nil, fmt.Errorf("cannot parse %q as date", raw)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// extractFields applies the schema SchemaSource selects for the page.
+func (cp *ContentProcessor) extractFields(d *Document) error {
+	if cp.Schemas == nil || d.Base == nil {
+		return nil
+	}
+	schema := cp.Schemas.SchemaFor(d.Base)
+	if schema == nil {
+		return nil
+	}
+	record, violations := schema.Extract(d.Root.Selection, d.Base)
+	if len(violations) > 0 && schema.Strict {
+		return fmt.Errorf("extracted record does not match schema: %s", strings.Join(violations, "; "))
+	}
+	d.Extracted, d.SchemaErrors = record, violations
+	return nil
+}
@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+// hostSchemas applies its schemas by exact host.
+type hostSchemas map[string]*Schema
+
+func (h hostSchemas) SchemaFor(u *url.URL) *Schema { return h[u.Hostname()] }
+
+func productSchema() *Schema {
+	return &Schema{Fields: []Field{
+		{Name: "name", Selector: "h1.name", Required: true},
+		{Name: "sku", Selector: "article.product", Attr: "data-sku"},
+		{Name: "price", Selector: ".price", Type: FieldNumber, Required: true},
+		{Name: "stock", Selector: ".qty", Type: FieldInteger},
+		{Name: "featured", Selector: ".featured", Type: FieldBoolean},
+		{Name: "released", Selector: "time", Attr: "datetime", Type: FieldDate},
+		{Name: "tags", Selector: ".tags li", Type: FieldList},
+		{Name: "weight", Selector: ".specs tr:nth-child(2) td", Pattern: `([\d.]+)\s*kg`, Type: FieldNumber},
+		{Name: "seller", Selector: ".seller", Type: FieldObject, Fields: []Field{
+			{Name: "name", Selector: "a"},
+			{Name: "url", Selector: "a", Attr: "href"},
+			{Name: "rating", Selector: ".rating", Pattern: `^[\d.]+`, Type: FieldNumber},
+		}},
+		{Name: "reviews", Selector: ".review", Type: FieldList, Items: FieldObject, Fields: []Field{
+			{Name: "author", Selector: ".who"},
+			{Name: "stars", Selector: ".stars", Type: FieldInteger},
+		}},
+		{Name: "gtin", Selector: ".gtin", Required: true},
+	}}
+}
+
+func processProduct(t *testing.T, schema *Schema, pageURL string) (*models.Page, error) {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("testdata", "schema", "product.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &models.Page{Content: string(src)}
+	cp := &ContentProcessor{Schemas: hostSchemas{"shop.example.com": schema}}
+	return page, cp.ProcessPage(page, pageURL)
+}
+
+func TestSchemaExtraction(t *testing.T) {
+	page, err := processProduct(t, productSchema(), "https://shop.example.com/cookware/skillet")
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	want := map[string]any{
+		"name":     "Pre-seasoned skillet",
+		"sku":      "SK-26",
+		"price":    1039.5,
+		"stock":    int64(14),
+		"featured": true,
+		"released": time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC),
+		"tags":     []any{"cast iron", "skillet", "oven safe"},
+		"weight":   2.4,
+		"seller":   map[string]any{"name": "Ironworks Ltd", "url": "https://shop.example.com/sellers/ironworks", "rating": 4.8},
+		"reviews": []any{
+			map[string]any{"author": "Jo", "stars": int64(5)},
+			map[string]any{"author": "Sam", "stars": int64(4)},
+			map[string]any{"author": "Ana"},
+		},
+	}
+	if !reflect.DeepEqual(page.Extracted, want) {
+		t.Errorf("record mismatch\n got %#v\nwant %#v", page.Extracted, want)
+	}
+	wantErrors := []string{`reviews[2].stars: cannot parse "four" as integer`, "gtin: required field missing"}
+	if !reflect.DeepEqual(page.Quality.SchemaErrors, wantErrors) {
+		t.Errorf("schema errors %q, want %q", page.Quality.SchemaErrors, wantErrors)
+	}
+	if page.Title != "Pre-seasoned skillet | Ironworks" || !strings.Contains(page.CleanedText, "Heavy but great.") {
+		t.Errorf("field extraction should not disturb processing: title %q text %q", page.Title, page.CleanedText)
+	}
+
+	other, err := processProduct(t, productSchema(), "https://blog.example.com/post")
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if other.Extracted != nil || other.Quality.SchemaErrors != nil {
+		t.Errorf("expected no record for a site without schema, got %v %q", other.Extracted, other.Quality.SchemaErrors)
+	}
+}
+
+func TestSchemaStrict(t *testing.T) {
+	schema := productSchema()
+	schema.Strict = true
+	if _, err := processProduct(t, schema, "https://shop.example.com/cookware/skillet"); err == nil || !strings.Contains(err.Error(), "gtin: required field missing") {
+		t.Fatalf("expected a schema violation error, got %v", err)
+	}
+
+	schema = productSchema()
+	schema.Strict = true
+	schema.Fields = schema.Fields[:len(schema.Fields)-2]
+	page, err := processProduct(t, schema, "https://shop.example.com/cookware/skillet")
+	if err != nil {
+		t.Fatalf("a conforming record should pass strict validation: %v", err)
+	}
+	if page.Extracted["name"] != "Pre-seasoned skillet" || page.Quality.SchemaErrors != nil {
+		t.Fatalf("unexpected record %v errors %q", page.Extracted, page.Quality.SchemaErrors)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	for name, fields := range map[string][]Field{
+		"missing name":   {{Selector: "h1"}},
+		"duplicate":      {{Name: "a"}, {Name: "a"}},
+		"bad selector":   {{Name: "a", Selector: "h1["}},
+		"bad pattern":    {{Name: "a", Pattern: "("}},
+		"unknown type":   {{Name: "a", Type: "money"}},
+		"unknown items":  {{Name: "a", Type: FieldList, Items: "money"}},
+		"items on value": {{Name: "a", Items: FieldNumber}},
+		"empty object":   {{Name: "a", Type: FieldObject}},
+		"fields on text": {{Name: "a", Fields: []Field{{Name: "b"}}}},
+		"nested":         {{Name: "a", Type: FieldObject, Fields: []Field{{Name: "b", Type: "money"}}}},
+	} {
+		if err := (&Schema{Fields: fields}).Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := productSchema().Validate(); err != nil {
+		t.Fatalf("valid schema rejected: %v", err)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	for _, c := range []struct {
+		raw, typ string
+		want     any
+	}{
+		{"$1,250.00", FieldNumber, 1250.0},
+		{"-3.5 °C", FieldNumber, -3.5},
+		{"12 items", FieldInteger, int64(12)},
+		{"Off", FieldBoolean, false},
+		{"TRUE", FieldBoolean, true},
+		{"2024-03-01", FieldDate, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		got, err := coerce(c.raw, c.typ)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("coerce(%q, %s) = %v, %v; want %v", c.raw, c.typ, got, err, c.want)
+		}
+	}
+	for _, c := range [][2]string{{"n/a", FieldNumber}, {"1.5", FieldInteger}, {"maybe", FieldBoolean}, {"soon", FieldDate}} {
+		if _, err := coerce(c[0], c[1]); err == nil {
+			t.Errorf("coerce(%q, %s): expected an error", c[0], c[1])
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"hash/fnv"
 	"math"
+	"math/bits"
 	"sync"
 	"time"
 
@@ -34,7 +35,9 @@ type LimiterSnapshot struct {
 	Denied           int64
 	OpenCircuits     int64
 	HalfOpenCircuits int64
-	Domains          []DomainSummary
+	// RestoredDomains counts domains whose state was loaded by LoadState.
+	RestoredDomains int
+	Domains         []DomainSummary
 }
 
 type DomainSummary struct {
@@ -42,6 +45,10 @@ type DomainSummary struct {
 	FillRate     float64
 	CircuitState string
 	LastActivity time.Time
+	LatencyEWMA  time.Duration
+	ErrorRate    float64
+	// Restored marks state carried over from a previous run by LoadState.
+	Restored bool
 }
 
 type AdaptiveRateLimiter struct {
@@ -55,6 +62,11 @@ type AdaptiveRateLimiter struct {
 	evictWG       sync.WaitGroup
 	evictInterval time.Duration
 	stopOnce      sync.Once
+	// carried holds state loaded by LoadState for domains not (or no longer) live, so
+	// SaveState keeps it for the next run until it ages out.
+	carriedMu sync.Mutex
+	carried   map[string]domainRecord
+	restored  int
 }
 
 type domainShard struct {
@@ -126,39 +138,51 @@ func (l *AdaptiveRateLimiter) Acquire(ctx context.Context, domain string) (Permi
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	if !l.cfg.Enabled {
-		return immediatePermit{}, nil
-	}
-	normalized, err := normalizeDomain(domain)
-	if err != nil {
-		return nil, err
-	}
-	state := l.getOrCreateDomainState(normalized)
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
-		now := l.clock.Now()
-		wait, err := state.planRequest(l.cfg, now)
-		if err != nil {
-			if errors.Is(err, ErrCircuitOpen) {
-				l.withMetrics(func(m *LimiterSnapshot) { m.Denied++ })
-			}
-			return nil, err
-		}
-		if wait <= 0 {
-			l.withMetrics(func(m *LimiterSnapshot) { m.TotalRequests++ })
-			return immediatePermit{}, nil
+		permit, wait, err := l.TryAcquire(domain)
+		if err != nil || wait <= 0 {
+			return permit, err
 		}
-		l.withMetrics(func(m *LimiterSnapshot) { m.Throttled++ })
 		if !sleepWithContext(ctx, l.clock, wait) {
 			return nil, ctx.Err()
 		}
 	}
 }
 
+// TryAcquire is the non-blocking form of Acquire: it returns a permit when domain may
+// be requested now and otherwise how long until it may (nothing is consumed then). It
+// fails with ErrCircuitOpen while the domain's circuit is open.
+func (l *AdaptiveRateLimiter) TryAcquire(domain string) (Permit, time.Duration, error) {
+	if !l.cfg.Enabled {
+		return immediatePermit{}, 0, nil
+	}
+	normalized, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, 0, err
+	}
+	state := l.getOrCreateDomainState(normalized)
+	wait, delayed, err := state.planRequest(l.cfg, l.clock.Now())
+	if err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			l.withMetrics(func(m *LimiterSnapshot) { m.Denied++ })
+		}
+		return nil, 0, err
+	}
+	if wait > 0 {
+		if delayed {
+			l.withMetrics(func(m *LimiterSnapshot) { m.Throttled++ })
+		}
+		return nil, wait, nil
+	}
+	l.withMetrics(func(m *LimiterSnapshot) { m.TotalRequests++ })
+	return immediatePermit{}, 0, nil
+}
+
 func (l *AdaptiveRateLimiter) Feedback(domain string, fb Feedback) {
 	if !l.cfg.Enabled {
 		return
@@ -171,6 +195,30 @@ func (l *AdaptiveRateLimiter) Feedback(domain string, fb Feedback) {
 	state.applyFeedback(l.cfg, fb, l.clock.Now())
 }
 
+// SetDomainCeiling caps the fill rate (requests per second) for domain, e.g. from a
+// robots.txt Crawl-delay. While a ceiling is set the domain gets no burst capacity.
+// A non-positive rps removes the cap.
+func (l *AdaptiveRateLimiter) SetDomainCeiling(domain string, rps float64) {
+	normalized, err := normalizeDomain(domain)
+	if err != nil {
+		return
+	}
+	state := l.getOrCreateDomainState(normalized)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if rps <= 0 {
+		state.ceiling = 0
+		return
+	}
+	state.ceiling = rps
+	if state.fillRate > rps {
+		state.fillRate = rps
+	}
+	if state.tokens > 1 {
+		state.tokens = 1
+	}
+}
+
 func (l *AdaptiveRateLimiter) Snapshot() LimiterSnapshot {
 	base := func() LimiterSnapshot { l.metricsMu.Lock(); defer l.metricsMu.Unlock(); return l.metrics }()
 	var open, halfOpen int64
@@ -188,7 +236,7 @@ func (l *AdaptiveRateLimiter) Snapshot() LimiterSnapshot {
 				cs = "half-open"
 				halfOpen++
 			}
-			domains = append(domains, DomainSummary{Domain: name, FillRate: state.fillRate, CircuitState: cs, LastActivity: state.lastActivity})
+			domains = append(domains, DomainSummary{Domain: name, FillRate: state.fillRate, CircuitState: cs, LastActivity: state.lastActivity, LatencyEWMA: state.latency, ErrorRate: state.window.rate(), Restored: state.restored})
 			state.mu.Unlock()
 		}
 		shard.mu.RUnlock()
@@ -208,6 +256,9 @@ func (l *AdaptiveRateLimiter) Snapshot() LimiterSnapshot {
 	base.Domains = domains
 	base.OpenCircuits = open
 	base.HalfOpenCircuits = halfOpen
+	l.carriedMu.Lock()
+	base.RestoredDomains = l.restored
+	l.carriedMu.Unlock()
 	return base
 }
 
@@ -304,15 +355,74 @@ type domainState struct {
 	breaker      breakerState
 	tokens       float64
 	lastRefill   time.Time
+	// retryAfter holds requests until the server supplied Retry-After deadline.
+	retryAfter time.Time
+	// ceiling caps fillRate (requests per second); zero means uncapped.
+	ceiling float64
+	// waiting is set once a request was told to wait and cleared by the next grant, so
+	// a caller polling until its wait elapses is counted as throttled only once.
+	waiting bool
+	// latency is an exponentially weighted moving average of response latency.
+	latency time.Duration
+	window  errorWindow
+	// restored marks state loaded from a previous run; restoredAt and savedActivity
+	// let an untouched restored domain keep its original last activity when saved.
+	restored      bool
+	restoredAt    time.Time
+	savedActivity time.Time
+}
+
+// latencyAlpha weights the newest sample in the latency EWMA.
+const latencyAlpha = 0.2
+
+// errorWindow records the outcomes of the last 64 requests, one bit per request (1 =
+// failure), newest in the lowest bit.
+type errorWindow struct {
+	bits    uint64
+	samples int
+}
+
+func (w *errorWindow) record(failed bool) {
+	w.bits <<= 1
+	if failed {
+		w.bits |= 1
+	}
+	if w.samples < 64 {
+		w.samples++
+	}
+}
+
+func (w errorWindow) rate() float64 {
+	if w.samples == 0 {
+		return 0
+	}
+	mask := uint64(math.MaxUint64)
+	if w.samples < 64 {
+		mask = 1<<uint(w.samples) - 1
+	}
+	return float64(bits.OnesCount64(w.bits&mask)) / float64(w.samples)
 }
 
 func newDomainState(cfg engmodels.RateLimitConfig, now time.Time) *domainState {
 	return &domainState{lastActivity: now, fillRate: 1, tokens: 1, lastRefill: now}
 }
 
-func (d *domainState) planRequest(cfg engmodels.RateLimitConfig, now time.Time) (time.Duration, error) {
+// planRequest takes a token for a request to the domain now or returns how long to
+// wait for one. delayed reports that the wait starts a new delay rather than answering
+// another poll of a request already waiting.
+func (d *domainState) planRequest(cfg engmodels.RateLimitConfig, now time.Time) (wait time.Duration, delayed bool, err error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	wait, err = d.planLocked(now)
+	if err != nil || wait <= 0 {
+		d.waiting = false
+		return wait, false, err
+	}
+	delayed, d.waiting = !d.waiting, true
+	return wait, delayed, nil
+}
+
+func (d *domainState) planLocked(now time.Time) (time.Duration, error) {
 	d.lastActivity = now
 	// breaker logic
 	if d.breaker.state == circuitOpen {
@@ -322,12 +432,19 @@ func (d *domainState) planRequest(cfg engmodels.RateLimitConfig, now time.Time)
 			return 0, ErrCircuitOpen
 		}
 	}
+	if now.Before(d.retryAfter) {
+		return d.retryAfter.Sub(now), nil
+	}
 	// token refill simplistic
 	elapsed := now.Sub(d.lastRefill).Seconds()
 	if elapsed > 0 {
 		d.tokens += elapsed * d.fillRate
-		if d.tokens > 10 {
-			d.tokens = 10
+		burst := 10.0
+		if d.ceiling > 0 {
+			burst = 1
+		}
+		if d.tokens > burst {
+			d.tokens = burst
 		}
 		d.lastRefill = now
 	}
@@ -335,7 +452,11 @@ func (d *domainState) planRequest(cfg engmodels.RateLimitConfig, now time.Time)
 		d.tokens -= 1
 		return 0, nil
 	}
-	waitSeconds := (1 - d.tokens) / math.Max(d.fillRate, 0.1)
+	minRate := 0.1
+	if d.ceiling > 0 && d.ceiling < minRate {
+		minRate = d.ceiling
+	}
+	waitSeconds := (1 - d.tokens) / math.Max(d.fillRate, minRate)
 	return time.Duration(waitSeconds * float64(time.Second)), nil
 }
 
@@ -343,8 +464,26 @@ func (d *domainState) applyFeedback(cfg engmodels.RateLimitConfig, fb Feedback,
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.lastActivity = now
+	if retryAfter := fb.RetryAfter; retryAfter > 0 {
+		// Server supplied delays are capped so a hostile Retry-After cannot stall the domain.
+		if cfg.RetryMaxDelay > 0 && retryAfter > cfg.RetryMaxDelay {
+			retryAfter = cfg.RetryMaxDelay
+		}
+		if until := now.Add(retryAfter); until.After(d.retryAfter) {
+			d.retryAfter = until
+		}
+	}
+	if fb.Latency > 0 {
+		if d.latency == 0 {
+			d.latency = fb.Latency
+		} else {
+			d.latency = time.Duration(latencyAlpha*float64(fb.Latency) + (1-latencyAlpha)*float64(d.latency))
+		}
+	}
+	failed := fb.Err != nil || fb.StatusCode >= 500 || fb.StatusCode == 429
+	d.window.record(failed)
 	// adjust fill rate heuristically
-	if fb.Err != nil || fb.StatusCode >= 500 || fb.StatusCode == 429 {
+	if failed {
 		d.fillRate *= 0.8
 		if d.fillRate < 0.1 {
 			d.fillRate = 0.1
@@ -359,6 +498,9 @@ func (d *domainState) applyFeedback(cfg engmodels.RateLimitConfig, fb Feedback,
 			d.breaker.successes++
 		}
 	}
+	if d.ceiling > 0 && d.fillRate > d.ceiling {
+		d.fillRate = d.ceiling
+	}
 	if d.breaker.state == circuitHalfOpen {
 		if d.breaker.successes >= 3 {
 			d.breaker = breakerState{state: circuitClosed}
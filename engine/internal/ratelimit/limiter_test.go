@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func TestDomainCeilingCapsFillRate(t *testing.T) {
+	l := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true})
+	defer func() { _ = l.Close() }()
+	l.SetDomainCeiling("slow.test", 0.05) // Crawl-delay: 20
+	for i := 0; i < 50; i++ {
+		l.Feedback("slow.test", Feedback{StatusCode: 200, Latency: time.Millisecond})
+	}
+	l.Feedback("slow.test", Feedback{StatusCode: 503})
+	for _, d := range l.Snapshot().Domains {
+		if d.Domain == "slow.test" && d.FillRate > 0.05 {
+			t.Fatalf("fill rate %v exceeds ceiling", d.FillRate)
+		}
+	}
+	state := l.getOrCreateDomainState("slow.test")
+	now := time.Now()
+	if wait, _, _ := state.planRequest(l.cfg, now); wait != 0 {
+		t.Fatalf("first request should pass immediately")
+	}
+	if wait, _, _ := state.planRequest(l.cfg, now.Add(time.Second)); wait < 15*time.Second {
+		t.Fatalf("expected crawl-delay sized wait, got %v", wait)
+	}
+}
+
+type fixedClock struct{ now time.Time }
+
+func (c *fixedClock) Now() time.Time      { return c.now }
+func (c *fixedClock) Sleep(time.Duration) {}
+
+func TestTryAcquireReportsWait(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	l := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true}).WithClock(clock)
+	defer func() { _ = l.Close() }()
+	if permit, wait, err := l.TryAcquire("a.test"); err != nil || wait != 0 || permit == nil {
+		t.Fatalf("first request: permit=%v wait=%v err=%v", permit, wait, err)
+	}
+	permit, wait, err := l.TryAcquire("a.test")
+	if err != nil || permit != nil || wait <= 0 || wait > time.Second {
+		t.Fatalf("expected a wait without permit, got permit=%v wait=%v err=%v", permit, wait, err)
+	}
+	if permit, _, _ := l.TryAcquire("b.test"); permit == nil {
+		t.Fatalf("a throttled domain must not hold back another")
+	}
+	// Polling again while waiting is the same delayed request.
+	if permit, _, _ := l.TryAcquire("a.test"); permit != nil {
+		t.Fatalf("expected the wait to continue")
+	}
+	clock.now = clock.now.Add(wait)
+	if permit, wait, _ := l.TryAcquire("a.test"); permit == nil || wait != 0 {
+		t.Fatalf("expected a permit once the wait elapsed, got wait=%v", wait)
+	}
+	if snap := l.Snapshot(); snap.TotalRequests != 3 || snap.Throttled != 1 {
+		t.Fatalf("unexpected counters: requests=%d throttled=%d", snap.TotalRequests, snap.Throttled)
+	}
+	// The next request to wait is a new delay.
+	if _, wait, _ := l.TryAcquire("a.test"); wait <= 0 {
+		t.Fatalf("expected a wait after the permit")
+	}
+	if snap := l.Snapshot(); snap.Throttled != 2 {
+		t.Fatalf("expected a second throttle, got %d", snap.Throttled)
+	}
+}
+
+func TestRetryAfterCappedByRetryMaxDelay(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	l := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true, RetryMaxDelay: 5 * time.Second}).WithClock(clock)
+	defer func() { _ = l.Close() }()
+	l.Feedback("busy.test", Feedback{StatusCode: 503, RetryAfter: 24 * time.Hour})
+	if _, wait, _ := l.TryAcquire("busy.test"); wait <= 0 || wait > 5*time.Second {
+		t.Fatalf("expected Retry-After capped at 5s, got wait %v", wait)
+	}
+	clock.now = clock.now.Add(5 * time.Second)
+	if permit, wait, _ := l.TryAcquire("busy.test"); permit == nil {
+		t.Fatalf("expected a permit once the capped delay elapsed, got wait %v", wait)
+	}
+}
+
+func TestLimiterStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.json")
+	clock := &fixedClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	l := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true}).WithClock(clock)
+	for i := 0; i < 5; i++ {
+		l.Feedback("slow.test", Feedback{StatusCode: 503, Latency: 2 * time.Second})
+	}
+	l.Feedback("stale.test", Feedback{StatusCode: 200, Latency: time.Millisecond})
+	l.getOrCreateDomainState("stale.test").lastActivity = clock.now.Add(-48 * time.Hour)
+	if err := l.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+
+	clock.now = clock.now.Add(2 * time.Second)
+	next := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true}).WithClock(clock)
+	defer func() { _ = next.Close() }()
+	n, err := next.LoadState(path, 24*time.Hour)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one domain restored, got %d (%v)", n, err)
+	}
+	snap := next.Snapshot()
+	if snap.RestoredDomains != 1 || len(snap.Domains) != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	d := snap.Domains[0]
+	if d.Domain != "slow.test" || !d.Restored || d.CircuitState != "open" || d.ErrorRate != 1 || d.LatencyEWMA != 2*time.Second || d.FillRate >= 1 {
+		t.Fatalf("state not restored: %+v", d)
+	}
+	if _, err := next.Acquire(context.Background(), "slow.test"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the restored circuit to stay open, got %v", err)
+	}
+	clock.now = clock.now.Add(5 * time.Second)
+	if _, err := next.Acquire(context.Background(), "slow.test"); err != nil {
+		t.Fatalf("expected a half-open probe once the open time elapsed, got %v", err)
+	}
+}
+
+func TestLoadStateRejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.json")
+	if err := os.WriteFile(path, []byte(`{"version":99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := NewAdaptiveRateLimiter(engmodels.RateLimitConfig{Enabled: true})
+	defer func() { _ = l.Close() }()
+	if _, err := l.LoadState(path, 0); err == nil {
+		t.Fatalf("expected an error for an unknown state version")
+	}
+	if n, err := l.LoadState(filepath.Join(t.TempDir(), "missing.json"), 0); err != nil || n != 0 {
+		t.Fatalf("a missing state file must be ignored, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// stateVersion is the on-disk format version written by SaveState.
+const stateVersion = 1
+
+// DefaultStateMaxAge is the age beyond which LoadState ignores a domain's saved state
+// when no maximum is given.
+const DefaultStateMaxAge = 24 * time.Hour
+
+type stateFile struct {
+	Version int            `json:"version"`
+	SavedAt time.Time      `json:"saved_at"`
+	Domains []domainRecord `json:"domains"`
+}
+
+// domainRecord is the persisted form of a domainState. OpenRemaining is how long an
+// open circuit still had to stay open when the state was saved.
+type domainRecord struct {
+	Domain        string        `json:"domain"`
+	FillRate      float64       `json:"fill_rate"`
+	LatencyEWMA   time.Duration `json:"latency_ewma"`
+	ErrorBits     uint64        `json:"error_bits"`
+	ErrorSamples  int           `json:"error_samples"`
+	CircuitState  string        `json:"circuit_state"`
+	Failures      int           `json:"failures,omitempty"`
+	OpenRemaining time.Duration `json:"open_remaining,omitempty"`
+	LastActivity  time.Time     `json:"last_activity"`
+}
+
+// SaveState writes the learned per-domain state (fill rate, latency EWMA, error window
+// and circuit state) to path, replacing it atomically. Domains restored by LoadState
+// that were not used in this run are written back unchanged.
+func (l *AdaptiveRateLimiter) SaveState(path string) error {
+	now := l.clock.Now()
+	out := stateFile{Version: stateVersion, SavedAt: now}
+	live := make(map[string]struct{})
+	for _, shard := range l.shards {
+		shard.mu.RLock()
+		for name, state := range shard.domains {
+			state.mu.Lock()
+			out.Domains = append(out.Domains, state.record(name, now))
+			state.mu.Unlock()
+			live[name] = struct{}{}
+		}
+		shard.mu.RUnlock()
+	}
+	l.carriedMu.Lock()
+	for name, rec := range l.carried {
+		if _, ok := live[name]; !ok {
+			out.Domains = append(out.Domains, rec)
+		}
+	}
+	l.carriedMu.Unlock()
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ratelimit: encode state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("ratelimit: save state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("ratelimit: save state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores per-domain state saved by SaveState, skipping domains whose last
+// activity is older than maxAge (DefaultStateMaxAge when zero or negative). A missing
+// file is not an error. Open circuits stay open for the time they had left when
+// saved, measured from the save time. It returns the number of domains restored.
+func (l *AdaptiveRateLimiter) LoadState(path string, maxAge time.Duration) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ratelimit: load state: %w", err)
+	}
+	var in stateFile
+	if err := json.Unmarshal(data, &in); err != nil {
+		return 0, fmt.Errorf("ratelimit: load state %s: %w", path, err)
+	}
+	if in.Version != stateVersion {
+		return 0, fmt.Errorf("ratelimit: load state %s: unsupported version %d", path, in.Version)
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultStateMaxAge
+	}
+	now := l.clock.Now()
+	restored := 0
+	for _, rec := range in.Domains {
+		if rec.Domain == "" || now.Sub(rec.LastActivity) > maxAge {
+			continue
+		}
+		state := l.getOrCreateDomainState(rec.Domain)
+		state.mu.Lock()
+		state.restore(rec, in.SavedAt, now)
+		state.mu.Unlock()
+		l.carriedMu.Lock()
+		if l.carried == nil {
+			l.carried = make(map[string]domainRecord)
+		}
+		l.carried[rec.Domain] = rec
+		l.carriedMu.Unlock()
+		restored++
+	}
+	l.carriedMu.Lock()
+	l.restored += restored
+	l.carriedMu.Unlock()
+	return restored, nil
+}
+
+func (d *domainState) record(name string, now time.Time) domainRecord {
+	rec := domainRecord{Domain: name, FillRate: d.fillRate, LatencyEWMA: d.latency, ErrorBits: d.window.bits, ErrorSamples: d.window.samples, CircuitState: "closed", Failures: d.breaker.failures, LastActivity: d.lastActivity}
+	if d.restored && d.lastActivity.Equal(d.restoredAt) {
+		rec.LastActivity = d.savedActivity // not used this run: keep ageing
+	}
+	switch d.breaker.state {
+	case circuitOpen:
+		rec.CircuitState = "open"
+		if rem := d.breaker.nextAttempt.Sub(now); rem > 0 {
+			rec.OpenRemaining = rem
+		}
+	case circuitHalfOpen:
+		rec.CircuitState = "half-open"
+	}
+	return rec
+}
+
+// restore applies rec to a freshly created state. Activity is stamped with now so the
+// idle eviction loop does not discard restored domains straight away; record reports
+// the saved activity again unless the domain is used in the meantime.
+func (d *domainState) restore(rec domainRecord, savedAt, now time.Time) {
+	if rec.FillRate > 0 {
+		d.fillRate = rec.FillRate
+	}
+	d.latency = rec.LatencyEWMA
+	d.window = errorWindow{bits: rec.ErrorBits, samples: min(max(rec.ErrorSamples, 0), 64)}
+	d.breaker = breakerState{failures: rec.Failures}
+	switch rec.CircuitState {
+	case "open":
+		d.breaker.state = circuitOpen
+		d.breaker.nextAttempt = savedAt.Add(rec.OpenRemaining)
+	case "half-open":
+		d.breaker.state = circuitHalfOpen
+	}
+	d.lastActivity = now
+	d.restored, d.restoredAt, d.savedActivity = true, now, rec.LastActivity
+}
@@ -7,88 +7,148 @@ package resources
 // data rather than this implementation.
 
 import (
-	"bufio"
 	"container/list"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	engmodels "github.com/99souls/ariadne/engine/models"
 )
 
 type Config struct {
-	CacheCapacity      int
-	MaxInFlight        int
-	SpillDirectory     string
-	CheckpointPath     string
-	CheckpointInterval time.Duration
+	CacheCapacity int
+	// CacheMaxBytes bounds the estimated size (Content, Markdown and CleanedText) of
+	// the cached pages; zero means only CacheCapacity applies.
+	CacheMaxBytes int64
+	// HeapSoftLimit, when set, is compared with the live heap (runtime/metrics) every
+	// HeapCheckInterval (default 250ms). Above it the cache sheds half its bytes per
+	// sample, and Acquire holds back new fetches while shedding and a forced
+	// collection cannot bring the heap below the limit.
+	HeapSoftLimit     int64
+	HeapCheckInterval time.Duration
+	MaxInFlight       int
+	SpillDirectory    string
+	// SpillFormat selects the format of spilled pages (default SpillJSON).
+	SpillFormat SpillFormat
+	// SpillSegmentBytes is the size at which a spill segment rolls over (default 4MiB).
+	SpillSegmentBytes int64
+	// SpillMaxAge and SpillMaxBytes bound the spill directory: files older than
+	// SpillMaxAge and, oldest first, files beyond SpillMaxBytes are deleted. Zero
+	// means no limit.
+	SpillMaxAge   time.Duration
+	SpillMaxBytes int64
+	// SpillReuse adopts spill files left by an earlier run as a warm cache and keeps
+	// the spill files on Close. Without it stale spill files are deleted on startup
+	// and the manager's own on Close. Processed pages (StoreProcessed) are always kept.
+	// Adopted pages are served without revalidation, so SpillReuse requires
+	// SpillMaxAge to bound their age.
+	SpillReuse bool
 }
 
 type Manager struct {
-	cfg          Config
-	slots        chan struct{}
-	mu           sync.Mutex
-	lru          *list.List
-	cache        map[string]*list.Element
-	spill        map[string]string
-	checkpointCh chan string
-	wg           sync.WaitGroup
+	cfg   Config
+	slots chan struct{}
+	mu    sync.Mutex
+	lru   *list.List
+	cache map[string]*list.Element
+	// cacheBytes is the estimated size of the cached pages.
+	cacheBytes int64
+
+	readHeap    func() uint64
+	heapSampled time.Time
+	heapLive    uint64
+	pressure    bool
+	collected   time.Time // last collection forced by awaitHeap
+	shed        int64
+	active      atomic.Int64 // fetches between Acquire and Release
+	throttled   atomic.Int64
+
+	spill      map[string]spillEntry
+	spillFiles []*spillFile // oldest first
+	spillBytes int64
+	segment    *spillFile // active segment, appended to through segmentW
+	segmentW   *os.File
+	nextSeq    int
+	evictions  int64
 }
 
 type Stats struct {
-	CacheEntries     int
-	SpillFiles       int
-	InFlight         int
-	CheckpointQueued int
+	CacheEntries int
+	CacheBytes   int64
+	// SpillFiles and SpillBytes count the files in the spill directory holding
+	// spilled pages (segments count once) and their total size.
+	SpillFiles int
+	SpillBytes int64
+	// Evictions counts pages evicted from the cache, spilled or not.
+	Evictions int64
+	InFlight  int
+	// HeapLive is the last live heap sample (with HeapSoftLimit); Pressure reports it
+	// above the limit. Shed counts pages evicted because of pressure and Throttled the
+	// fetches held back by it.
+	HeapLive  uint64
+	Pressure  bool
+	Shed      int64
+	Throttled int64
 }
 
 func NewManager(cfg Config) (*Manager, error) {
-	m := &Manager{cfg: cfg, lru: list.New(), cache: make(map[string]*list.Element), spill: make(map[string]string)}
+	switch cfg.SpillFormat {
+	case "":
+		cfg.SpillFormat = SpillJSON
+	case SpillJSON, SpillSegments:
+	default:
+		return nil, fmt.Errorf("unknown spill format %q", cfg.SpillFormat)
+	}
+	if cfg.SpillReuse && cfg.SpillMaxAge <= 0 {
+		return nil, errors.New("spill reuse requires a spill max age")
+	}
+	m := &Manager{cfg: cfg, lru: list.New(), cache: make(map[string]*list.Element), spill: make(map[string]spillEntry), readHeap: readHeapLive}
 	if cfg.MaxInFlight > 0 {
 		m.slots = make(chan struct{}, cfg.MaxInFlight)
 	}
 	if cfg.SpillDirectory != "" {
-		if err := os.MkdirAll(cfg.SpillDirectory, 0o755); err != nil {
-			return nil, fmt.Errorf("create spill directory: %w", err)
+		if err := m.openSpill(); err != nil {
+			return nil, err
 		}
 	}
-	if cfg.CheckpointPath != "" {
-		if err := os.MkdirAll(filepath.Dir(cfg.CheckpointPath), 0o755); err != nil {
-			return nil, fmt.Errorf("create checkpoint directory: %w", err)
-		}
-		m.checkpointCh = make(chan string, 1024)
-		m.wg.Add(1)
-		go m.checkpointLoop()
-	}
 	return m, nil
 }
 
+// Close releases the spill directory (see Config.SpillReuse).
 func (m *Manager) Close() error {
-	if m.checkpointCh != nil {
-		close(m.checkpointCh)
-		m.wg.Wait()
+	if m.cfg.SpillDirectory == "" {
+		return nil
 	}
-	return nil
+	return m.closeSpill()
 }
 
 func (m *Manager) Acquire(ctx context.Context) error {
-	if m.slots == nil {
-		return nil
+	if err := m.awaitHeap(ctx); err != nil {
+		return err
 	}
-	select {
-	case m.slots <- struct{}{}:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	m.active.Add(1)
+	return nil
 }
 func (m *Manager) Release() {
+	if m.active.Add(-1) < 0 {
+		m.active.Add(1)
+	}
 	if m.slots == nil {
 		return
 	}
@@ -101,6 +161,7 @@ func (m *Manager) Release() {
 type cacheEntry struct {
 	url  string
 	page *engmodels.Page
+	size int64
 }
 
 func (m *Manager) StorePage(key string, page *engmodels.Page) error {
@@ -110,18 +171,20 @@ func (m *Manager) StorePage(key string, page *engmodels.Page) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	pc := m.deepCopyPage(page)
+	size := estimateSize(pc)
 	if el, ok := m.cache[key]; ok {
-		el.Value.(*cacheEntry).page = pc
+		entry := el.Value.(*cacheEntry)
+		m.cacheBytes += size - entry.size
+		entry.page, entry.size = pc, size
 		m.lru.MoveToFront(el)
-		return nil
+	} else {
+		m.cache[key] = m.lru.PushFront(&cacheEntry{url: key, page: pc, size: size})
+		m.cacheBytes += size
 	}
-	el := m.lru.PushFront(&cacheEntry{url: key, page: pc})
-	m.cache[key] = el
-	if m.cfg.CacheCapacity > 0 {
-		for len(m.cache) > m.cfg.CacheCapacity {
-			m.evictOldest()
-		}
+	for m.overBudgetLocked() {
+		m.evictOldest()
 	}
+	m.pressureLocked(time.Now(), false)
 	return nil
 }
 
@@ -137,97 +200,79 @@ func (m *Manager) GetPage(key string) (*engmodels.Page, bool, error) {
 		m.mu.Unlock()
 		return pg, true, nil
 	}
-	path, spilled := m.spill[key]
+	pg, spilled, err := m.takeSpillLocked(key)
 	m.mu.Unlock()
-	if !spilled {
-		return nil, false, nil
+	if err != nil || !spilled {
+		return nil, false, err
+	}
+	if err := m.StorePage(key, pg); err != nil {
+		return nil, false, err
+	}
+	return pg, true, nil
+}
+
+// StoreProcessed durably keeps the processed form of the page for key in the spill
+// directory so a later run can reuse it when the page is revalidated as unchanged.
+// It is a no-op without a spill directory.
+func (m *Manager) StoreProcessed(key string, page *engmodels.Page) error {
+	if key == "" || page == nil || m.cfg.SpillDirectory == "" {
+		return nil
 	}
-	data, err := os.ReadFile(path)
+	data, err := json.Marshal(page)
 	if err != nil {
-		return nil, false, fmt.Errorf("read spill file: %w", err)
+		return fmt.Errorf("encode processed page: %w", err)
 	}
-	var pg engmodels.Page
-	if err := json.Unmarshal(data, &pg); err != nil {
-		return nil, false, fmt.Errorf("decode spill file: %w", err)
+	path := m.processedPath(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write processed page: %w", err)
 	}
-	pgPtr := &pg
-	if err := m.StorePage(key, pgPtr); err != nil {
-		return nil, false, err
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write processed page: %w", err)
 	}
-	m.mu.Lock()
-	delete(m.spill, key)
-	m.mu.Unlock()
-	return pgPtr, true, nil
+	return nil
 }
 
-func (m *Manager) Checkpoint(u string) {
-	if m.checkpointCh == nil || u == "" {
-		return
+// LoadProcessed returns the page last stored with StoreProcessed for key.
+func (m *Manager) LoadProcessed(key string) (*engmodels.Page, bool, error) {
+	if key == "" || m.cfg.SpillDirectory == "" {
+		return nil, false, nil
 	}
-	select {
-	case m.checkpointCh <- u:
-	default:
-		return
+	data, err := os.ReadFile(m.processedPath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read processed page: %w", err)
+	}
+	var pg engmodels.Page
+	if err := json.Unmarshal(data, &pg); err != nil {
+		return nil, false, fmt.Errorf("decode processed page: %w", err)
 	}
+	return &pg, true, nil
+}
+
+func (m *Manager) processedPath(key string) string {
+	return filepath.Join(m.cfg.SpillDirectory, "processed-"+hashKey(key)+".json")
 }
 
 func (m *Manager) Stats() Stats {
 	var s Stats
 	m.mu.Lock()
 	s.CacheEntries = len(m.cache)
-	s.SpillFiles = len(m.spill)
+	s.CacheBytes = m.cacheBytes
+	s.HeapLive, s.Pressure, s.Shed = m.heapLive, m.pressure, m.shed
+	s.SpillFiles = len(m.spillFiles)
+	s.SpillBytes = m.spillBytes
+	s.Evictions = m.evictions
 	m.mu.Unlock()
 	if m.slots != nil {
 		s.InFlight = len(m.slots)
 	}
-	if m.checkpointCh != nil {
-		s.CheckpointQueued = len(m.checkpointCh)
-	}
+	s.Throttled = m.throttled.Load()
 	return s
 }
 
-func (m *Manager) checkpointLoop() {
-	defer m.wg.Done()
-	interval := m.cfg.CheckpointInterval
-	if interval <= 0 {
-		interval = 50 * time.Millisecond
-	}
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	buf := make([]string, 0, 64)
-	flush := func() {
-		if len(buf) == 0 {
-			return
-		}
-		f, err := os.OpenFile(m.cfg.CheckpointPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-		if err != nil {
-			return
-		}
-		w := bufio.NewWriter(f)
-		for _, e := range buf {
-			_, _ = fmt.Fprintln(w, e)
-		}
-		_ = w.Flush()
-		_ = f.Close()
-		buf = buf[:0]
-	}
-	for {
-		select {
-		case e, ok := <-m.checkpointCh:
-			if !ok {
-				flush()
-				return
-			}
-			buf = append(buf, e)
-			if len(buf) >= 64 {
-				flush()
-			}
-		case <-ticker.C:
-			flush()
-		}
-	}
-}
-
 func (m *Manager) evictOldest() {
 	back := m.lru.Back()
 	if back == nil {
@@ -236,26 +281,16 @@ func (m *Manager) evictOldest() {
 	entry := back.Value.(*cacheEntry)
 	delete(m.cache, entry.url)
 	m.lru.Remove(back)
-	if m.cfg.SpillDirectory == "" {
-		return
-	}
-	filename := fmt.Sprintf("spill-%d-%s.spill.json", time.Now().UnixNano(), hashKey(entry.url))
-	path := filepath.Join(m.cfg.SpillDirectory, filename)
-	data, err := json.Marshal(entry.page)
-	if err != nil {
-		return
-	}
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		return
-	}
-	m.spill[entry.url] = path
+	m.cacheBytes -= entry.size
+	m.evictions++
+	m.spillLocked(entry.url, entry.page)
 }
 
 func (m *Manager) deepCopyPage(p *engmodels.Page) *engmodels.Page {
 	if p == nil {
 		return nil
 	}
-	pc := &engmodels.Page{Title: p.Title, Content: p.Content, CleanedText: p.CleanedText, Markdown: p.Markdown, Images: make([]string, len(p.Images)), CrawledAt: p.CrawledAt, ProcessedAt: p.ProcessedAt}
+	pc := &engmodels.Page{Title: p.Title, Content: p.Content, CleanedText: p.CleanedText, Markdown: p.Markdown, Images: make([]string, len(p.Images)), CrawledAt: p.CrawledAt, ProcessedAt: p.ProcessedAt, ContentHash: p.ContentHash}
 	if p.URL != nil {
 		u := *p.URL
 		pc.URL = &u
@@ -270,8 +305,28 @@ func (m *Manager) deepCopyPage(p *engmodels.Page) *engmodels.Page {
 			}
 		}
 	}
-	pc.Metadata = engmodels.PageMeta{Author: p.Metadata.Author, Description: p.Metadata.Description, Keywords: make([]string, len(p.Metadata.Keywords)), PublishDate: p.Metadata.PublishDate, WordCount: p.Metadata.WordCount, Headers: make(map[string]string), OpenGraph: engmodels.OpenGraphMeta{Title: p.Metadata.OpenGraph.Title, Description: p.Metadata.OpenGraph.Description, Image: p.Metadata.OpenGraph.Image, URL: p.Metadata.OpenGraph.URL, Type: p.Metadata.OpenGraph.Type}}
+	pc.Metadata = p.Metadata
+	pc.Metadata.Keywords = make([]string, len(p.Metadata.Keywords))
 	copy(pc.Metadata.Keywords, p.Metadata.Keywords)
+	pc.Metadata.Headers = make(map[string]string)
+	pc.Metadata.Alternates = append([]engmodels.AlternateLink(nil), p.Metadata.Alternates...)
+	if sd := p.Metadata.Structured; sd != nil {
+		// Raw items are shared: nothing modifies decoded structured data.
+		pc.Metadata.Structured = &engmodels.StructuredData{Articles: append([]engmodels.ArticleData(nil), sd.Articles...), Products: append([]engmodels.ProductData(nil), sd.Products...), FAQ: append([]engmodels.FAQEntry(nil), sd.FAQ...), Breadcrumbs: append([]engmodels.BreadcrumbList(nil), sd.Breadcrumbs...), Raw: append([]map[string]any(nil), sd.Raw...)}
+	}
+	if p.Quality != nil {
+		q := *p.Quality
+		q.Issues = append([]string(nil), q.Issues...)
+		q.SchemaErrors = append([]string(nil), q.SchemaErrors...)
+		pc.Quality = &q
+	}
+	if p.Extracted != nil {
+		// Nested records are shared like structured data: nothing modifies them.
+		pc.Extracted = maps.Clone(p.Extracted)
+	}
+	if len(p.Aliases) > 0 {
+		pc.Aliases = append([]string(nil), p.Aliases...)
+	}
 	for k, v := range p.Metadata.Headers {
 		pc.Metadata.Headers[k] = v
 	}
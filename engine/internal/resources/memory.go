@@ -0,0 +1,114 @@
+package resources
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+const (
+	heapLiveMetric           = "/gc/heap/live:bytes"
+	defaultHeapCheckInterval = 250 * time.Millisecond
+	// pageOverheadBytes approximates what a cached page holds beyond its text bodies
+	// (URL, links, metadata).
+	pageOverheadBytes = 512
+)
+
+// estimateSize is the cache's byte estimate of a page.
+func estimateSize(p *engmodels.Page) int64 {
+	return int64(len(p.Content)+len(p.Markdown)+len(p.CleanedText)+len(p.Title)) + pageOverheadBytes
+}
+
+func readHeapLive() uint64 {
+	s := []metrics.Sample{{Name: heapLiveMetric}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+// overBudgetLocked reports whether the cache exceeds CacheCapacity or CacheMaxBytes.
+// A single page larger than CacheMaxBytes is not kept either.
+func (m *Manager) overBudgetLocked() bool {
+	if m.cfg.CacheCapacity > 0 && len(m.cache) > m.cfg.CacheCapacity {
+		return true
+	}
+	return m.cfg.CacheMaxBytes > 0 && m.cacheBytes > m.cfg.CacheMaxBytes && len(m.cache) > 0
+}
+
+func (m *Manager) heapInterval() time.Duration {
+	if m.cfg.HeapCheckInterval > 0 {
+		return m.cfg.HeapCheckInterval
+	}
+	return defaultHeapCheckInterval
+}
+
+// pressureLocked samples the live heap, at most once per HeapCheckInterval unless
+// forced, and reports whether it exceeds HeapSoftLimit. Every sample above the limit
+// sheds the older half of the cache (by estimated bytes), spilling it when a spill
+// directory is configured.
+func (m *Manager) pressureLocked(now time.Time, force bool) bool {
+	if m.cfg.HeapSoftLimit <= 0 {
+		return false
+	}
+	if !force && now.Sub(m.heapSampled) < m.heapInterval() {
+		return m.pressure
+	}
+	m.heapSampled = now
+	m.heapLive = m.readHeap()
+	m.pressure = m.heapLive > uint64(m.cfg.HeapSoftLimit)
+	if m.pressure {
+		target := m.cacheBytes / 2
+		for len(m.cache) > 0 && m.cacheBytes > target {
+			m.evictOldest()
+			m.shed++
+		}
+	}
+	return m.pressure
+}
+
+// awaitHeap throttles intake while the heap stays above HeapSoftLimit although the
+// cache was shed and a collection forced (at most once per HeapCheckInterval). A
+// fetch is always let through when none is in flight, so the crawl keeps progressing.
+func (m *Manager) awaitHeap(ctx context.Context) error {
+	if m.cfg.HeapSoftLimit <= 0 {
+		return nil
+	}
+	waited := false
+	for {
+		now := time.Now()
+		m.mu.Lock()
+		pressure := m.pressureLocked(now, false)
+		collect := pressure && now.Sub(m.collected) >= m.heapInterval()
+		if collect {
+			m.collected = now
+		}
+		m.mu.Unlock()
+		if !pressure || m.active.Load() == 0 {
+			return nil
+		}
+		if collect {
+			// Shed pages only free memory once collected.
+			runtime.GC()
+			m.mu.Lock()
+			pressure = m.pressureLocked(time.Now(), true)
+			m.mu.Unlock()
+			if !pressure {
+				return nil
+			}
+		}
+		if !waited {
+			waited = true
+			m.throttled.Add(1)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.heapInterval()):
+		}
+	}
+}
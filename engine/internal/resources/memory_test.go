@@ -0,0 +1,111 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func sizedPage(n int) *engmodels.Page {
+	return &engmodels.Page{Content: strings.Repeat("x", n)}
+}
+
+func TestCacheByteBudget(t *testing.T) {
+	pageBytes := estimateSize(sizedPage(1000))
+	m, err := NewManager(Config{CacheMaxBytes: 3 * pageBytes, SpillDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		_ = m.StorePage(fmt.Sprintf("k%d", i), sizedPage(1000))
+	}
+	if st := m.Stats(); st.CacheEntries != 3 || st.CacheBytes != 3*pageBytes || st.Evictions != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	// A page larger than the whole budget goes straight to the spill directory.
+	_ = m.StorePage("huge", sizedPage(10_000))
+	st := m.Stats()
+	if st.CacheEntries != 0 || st.CacheBytes != 0 {
+		t.Fatalf("expected the huge page to flush the cache, got %+v", st)
+	}
+	if pg, hit, err := m.GetPage("huge"); err != nil || !hit || len(pg.Content) != 10_000 {
+		t.Fatalf("expected the huge page from spill, got %v %v", hit, err)
+	}
+}
+
+func TestCacheShedsUnderHeapPressure(t *testing.T) {
+	var heap atomic.Uint64
+	m, err := NewManager(Config{HeapSoftLimit: 1 << 20, HeapCheckInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	m.readHeap = heap.Load
+	for i := 0; i < 8; i++ {
+		_ = m.StorePage(fmt.Sprintf("k%d", i), sizedPage(1000))
+	}
+	if st := m.Stats(); st.CacheEntries != 8 || st.Pressure {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	heap.Store(2 << 20)
+	m.mu.Lock()
+	m.heapSampled = time.Time{}
+	m.mu.Unlock()
+	_ = m.StorePage("k8", sizedPage(1000))
+	st := m.Stats()
+	if !st.Pressure || st.CacheEntries != 4 || st.Shed != 5 || st.HeapLive != 2<<20 {
+		t.Fatalf("expected the cache to shed half its bytes, got %+v", st)
+	}
+	if _, hit, _ := m.GetPage("k8"); !hit {
+		t.Fatalf("newest pages must survive shedding")
+	}
+}
+
+func TestAcquireThrottledUnderHeapPressure(t *testing.T) {
+	var heap atomic.Uint64
+	heap.Store(2 << 20)
+	m, err := NewManager(Config{HeapSoftLimit: 1 << 20, HeapCheckInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	m.readHeap = heap.Load
+	ctx := context.Background()
+	// With nothing in flight one fetch always proceeds.
+	if err := m.Acquire(ctx); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- m.Acquire(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("expected the second fetch to be held back, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	heap.Store(0)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("second acquire: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("second fetch not released once the heap dropped")
+	}
+	if st := m.Stats(); st.Throttled != 1 || st.Pressure {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	m.Release()
+	m.Release()
+
+	heap.Store(2 << 20)
+	time.Sleep(10 * time.Millisecond) // let the last sample expire
+	_ = m.Acquire(ctx)
+	cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := m.Acquire(cctx); err == nil {
+		t.Fatalf("expected the held back fetch to honor its context")
+	}
+}
@@ -0,0 +1,549 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+// SpillFormat selects how pages evicted from the cache are written to the spill
+// directory.
+type SpillFormat string
+
+const (
+	// SpillJSON writes one uncompressed "spill-<nanos>-<hash>.spill.json" file per page.
+	SpillJSON SpillFormat = "json"
+	// SpillSegments appends gzip-compressed pages to "spill-<seq>.seg" segment files.
+	// Each record is framed as uint32 payload length, uint32 CRC-32 (IEEE) of the
+	// payload, then the payload: uvarint key length, key, compressed page JSON.
+	// Segments roll over at SpillSegmentBytes; on Close the live records of every
+	// segment are written to "spill.index" so adopting them needs no rescan.
+	SpillSegments SpillFormat = "segments"
+)
+
+const (
+	spillIndexFile          = "spill.index"
+	spillIndexVersion       = 1
+	defaultSpillSegmentSize = 4 << 20
+	spillHeaderBytes        = 8
+	maxSpillRecordBytes     = 64 << 20
+)
+
+var errSpillCorrupt = errors.New("corrupt spill record")
+
+// spillFile is one file in the spill directory, the unit of retention.
+type spillFile struct {
+	path    string
+	seq     int // segment sequence number; 0 for a JSON file
+	size    int64
+	modTime time.Time
+	keys    []string // keys written to the file, live or not
+	records int
+	live    int
+}
+
+// spillEntry locates the spilled page of a key.
+type spillEntry struct {
+	file   *spillFile
+	offset int64 // record offset within a segment
+	length int64
+}
+
+// spillRecord is the document of a JSON spill file.
+type spillRecord struct {
+	Key  string          `json:"key"`
+	Page *engmodels.Page `json:"page"`
+}
+
+type spillIndex struct {
+	Version  int                       `json:"version"`
+	Segments map[string]indexedSegment `json:"segments"`
+}
+
+type indexedSegment struct {
+	Size    int64          `json:"size"`
+	Records int            `json:"records"`
+	Entries []indexedEntry `json:"entries"`
+}
+
+type indexedEntry struct {
+	Key    string `json:"key"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+}
+
+func isSpillFile(name string) bool {
+	return strings.HasPrefix(name, "spill-") && (strings.HasSuffix(name, ".spill.json") || strings.HasSuffix(name, ".seg"))
+}
+
+func segmentSeq(name string) int {
+	var seq int
+	if !strings.HasSuffix(name, ".seg") {
+		return 0
+	}
+	if _, err := fmt.Sscanf(name, "spill-%d.seg", &seq); err != nil {
+		return 0
+	}
+	return seq
+}
+
+// openSpill prepares the spill directory. With SpillReuse, spill files left by an
+// earlier run are adopted (oldest first, so the newest copy of a page wins) and the
+// retention limits applied; otherwise they are deleted. Files that cannot be read are
+// deleted as well.
+func (m *Manager) openSpill() error {
+	dir := m.cfg.SpillDirectory
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create spill directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read spill directory: %w", err)
+	}
+	index := m.readSpillIndex()
+	_ = os.Remove(filepath.Join(dir, spillIndexFile))
+	var files []*spillFile
+	for _, de := range entries {
+		name := de.Name()
+		path := filepath.Join(dir, name)
+		if strings.HasPrefix(name, "spill-") && strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(path)
+			continue
+		}
+		if !isSpillFile(name) || de.IsDir() {
+			continue
+		}
+		if !m.cfg.SpillReuse {
+			_ = os.Remove(path)
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		f := &spillFile{path: path, seq: segmentSeq(name), size: info.Size(), modTime: info.ModTime()}
+		m.nextSeq = max(m.nextSeq, f.seq)
+		files = append(files, f)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].seq < files[j].seq
+	})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range files {
+		m.spillFiles = append(m.spillFiles, f)
+		m.spillBytes += f.size
+		if err := m.adoptLocked(f, index); err != nil || f.live == 0 {
+			m.removeFileLocked(f)
+		}
+	}
+	m.enforceSpillLocked(time.Now())
+	return nil
+}
+
+// adoptLocked registers the pages of a spill file left by an earlier run.
+func (m *Manager) adoptLocked(f *spillFile, index spillIndex) error {
+	if f.seq == 0 {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+		var rec spillRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		if rec.Key == "" || rec.Page == nil {
+			return errSpillCorrupt
+		}
+		m.addEntryLocked(rec.Key, spillEntry{file: f})
+		return nil
+	}
+	if seg, ok := index.Segments[filepath.Base(f.path)]; ok && seg.Size == f.size {
+		for _, e := range seg.Entries {
+			m.addEntryLocked(e.Key, spillEntry{file: f, offset: e.Offset, length: e.Length})
+		}
+		f.records = max(seg.Records, f.records)
+		return nil
+	}
+	return m.scanSegmentLocked(f)
+}
+
+// scanSegmentLocked rebuilds the entries of a segment missing from the index. A torn
+// trailing record is cut off.
+func (m *Manager) scanSegmentLocked(f *spillFile) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	var off int64
+	var hdr [spillHeaderBytes]byte
+	for {
+		if _, err := file.ReadAt(hdr[:], off); err != nil {
+			break
+		}
+		n := int64(binary.LittleEndian.Uint32(hdr[0:]))
+		if n == 0 || n > maxSpillRecordBytes || off+spillHeaderBytes+n > f.size {
+			break
+		}
+		payload := make([]byte, n)
+		if _, err := file.ReadAt(payload, off+spillHeaderBytes); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(hdr[4:]) {
+			break
+		}
+		key, _, err := splitSpillPayload(payload)
+		if err != nil {
+			break
+		}
+		m.addEntryLocked(key, spillEntry{file: f, offset: off, length: spillHeaderBytes + n})
+		off += spillHeaderBytes + n
+	}
+	if off < f.size {
+		if err := os.Truncate(f.path, off); err != nil {
+			return err
+		}
+		m.spillBytes -= f.size - off
+		f.size = off
+	}
+	return nil
+}
+
+func (m *Manager) readSpillIndex() spillIndex {
+	var index spillIndex
+	data, err := os.ReadFile(filepath.Join(m.cfg.SpillDirectory, spillIndexFile))
+	if err != nil || json.Unmarshal(data, &index) != nil || index.Version != spillIndexVersion {
+		return spillIndex{}
+	}
+	return index
+}
+
+func (m *Manager) writeSpillIndexLocked() error {
+	index := spillIndex{Version: spillIndexVersion, Segments: make(map[string]indexedSegment)}
+	for _, f := range m.spillFiles {
+		if f.seq > 0 {
+			index.Segments[filepath.Base(f.path)] = indexedSegment{Size: f.size, Records: f.records}
+		}
+	}
+	if len(index.Segments) == 0 {
+		return nil
+	}
+	for key, e := range m.spill {
+		if e.file.seq == 0 {
+			continue
+		}
+		name := filepath.Base(e.file.path)
+		seg := index.Segments[name]
+		seg.Entries = append(seg.Entries, indexedEntry{Key: key, Offset: e.offset, Length: e.length})
+		index.Segments[name] = seg
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("encode spill index: %w", err)
+	}
+	path := filepath.Join(m.cfg.SpillDirectory, spillIndexFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("write spill index: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("write spill index: %w", err)
+	}
+	return nil
+}
+
+// spillLocked writes an evicted page to the spill directory and applies the
+// retention limits. Write errors only cost the page its spill copy.
+func (m *Manager) spillLocked(key string, page *engmodels.Page) {
+	if m.cfg.SpillDirectory == "" {
+		return
+	}
+	var (
+		e   spillEntry
+		err error
+	)
+	if m.cfg.SpillFormat == SpillSegments {
+		e, err = m.writeSegmentLocked(key, page)
+	} else {
+		e, err = m.writeJSONLocked(key, page)
+	}
+	if err != nil {
+		return
+	}
+	m.addEntryLocked(key, e)
+	m.enforceSpillLocked(time.Now())
+}
+
+func (m *Manager) writeJSONLocked(key string, page *engmodels.Page) (spillEntry, error) {
+	data, err := json.Marshal(spillRecord{Key: key, Page: page})
+	if err != nil {
+		return spillEntry{}, err
+	}
+	filename := fmt.Sprintf("spill-%d-%s.spill.json", time.Now().UnixNano(), hashKey(key))
+	path := filepath.Join(m.cfg.SpillDirectory, filename)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return spillEntry{}, err
+	}
+	f := &spillFile{path: path, size: int64(len(data)), modTime: time.Now()}
+	m.spillFiles = append(m.spillFiles, f)
+	m.spillBytes += f.size
+	return spillEntry{file: f}, nil
+}
+
+func (m *Manager) writeSegmentLocked(key string, page *engmodels.Page) (spillEntry, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(page); err != nil {
+		return spillEntry{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return spillEntry{}, err
+	}
+	payload := binary.AppendUvarint(nil, uint64(len(key)))
+	payload = append(payload, key...)
+	payload = append(payload, buf.Bytes()...)
+	if len(payload) > maxSpillRecordBytes {
+		return spillEntry{}, fmt.Errorf("spilled page too large (%d bytes)", len(payload))
+	}
+	return m.appendRecordLocked(payload)
+}
+
+// appendRecordLocked appends one framed record to the active segment, rolling over
+// to a new segment once it reached SpillSegmentBytes.
+func (m *Manager) appendRecordLocked(payload []byte) (spillEntry, error) {
+	limit := m.cfg.SpillSegmentBytes
+	if limit <= 0 {
+		limit = defaultSpillSegmentSize
+	}
+	if m.segment != nil && m.segment.size >= limit {
+		m.sealSegmentLocked()
+	}
+	if m.segment == nil {
+		m.nextSeq++
+		path := filepath.Join(m.cfg.SpillDirectory, fmt.Sprintf("spill-%08d.seg", m.nextSeq))
+		w, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return spillEntry{}, err
+		}
+		m.segment, m.segmentW = &spillFile{path: path, seq: m.nextSeq, modTime: time.Now()}, w
+		m.spillFiles = append(m.spillFiles, m.segment)
+	}
+	record := make([]byte, spillHeaderBytes, spillHeaderBytes+len(payload))
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+	f := m.segment
+	if _, err := m.segmentW.Write(record); err != nil {
+		return spillEntry{}, err
+	}
+	e := spillEntry{file: f, offset: f.size, length: int64(len(record))}
+	f.size += e.length
+	f.modTime = time.Now()
+	m.spillBytes += e.length
+	return e, nil
+}
+
+func (m *Manager) sealSegmentLocked() error {
+	if m.segmentW == nil {
+		return nil
+	}
+	err := m.segmentW.Close()
+	m.segment, m.segmentW = nil, nil
+	return err
+}
+
+// addEntryLocked points key at e, releasing any older spilled copy.
+func (m *Manager) addEntryLocked(key string, e spillEntry) {
+	if old, ok := m.spill[key]; ok {
+		delete(m.spill, key)
+		m.releaseLocked(old)
+	}
+	m.spill[key] = e
+	e.file.keys = append(e.file.keys, key)
+	e.file.records++
+	e.file.live++
+}
+
+// releaseLocked drops one live record from its file, deleting the file once nothing
+// in it is live (the active segment excepted).
+func (m *Manager) releaseLocked(e spillEntry) {
+	e.file.live--
+	if e.file.live <= 0 && e.file != m.segment {
+		m.removeFileLocked(e.file)
+	}
+}
+
+// takeSpillLocked reads and forgets the spilled page of key. A sealed segment left
+// with fewer live records than dead ones is compacted: its live records are copied
+// to the active segment and the file deleted.
+func (m *Manager) takeSpillLocked(key string) (*engmodels.Page, bool, error) {
+	e, ok := m.spill[key]
+	if !ok {
+		return nil, false, nil
+	}
+	pg, err := m.readEntry(key, e)
+	if errors.Is(err, os.ErrNotExist) {
+		delete(m.spill, key)
+		m.releaseLocked(e)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	delete(m.spill, key)
+	m.releaseLocked(e)
+	if f := e.file; f.seq > 0 && f != m.segment && f.live > 0 && f.live*2 < f.records {
+		m.compactSegmentLocked(f)
+	}
+	return pg, true, nil
+}
+
+func (m *Manager) readEntry(key string, e spillEntry) (*engmodels.Page, error) {
+	if e.file.seq == 0 {
+		data, err := os.ReadFile(e.file.path)
+		if err != nil {
+			return nil, fmt.Errorf("read spill file: %w", err)
+		}
+		var rec spillRecord
+		if err := json.Unmarshal(data, &rec); err != nil || rec.Page == nil {
+			return nil, fmt.Errorf("decode spill file: %w", errors.Join(err, errSpillCorrupt))
+		}
+		return rec.Page, nil
+	}
+	payload, err := readSpillRecord(e)
+	if err != nil {
+		return nil, fmt.Errorf("read spill segment: %w", err)
+	}
+	k, compressed, err := splitSpillPayload(payload)
+	if err != nil || k != key {
+		return nil, fmt.Errorf("read spill segment: %w", errSpillCorrupt)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("decode spill segment: %w", err)
+	}
+	var pg engmodels.Page
+	if err := json.NewDecoder(zr).Decode(&pg); err != nil {
+		return nil, fmt.Errorf("decode spill segment: %w", err)
+	}
+	return &pg, nil
+}
+
+// readSpillRecord returns the verified payload of the record at e.
+func readSpillRecord(e spillEntry) ([]byte, error) {
+	if e.length <= spillHeaderBytes || e.length > spillHeaderBytes+maxSpillRecordBytes {
+		return nil, errSpillCorrupt
+	}
+	file, err := os.Open(e.file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	record := make([]byte, e.length)
+	if _, err := file.ReadAt(record, e.offset); err != nil {
+		return nil, err
+	}
+	payload := record[spillHeaderBytes:]
+	if int64(binary.LittleEndian.Uint32(record[0:])) != int64(len(payload)) || crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(record[4:]) {
+		return nil, errSpillCorrupt
+	}
+	return payload, nil
+}
+
+func splitSpillPayload(payload []byte) (string, []byte, error) {
+	n, k := binary.Uvarint(payload)
+	if k <= 0 || uint64(len(payload)-k) < n {
+		return "", nil, errSpillCorrupt
+	}
+	return string(payload[k : k+int(n)]), payload[k+int(n):], nil
+}
+
+// compactSegmentLocked moves the live records of f to the active segment, keeping
+// them compressed, and deletes f. Records that cannot be read are dropped.
+func (m *Manager) compactSegmentLocked(f *spillFile) {
+	for _, key := range f.keys {
+		e, ok := m.spill[key]
+		if !ok || e.file != f {
+			continue
+		}
+		delete(m.spill, key)
+		payload, err := readSpillRecord(e)
+		if err != nil {
+			continue
+		}
+		moved, err := m.appendRecordLocked(payload)
+		if err != nil {
+			continue
+		}
+		m.addEntryLocked(key, moved)
+	}
+	m.removeFileLocked(f)
+}
+
+// removeFileLocked deletes a spill file and forgets the pages it holds.
+func (m *Manager) removeFileLocked(f *spillFile) {
+	i := slices.Index(m.spillFiles, f)
+	if i < 0 {
+		return
+	}
+	m.spillFiles = append(m.spillFiles[:i], m.spillFiles[i+1:]...)
+	if f == m.segment {
+		_ = m.sealSegmentLocked()
+	}
+	_ = os.Remove(f.path)
+	m.spillBytes -= f.size
+	for _, key := range f.keys {
+		if e, ok := m.spill[key]; ok && e.file == f {
+			delete(m.spill, key)
+		}
+	}
+	f.keys, f.live = nil, 0
+}
+
+// enforceSpillLocked deletes spill files older than SpillMaxAge and, oldest first,
+// files beyond SpillMaxBytes.
+func (m *Manager) enforceSpillLocked(now time.Time) {
+	for len(m.spillFiles) > 0 {
+		f := m.spillFiles[0]
+		expired := m.cfg.SpillMaxAge > 0 && now.Sub(f.modTime) > m.cfg.SpillMaxAge
+		over := m.cfg.SpillMaxBytes > 0 && m.spillBytes > m.cfg.SpillMaxBytes
+		if !expired && !over {
+			return
+		}
+		m.removeFileLocked(f)
+	}
+}
+
+// closeSpill seals the active segment. With SpillReuse the retention limits are
+// applied and the segment index written; otherwise every spill file is deleted.
+func (m *Manager) closeSpill() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.sealSegmentLocked()
+	if err != nil {
+		err = fmt.Errorf("close spill segment: %w", err)
+	}
+	if !m.cfg.SpillReuse {
+		for len(m.spillFiles) > 0 {
+			m.removeFileLocked(m.spillFiles[0])
+		}
+		return err
+	}
+	m.enforceSpillLocked(time.Now())
+	return errors.Join(err, m.writeSpillIndexLocked())
+}
@@ -0,0 +1,172 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+func testPage(key string) *engmodels.Page {
+	return &engmodels.Page{Title: key, Content: strings.Repeat("<p>"+key+"</p>", 100)}
+}
+
+func spillNames(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "spill-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestSpillRetentionByBytes(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(Config{CacheCapacity: 1, SpillDirectory: dir, SpillMaxBytes: 5000})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		_ = m.StorePage(fmt.Sprintf("k%d", i), testPage(fmt.Sprintf("k%d", i)))
+	}
+	st := m.Stats()
+	if st.Evictions != 5 || st.SpillFiles == 0 || st.SpillFiles >= 5 || st.SpillBytes > 5000 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if len(spillNames(t, dir)) != st.SpillFiles {
+		t.Fatalf("expected %d spill files on disk, got %v", st.SpillFiles, spillNames(t, dir))
+	}
+	if _, hit, _ := m.GetPage("k0"); hit {
+		t.Fatalf("oldest spilled page should have been dropped")
+	}
+	if pg, hit, err := m.GetPage("k4"); err != nil || !hit || pg.Title != "k4" {
+		t.Fatalf("expected newest spilled page, got %v %v %v", pg, hit, err)
+	}
+}
+
+func TestSpillCleanupWithoutReuse(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "spill-1-abc.spill.json")
+	processed := filepath.Join(dir, "processed-abc.json")
+	for _, p := range []string{stale, processed} {
+		if err := os.WriteFile(p, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := NewManager(Config{CacheCapacity: 1, SpillDirectory: dir, SpillFormat: SpillSegments})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale spill file to be removed at startup")
+	}
+	for i := 0; i < 3; i++ {
+		_ = m.StorePage(fmt.Sprintf("k%d", i), testPage(fmt.Sprintf("k%d", i)))
+	}
+	if st := m.Stats(); st.SpillFiles != 1 || st.SpillBytes == 0 {
+		t.Fatalf("expected one segment, got %+v", st)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if names := spillNames(t, dir); len(names) != 0 {
+		t.Fatalf("expected spill files removed on close, got %v", names)
+	}
+	if _, err := os.Stat(processed); err != nil {
+		t.Fatalf("processed pages must survive cleanup: %v", err)
+	}
+}
+
+func TestSpillReuseAdoptsFiles(t *testing.T) {
+	for _, format := range []SpillFormat{SpillJSON, SpillSegments} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := Config{CacheCapacity: 1, SpillDirectory: dir, SpillFormat: format, SpillReuse: true, SpillMaxAge: time.Hour}
+			m, err := NewManager(cfg)
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+			for i := 0; i < 4; i++ {
+				_ = m.StorePage(fmt.Sprintf("k%d", i), testPage(fmt.Sprintf("k%d", i)))
+			}
+			if err := m.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+			warm, err := NewManager(cfg)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer func() { _ = warm.Close() }()
+			for i := 0; i < 3; i++ {
+				key := fmt.Sprintf("k%d", i)
+				if pg, hit, err := warm.GetPage(key); err != nil || !hit || pg.Title != key {
+					t.Fatalf("expected adopted page %s, got %v %v %v", key, pg, hit, err)
+				}
+			}
+			if _, hit, _ := warm.GetPage("k3"); hit {
+				t.Fatalf("pages only held in the cache are not spilled")
+			}
+		})
+	}
+}
+
+func TestSpillSegmentRecovery(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{CacheCapacity: 1, SpillDirectory: dir, SpillFormat: SpillSegments, SpillReuse: true, SpillMaxAge: time.Hour}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		_ = m.StorePage(fmt.Sprintf("k%d", i), testPage(fmt.Sprintf("k%d", i)))
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// Crash: the index is lost and a torn record trails the segment.
+	if err := os.Remove(filepath.Join(dir, spillIndexFile)); err != nil {
+		t.Fatalf("expected a spill index: %v", err)
+	}
+	seg := spillNames(t, dir)[0]
+	f, _ := os.OpenFile(seg, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.Write([]byte{0x40, 0, 0, 0, 1, 2})
+	_ = f.Close()
+
+	r, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	if st := r.Stats(); st.SpillFiles != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	// Taking three of four pages leaves the segment mostly dead, so it is compacted.
+	for i := 0; i < 3; i++ {
+		if _, hit, err := r.GetPage(fmt.Sprintf("k%d", i)); err != nil || !hit {
+			t.Fatalf("expected k%d from the rescanned segment: %v %v", i, hit, err)
+		}
+	}
+	if _, err := os.Stat(seg); !os.IsNotExist(err) {
+		t.Fatalf("expected compacted segment %s to be removed", seg)
+	}
+	if pg, hit, err := r.GetPage("k3"); err != nil || !hit || pg.Title != "k3" {
+		t.Fatalf("expected k3 to survive compaction, got %v %v %v", pg, hit, err)
+	}
+}
+
+func TestNewManagerRejectsUnknownSpillFormat(t *testing.T) {
+	if _, err := NewManager(Config{SpillFormat: "zip"}); err == nil {
+		t.Fatalf("expected an error for an unknown spill format")
+	}
+}
+
+func TestNewManagerRequiresSpillMaxAgeForReuse(t *testing.T) {
+	// Adopted pages are served without revalidation, so their age must be bounded.
+	if _, err := NewManager(Config{SpillDirectory: t.TempDir(), SpillReuse: true}); err == nil {
+		t.Fatalf("expected an error for spill reuse without a max age")
+	}
+}
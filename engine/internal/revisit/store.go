@@ -0,0 +1,163 @@
+// Package revisit persists HTTP cache validators (ETag / Last-Modified) per canonical
+// URL so recrawls can issue conditional requests and skip unchanged pages.
+package revisit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Validators are the response validators recorded for one URL.
+type Validators struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Updated      time.Time `json:"updated"`
+}
+
+// Empty reports whether no validator is present.
+func (v Validators) Empty() bool { return v.ETag == "" && v.LastModified == "" }
+
+type record struct {
+	Key string `json:"key"`
+	Validators
+}
+
+// Store is an append-only JSON lines file of validator records, loaded into memory on
+// Open (last record per key wins) and compacted on Close. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Validators
+	f       *os.File
+	appends int
+}
+
+// Open loads the store at path, creating it if missing. A torn trailing line left by
+// a crash is ignored.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("revisit: %w", err)
+	}
+	s := &Store{path: path, entries: make(map[string]Validators)}
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64<<10), 1<<20)
+		for sc.Scan() {
+			var r record
+			if json.Unmarshal(sc.Bytes(), &r) != nil || r.Key == "" {
+				continue
+			}
+			if r.Empty() {
+				delete(s.entries, r.Key)
+			} else {
+				s.entries[r.Key] = r.Validators
+			}
+		}
+		_ = f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("revisit: read %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("revisit: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("revisit: %w", err)
+	}
+	s.f = f
+	return s, nil
+}
+
+// Get returns the validators recorded for key.
+func (s *Store) Get(key string) (Validators, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.entries[key]
+	return v, ok
+}
+
+// Put records v for key; empty validators remove the entry.
+func (s *Store) Put(key string, v Validators) error {
+	if v.Updated.IsZero() {
+		v.Updated = time.Now().UTC()
+	}
+	line, err := json.Marshal(record{Key: key, Validators: v})
+	if err != nil {
+		return fmt.Errorf("revisit: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("revisit: store closed")
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("revisit: %w", err)
+	}
+	s.appends++
+	if v.Empty() {
+		delete(s.entries, key)
+	} else {
+		s.entries[key] = v
+	}
+	return nil
+}
+
+// Len returns the number of URLs with recorded validators.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close compacts the file to one record per URL when it holds superseded records and
+// releases it.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	if err != nil || s.appends == 0 {
+		return err
+	}
+	return s.compactLocked()
+}
+
+func (s *Store) compactLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("revisit: compact: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for key, v := range s.entries {
+		if err := enc.Encode(record{Key: key, Validators: v}); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("revisit: compact: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("revisit: compact: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("revisit: compact: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("revisit: compact: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("revisit: compact: %w", err)
+	}
+	return nil
+}
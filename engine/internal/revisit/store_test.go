@@ -0,0 +1,49 @@
+package revisit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStorePersistsAndCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "checkpoint.log.validators")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(s.Put("https://a.test/", Validators{ETag: `"v1"`}))
+	must(s.Put("https://a.test/", Validators{ETag: `"v2"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}))
+	must(s.Put("https://a.test/gone", Validators{ETag: `W/"x"`}))
+	must(s.Put("https://a.test/gone", Validators{}))
+	must(s.Close())
+
+	// Simulate a crash mid-append on top of the compacted file.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"key":"https://a.test/torn","et`)
+	_ = f.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	v, ok := s.Get("https://a.test/")
+	if !ok || v.ETag != `"v2"` || v.LastModified == "" || v.Updated.IsZero() {
+		t.Fatalf("unexpected validators: %+v ok=%v", v, ok)
+	}
+	if _, ok := s.Get("https://a.test/gone"); ok || s.Len() != 1 {
+		t.Fatalf("expected removed entry and one key, len=%d", s.Len())
+	}
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Fatalf("expected compacted file with one record (+ torn tail), got %d lines", n)
+	}
+}
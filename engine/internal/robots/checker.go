@@ -0,0 +1,224 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowed is wrapped by errors reported for URLs excluded by robots.txt.
+var ErrDisallowed = errors.New("robots: disallowed")
+
+// ErrUnavailable is also wrapped when the exclusion is only temporary: robots.txt
+// could not be fetched (5xx or transport failure) and is retried after ErrorTTL.
+var ErrUnavailable = errors.New("robots.txt unavailable")
+
+// FetchFunc retrieves a robots.txt URL. It returns the HTTP status and body for any
+// completed response; err is reserved for transport failures.
+type FetchFunc func(ctx context.Context, robotsURL string) (status int, body []byte, err error)
+
+// Config controls robots.txt retrieval and caching.
+type Config struct {
+	// UserAgent selects the robots group; only its product token is used.
+	UserAgent string
+	// CacheTTL is how long a fetched robots.txt is trusted (default 24h).
+	CacheTTL time.Duration
+	// ErrorTTL is how long a host stays fully disallowed after a 5xx or network
+	// failure fetching its robots.txt before the file is retried (default 1m).
+	ErrorTTL time.Duration
+	// Overrides maps a host (as in URL.Host, lowercase) to the robots.txt body used
+	// instead of fetching. An empty body allows everything.
+	Overrides map[string]string
+	// Fetch retrieves robots.txt; nil uses a plain net/http client.
+	Fetch FetchFunc
+}
+
+// Decision is the outcome of a robots check for one URL.
+type Decision struct {
+	Allowed bool
+	// CrawlDelay is the origin's Crawl-delay for the configured agent (zero when absent).
+	CrawlDelay time.Duration
+	// Reason explains a denial: "disallowed" or "robots.txt unavailable".
+	Reason string
+	// RetryAfter is how long a temporary denial lasts before robots.txt is fetched
+	// again.
+	RetryAfter time.Duration
+}
+
+const reasonUnavailable = "robots.txt unavailable"
+
+// Temporary reports whether the denial is due to robots.txt being unavailable rather
+// than its rules, so the URL may be retried.
+func (d Decision) Temporary() bool { return !d.Allowed && d.Reason == reasonUnavailable }
+
+// Err returns nil for allowed decisions and an error wrapping ErrDisallowed otherwise;
+// temporary denials also wrap ErrUnavailable.
+func (d Decision) Err(rawURL string) error {
+	if d.Allowed {
+		return nil
+	}
+	if d.Temporary() {
+		return fmt.Errorf("%w: %s (%w)", ErrDisallowed, rawURL, ErrUnavailable)
+	}
+	return fmt.Errorf("%w: %s (%s)", ErrDisallowed, rawURL, d.Reason)
+}
+
+type policyKind int
+
+const (
+	allowAll policyKind = iota
+	disallowAll
+	useRules
+)
+
+type entry struct {
+	ready   chan struct{}
+	kind    policyKind
+	group   Group
+	expires time.Time
+}
+
+// Checker answers robots decisions from a per-origin cache, fetching robots.txt on
+// first use. Concurrent checks for the same origin share a single fetch.
+type Checker struct {
+	cfg     Config
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewChecker creates a Checker, applying defaults to zero-valued fields.
+func NewChecker(cfg Config) *Checker {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 24 * time.Hour
+	}
+	if cfg.ErrorTTL <= 0 {
+		cfg.ErrorTTL = time.Minute
+	}
+	if cfg.Fetch == nil {
+		cfg.Fetch = httpFetch(&http.Client{Timeout: 30 * time.Second}, cfg.UserAgent)
+	}
+	overrides := make(map[string]string, len(cfg.Overrides))
+	for host, body := range cfg.Overrides {
+		overrides[strings.ToLower(host)] = body
+	}
+	cfg.Overrides = overrides
+	return &Checker{cfg: cfg, entries: make(map[string]*entry), now: time.Now}
+}
+
+// Check evaluates u against its origin's robots.txt. Non-http(s) URLs are allowed.
+func (c *Checker) Check(ctx context.Context, u *url.URL) Decision {
+	if u == nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Decision{Allowed: true}
+	}
+	e, err := c.lookup(ctx, u)
+	if err != nil {
+		return Decision{Allowed: false, Reason: reasonUnavailable}
+	}
+	switch e.kind {
+	case disallowAll:
+		return Decision{Allowed: false, Reason: reasonUnavailable, RetryAfter: max(e.expires.Sub(c.now()), 0)}
+	case useRules:
+		path := u.EscapedPath()
+		if u.RawQuery != "" {
+			path += "?" + u.RawQuery
+		}
+		if !e.group.Allowed(path) {
+			return Decision{Allowed: false, CrawlDelay: e.group.CrawlDelay, Reason: "disallowed"}
+		}
+		return Decision{Allowed: true, CrawlDelay: e.group.CrawlDelay}
+	default:
+		return Decision{Allowed: true}
+	}
+}
+
+func (c *Checker) lookup(ctx context.Context, u *url.URL) (*entry, error) {
+	host := strings.ToLower(u.Host)
+	origin := u.Scheme + "://" + host
+	for {
+		c.mu.Lock()
+		e, ok := c.entries[origin]
+		if ok {
+			c.mu.Unlock()
+			select {
+			case <-e.ready:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if c.now().Before(e.expires) {
+				return e, nil
+			}
+			c.mu.Lock()
+			if c.entries[origin] == e {
+				delete(c.entries, origin)
+			}
+			c.mu.Unlock()
+			continue
+		}
+		e = &entry{ready: make(chan struct{})}
+		c.entries[origin] = e
+		c.mu.Unlock()
+
+		if err := c.load(ctx, e, origin, host); err != nil {
+			// Abandoned by the caller: forget the entry so the next check refetches.
+			c.mu.Lock()
+			delete(c.entries, origin)
+			c.mu.Unlock()
+			close(e.ready)
+			return nil, err
+		}
+		close(e.ready)
+		return e, nil
+	}
+}
+
+// load populates e following RFC 9309 error handling: 4xx allows everything, 5xx and
+// transport failures disallow everything for ErrorTTL.
+func (c *Checker) load(ctx context.Context, e *entry, origin, host string) error {
+	now := c.now()
+	if body, ok := c.cfg.Overrides[host]; ok {
+		e.kind, e.group, e.expires = useRules, Parse([]byte(body)).Group(c.cfg.UserAgent), now.Add(c.cfg.CacheTTL)
+		return nil
+	}
+	status, body, err := c.cfg.Fetch(ctx, origin+"/robots.txt")
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	switch {
+	case err != nil || status >= 500:
+		e.kind, e.expires = disallowAll, now.Add(c.cfg.ErrorTTL)
+	case status >= 400:
+		e.kind, e.expires = allowAll, now.Add(c.cfg.CacheTTL)
+	default:
+		e.kind, e.group, e.expires = useRules, Parse(body).Group(c.cfg.UserAgent), now.Add(c.cfg.CacheTTL)
+	}
+	return nil
+}
+
+func httpFetch(client *http.Client, userAgent string) FetchFunc {
+	return func(ctx context.Context, robotsURL string) (int, []byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		if userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			return 0, nil, err
+		}
+		return resp.StatusCode, body, nil
+	}
+}
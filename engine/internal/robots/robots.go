@@ -0,0 +1,193 @@
+// Package robots implements robots.txt parsing and a per-origin policy cache used by
+// the pipeline to honor crawl exclusions and Crawl-delay hints.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodyBytes bounds the parsed portion of a robots.txt file (RFC 9309 requires at
+// least 500 KiB to be honored).
+const maxBodyBytes = 512 << 10
+
+// Robots is a parsed robots.txt file.
+type Robots struct {
+	groups []group
+	// Sitemaps lists Sitemap: URLs in file order (they apply to all agents).
+	Sitemaps []string
+}
+
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// Group is the merged rule set that applies to one user agent.
+type Group struct {
+	rules []rule
+	// CrawlDelay is the requested delay between requests (zero when absent).
+	CrawlDelay time.Duration
+}
+
+// Parse parses a robots.txt body. Unknown directives and malformed lines are ignored;
+// rules appearing before any User-agent line are dropped.
+func Parse(body []byte) *Robots {
+	if len(body) > maxBodyBytes {
+		body = body[:maxBodyBytes]
+	}
+	r := &Robots{}
+	var cur *group
+	inAgents := false
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 0, 4096), maxBodyBytes)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				r.groups = append(r.groups, group{})
+				cur = &r.groups[len(r.groups)-1]
+				inAgents = true
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if cur == nil || value == "" {
+				continue // empty Disallow means "allow everything"
+			}
+			cur.rules = append(cur.rules, rule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			inAgents = false
+			if cur == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				r.Sitemaps = append(r.Sitemaps, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return r
+}
+
+// Group returns the rules for userAgent: every group naming the agent's product token
+// (case-insensitive) merged together, or the "*" groups when none match.
+func (r *Robots) Group(userAgent string) Group {
+	token := ProductToken(userAgent)
+	var specific, wildcard Group
+	var matched bool
+	for _, g := range r.groups {
+		switch {
+		case token != "" && g.names(token):
+			specific.merge(g)
+			matched = true
+		case g.names("*"):
+			wildcard.merge(g)
+		}
+	}
+	if matched {
+		return specific
+	}
+	return wildcard
+}
+
+func (g group) names(agent string) bool {
+	for _, a := range g.agents {
+		if a == agent {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) merge(o group) {
+	g.rules = append(g.rules, o.rules...)
+	if o.crawlDelay > g.CrawlDelay {
+		g.CrawlDelay = o.crawlDelay
+	}
+}
+
+// Allowed reports whether path (escaped path plus optional "?query") may be fetched.
+// The longest matching pattern wins; Allow wins ties. /robots.txt is always allowed.
+func (g Group) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	best, allowed := -1, true
+	for _, rl := range g.rules {
+		if !matchPattern(rl.pattern, path) {
+			continue
+		}
+		n := len(rl.pattern)
+		if n > best || (n == best && rl.allow) {
+			best, allowed = n, rl.allow
+		}
+	}
+	return allowed
+}
+
+// ProductToken extracts the lowercase product token used for group matching, e.g.
+// "Ariadne/1.0 (+https://...)" yields "ariadne".
+func ProductToken(userAgent string) string {
+	token := strings.TrimSpace(userAgent)
+	if i := strings.IndexAny(token, "/ ("); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// matchPattern implements robots.txt path matching: '*' matches any sequence and a
+// trailing '$' anchors the pattern to the end of the path.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, mid := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, mid)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(mid):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,157 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const sample = `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/ok$
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: Ariadne
+User-agent: OtherBot
+Disallow: /tmp
+Allow: /tmp/public
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseAndMatch(t *testing.T) {
+	r := Parse([]byte(sample))
+	if len(r.Sitemaps) != 1 || r.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Fatalf("unexpected sitemaps: %v", r.Sitemaps)
+	}
+	generic := r.Group("SomeBot/2.0")
+	specific := r.Group("Ariadne/1.0 (+https://github.com/99souls/ariadne)")
+	if generic.CrawlDelay != 2*time.Second || specific.CrawlDelay != 500*time.Millisecond {
+		t.Fatalf("unexpected crawl delays: %v %v", generic.CrawlDelay, specific.CrawlDelay)
+	}
+	cases := []struct {
+		g    Group
+		path string
+		want bool
+	}{
+		{generic, "/", true},
+		{generic, "/private/x", false},
+		{generic, "/private/ok", true},
+		{generic, "/private/ok/more", false},
+		{generic, "/docs/a.pdf", false},
+		{generic, "/docs/a.pdf?x=1", true},
+		{generic, "/robots.txt", true},
+		{specific, "/private/x", true}, // specific group replaces "*"
+		{specific, "/tmp/secret", false},
+		{specific, "/tmp/public/page", true},
+	}
+	for _, c := range cases {
+		if got := c.g.Allowed(c.path); got != c.want {
+			t.Errorf("Allowed(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := map[[2]string]bool{
+		{"/a*b*c$", "/a-x-b-y-c"}:                     true,
+		{"/a*b*c$", "/a-x-b-y-c-d"}:                   false,
+		{"*", "/anything"}:                            true,
+		{"/fish*.php", "/fishheads/catfish.php?id=2"}: true,
+		{"/fish", "/Fish"}:                            false,
+	}
+	for in, want := range cases {
+		if got := matchPattern(in[0], in[1]); got != want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestCheckerStatusHandlingAndCache(t *testing.T) {
+	var calls int32
+	statuses := map[string]int{"https://gone.test/robots.txt": 404, "https://down.test/robots.txt": 503}
+	c := NewChecker(Config{
+		UserAgent: "Ariadne/1.0",
+		ErrorTTL:  time.Minute,
+		Overrides: map[string]string{"Override.test:8080": "User-agent: *\nDisallow: /"},
+		Fetch: func(ctx context.Context, robotsURL string) (int, []byte, error) {
+			atomic.AddInt32(&calls, 1)
+			if robotsURL == "https://offline.test/robots.txt" {
+				return 0, nil, errors.New("dial failed")
+			}
+			if st, ok := statuses[robotsURL]; ok {
+				return st, nil, nil
+			}
+			return 200, []byte(sample), nil
+		},
+	})
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+	check := func(raw string) Decision {
+		u, _ := url.Parse(raw)
+		return c.Check(context.Background(), u)
+	}
+
+	if !check("https://gone.test/anything").Allowed {
+		t.Fatalf("4xx robots.txt must allow everything")
+	}
+	if d := check("https://down.test/a"); d.Allowed || !d.Temporary() || d.RetryAfter != time.Minute || !errors.Is(d.Err("x"), ErrDisallowed) || !errors.Is(d.Err("x"), ErrUnavailable) {
+		t.Fatalf("5xx robots.txt must disallow temporarily, got %+v", d)
+	}
+	if d := check("https://site.test/tmp/x"); d.Temporary() || errors.Is(d.Err("x"), ErrUnavailable) {
+		t.Fatalf("rule exclusions are not temporary, got %+v", d)
+	}
+	if check("https://offline.test/a").Allowed {
+		t.Fatalf("network failure must disallow")
+	}
+	if check("http://override.test:8080/a").Allowed {
+		t.Fatalf("override body must apply")
+	}
+	if d := check("https://site.test/tmp/x"); d.Allowed || d.CrawlDelay != 500*time.Millisecond {
+		t.Fatalf("unexpected decision %+v", d)
+	}
+	check("https://site.test/other")
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("expected one fetch per origin (4), got %d", n)
+	}
+
+	// Errors are retried after ErrorTTL, successes cached for CacheTTL.
+	now = now.Add(2 * time.Minute)
+	statuses["https://down.test/robots.txt"] = 200
+	if !check("https://down.test/a").Allowed {
+		t.Fatalf("expected recovery after ErrorTTL")
+	}
+	check("https://site.test/a")
+	if n := atomic.LoadInt32(&calls); n != 5 {
+		t.Fatalf("expected only the failed origin refetched, got %d calls", n)
+	}
+}
+
+func TestCheckerSharesInFlightFetch(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := NewChecker(Config{Fetch: func(ctx context.Context, robotsURL string) (int, []byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 200, nil, nil
+	}})
+	u, _ := url.Parse("https://busy.test/")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); c.Check(context.Background(), u) }()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected single fetch, got %d", n)
+	}
+}
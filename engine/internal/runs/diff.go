@@ -0,0 +1,358 @@
+package runs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is one page that differs between two runs.
+type Change struct {
+	URL     string
+	Title   string
+	OldHash string
+	NewHash string
+	// Diff is the unified diff of the stored page text (modified pages only).
+	Diff string
+}
+
+// Diff lists the pages added, removed and modified between two runs, each sorted by URL.
+type Diff struct {
+	OldRun    string
+	NewRun    string
+	Added     []Change
+	Removed   []Change
+	Modified  []Change
+	Unchanged int
+}
+
+// Compare diffs the runs old and cur. Modified pages carry a unified diff of their text
+// with context lines of context (zero or negative for none).
+func Compare(old, cur *Manifest, context int) (*Diff, error) {
+	d := &Diff{OldRun: old.RunID, NewRun: cur.RunID}
+	before := make(map[string]Entry, len(old.Pages))
+	for _, e := range old.Pages {
+		before[e.URL] = e
+	}
+	seen := make(map[string]struct{}, len(cur.Pages))
+	for _, e := range sortedEntries(cur.Pages) {
+		seen[e.URL] = struct{}{}
+		prev, ok := before[e.URL]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, Change{URL: e.URL, Title: e.Title, NewHash: e.ContentHash})
+		case prev.ContentHash == e.ContentHash:
+			d.Unchanged++
+		default:
+			a, err := old.Text(prev)
+			if err != nil {
+				return nil, err
+			}
+			b, err := cur.Text(e)
+			if err != nil {
+				return nil, err
+			}
+			title := e.Title
+			if title == "" {
+				title = prev.Title
+			}
+			d.Modified = append(d.Modified, Change{URL: e.URL, Title: title, OldHash: prev.ContentHash, NewHash: e.ContentHash,
+				Diff: Unified(old.RunID+"/"+e.URL, cur.RunID+"/"+e.URL, a, b, context)})
+		}
+	}
+	for _, e := range sortedEntries(old.Pages) {
+		if _, ok := seen[e.URL]; !ok {
+			d.Removed = append(d.Removed, Change{URL: e.URL, Title: e.Title, OldHash: e.ContentHash})
+		}
+	}
+	return d, nil
+}
+
+func sortedEntries(entries []Entry) []Entry {
+	out := append([]Entry(nil), entries...)
+	sort.Slice(out, func(i, j int) bool { return out[i].URL < out[j].URL })
+	return out
+}
+
+// maxEditDistance bounds the line diff search (time grows with it, memory does not);
+// beyond it the differing middle section is reported as one replacement.
+const maxEditDistance = 4096
+
+// Unified returns a unified diff (as produced by diff -u) turning a into b, or "" when
+// they are equal.
+func Unified(oldName, newName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	if context < 0 {
+		context = 0
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(edits); {
+		if edits[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(edits) {
+			if edits[end].kind != opEqual {
+				end++
+				continue
+			}
+			j := end
+			for j < len(edits) && edits[j].kind == opEqual {
+				j++
+			}
+			if j == len(edits) || j-end > 2*context {
+				break
+			}
+			end = j
+		}
+		stop := min(end+context, len(edits))
+		writeHunk(&sb, edits[start:stop])
+		i = stop
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type opKind int8
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// edit is one line of the edit script; a and b are the line's index in the old and new
+// text (for inserts and deletes, the index of the next line on the other side).
+type edit struct {
+	kind opKind
+	a, b int
+	line string
+}
+
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	var aLen, bLen int
+	for _, e := range hunk {
+		if e.kind != opInsert {
+			aLen++
+		}
+		if e.kind != opDelete {
+			bLen++
+		}
+	}
+	aStart, bStart := hunk[0].a, hunk[0].b
+	if aLen > 0 {
+		aStart++
+	}
+	if bLen > 0 {
+		bStart++
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, e := range hunk {
+		switch e.kind {
+		case opEqual:
+			sb.WriteByte(' ')
+		case opDelete:
+			sb.WriteByte('-')
+		case opInsert:
+			sb.WriteByte('+')
+		}
+		sb.WriteString(e.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm after trimming the
+// common prefix and suffix.
+func diffLines(a, b []string) []edit {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	edits := make([]edit, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		edits = append(edits, edit{kind: opEqual, a: i, b: i, line: a[i]})
+	}
+	edits = append(edits, myers(a[pre:len(a)-suf], b[pre:len(b)-suf], pre)...)
+	for i := suf; i > 0; i-- {
+		ai, bi := len(a)-i, len(b)-i
+		edits = append(edits, edit{kind: opEqual, a: ai, b: bi, line: a[ai]})
+	}
+	return edits
+}
+
+// myers diffs a and b, whose first lines sit at index off in both full texts, with the
+// linear-space (middle snake) variant of Myers' algorithm.
+func myers(a, b []string, off int) []edit {
+	if out, ok := bisect(nil, a, b, off, off, maxEditDistance); ok {
+		return deletesFirst(out)
+	}
+	// Too different to search exhaustively: replace the whole section.
+	out := make([]edit, 0, len(a)+len(b))
+	for i, l := range a {
+		out = append(out, edit{kind: opDelete, a: off + i, b: off, line: l})
+	}
+	for j, l := range b {
+		out = append(out, edit{kind: opInsert, a: off + len(a), b: off + j, line: l})
+	}
+	return out
+}
+
+// bisect appends the edits turning a into b, which start at aOff and bOff in the full
+// texts, splitting the problem at a middle snake. It reports false if the edit distance
+// exceeds limit (negative: unbounded); out is then incomplete.
+func bisect(out []edit, a, b []string, aOff, bOff, limit int) ([]edit, bool) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		out = append(out, edit{kind: opEqual, a: aOff, b: bOff, line: a[0]})
+		a, b, aOff, bOff = a[1:], b[1:], aOff+1, bOff+1
+	}
+	suf := 0
+	for suf < len(a) && suf < len(b) && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	n, m := len(a)-suf, len(b)-suf
+	switch {
+	case n == 0:
+		for j := 0; j < m; j++ {
+			out = append(out, edit{kind: opInsert, a: aOff, b: bOff + j, line: b[j]})
+		}
+	case m == 0:
+		for i := 0; i < n; i++ {
+			out = append(out, edit{kind: opDelete, a: aOff + i, b: bOff, line: a[i]})
+		}
+	default:
+		x, y, u, v, ok := middleSnake(a[:n], b[:m], limit)
+		if !ok {
+			return out, false
+		}
+		// Both halves are strictly smaller: each side of a middle snake of a trimmed
+		// problem holds at least one edit.
+		out, _ = bisect(out, a[:x], b[:y], aOff, bOff, -1)
+		for ; x < u; x, y = x+1, y+1 {
+			out = append(out, edit{kind: opEqual, a: aOff + x, b: bOff + y, line: a[x]})
+		}
+		out, _ = bisect(out, a[u:n], b[v:m], aOff+u, bOff+v, -1)
+	}
+	for i := 0; i < suf; i++ {
+		out = append(out, edit{kind: opEqual, a: aOff + n + i, b: bOff + m + i, line: a[n+i]})
+	}
+	return out, true
+}
+
+// middleSnake runs the forward and reverse searches of Myers' algorithm towards each
+// other and returns the snake (x,y)-(u,v) where they meet, which lies on a shortest
+// edit path. It reports false if the edit distance exceeds limit (negative: unbounded).
+// Only two diagonal vectors are kept, so memory is linear in len(a)+len(b).
+func middleSnake(a, b []string, limit int) (x, y, u, v int, ok bool) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	if limit >= 0 {
+		maxD = min(maxD, (limit+1)/2)
+	}
+	// fwd[c+k] is the furthest x reached on diagonal k (x-y) from (0,0), or -1;
+	// rev[c+r] is the smallest x reached on diagonal delta+r from (n,m), or n+1.
+	c := maxD + 1
+	fwd := make([]int, 2*maxD+3)
+	rev := make([]int, 2*maxD+3)
+	for i := range fwd {
+		fwd[i], rev[i] = -1, n+1
+	}
+	fwd[c+1], rev[c-1] = 0, n
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			// Step down from diagonal k+1 or right from k-1, whichever gets further.
+			x := -1
+			if p := fwd[c+k+1]; p >= 0 && p-k <= m {
+				x = p
+			}
+			if p := fwd[c+k-1]; p >= 0 && p < n && p+1 > x {
+				x = p + 1
+			}
+			if x < 0 {
+				fwd[c+k] = -1
+				continue
+			}
+			sx, sy := x, x-k
+			for x < n && x-k < m && a[x] == b[x-k] {
+				x++
+			}
+			fwd[c+k] = x
+			if r := k - delta; odd && r >= 1-d && r <= d-1 && rev[c+r] <= x {
+				return sx, sy, x, x - k, true
+			}
+		}
+		for r := -d; r <= d; r += 2 {
+			k := delta + r
+			// Step up from diagonal k-1 or left from k+1, whichever gets further back.
+			x := n + 1
+			if p := rev[c+r-1]; p <= n && p-k >= 0 {
+				x = p
+			}
+			if p := rev[c+r+1]; p <= n && p > 0 && p-1 < x {
+				x = p - 1
+			}
+			if x > n {
+				rev[c+r] = n + 1
+				continue
+			}
+			ex, ey := x, x-k
+			for x > 0 && x-k > 0 && a[x-1] == b[x-k-1] {
+				x--
+			}
+			rev[c+r] = x
+			if !odd && k >= -d && k <= d && fwd[c+k] >= x {
+				return x, x - k, ex, ey, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// deletesFirst reorders every run of changed lines so its deletions precede its
+// insertions, as diff -u prints them.
+func deletesFirst(edits []edit) []edit {
+	for i := 0; i < len(edits); {
+		if edits[i].kind == opEqual {
+			i++
+			continue
+		}
+		j := i
+		var del, ins []edit
+		for ; j < len(edits) && edits[j].kind != opEqual; j++ {
+			if edits[j].kind == opDelete {
+				del = append(del, edits[j])
+			} else {
+				ins = append(ins, edits[j])
+			}
+		}
+		a, b := edits[i].a, edits[i].b
+		k := i
+		for n, e := range del {
+			edits[k] = edit{kind: opDelete, a: a + n, b: b, line: e.line}
+			k++
+		}
+		for n, e := range ins {
+			edits[k] = edit{kind: opInsert, a: a + len(del), b: b + n, line: e.line}
+			k++
+		}
+		i = j
+	}
+	return edits
+}
@@ -0,0 +1,243 @@
+// Package runs records a manifest of the pages produced by a crawl run (URL, title and
+// content hash, plus the page text for diffing) and compares two runs.
+package runs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+const (
+	manifestFile = "manifest.json"
+	pagesDir     = "pages"
+	hashPrefix   = "sha256:"
+)
+
+// ContentHash returns a hash of the page text that ignores formatting noise:
+// whitespace runs in cleanedText collapse to one space, and markdown has line endings
+// normalized and trailing spaces trimmed. Equal hashes mean unchanged content.
+func ContentHash(cleanedText, markdown string) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join(strings.Fields(cleanedText), " ")))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeMarkdown(markdown)))
+	return hashPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizeMarkdown(md string) string {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Entry describes one page of a run.
+type Entry struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	ContentHash string    `json:"content_hash"`
+	CrawledAt   time.Time `json:"crawled_at"`
+}
+
+// Manifest is the persisted record of a run: manifest.json in the run directory, with
+// page texts stored as pages/<hash>.md.
+type Manifest struct {
+	RunID      string    `json:"run_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Pages      []Entry   `json:"pages"`
+
+	dir string
+}
+
+// Dir returns the run directory the manifest was loaded from.
+func (m *Manifest) Dir() string { return m.dir }
+
+// Text returns the stored markdown (or cleaned text) of e.
+func (m *Manifest) Text(e Entry) (string, error) {
+	if e.ContentHash == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(pagePath(m.dir, e.ContentHash))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("runs: %w", err)
+	}
+	return string(data), nil
+}
+
+// Load reads the manifest of the run stored in dir.
+func Load(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("runs: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("runs: decode %s: %w", filepath.Join(dir, manifestFile), err)
+	}
+	m.dir = dir
+	return &m, nil
+}
+
+func pagePath(dir, hash string) string {
+	return filepath.Join(dir, pagesDir, strings.TrimPrefix(hash, hashPrefix)+".md")
+}
+
+// Recorder builds the manifest of a running crawl. It has the shape of an output sink:
+// Write records a processed page, Flush persists the manifest so far and Close
+// finalizes it. It is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	manifest Manifest
+	index    map[string]int
+	stored   map[string]struct{}
+	closed   bool
+}
+
+// Create starts recording run runID into dir, which must not already hold a manifest.
+func Create(dir, runID string, startedAt time.Time) (*Recorder, error) {
+	if _, err := os.Stat(filepath.Join(dir, manifestFile)); err == nil {
+		return nil, fmt.Errorf("runs: %s already holds a run manifest", dir)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, pagesDir), 0o755); err != nil {
+		return nil, fmt.Errorf("runs: %w", err)
+	}
+	return &Recorder{
+		manifest: Manifest{RunID: runID, StartedAt: startedAt.UTC(), Pages: []Entry{}, dir: dir},
+		index:    make(map[string]int),
+		stored:   make(map[string]struct{}),
+	}, nil
+}
+
+// Append resumes recording into the run stored in dir: its pages are kept and pages
+// written again replace their entries. A dir without a manifest starts a new run.
+func Append(dir, runID string, startedAt time.Time) (*Recorder, error) {
+	if _, err := os.Stat(filepath.Join(dir, manifestFile)); errors.Is(err, os.ErrNotExist) {
+		return Create(dir, runID, startedAt)
+	}
+	m, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, pagesDir), 0o755); err != nil {
+		return nil, fmt.Errorf("runs: %w", err)
+	}
+	r := &Recorder{manifest: *m, index: make(map[string]int), stored: make(map[string]struct{})}
+	if r.manifest.Pages == nil {
+		r.manifest.Pages = []Entry{}
+	}
+	for i, e := range r.manifest.Pages {
+		r.index[e.URL] = i
+		if e.ContentHash != "" {
+			r.stored[e.ContentHash] = struct{}{}
+		}
+	}
+	return r, nil
+}
+
+// Name implements the output sink interface.
+func (r *Recorder) Name() string { return "run-manifest" }
+
+// Dir returns the run directory.
+func (r *Recorder) Dir() string { return r.manifest.dir }
+
+// Len returns the number of recorded pages.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.manifest.Pages)
+}
+
+// Write records page, storing its text once per distinct content hash.
+func (r *Recorder) Write(_ context.Context, page *models.Page) error {
+	if page == nil || page.URL == nil {
+		return nil
+	}
+	hash := page.ContentHash
+	if hash == "" {
+		hash = ContentHash(page.CleanedText, page.Markdown)
+	}
+	text := page.Markdown
+	if strings.TrimSpace(text) == "" {
+		text = page.CleanedText
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("runs: recorder closed")
+	}
+	if _, ok := r.stored[hash]; !ok {
+		if err := writeFileAtomic(pagePath(r.manifest.dir, hash), []byte(text)); err != nil {
+			return err
+		}
+		r.stored[hash] = struct{}{}
+	}
+	e := Entry{URL: page.URL.String(), Title: page.Title, ContentHash: hash, CrawledAt: page.CrawledAt}
+	if i, ok := r.index[e.URL]; ok {
+		r.manifest.Pages[i] = e
+	} else {
+		r.index[e.URL] = len(r.manifest.Pages)
+		r.manifest.Pages = append(r.manifest.Pages, e)
+	}
+	return nil
+}
+
+// Flush persists the manifest recorded so far.
+func (r *Recorder) Flush(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	return r.saveLocked()
+}
+
+// Close stamps the finish time and persists the final manifest.
+func (r *Recorder) Close(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.manifest.FinishedAt = time.Now().UTC()
+	return r.saveLocked()
+}
+
+func (r *Recorder) saveLocked() error {
+	m := r.manifest
+	m.Pages = append([]Entry(nil), m.Pages...)
+	sort.Slice(m.Pages, func(i, j int) bool { return m.Pages[i].URL < m.Pages[j].URL })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("runs: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(m.dir, manifestFile), append(data, '\n'))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("runs: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("runs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package runs
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99souls/ariadne/engine/models"
+)
+
+func TestContentHashIgnoresFormattingNoise(t *testing.T) {
+	a := ContentHash("Hello   world\n\tagain", "# Title  \r\n\r\nBody\n")
+	b := ContentHash(" Hello world again ", "# Title\n\nBody")
+	if a != b || !strings.HasPrefix(a, "sha256:") {
+		t.Fatalf("expected equal normalized hashes, got %s vs %s", a, b)
+	}
+	if a == ContentHash("Hello world again", "# Title\n\nBody changed") {
+		t.Fatalf("different markdown must change the hash")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	cur := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,4 +1,4 @@
+ a
+-b
++B
+ c
+ d
+@@ -9,2 +9,3 @@
+ i
+ j
++k
+`
+	if got := Unified("old", "new", old, cur, 2); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	if got := Unified("old", "new", "", "x\n", 3); got != "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+x\n" {
+		t.Fatalf("unexpected diff against empty text:\n%s", got)
+	}
+	if Unified("old", "new", old, old, 3) != "" {
+		t.Fatalf("equal texts must produce no diff")
+	}
+	if got := Unified("old", "new", old, cur, 0); got != "--- old\n+++ new\n@@ -2,1 +2,1 @@\n-b\n+B\n@@ -10,0 +11,1 @@\n+k\n" {
+		t.Fatalf("unexpected diff without context:\n%s", got)
+	}
+}
+
+func TestDiffLinesIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := func() []string {
+		out := make([]string, rng.Intn(12))
+		for i := range out {
+			out[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return out
+	}
+	for n := 0; n < 2000; n++ {
+		a, b := words(), words()
+		edits := diffLines(a, b)
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.kind != opInsert {
+				if a[e.a] != e.line {
+					t.Fatalf("%q -> %q: bad old index in %+v", a, b, e)
+				}
+				gotA = append(gotA, e.line)
+			}
+			if e.kind != opDelete {
+				if b[e.b] != e.line {
+					t.Fatalf("%q -> %q: bad new index in %+v", a, b, e)
+				}
+				gotB = append(gotB, e.line)
+			}
+			if e.kind != opEqual {
+				changes++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("%q -> %q: edit script does not reproduce the texts: %+v", a, b, edits)
+		}
+		// Shortest edit script length via the longest common subsequence.
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs[0][0]; changes != want {
+			t.Fatalf("%q -> %q: %d changes, shortest is %d", a, b, changes, want)
+		}
+	}
+
+	// Past maxEditDistance the differing section is replaced wholesale.
+	var a, b []string
+	for i := 0; i < maxEditDistance; i++ {
+		a, b = append(a, "old "+strconv.Itoa(i)), append(b, "new "+strconv.Itoa(i))
+	}
+	edits := diffLines(a, b)
+	if len(edits) != 2*maxEditDistance || edits[0].kind != opDelete || edits[maxEditDistance].kind != opInsert {
+		t.Fatalf("expected a wholesale replacement, got %d edits", len(edits))
+	}
+}
+
+func TestRecorderAndCompare(t *testing.T) {
+	root := t.TempDir()
+	record := func(id string, pages map[string]string) *Manifest {
+		t.Helper()
+		rec, err := Create(filepath.Join(root, id), id, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for raw, md := range pages {
+			u, _ := url.Parse(raw)
+			if err := rec.Write(context.Background(), &models.Page{URL: u, Title: "T " + u.Path, Markdown: md}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rec.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Create(filepath.Join(root, id), id, time.Now()); err == nil {
+			t.Fatalf("reusing a run directory must fail")
+		}
+		m, err := Load(filepath.Join(root, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	old := record("r1", map[string]string{"https://x.test/keep": "same\n", "https://x.test/edit": "one\ntwo\n", "https://x.test/gone": "bye\n"})
+	cur := record("r2", map[string]string{"https://x.test/keep": "same\n", "https://x.test/edit": "one\n2\n", "https://x.test/new": "hi\n"})
+	rec, err := Append(filepath.Join(root, "r2"), "r2", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://x.test/late")
+	if err := rec.Write(context.Background(), &models.Page{URL: u, Markdown: "late\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if appended, err := Load(filepath.Join(root, "r2")); err != nil || len(appended.Pages) != 4 || !appended.StartedAt.Equal(cur.StartedAt) {
+		t.Fatalf("expected the appended run to keep its pages and start time: %+v %v", appended, err)
+	}
+	d, err := Compare(old, cur, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Added) != 1 || d.Added[0].URL != "https://x.test/new" || len(d.Removed) != 1 || d.Removed[0].URL != "https://x.test/gone" || d.Unchanged != 1 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+	if len(d.Modified) != 1 || !strings.Contains(d.Modified[0].Diff, "-two\n+2\n") || !strings.HasPrefix(d.Modified[0].Diff, "--- r1/https://x.test/edit\n") {
+		t.Fatalf("unexpected modification: %+v", d.Modified)
+	}
+}
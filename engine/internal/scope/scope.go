@@ -0,0 +1,258 @@
+// Package scope decides which URLs belong to a crawl: domain allow/block lists, path
+// prefixes, include/exclude patterns and per-host budgets. Every rejection names the
+// rule responsible so it can be reported.
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Rule names reported for rejected URLs. Pattern rules are reported as
+// "<rule>:<pattern>".
+const (
+	RuleScheme          = "scheme"
+	RuleMaxDepth        = "max_depth"
+	RuleMaxPages        = "max_pages"
+	RuleMaxPagesPerHost = "max_pages_per_host"
+	RuleMaxBytesPerHost = "max_bytes_per_host"
+	// RuleOffsite rejects hosts outside the seed hosts when no AllowedDomains are set.
+	RuleOffsite        = "offsite"
+	RuleAllowedDomains = "allowed_domains"
+	RuleBlockedDomain  = "blocked_domain"
+	RulePathPrefix     = "path_prefix"
+	RuleInclude        = "include"
+	RuleExclude        = "exclude"
+	// RuleCancelled rejects URLs matching a pattern cancelled while the crawl runs.
+	RuleCancelled = "cancelled"
+)
+
+// Config lists the scope rules. Domains match the host and its subdomains. Patterns
+// prefixed "re:" are regular expressions matched against the whole URL; other patterns
+// are globs (* within a path segment, ** across segments, ? one character) matched
+// against the URL path when they start with "/" and against the whole URL otherwise.
+type Config struct {
+	AllowedDomains []string
+	BlockedDomains []string
+	PathPrefixes   []string
+	Include        []string
+	Exclude        []string
+
+	MaxPagesPerHost int
+	MaxBytesPerHost int64
+}
+
+type pattern struct {
+	src  string
+	re   *regexp.Regexp
+	path bool
+}
+
+func (p pattern) match(u *url.URL) bool {
+	if p.path {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return p.re.MatchString(path)
+	}
+	v := *u
+	v.Fragment = ""
+	return p.re.MatchString(v.String())
+}
+
+// Pattern is a single compiled pattern in the Config.Include / Exclude syntax.
+type Pattern struct{ p pattern }
+
+// CompilePattern compiles s ("re:" regular expression or glob).
+func CompilePattern(s string) (Pattern, error) {
+	ps, err := compilePatterns([]string{s})
+	if err != nil {
+		return Pattern{}, err
+	}
+	if len(ps) == 0 {
+		return Pattern{}, errors.New("scope: empty pattern")
+	}
+	return Pattern{ps[0]}, nil
+}
+
+// Match reports whether u matches the pattern.
+func (p Pattern) Match(u *url.URL) bool { return p.p.re != nil && p.p.match(u) }
+
+// String returns the pattern source.
+func (p Pattern) String() string { return p.p.src }
+
+// Rules is a compiled Config. A nil *Rules restricts nothing beyond the seed hosts.
+type Rules struct {
+	allowed, blocked []string
+	prefixes         []string
+	include, exclude []pattern
+
+	MaxPagesPerHost int
+	MaxBytesPerHost int64
+}
+
+// Compile validates cfg and compiles its patterns.
+func Compile(cfg Config) (*Rules, error) {
+	r := &Rules{MaxPagesPerHost: cfg.MaxPagesPerHost, MaxBytesPerHost: cfg.MaxBytesPerHost}
+	for _, d := range cfg.AllowedDomains {
+		if d = normalizeDomain(d); d != "" {
+			r.allowed = append(r.allowed, d)
+		}
+	}
+	for _, d := range cfg.BlockedDomains {
+		if d = normalizeDomain(d); d != "" {
+			r.blocked = append(r.blocked, d)
+		}
+	}
+	for _, p := range cfg.PathPrefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			if !strings.HasPrefix(p, "/") {
+				p = "/" + p
+			}
+			r.prefixes = append(r.prefixes, p)
+		}
+	}
+	var err error
+	if r.include, err = compilePatterns(cfg.Include); err != nil {
+		return nil, err
+	}
+	if r.exclude, err = compilePatterns(cfg.Exclude); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func compilePatterns(src []string) ([]pattern, error) {
+	var out []pattern
+	for _, s := range src {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(s, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("scope: pattern %q: %w", s, err)
+			}
+			out = append(out, pattern{src: s, re: re})
+			continue
+		}
+		out = append(out, pattern{src: s, re: globRegexp(s), path: strings.HasPrefix(s, "/")})
+	}
+	return out, nil
+}
+
+// globRegexp translates a glob into an anchored regular expression.
+func globRegexp(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func normalizeDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	d = strings.TrimPrefix(d, "*.")
+	return strings.TrimSuffix(d, ".")
+}
+
+// hostMatches reports whether host is domain or one of its subdomains.
+func hostMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// HasAllowedDomains reports whether AllowedDomains replaces the seed-host restriction.
+func (r *Rules) HasAllowedDomains() bool { return r != nil && len(r.allowed) > 0 }
+
+// Check applies the static rules to u (whose canonical host is host) and returns the
+// name of the first rule that rejects it, or "" when u is in scope. Blocked domains
+// are checked first, then allowed domains, path prefixes, exclude and include rules.
+func (r *Rules) Check(u *url.URL, host string) string {
+	if r == nil {
+		return ""
+	}
+	host = hostname(host)
+	for _, d := range r.blocked {
+		if hostMatches(host, d) {
+			return RuleBlockedDomain + ":" + d
+		}
+	}
+	if len(r.allowed) > 0 {
+		ok := false
+		for _, d := range r.allowed {
+			if hostMatches(host, d) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return RuleAllowedDomains
+		}
+	}
+	if len(r.prefixes) > 0 {
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		ok := false
+		for _, p := range r.prefixes {
+			if strings.HasPrefix(path, p) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return RulePathPrefix
+		}
+	}
+	for _, p := range r.exclude {
+		if p.match(u) {
+			return RuleExclude + ":" + p.src
+		}
+	}
+	if len(r.include) > 0 {
+		for _, p := range r.include {
+			if p.match(u) {
+				return ""
+			}
+		}
+		return RuleInclude
+	}
+	return ""
+}
+
+// hostname strips a port from a canonical host.
+func hostname(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		return host[:i]
+	}
+	return host
+}
+
+// ErrOutOfScope is wrapped by errors reported for admitted URLs dropped at dispatch
+// because their host exhausted a budget.
+var ErrOutOfScope = errors.New("scope: out of scope")
+
+// Err returns an error wrapping ErrOutOfScope that names rule.
+func Err(rawURL, rule string) error {
+	return fmt.Errorf("%w: %s (rule %s)", ErrOutOfScope, rawURL, rule)
+}
@@ -0,0 +1,58 @@
+package scope
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestCheckReportsFirstRejectingRule(t *testing.T) {
+	r, err := Compile(Config{
+		AllowedDomains: []string{"Example.com"},
+		BlockedDomains: []string{"ads.example.com"},
+		PathPrefixes:   []string{"docs"},
+		Include:        []string{"/docs/**", "re:[?&]lang=en"},
+		Exclude:        []string{"/docs/*/draft-?", "https://example.com/docs/private/**"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"https://example.com/docs/guide":            "",
+		"https://www.example.com:8443/docs/a/b/c":   "",
+		"https://ads.example.com/docs/guide":        RuleBlockedDomain + ":ads.example.com",
+		"https://notexample.com/docs/guide":         RuleAllowedDomains,
+		"https://example.com/blog/post":             RulePathPrefix,
+		"https://example.com/docs/v1/draft-2":       RuleExclude + ":/docs/*/draft-?",
+		"https://example.com/docs/v1/x/draft-2":     "",
+		"https://example.com/docs/private/a/b#frag": RuleExclude + ":https://example.com/docs/private/**",
+		"https://example.com/docsearch?lang=en":     "",
+		"https://example.com/docsearch?lang=de":     RuleInclude,
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := r.Check(u, u.Host); got != want {
+			t.Errorf("%s: got rule %q, want %q", raw, got, want)
+		}
+	}
+	if !r.HasAllowedDomains() {
+		t.Fatalf("expected allowed domains to be reported")
+	}
+}
+
+func TestNilRulesAllowEverything(t *testing.T) {
+	var r *Rules
+	u, _ := url.Parse("https://anything.test/x")
+	if r.Check(u, u.Host) != "" || r.HasAllowedDomains() {
+		t.Fatalf("nil rules must not restrict")
+	}
+}
+
+func TestCompileRejectsInvalidRegexp(t *testing.T) {
+	if _, err := Compile(Config{Exclude: []string{"re:("}}); err == nil {
+		t.Fatalf("expected an error for an invalid regular expression")
+	}
+	if err := Err("https://x.test/", RuleMaxBytesPerHost); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("expected ErrOutOfScope, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Package sitemap parses XML sitemaps and sitemap indexes (optionally gzip
+// compressed) and turns them into prioritized seed lists.
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99souls/ariadne/engine/internal/robots"
+)
+
+// maxBodyBytes caps a single (decompressed) sitemap; the protocol limit is 50MB.
+const maxBodyBytes = 50 << 20
+
+// DefaultPriority is the protocol default for entries without <priority>.
+const DefaultPriority = 0.5
+
+// wellKnownPaths are probed when robots.txt lists no sitemaps.
+var wellKnownPaths = []string{"/sitemap.xml", "/sitemap_index.xml"}
+
+// FetchFunc retrieves a URL, returning the HTTP status and body of any completed
+// response; err is reserved for transport failures.
+type FetchFunc func(ctx context.Context, rawURL string) (status int, body []byte, err error)
+
+// Entry is a single <url> element.
+type Entry struct {
+	Loc      string
+	LastMod  time.Time // zero when absent or unparseable
+	Priority float64
+}
+
+// Config controls sitemap loading.
+type Config struct {
+	// URLs lists sitemap or sitemap index URLs to load explicitly.
+	URLs []string
+	// Discover probes each seed origin's robots.txt Sitemap lines, falling back to
+	// well-known paths when robots.txt lists none.
+	Discover bool
+	// Since drops entries whose <lastmod> is before it (entries without lastmod are kept).
+	Since time.Time
+	// MaxEntries caps the number of returned entries (0 = unlimited).
+	MaxEntries int
+	// MaxIndexDepth bounds sitemap index recursion (default 3).
+	MaxIndexDepth int
+}
+
+// Result summarizes a load. Errors are collected rather than aborting so one broken
+// sitemap does not discard the rest.
+type Result struct {
+	Entries  []Entry
+	Sitemaps int // sitemaps successfully parsed (indexes included)
+	Filtered int // entries dropped by Since
+	Errors   []error
+}
+
+// Load resolves and parses all sitemaps for cfg and returns the de-duplicated entries
+// ordered by descending priority (stable within equal priority).
+func Load(ctx context.Context, fetch FetchFunc, cfg Config, seeds []string) Result {
+	if cfg.MaxIndexDepth <= 0 {
+		cfg.MaxIndexDepth = 3
+	}
+	l := &loader{fetch: fetch, cfg: cfg, visited: map[string]bool{}, seen: map[string]bool{}}
+	for _, u := range cfg.URLs {
+		l.load(ctx, u, 0, true)
+	}
+	if cfg.Discover {
+		for _, origin := range origins(seeds) {
+			l.discover(ctx, origin)
+		}
+	}
+	sort.SliceStable(l.res.Entries, func(i, j int) bool { return l.res.Entries[i].Priority > l.res.Entries[j].Priority })
+	if cfg.MaxEntries > 0 && len(l.res.Entries) > cfg.MaxEntries {
+		l.res.Entries = l.res.Entries[:cfg.MaxEntries]
+	}
+	return l.res
+}
+
+type loader struct {
+	fetch   FetchFunc
+	cfg     Config
+	visited map[string]bool
+	seen    map[string]bool
+	res     Result
+}
+
+func (l *loader) discover(ctx context.Context, origin string) {
+	status, body, err := l.fetch(ctx, origin+"/robots.txt")
+	if err == nil && status >= 200 && status < 300 {
+		if listed := robots.Parse(body).Sitemaps; len(listed) > 0 {
+			for _, u := range listed {
+				l.load(ctx, u, 0, true)
+			}
+			return
+		}
+	}
+	for _, p := range wellKnownPaths {
+		// Probes are speculative: a missing well-known sitemap is not an error.
+		l.load(ctx, origin+p, 0, false)
+	}
+}
+
+func (l *loader) load(ctx context.Context, rawURL string, depth int, required bool) {
+	if l.visited[rawURL] || ctx.Err() != nil {
+		return
+	}
+	l.visited[rawURL] = true
+	status, body, err := l.fetch(ctx, rawURL)
+	if err == nil && (status < 200 || status > 299) {
+		if !required {
+			return
+		}
+		err = fmt.Errorf("HTTP %d", status)
+	}
+	if err != nil {
+		l.res.Errors = append(l.res.Errors, fmt.Errorf("sitemap %s: %w", rawURL, err))
+		return
+	}
+	entries, children, err := Parse(body)
+	if err != nil {
+		if required {
+			l.res.Errors = append(l.res.Errors, fmt.Errorf("sitemap %s: %w", rawURL, err))
+		}
+		return
+	}
+	l.res.Sitemaps++
+	for _, e := range entries {
+		if l.seen[e.Loc] {
+			continue
+		}
+		if !l.cfg.Since.IsZero() && !e.LastMod.IsZero() && e.LastMod.Before(l.cfg.Since) {
+			l.res.Filtered++
+			continue
+		}
+		l.seen[e.Loc] = true
+		l.res.Entries = append(l.res.Entries, e)
+	}
+	for _, child := range children {
+		if depth+1 > l.cfg.MaxIndexDepth {
+			l.res.Errors = append(l.res.Errors, fmt.Errorf("sitemap %s: index nesting exceeds %d", child, l.cfg.MaxIndexDepth))
+			continue
+		}
+		l.load(ctx, child, depth+1, true)
+	}
+}
+
+type xmlLoc struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod"`
+	Priority string `xml:"priority"`
+}
+
+type xmlDoc struct {
+	XMLName  xml.Name
+	URLs     []xmlLoc `xml:"url"`
+	Sitemaps []xmlLoc `xml:"sitemap"`
+}
+
+// Parse decodes a <urlset> or <sitemapindex> document, transparently decompressing
+// gzip input. It returns page entries and child sitemap URLs respectively.
+func Parse(body []byte) (entries []Entry, sitemaps []string, err error) {
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %w", err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, maxBodyBytes))
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %w", err)
+		}
+	}
+	var doc xmlDoc
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parse: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			if !isHTTPURL(loc) {
+				continue
+			}
+			entries = append(entries, Entry{Loc: loc, LastMod: parseLastMod(u.LastMod), Priority: parsePriority(u.Priority)})
+		}
+	case "sitemapindex":
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); isHTTPURL(loc) {
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	default:
+		return nil, nil, errors.New("parse: root element must be urlset or sitemapindex, got " + doc.XMLName.Local)
+	}
+	return entries, sitemaps, nil
+}
+
+func parseLastMod(v string) time.Time {
+	v = strings.TrimSpace(v)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parsePriority(v string) float64 {
+	p, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || p < 0 || p > 1 {
+		return DefaultPriority
+	}
+	return p
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func origins(seeds []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range seeds {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		origin := u.Scheme + "://" + strings.ToLower(u.Host)
+		if !seen[origin] {
+			seen[origin] = true
+			out = append(out, origin)
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gz(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type site map[string][]byte
+
+func (s site) fetch(ctx context.Context, raw string) (int, []byte, error) {
+	if raw == "https://down.test/sitemap.xml" {
+		return 0, nil, errors.New("connection refused")
+	}
+	if body, ok := s[raw]; ok {
+		return 200, body, nil
+	}
+	return 404, nil, nil
+}
+
+func TestParse(t *testing.T) {
+	entries, children, err := Parse(gz(t, `<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://a.test/x </loc><lastmod>2024-05-01</lastmod><priority>0.9</priority></url>
+  <url><loc>https://a.test/y</loc><priority>bogus</priority></url>
+  <url><loc>mailto:nobody@a.test</loc></url>
+</urlset>`))
+	if err != nil || len(children) != 0 {
+		t.Fatalf("parse: %v children=%v", err, children)
+	}
+	if len(entries) != 2 || entries[0].Loc != "https://a.test/x" || entries[0].Priority != 0.9 || entries[1].Priority != DefaultPriority {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if !entries[0].LastMod.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !entries[1].LastMod.IsZero() {
+		t.Fatalf("unexpected lastmod: %+v", entries)
+	}
+	if _, _, err := Parse([]byte(`<html></html>`)); err == nil {
+		t.Fatalf("expected error for non-sitemap root")
+	}
+}
+
+func TestLoadDiscoversIndexesAndFilters(t *testing.T) {
+	s := site{
+		"https://a.test/robots.txt": []byte("User-agent: *\nSitemap: https://a.test/index.xml\n"),
+		"https://a.test/index.xml": []byte(`<sitemapindex><sitemap><loc>https://a.test/pages.xml.gz</loc></sitemap>
+			<sitemap><loc>https://a.test/broken.xml</loc></sitemap><sitemap><loc>https://a.test/index.xml</loc></sitemap></sitemapindex>`),
+		"https://a.test/broken.xml":  []byte(`<urlset><url><loc>`),
+		"https://b.test/sitemap.xml": []byte(`<urlset><url><loc>https://b.test/only</loc></url></urlset>`),
+	}
+	s["https://a.test/pages.xml.gz"] = gz(t, `<urlset>
+		<url><loc>https://a.test/old</loc><lastmod>2020-01-01T00:00:00Z</lastmod></url>
+		<url><loc>https://a.test/low</loc><priority>0.1</priority></url>
+		<url><loc>https://a.test/high</loc><lastmod>2025-01-01</lastmod><priority>1.0</priority></url>
+		<url><loc>https://a.test/high</loc></url>
+	</urlset>`)
+
+	res := Load(context.Background(), s.fetch, Config{Discover: true, Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		[]string{"https://a.test/", "https://a.test/docs", "https://b.test/", "https://down.test/"})
+
+	var locs []string
+	for _, e := range res.Entries {
+		locs = append(locs, e.Loc)
+	}
+	if got := strings.Join(locs, ","); got != "https://a.test/high,https://b.test/only,https://a.test/low" {
+		t.Fatalf("unexpected entries (priority order, dedup, lastmod filter): %s", got)
+	}
+	if res.Filtered != 1 || res.Sitemaps != 3 {
+		t.Fatalf("expected 1 filtered and 3 parsed sitemaps, got %+v", res)
+	}
+	if len(res.Errors) != 2 {
+		t.Fatalf("expected broken sitemap and unreachable host errors, got %v", res.Errors)
+	}
+	if !strings.Contains(res.Errors[0].Error(), "broken.xml") {
+		t.Fatalf("unexpected first error: %v", res.Errors[0])
+	}
+
+	capped := Load(context.Background(), s.fetch, Config{URLs: []string{"https://a.test/pages.xml.gz"}, MaxEntries: 1}, nil)
+	if len(capped.Entries) != 1 || capped.Entries[0].Loc != "https://a.test/high" {
+		t.Fatalf("expected cap to keep the highest priority entry, got %+v", capped.Entries)
+	}
+}
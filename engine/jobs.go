@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	engpipeline "github.com/99souls/ariadne/engine/internal/pipeline"
+	"github.com/99souls/ariadne/engine/internal/runs"
+	engmodels "github.com/99souls/ariadne/engine/models"
+)
+
+// maxFinishedJobs bounds how many finished jobs Snapshot.Jobs keeps reporting.
+const maxFinishedJobs = 32
+
+// JobSpec describes a crawl submitted with Engine.Submit.
+// Experimental: Field set may change before v1.0.
+type JobSpec struct {
+	// Name identifies the job in Snapshot.Jobs and events (default "job-<n>"). It must
+	// be unique among running jobs.
+	Name  string
+	Seeds []string
+	// Scope replaces Config.Scope for this job when non-nil.
+	Scope *ScopeConfig
+}
+
+// JobState is the lifecycle state of a submitted job.
+// Experimental.
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobCompleted JobState = "completed"
+	JobCancelled JobState = "cancelled"
+)
+
+// JobSnapshot is one job's share of Snapshot.Jobs.
+// Experimental: Field set may change before v1.0.
+type JobSnapshot struct {
+	Name       string    `json:"name"`
+	State      JobState  `json:"state"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	// Processed and Failed count the job's successful and failed results.
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+	Admitted  int `json:"admitted"`
+	Pending   int `json:"pending"`
+	Queued    int `json:"queued"`
+	// Rejected counts URLs kept out of the job by its scope.
+	Rejected int `json:"rejected"`
+}
+
+// Job is a crawl submitted with Engine.Submit. It has its own frontier, workers and
+// results channel while sharing the engine's rate limiter, resource manager (cache,
+// in-flight slots, checkpoint), fetcher, processors, output sinks and telemetry.
+// Experimental: API may change before v1.0.
+type Job struct {
+	name      string
+	pl        *engpipeline.Pipeline
+	results   chan *engmodels.CrawlResult
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startedAt time.Time
+
+	mu         sync.Mutex
+	state      JobState
+	finishedAt time.Time
+	final      *engpipeline.PipelineMetrics
+	processed  int
+	failed     int
+}
+
+// Name returns the job name.
+func (j *Job) Name() string { return j.name }
+
+// Results returns the job's results channel, closed once every URL admitted to the
+// job produced a result or the job was cancelled.
+func (j *Job) Results() <-chan *engmodels.CrawlResult { return j.results }
+
+// Done is closed once the job finished and its workers stopped.
+func (j *Job) Done() <-chan struct{} { return j.done }
+
+// Cancel stops the job; URLs in flight are abandoned and Results closes. The results
+// not yet received are discarded.
+func (j *Job) Cancel() { j.cancel() }
+
+// Stats returns the job's current counters.
+func (j *Job) Stats() JobSnapshot {
+	j.mu.Lock()
+	state, finishedAt, m := j.state, j.finishedAt, j.final
+	processed, failed := j.processed, j.failed
+	j.mu.Unlock()
+	if m == nil {
+		m = j.pl.Metrics()
+	}
+	s := JobSnapshot{Name: j.name, State: state, StartedAt: j.startedAt, FinishedAt: finishedAt, Processed: processed, Failed: failed, Admitted: m.URLsAdmitted, Pending: m.URLsPending, Queued: m.URLsQueued}
+	for _, n := range m.URLsRejected {
+		s.Rejected += n
+	}
+	return s
+}
+
+// Submit starts a named crawl job next to the engine's main crawl (Start) and any other
+// jobs. Jobs are independent runs with their own de-duplication, depth and page
+// budgets; they don't use the disk-backed frontier, Resume, sitemap loading, the run
+// manifest or the dead-letter store. Pages still reach EngineStrategies.OutputSinks.
+// Cancelling ctx cancels the job. Stop cancels all running jobs.
+// Experimental: Signature may change before v1.0.
+func (e *Engine) Submit(ctx context.Context, spec JobSpec) (*Job, error) {
+	if e.stopped.Load() {
+		return nil, errors.New("submit: engine stopped")
+	}
+	if len(spec.Seeds) == 0 {
+		return nil, errors.New("submit: no seeds")
+	}
+	scopeCfg := e.cfg.Scope
+	if spec.Scope != nil {
+		scopeCfg = *spec.Scope
+	}
+	rules, err := scopeCfg.rules()
+	if err != nil {
+		return nil, fmt.Errorf("submit: %w", err)
+	}
+
+	e.jobsMu.Lock()
+	name := spec.Name
+	if name == "" {
+		name = "job-" + strconv.Itoa(e.jobSeq+1)
+	}
+	for _, other := range e.jobs {
+		if other.name == name && other.running() {
+			e.jobsMu.Unlock()
+			return nil, fmt.Errorf("submit: job %q is already running", name)
+		}
+	}
+	pc := *e.pl.Config()
+	pc.Frontier, pc.Checkpoint, pc.DeadLetters = nil, nil, nil
+	// The run manifest and the dead-letter file belong to the main crawl; a job writes
+	// to the injected sinks only, through a sink list of its own.
+	pc.OutputSinks = nil
+	for _, sink := range e.pl.Config().OutputSinks {
+		if rec, ok := sink.(*runs.Recorder); ok && rec == e.manifest {
+			continue
+		}
+		pc.OutputSinks = append(pc.OutputSinks, sink)
+	}
+	pc.MaxDepth, pc.MaxPages, pc.Scope = scopeCfg.MaxDepth, scopeCfg.MaxPages, rules
+	pc.ScopeRejected = func(rawURL, rule string) { e.reportScopeReject(name, rawURL, rule) }
+	if pc.Autoscale != nil {
+		as := *pc.Autoscale
+		as.OnScale = func(d engpipeline.ScaleDecision) { e.reportScale(name, d) }
+		pc.Autoscale = &as
+	}
+	jctx, cancel := context.WithCancel(ctx)
+	j := &Job{name: name, pl: engpipeline.NewPipeline(&pc), results: make(chan *engmodels.CrawlResult, max(pc.BufferSize, 1)), cancel: cancel, done: make(chan struct{}), startedAt: time.Now(), state: JobRunning}
+	e.jobSeq++
+	e.jobs = append(e.jobs, j)
+	e.pruneJobsLocked()
+	e.jobsMu.Unlock()
+
+	src := j.pl.ProcessURLs(jctx, spec.Seeds)
+	e.controlEvent("job_submitted", map[string]string{"job": name})
+	go e.runJob(jctx, j, src)
+	return j, nil
+}
+
+func (e *Engine) runJob(ctx context.Context, j *Job, src <-chan *engmodels.CrawlResult) {
+	defer close(j.done)
+	for r := range src {
+		j.mu.Lock()
+		if r.Success {
+			j.processed++
+		} else {
+			j.failed++
+		}
+		j.mu.Unlock()
+		select {
+		case j.results <- r:
+		case <-ctx.Done(): // cancelled: nobody is expected to read the rest
+		}
+	}
+	close(j.results)
+	j.pl.Stop()
+	state := JobCompleted
+	if !j.pl.Finished() {
+		state = JobCancelled
+	}
+	j.mu.Lock()
+	j.state, j.finishedAt, j.final = state, time.Now(), j.pl.Metrics()
+	j.mu.Unlock()
+	j.cancel()
+	e.controlEvent("job_finished", map[string]string{"job": j.name, "state": string(state)})
+}
+
+func (j *Job) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state == JobRunning
+}
+
+// pruneJobsLocked drops the oldest finished jobs beyond maxFinishedJobs.
+func (e *Engine) pruneJobsLocked() {
+	finished := 0
+	for _, j := range e.jobs {
+		if !j.running() {
+			finished++
+		}
+	}
+	if finished <= maxFinishedJobs {
+		return
+	}
+	kept := e.jobs[:0]
+	for _, j := range e.jobs {
+		if finished > maxFinishedJobs && !j.running() {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	clear(e.jobs[len(kept):])
+	e.jobs = kept
+}
+
+// Jobs returns the running jobs and the most recently finished ones, oldest first.
+// Experimental.
+func (e *Engine) Jobs() []*Job {
+	e.jobsMu.Lock()
+	defer e.jobsMu.Unlock()
+	return append([]*Job(nil), e.jobs...)
+}
+
+// stopJobs cancels every running job and waits for its workers to stop.
+func (e *Engine) stopJobs() {
+	for _, j := range e.Jobs() {
+		j.cancel()
+		<-j.done
+	}
+}
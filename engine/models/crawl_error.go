@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+)
+
+// ErrorCode is the stable classification of a CrawlError. Codes are part of the JSON
+// form and will not be renamed.
+// Experimental: New codes may be added before v1.0.
+type ErrorCode string
+
+// Error codes.
+// Experimental: New codes may be added before v1.0.
+const (
+	ErrorCodeDNS          ErrorCode = "dns"
+	ErrorCodeConnect      ErrorCode = "connect"
+	ErrorCodeTLS          ErrorCode = "tls"
+	ErrorCodeTimeout      ErrorCode = "timeout"
+	ErrorCodeHTTP4xx      ErrorCode = "http_4xx"
+	ErrorCodeHTTP5xx      ErrorCode = "http_5xx"
+	ErrorCodeRobotsDenied ErrorCode = "robots_denied"
+	ErrorCodeOutOfScope   ErrorCode = "out_of_scope"
+	ErrorCodeCircuitOpen  ErrorCode = "circuit_open"
+	ErrorCodeParse        ErrorCode = "parse"
+	ErrorCodeProcessing   ErrorCode = "processing"
+	ErrorCodeOutput       ErrorCode = "output"
+	ErrorCodeUnknown      ErrorCode = "unknown"
+)
+
+// codeSentinels maps codes to the sentinel error errors.Is matches for them.
+var codeSentinels = map[ErrorCode]error{
+	ErrorCodeDNS:          ErrHTTPError,
+	ErrorCodeConnect:      ErrHTTPError,
+	ErrorCodeTLS:          ErrHTTPError,
+	ErrorCodeTimeout:      ErrHTTPError,
+	ErrorCodeHTTP4xx:      ErrHTTPError,
+	ErrorCodeHTTP5xx:      ErrHTTPError,
+	ErrorCodeRobotsDenied: ErrURLNotAllowed,
+	ErrorCodeOutOfScope:   ErrURLNotAllowed,
+	ErrorCodeParse:        ErrHTMLParsingFailed,
+}
+
+// sentinelNames names the package sentinels recorded in the JSON form.
+var sentinelNames = []struct {
+	name string
+	err  error
+}{
+	{"ErrURLNotAllowed", ErrURLNotAllowed},
+	{"ErrMaxDepthExceeded", ErrMaxDepthExceeded},
+	{"ErrMaxPagesExceeded", ErrMaxPagesExceeded},
+	{"ErrContentNotFound", ErrContentNotFound},
+	{"ErrHTTPError", ErrHTTPError},
+	{"ErrHTMLParsingFailed", ErrHTMLParsingFailed},
+	{"ErrMarkdownConversion", ErrMarkdownConversion},
+	{"ErrAssetDownloadFailed", ErrAssetDownloadFailed},
+	{"ErrOutputDirCreation", ErrOutputDirCreation},
+	{"ErrFileWriteFailed", ErrFileWriteFailed},
+	{"ErrTemplateExecution", ErrTemplateExecution},
+}
+
+// Is matches the sentinel for e's code (e.g. ErrHTTPError for HTTP and network
+// failures) and, after a JSON round trip, the sentinels the original error matched.
+func (e *CrawlError) Is(target error) bool {
+	if s, ok := codeSentinels[e.Code]; ok && s == target {
+		return true
+	}
+	for _, s := range e.sentinels {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+type crawlErrorJSON struct {
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	URL        string    `json:"url,omitempty"`
+	Stage      string    `json:"stage,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	Attempts   int       `json:"attempts,omitempty"`
+	// Is names the package sentinel errors (e.g. "ErrContentNotFound") the error matches.
+	Is []string `json:"is,omitempty"`
+}
+
+// MarshalJSON encodes the classification and the error message.
+func (e *CrawlError) MarshalJSON() ([]byte, error) {
+	out := crawlErrorJSON{Code: e.Code, Message: e.Error(), URL: e.URL, Stage: e.Stage, StatusCode: e.StatusCode, Retryable: e.Retryable, Attempts: e.Attempts}
+	if out.Code == "" {
+		out.Code = ErrorCodeUnknown
+	}
+	for _, s := range sentinelNames {
+		if errors.Is(e, s.err) {
+			out.Is = append(out.Is, s.name)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON restores an error encoded by MarshalJSON. The cause is replaced by a
+// plain error carrying the original message.
+func (e *CrawlError) UnmarshalJSON(data []byte) error {
+	var in crawlErrorJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = CrawlError{URL: in.URL, Stage: in.Stage, Code: in.Code, StatusCode: in.StatusCode, Retryable: in.Retryable, Attempts: in.Attempts, Err: errors.New(in.Message)}
+	for _, name := range in.Is {
+		for _, s := range sentinelNames {
+			if s.name == name {
+				e.sentinels = append(e.sentinels, s.err)
+			}
+		}
+	}
+	return nil
+}
+
+// classify derives a code from the stage and standard library error types. The
+// pipeline refines fetch failures it has richer types for (HTTP status, robots,
+// scope, circuit breaker).
+func classify(stage string, err error) (ErrorCode, bool) {
+	switch stage {
+	case "robots":
+		return ErrorCodeRobotsDenied, false
+	case "scope":
+		return ErrorCodeOutOfScope, false
+	case "processing":
+		return ErrorCodeProcessing, false
+	case "output":
+		return ErrorCodeOutput, false
+	case "discovery":
+		return ErrorCodeParse, false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorCodeDNS, dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	if isTLSError(err) {
+		return ErrorCodeTLS, false
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorCodeTimeout, true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorCodeConnect, true
+	}
+	var urlErr *url.Error
+	if errors.Is(err, ErrHTMLParsingFailed) || (errors.As(err, &urlErr) && urlErr.Op == "parse") {
+		return ErrorCodeParse, false
+	}
+	return ErrorCodeUnknown, false
+}
+
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		headerErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostErr      x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &headerErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr)
+}
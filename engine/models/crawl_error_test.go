@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestCrawlResultErrorRoundTrip(t *testing.T) {
+	ce := NewCrawlError("https://x.test/a", "processing", fmt.Errorf("extract: %w", ErrContentNotFound))
+	ce.Attempts = 2
+	data, err := json.Marshal(&CrawlResult{URL: "https://x.test/a", Error: ce, Stage: "processing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back CrawlResult
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	got := back.Error
+	if got == nil || got.Code != ErrorCodeProcessing || got.Stage != "processing" || got.URL != "https://x.test/a" || got.Attempts != 2 || got.Error() != ce.Error() {
+		t.Fatalf("unexpected round trip: %s -> %+v", data, got)
+	}
+	if !errors.Is(got, ErrContentNotFound) || errors.Is(got, ErrHTTPError) {
+		t.Fatalf("errors.Is must match the original sentinels only")
+	}
+	again, _ := json.Marshal(&back)
+	if string(again) != string(data) {
+		t.Fatalf("second encoding differs:\n%s\n%s", data, again)
+	}
+}
+
+func TestNewCrawlErrorClassifies(t *testing.T) {
+	cases := []struct {
+		stage     string
+		err       error
+		code      ErrorCode
+		retryable bool
+	}{
+		{"extraction", &net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}, ErrorCodeDNS, false},
+		{"extraction", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ErrorCodeConnect, true},
+		{"extraction", fmt.Errorf("get: %w", &net.OpError{Op: "read", Err: timeoutErr{}}), ErrorCodeTimeout, true},
+		{"extraction", fmt.Errorf("%w: bad markup", ErrHTMLParsingFailed), ErrorCodeParse, false},
+		{"output", errors.New("disk full"), ErrorCodeOutput, false},
+		{"extraction", errors.New("odd"), ErrorCodeUnknown, false},
+	}
+	for _, c := range cases {
+		ce := NewCrawlError("https://x.test/", c.stage, c.err)
+		if ce.Code != c.code || ce.Retryable != c.retryable {
+			t.Errorf("%v: got %s/%v, want %s/%v", c.err, ce.Code, ce.Retryable, c.code, c.retryable)
+		}
+	}
+	if ce := NewCrawlError("https://x.test/", "extraction", &net.DNSError{Err: "timeout", IsTimeout: true}); !errors.Is(ce, ErrHTTPError) {
+		t.Fatalf("network failures must match ErrHTTPError")
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
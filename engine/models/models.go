@@ -23,10 +23,35 @@ type Page struct {
 	Metadata    PageMeta   `json:"metadata"`
 	CrawledAt   time.Time  `json:"crawled_at"`
 	ProcessedAt time.Time  `json:"processed_at"`
+	// Aliases lists other URLs that resolved to this page via rel=canonical.
+	Aliases []string `json:"aliases,omitempty"`
+	// ContentHash is a normalized hash ("sha256:<hex>") of CleanedText and Markdown set
+	// by the processing stage; equal hashes across runs mean the content did not change.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Quality is the processing stage's validation of the extracted content; nil when
+	// the page was not processed.
+	Quality *ContentQuality `json:"quality,omitempty"`
+	// Extracted is the record built from the extraction schema of the page's site
+	// (field name to string, float64, int64, bool, time.Time, []any or
+	// map[string]any); nil when no schema applies.
+	Extracted map[string]any `json:"extracted,omitempty"`
+}
+
+// ContentQuality scores extracted content from 0 to 1 and lists the issues found
+// (for example "missing_title", "content_too_short", "no_headings"). Extractor names
+// the strategy that located the content and Confidence (0 to 1) how sure it was.
+// SchemaErrors lists where Page.Extracted violates its schema.
+// Experimental: Scoring and issue names may change before v1.0.
+type ContentQuality struct {
+	Score        float64  `json:"score"`
+	Issues       []string `json:"issues,omitempty"`
+	Extractor    string   `json:"extractor,omitempty"`
+	Confidence   float64  `json:"confidence"`
+	SchemaErrors []string `json:"schema_errors,omitempty"`
 }
 
 // PageMeta contains structured metadata extracted from the page.
-// Experimental: Field set may expand (front‑matter, canonical URL) prior to v1.0.
+// Experimental: Field set may expand (front‑matter) prior to v1.0.
 type PageMeta struct {
 	Author      string            `json:"author,omitempty"`
 	Description string            `json:"description,omitempty"`
@@ -35,6 +60,20 @@ type PageMeta struct {
 	WordCount   int               `json:"word_count"`
 	Headers     map[string]string `json:"headers,omitempty"`
 	OpenGraph   OpenGraphMeta     `json:"open_graph,omitempty"`
+	// Canonical is the absolute <link rel="canonical"> URL declared by the page.
+	Canonical string `json:"canonical,omitempty"`
+	// ModifiedDate is when the page was last modified (UTC), from structured data,
+	// article/Open Graph properties or the Last-Modified header.
+	ModifiedDate time.Time `json:"modified_date,omitempty"`
+	// Language is the page language (for example "en-GB") from <html lang>,
+	// Content-Language or og:locale.
+	Language string `json:"language,omitempty"`
+	// Alternates are the <link rel="alternate" hreflang> translations of the page.
+	Alternates []AlternateLink `json:"alternates,omitempty"`
+	Twitter    TwitterCardMeta `json:"twitter,omitempty"`
+	// Structured holds the schema.org JSON-LD and microdata items of the page; nil
+	// when it has none.
+	Structured *StructuredData `json:"structured,omitempty"`
 }
 
 // OpenGraphMeta captures a subset of Open Graph tags.
@@ -45,17 +84,102 @@ type OpenGraphMeta struct {
 	Image       string `json:"image,omitempty"`
 	URL         string `json:"url,omitempty"`
 	Type        string `json:"type,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+}
+
+// TwitterCardMeta captures the twitter:* card tags.
+// Experimental: May merge with future SocialMeta struct.
+type TwitterCardMeta struct {
+	Card        string `json:"card,omitempty"`
+	Site        string `json:"site,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// AlternateLink is a translation of a page declared with hreflang.
+type AlternateLink struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// StructuredData holds the schema.org items of a page. Articles, Products, FAQ and
+// Breadcrumbs are typed views of the recognized items; Raw keeps every item as
+// decoded (JSON-LD objects as is, microdata items with "@type" and their properties).
+// Experimental: Typed views may grow before v1.0.
+type StructuredData struct {
+	Articles    []ArticleData    `json:"articles,omitempty"`
+	Products    []ProductData    `json:"products,omitempty"`
+	FAQ         []FAQEntry       `json:"faq,omitempty"`
+	Breadcrumbs []BreadcrumbList `json:"breadcrumbs,omitempty"`
+	Raw         []map[string]any `json:"raw,omitempty"`
+}
+
+// ArticleData is a schema.org Article (or NewsArticle, BlogPosting, ...).
+type ArticleData struct {
+	Type          string    `json:"type"`
+	Headline      string    `json:"headline,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Authors       []string  `json:"authors,omitempty"`
+	Publisher     string    `json:"publisher,omitempty"`
+	DatePublished time.Time `json:"date_published,omitempty"`
+	DateModified  time.Time `json:"date_modified,omitempty"`
+	Image         string    `json:"image,omitempty"`
+	URL           string    `json:"url,omitempty"`
+}
+
+// ProductData is a schema.org Product.
+type ProductData struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Brand       string      `json:"brand,omitempty"`
+	SKU         string      `json:"sku,omitempty"`
+	Image       string      `json:"image,omitempty"`
+	Offers      []OfferData `json:"offers,omitempty"`
+	RatingValue float64     `json:"rating_value,omitempty"`
+	ReviewCount int         `json:"review_count,omitempty"`
+}
+
+// OfferData is a schema.org Offer of a product.
+type OfferData struct {
+	Price         string `json:"price,omitempty"`
+	PriceCurrency string `json:"price_currency,omitempty"`
+	Availability  string `json:"availability,omitempty"`
+	URL           string `json:"url,omitempty"`
+}
+
+// FAQEntry is a question and accepted answer of a schema.org FAQPage.
+type FAQEntry struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+// BreadcrumbList is a schema.org BreadcrumbList, ordered by position.
+type BreadcrumbList struct {
+	Items []Breadcrumb `json:"items"`
+}
+
+// Breadcrumb is one entry of a BreadcrumbList.
+type Breadcrumb struct {
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
 }
 
 // CrawlResult represents the result of processing a single URL through the pipeline.
-// Experimental: Error and Stage fields may change shape (structured error) before v1.0.
+// Experimental: Stage values may change before v1.0.
 type CrawlResult struct {
-	URL     string `json:"url"`
-	Page    *Page  `json:"page"`
-	Error   error  `json:"error,omitempty"`
-	Stage   string `json:"stage"`
-	Success bool   `json:"success"`
-	Retry   bool   `json:"retry"`
+	URL     string      `json:"url"`
+	Page    *Page       `json:"page"`
+	Error   *CrawlError `json:"error,omitempty"`
+	Stage   string      `json:"stage"`
+	Success bool        `json:"success"`
+	Retry   bool        `json:"retry"`
+	// Unchanged marks a page revalidated with 304 Not Modified whose Page is the
+	// previously processed copy.
+	Unchanged bool `json:"unchanged,omitempty"`
 }
 
 // CrawlStats aggregates crawl progress metrics.
@@ -98,6 +222,12 @@ type RateLimitConfig struct {
 	StatsBucket    time.Duration `json:"stats_bucket"`
 	DomainStateTTL time.Duration `json:"domain_state_ttl"`
 	Shards         int           `json:"shards"`
+
+	// StatePath, when set, persists learned per-domain state (fill rate, latency,
+	// error window, circuit state) across runs: loaded on engine construction and
+	// saved on Stop. Entries idle for longer than StateMaxAge (default 24h) are ignored.
+	StatePath   string        `json:"state_path"`
+	StateMaxAge time.Duration `json:"state_max_age"`
 }
 
 // ScraperConfig holds crawler configuration formerly defined in legacy pkg/models.
@@ -218,16 +348,39 @@ var (
 	ErrTemplateExecution     = errors.New("failed to execute template")
 )
 
-// CrawlError wraps a stage-specific error with page context.
-// Experimental: May gain more structured classification fields.
+// CrawlError wraps a stage-specific error with page context and a stable
+// classification (see ErrorCode). It marshals to JSON as an object and unmarshals back
+// into an equivalent error: the cause becomes its message and errors.Is keeps matching
+// the package sentinel errors the original matched.
+// Experimental: Field set may change before v1.0.
 type CrawlError struct {
 	URL   string
 	Stage string
-	Err   error
+	Code  ErrorCode
+	// StatusCode is the HTTP status for ErrorCodeHTTP4xx / ErrorCodeHTTP5xx.
+	StatusCode int
+	// Retryable reports whether the failure is transient (timeouts, connection
+	// failures, 408/429/5xx, open circuits), independent of retries left.
+	Retryable bool
+	// Attempts is the number of fetch attempts made before giving up.
+	Attempts int
+	Err      error
+
+	// sentinels lists sentinel errors matched before a JSON round trip.
+	sentinels []error
 }
 
-func (e *CrawlError) Error() string { return e.Err.Error() }
+func (e *CrawlError) Error() string {
+	if e.Err == nil {
+		return string(e.Code)
+	}
+	return e.Err.Error()
+}
 func (e *CrawlError) Unwrap() error { return e.Err }
+
+// NewCrawlError wraps err, classifying it from the stage and standard library error
+// types (DNS, connection, TLS and timeout failures).
 func NewCrawlError(url, stage string, err error) *CrawlError {
-	return &CrawlError{URL: url, Stage: stage, Err: err}
+	code, retryable := classify(stage, err)
+	return &CrawlError{URL: url, Stage: stage, Code: code, Retryable: retryable, Err: err}
 }
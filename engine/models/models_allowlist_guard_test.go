@@ -15,7 +15,9 @@ import (
 // Adjust deliberately (Wave 3) and update CHANGELOG + API report if changed.
 func TestModelsExportAllowlist(t *testing.T) {
     allowed := map[string]struct{}{
-        "Page": {}, "PageMeta": {}, "OpenGraphMeta": {},
+        "Page": {}, "PageMeta": {}, "OpenGraphMeta": {}, "ContentQuality": {},
+        "TwitterCardMeta": {}, "AlternateLink": {}, "StructuredData": {}, "ArticleData": {}, "ProductData": {},
+        "OfferData": {}, "FAQEntry": {}, "BreadcrumbList": {}, "Breadcrumb": {},
         "CrawlResult": {}, "CrawlStats": {}, "RateLimitConfig": {},
         "ScraperConfig": {}, "DefaultConfig": {},
         "ErrMissingStartURL": {}, "ErrMissingAllowedDomains": {}, "ErrInvalidMaxDepth": {},
@@ -24,6 +26,9 @@ func TestModelsExportAllowlist(t *testing.T) {
         "ErrMarkdownConversion": {}, "ErrAssetDownloadFailed": {}, "ErrOutputDirCreation": {},
         "ErrFileWriteFailed": {}, "ErrTemplateExecution": {},
         "CrawlError": {}, "NewCrawlError": {},
+        "ErrorCode": {}, "ErrorCodeDNS": {}, "ErrorCodeConnect": {}, "ErrorCodeTLS": {}, "ErrorCodeTimeout": {},
+        "ErrorCodeHTTP4xx": {}, "ErrorCodeHTTP5xx": {}, "ErrorCodeRobotsDenied": {}, "ErrorCodeOutOfScope": {},
+        "ErrorCodeCircuitOpen": {}, "ErrorCodeParse": {}, "ErrorCodeProcessing": {}, "ErrorCodeOutput": {}, "ErrorCodeUnknown": {},
     }
     _, fname, _, _ := runtime.Caller(0)
     dir := filepath.Dir(fname)
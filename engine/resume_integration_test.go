@@ -2,9 +2,15 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/99souls/ariadne/engine/internal/testutil/httpmock"
 )
 
 func TestEngineResumeFiltering(t *testing.T) {
@@ -15,7 +21,10 @@ func TestEngineResumeFiltering(t *testing.T) {
 	defer func() { _ = os.Remove(tmpFile.Name()) }()
 	defer func() { _ = tmpFile.Close() }()
 
-	seedAll := []string{"https://a.example/1", "https://a.example/2", "https://a.example/3"}
+	srv := httpmock.NewServer([]httpmock.RouteSpec{{Pattern: "/", MatchPrefix: true, Body: "<html><body>ok</body></html>"}})
+	defer srv.Close()
+
+	seedAll := []string{srv.URL() + "/1", srv.URL() + "/2", srv.URL() + "/3"}
 	// Pretend first two already processed
 	if _, err := tmpFile.WriteString(seedAll[0] + "\n" + seedAll[1] + "\n"); err != nil {
 		t.Fatalf("write checkpoint: %v", err)
@@ -54,3 +63,97 @@ func TestEngineResumeFiltering(t *testing.T) {
 		t.Fatalf("unexpected resume snapshot: %+v", snap.Resume)
 	}
 }
+
+func TestEngineResumeRebuildsFrontierAfterCrash(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`)
+			return
+		}
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+		_, _ = fmt.Fprint(w, `<a href="/">home</a>`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	config := func(path string, resume bool) Config {
+		cfg := Defaults()
+		cfg.RateLimit.Enabled = false
+		cfg.Robots.Enabled = false
+		cfg.Scope.MaxDepth = 1
+		cfg.ExtractionWorkers = 1
+		cfg.Resources.MaxInFlight = 0
+		cfg.CheckpointPath = path
+		cfg.Resume = resume
+		return cfg
+	}
+
+	// First run: the seed finishes, the first link fetched hangs in flight and the other
+	// two stay queued behind it.
+	crashed, err := New(config(filepath.Join(dir, "run1.log"), false))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = crashed.Stop() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := crashed.Start(ctx, []string{srv.URL + "/"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for {
+		snap := crashed.Snapshot()
+		if cp := snap.Checkpoint; cp != nil && cp.Succeeded == 1 && cp.InFlight == 1 && cp.Queued == 2 && snap.Resources.CheckpointQueued == 0 {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("checkpoint never reached the expected state: %+v", snap.Checkpoint)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Crash: take the log as synced so far, without stopping the engine.
+	data, err := os.ReadFile(filepath.Join(dir, "run1.log"))
+	if err != nil {
+		t.Fatalf("read checkpoint: %v", err)
+	}
+	resumePath := filepath.Join(dir, "run2.log")
+	if err := os.WriteFile(resumePath, data, 0o644); err != nil {
+		t.Fatalf("write checkpoint: %v", err)
+	}
+
+	close(block)
+	eng, err := New(config(resumePath, true))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	results, err := eng.Start(ctx, []string{srv.URL + "/"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	got := map[string]bool{}
+	for r := range results {
+		got[r.URL] = r.Success
+	}
+	if len(got) != 3 || !got[srv.URL+"/a"] || !got[srv.URL+"/b"] || !got[srv.URL+"/c"] {
+		t.Fatalf("expected only the in-flight and queued URLs, got %v", got)
+	}
+	snap := eng.Snapshot()
+	if r := snap.Resume; r == nil || r.Skipped != 1 || r.Finished != 1 || r.Requeued != 3 {
+		t.Fatalf("unexpected resume snapshot: %+v", snap.Resume)
+	}
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	// The compacted log of the finished crawl resumes to nothing.
+	eng, err = New(config(resumePath, true))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer func() { _ = eng.Stop() }()
+	if cp := eng.Snapshot().Checkpoint; cp == nil || cp.Succeeded != 4 || cp.Queued+cp.InFlight != 0 || cp.Records != 4 {
+		t.Fatalf("unexpected checkpoint after a completed run: %+v", cp)
+	}
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/99souls/ariadne/engine/internal/runs"
+)
+
+// RunDiff reports how the pages of two recorded runs differ. Pages are matched by URL
+// and compared by content hash; each list is sorted by URL.
+// Experimental: Field set may change before v1.0.
+type RunDiff struct {
+	OldRun    string       `json:"old_run"`
+	NewRun    string       `json:"new_run"`
+	Added     []PageChange `json:"added"`
+	Removed   []PageChange `json:"removed"`
+	Modified  []PageChange `json:"modified"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// PageChange is one added, removed or modified page.
+// Experimental: Field set may change before v1.0.
+type PageChange struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	OldHash string `json:"old_hash,omitempty"`
+	NewHash string `json:"new_hash,omitempty"`
+	// Diff is the unified diff of the page markdown (modified pages only).
+	Diff string `json:"diff,omitempty"`
+}
+
+// RunDiffOptions tunes DiffRuns.
+// Experimental.
+type RunDiffOptions struct {
+	// Context is the number of unchanged lines shown around each change; zero shows
+	// none and a negative value selects the default of 3.
+	Context int
+}
+
+// DiffRuns compares two run directories recorded with Config.Manifest (see
+// ManifestSnapshot.Dir).
+// Experimental: May move to a dedicated reporting package.
+func DiffRuns(oldRunDir, newRunDir string, opts RunDiffOptions) (*RunDiff, error) {
+	if opts.Context < 0 {
+		opts.Context = 3
+	}
+	before, err := runs.Load(oldRunDir)
+	if err != nil {
+		return nil, err
+	}
+	after, err := runs.Load(newRunDir)
+	if err != nil {
+		return nil, err
+	}
+	d, err := runs.Compare(before, after, opts.Context)
+	if err != nil {
+		return nil, err
+	}
+	return &RunDiff{OldRun: d.OldRun, NewRun: d.NewRun, Added: pageChanges(d.Added), Removed: pageChanges(d.Removed), Modified: pageChanges(d.Modified), Unchanged: d.Unchanged}, nil
+}
+
+func pageChanges(in []runs.Change) []PageChange {
+	out := make([]PageChange, 0, len(in))
+	for _, c := range in {
+		out = append(out, PageChange{URL: c.URL, Title: c.Title, OldHash: c.OldHash, NewHash: c.NewHash, Diff: c.Diff})
+	}
+	return out
+}
+
+// Empty reports whether the runs hold the same pages with the same content.
+func (d *RunDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Markdown renders the diff as a Markdown report suitable for change notifications.
+func (d *RunDiff) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Crawl changes: %s → %s\n\n", d.OldRun, d.NewRun)
+	fmt.Fprintf(&sb, "%d added, %d removed, %d modified, %d unchanged.\n", len(d.Added), len(d.Removed), len(d.Modified), d.Unchanged)
+	list := func(heading string, changes []PageChange) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", heading)
+		for _, c := range changes {
+			fmt.Fprintf(&sb, "- %s\n", markdownLink(c))
+		}
+	}
+	list("Added", d.Added)
+	list("Removed", d.Removed)
+	if len(d.Modified) > 0 {
+		sb.WriteString("\n## Modified\n")
+		for _, c := range d.Modified {
+			fmt.Fprintf(&sb, "\n### %s\n\n", markdownLink(c))
+			if c.Diff == "" {
+				sb.WriteString("_Content hash changed; no textual difference in the stored markdown._\n")
+				continue
+			}
+			fence := codeFence(c.Diff)
+			fmt.Fprintf(&sb, "%sdiff\n%s%s\n", fence, c.Diff, fence)
+		}
+	}
+	return sb.String()
+}
+
+func markdownLink(c PageChange) string {
+	title := strings.TrimSpace(c.Title)
+	if title == "" {
+		title = c.URL
+	}
+	title = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title)
+	return fmt.Sprintf("[%s](<%s>)", title, c.URL)
+}
+
+// codeFence returns a backtick fence longer than any backtick run in body.
+func codeFence(body string) string {
+	longest, run := 0, 0
+	for _, r := range body {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}